@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Inspect or clear the data cache",
+	Long: `Inspect or clear the data cache.
+
+The cache lives in each process. With --cache-file, static controller info
+(model, firmware, PCI address) is also kept in that file between runs until
+the next reboot; drive rosters and state are always refetched. These
+commands operate on the persisted cache, so they need --cache-file.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "List cached keys with their age and remaining TTL",
+	Run:   runCacheStats,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear [prefix]",
+	Short: "Delete cached keys (all, or those starting with prefix)",
+	Long: `Delete cached keys from the persisted cache, forcing a refetch on the
+next run.
+
+Examples:
+  jbodgod --cache-file /var/lib/jbodgod/cache cache clear          # Delete everything
+  jbodgod --cache-file /var/lib/jbodgod/cache cache clear storcli: # Delete cached storcli info`,
+	Args: cobra.MaximumNArgs(1),
+	Run:  runCacheClear,
+}
+
+func init() {
+	cacheStatsCmd.Flags().Bool("json", false, "Output as JSON")
+
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+}
+
+// CacheKeyStats describes one cache entry for 'cache stats'
+type CacheKeyStats struct {
+	Key          string    `json:"key"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	AgeSeconds   int64     `json:"age_seconds"`
+	TTLRemaining int64     `json:"ttl_remaining_seconds"` // negative once expired
+}
+
+func runCacheStats(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+
+	c := cache.Global()
+	keys := c.Keys()
+	sort.Strings(keys)
+
+	stats := make([]CacheKeyStats, 0, len(keys))
+	for _, k := range keys {
+		entry := c.GetEntry(k)
+		if entry == nil {
+			continue
+		}
+		stats = append(stats, CacheKeyStats{
+			Key:          k,
+			FetchedAt:    entry.FetchedAt,
+			ExpiresAt:    entry.ExpiresAt,
+			AgeSeconds:   int64(entry.Age().Seconds()),
+			TTLRemaining: int64(time.Until(entry.ExpiresAt).Seconds()),
+		})
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(stats)
+		return
+	}
+
+	if len(stats) == 0 {
+		if cacheFile == "" {
+			fmt.Println("Cache is empty (persistence is off; pass --cache-file to enable it)")
+		} else {
+			fmt.Printf("Cache is empty (%s)\n", cacheFile)
+		}
+		return
+	}
+
+	fmt.Printf("%-50s %-10s %s\n", "KEY", "AGE", "TTL LEFT")
+	fmt.Println(strings.Repeat("-", 75))
+	for _, s := range stats {
+		ttl := "expired"
+		if s.TTLRemaining > 0 {
+			ttl = (time.Duration(s.TTLRemaining) * time.Second).String()
+		}
+		fmt.Printf("%-50s %-10s %s\n", s.Key, (time.Duration(s.AgeSeconds) * time.Second).String(), ttl)
+	}
+	fmt.Printf("\nTotal: %d keys\n", len(stats))
+}
+
+func runCacheClear(cmd *cobra.Command, args []string) {
+	prefix := ""
+	if len(args) > 0 {
+		prefix = args[0]
+	}
+
+	n := cache.Global().DeletePrefix(prefix)
+
+	// Flush now rather than in PersistentPostRun so a failure is reported
+	if err := cache.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if prefix == "" {
+		fmt.Printf("Deleted %d cached key(s)\n", n)
+	} else {
+		fmt.Printf("Deleted %d cached key(s) starting with '%s'\n", n, prefix)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/db"
+	"github.com/spf13/cobra"
+)
+
+// defaultCMDBColumns is the column set for 'inventory cmdb-export'
+const defaultCMDBColumns = "asset_tag,serial,model,location,pool,state,last_seen"
+
+var inventoryCMDBExportCmd = &cobra.Command{
+	Use:   "cmdb-export",
+	Short: "Export drives as CSV keyed on asset tag for CMDB import",
+	Long: `Export the inventory as CSV for a CMDB, keyed on asset tag.
+
+The asset tag comes from the drive's metadata (the asset_tag column when
+seeding with 'inventory seed'). Only present drives (active or standby) are
+exported unless --all is given. Rows are sorted by asset tag, then serial.
+
+Columns (--columns, comma-separated):
+  asset_tag, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
+  location (enclosure:slot), enclosure, slot, device, wwn, pool, state,
+  first_seen, last_seen, last_smart_ok, purchase_date, warranty_expires
+
+Any other column name is looked up in the drive's metadata.
+
+Examples:
+  jbodgod inventory cmdb-export > cmdb.csv
+  jbodgod inventory cmdb-export --all --columns asset_tag,serial,state,supplier`,
+	Run: runInventoryCMDBExport,
+}
+
+func init() {
+	inventoryCMDBExportCmd.Flags().String("columns", defaultCMDBColumns, "Comma-separated columns to export")
+	inventoryCMDBExportCmd.Flags().Bool("all", false, "Include missing, failed and not-yet-installed drives")
+	inventoryCmd.AddCommand(inventoryCMDBExportCmd)
+}
+
+func runInventoryCMDBExport(cmd *cobra.Command, args []string) {
+	columnsFlag, _ := cmd.Flags().GetString("columns")
+	all, _ := cmd.Flags().GetBool("all")
+
+	var columns []string
+	for _, c := range strings.Split(columnsFlag, ",") {
+		if c = strings.ToLower(strings.TrimSpace(c)); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	if len(columns) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: no columns specified\n")
+		os.Exit(1)
+	}
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	drives, err := database.GetAllDrives()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying drives: %v\n", err)
+		os.Exit(1)
+	}
+
+	var rows []*db.DriveRecord
+	for _, d := range drives {
+		if all || d.CurrentState == db.StateActive || d.CurrentState == db.StateStandby {
+			rows = append(rows, d)
+		}
+	}
+
+	// Drives without an asset tag sort last so the CMDB key column leads
+	sort.SliceStable(rows, func(i, j int) bool {
+		ti, tj := rows[i].Metadata["asset_tag"], rows[j].Metadata["asset_tag"]
+		if (ti == "") != (tj == "") {
+			return tj == ""
+		}
+		if ti != tj {
+			return ti < tj
+		}
+		return rows[i].Serial < rows[j].Serial
+	})
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write(columns)
+	for _, d := range rows {
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = cmdbColumnValue(d, col)
+		}
+		w.Write(record)
+	}
+	w.Flush()
+
+	if err := w.Error(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// cmdbColumnValue returns a drive's value for an export column, falling back
+// to metadata for unrecognised names
+func cmdbColumnValue(d *db.DriveRecord, column string) string {
+	formatTime := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return t.UTC().Format(time.RFC3339)
+	}
+
+	switch column {
+	case "serial":
+		return d.Serial
+	case "serial_vpd":
+		return d.SerialVPD
+	case "model":
+		return d.Model
+	case "manufacturer":
+		return d.Manufacturer
+	case "firmware":
+		return d.Firmware
+	case "size_bytes":
+		if d.SizeBytes == 0 {
+			return ""
+		}
+		return strconv.FormatInt(d.SizeBytes, 10)
+	case "location":
+		if d.EnclosureID != nil && d.Slot != nil {
+			return fmt.Sprintf("%d:%d", *d.EnclosureID, *d.Slot)
+		}
+		return ""
+	case "enclosure":
+		if d.EnclosureID != nil {
+			return strconv.Itoa(*d.EnclosureID)
+		}
+		return ""
+	case "slot":
+		if d.Slot != nil {
+			return strconv.Itoa(*d.Slot)
+		}
+		return ""
+	case "device":
+		return d.DevicePath
+	case "wwn":
+		return d.WWN
+	case "pool":
+		return d.ZpoolName
+	case "state":
+		return d.CurrentState
+	case "first_seen":
+		return formatTime(d.FirstSeen)
+	case "last_seen":
+		return formatTime(d.LastSeen)
+	case "last_smart_ok":
+		if d.LastSmartOK != nil {
+			return formatTime(*d.LastSmartOK)
+		}
+		return ""
+	case "purchase_date":
+		return d.PurchaseDate
+	case "warranty_expires":
+		return d.WarrantyExpires
+	default:
+		return d.Metadata[column]
+	}
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/sigreer/jbodgod/internal/collector"
+	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/zfs"
+	"github.com/spf13/cobra"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Config file tools",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file for mistakes",
+	Long: `Load the config file (--config, or the first of /etc/jbodgod/config.yaml,
+~/.config/jbodgod/config.yaml and ./config.yaml) and check it without
+discovering drives:
+
+  - the YAML parses and has no unknown keys (typos are otherwise ignored)
+  - statically configured drive devices exist and are block devices
+  - thresholds, layouts, self-test hours and alert settings are sane
+  - drive names, devices and serials aren't duplicated
+  - enclosures under 'expected' are present
+  - pools under 'critical_pools' are imported
+
+Errors make the command exit 1; warnings don't.
+
+Examples:
+  jbodgod config validate
+  jbodgod --config ./config.yaml config validate --json`,
+	Args: cobra.NoArgs,
+	Run:  runConfigValidate,
+}
+
+func init() {
+	configValidateCmd.Flags().Bool("json", false, "Output as JSON")
+	configCmd.AddCommand(configValidateCmd)
+}
+
+func runConfigValidate(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+
+	v, err := config.Validate(cfgFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Expected enclosures and critical pools are checked against the live
+	// system
+	if len(v.Errors) == 0 {
+		cfg, err := config.LoadFile(v.Path)
+		if err == nil && len(cfg.Expected) > 0 {
+			enclosures := collector.CollectSysfsEnclosures()
+			for _, exp := range cfg.Expected {
+				found := false
+				for _, e := range enclosures {
+					if e.HCTL == exp.Enclosure || normalizeEnclosureID(e.ID) == normalizeEnclosureID(exp.Enclosure) {
+						found = true
+						break
+					}
+				}
+				if !found {
+					v.Warnings = append(v.Warnings, fmt.Sprintf("expected enclosure %s not found in /sys/class/enclosure", exp.Enclosure))
+				}
+			}
+		}
+		if err == nil && len(cfg.CriticalPools) > 0 {
+			// A typo here silently drops the failmode=wait check
+			if pools, err := zfs.ListPools(); err == nil {
+				imported := make(map[string]bool, len(pools))
+				for _, p := range pools {
+					imported[p] = true
+				}
+				for _, pool := range cfg.CriticalPools {
+					if !imported[pool] {
+						v.Warnings = append(v.Warnings, fmt.Sprintf("critical_pools: pool %s is not imported", pool))
+					}
+				}
+			}
+		}
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(v)
+	} else {
+		fmt.Printf("Config: %s\n", v.Path)
+		for _, e := range v.Errors {
+			fmt.Printf("  ✗ %s\n", e)
+		}
+		for _, w := range v.Warnings {
+			fmt.Printf("  ⚠ %s\n", w)
+		}
+		if len(v.Errors) == 0 && len(v.Warnings) == 0 {
+			fmt.Println("  ✓ no problems found")
+		}
+		fmt.Printf("\n%d error(s), %d warning(s)\n", len(v.Errors), len(v.Warnings))
+	}
+
+	if len(v.Errors) > 0 {
+		os.Exit(1)
+	}
+}
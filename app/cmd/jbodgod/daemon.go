@@ -0,0 +1,284 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/db"
+	"github.com/sigreer/jbodgod/internal/drive"
+	"github.com/spf13/cobra"
+)
+
+var daemonCmd = &cobra.Command{
+	Use:   "daemon",
+	Short: "Run sync, healthcheck and temperature sampling on a schedule",
+	Long: `Run as a long-lived process (e.g. a systemd service) instead of driving
+jbodgod from cron. On the intervals under 'daemon' in config it:
+  - syncs the inventory (sync_interval, default 1h; after the first sync
+    only drives that changed are touched)
+  - runs the healthcheck, saving alerts and notifying new critical ones
+    (healthcheck_interval, default 5m)
+  - records a temperature sample for each active drive (temp_interval,
+    default 5m)
+  - runs the self-test scheduler, as 'selftest run' does
+    (selftest_interval, default 1h)
+and serves the latest results as Prometheus metrics on /metrics
+(daemon.listen or --listen, default :9586; "off" disables it).
+
+The sync and healthcheck also run once at startup (the sync records
+temperatures too). SIGHUP reloads the config (intervals, listen
+address, thresholds, alert settings); the database path is only read at
+startup. SIGTERM or SIGINT stops the daemon once the running job finishes.`,
+	Run: runDaemon,
+}
+
+func init() {
+	daemonCmd.Flags().String("listen", "", `Address of the /metrics endpoint, overriding daemon.listen ("off" to disable)`)
+}
+
+// daemonState holds the latest result of each job for /metrics
+type daemonState struct {
+	mu         sync.Mutex
+	lastHealth *HealthcheckResult
+	lastSync   *db.SyncRun
+	tempAt     time.Time
+}
+
+// daemon runs the periodic jobs against one database
+type daemon struct {
+	database   *db.DB
+	cfg        *config.Config
+	listenFlag string
+	state      daemonState
+	server     *http.Server
+	listenAddr string
+}
+
+func runDaemon(cmd *cobra.Command, args []string) {
+	listenFlag, _ := cmd.Flags().GetString("listen")
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	d := &daemon{database: database, listenFlag: listenFlag}
+	if err := d.apply(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer d.stopServer()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	syncTicker := time.NewTicker(cfg.Daemon.SyncInterval)
+	defer syncTicker.Stop()
+	healthTicker := time.NewTicker(cfg.Daemon.HealthcheckInterval)
+	defer healthTicker.Stop()
+	tempTicker := time.NewTicker(cfg.Daemon.TempInterval)
+	defer tempTicker.Stop()
+	selfTestTicker := time.NewTicker(cfg.Daemon.SelfTestInterval)
+	defer selfTestTicker.Stop()
+
+	fmt.Printf("jbodgod daemon started (sync every %s, healthcheck every %s, temperatures every %s, self-tests every %s)\n",
+		cfg.Daemon.SyncInterval, cfg.Daemon.HealthcheckInterval, cfg.Daemon.TempInterval, cfg.Daemon.SelfTestInterval)
+
+	d.runSync(false)
+	d.runHealthcheck()
+	cache.Flush()
+
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Println("jbodgod daemon stopping")
+			return
+
+		case <-hup:
+			newCfg, err := config.Load(cfgFile)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: config reload failed, keeping the current config: %v\n", err)
+				continue
+			}
+			if err := d.apply(newCfg); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+			syncTicker.Reset(newCfg.Daemon.SyncInterval)
+			healthTicker.Reset(newCfg.Daemon.HealthcheckInterval)
+			tempTicker.Reset(newCfg.Daemon.TempInterval)
+			selfTestTicker.Reset(newCfg.Daemon.SelfTestInterval)
+			fmt.Println("Config reloaded")
+
+		case <-syncTicker.C:
+			d.runSync(true)
+		case <-healthTicker.C:
+			d.runHealthcheck()
+		case <-tempTicker.C:
+			d.runTemps()
+		case <-selfTestTicker.C:
+			d.runSelfTests()
+		}
+
+		// Persist cache between jobs so a restart starts warm
+		cache.Flush()
+	}
+}
+
+// apply switches the daemon to a (re)loaded config, moving the metrics
+// endpoint if its address changed. On error the old endpoint keeps running.
+func (d *daemon) apply(cfg *config.Config) error {
+	d.cfg = cfg
+
+	addr := cfg.Daemon.Listen
+	if d.listenFlag != "" {
+		addr = d.listenFlag
+	}
+	if addr == "off" {
+		d.stopServer()
+		return nil
+	}
+	if d.server != nil && addr == d.listenAddr {
+		return nil
+	}
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	d.stopServer()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", d.serveMetrics)
+	d.server = &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	d.listenAddr = addr
+	go func(srv *http.Server) {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintf(os.Stderr, "Warning: metrics endpoint: %v\n", err)
+		}
+	}(d.server)
+
+	fmt.Printf("Serving metrics on %s/metrics\n", addr)
+	return nil
+}
+
+// stopServer shuts the metrics endpoint down, if it's running
+func (d *daemon) stopServer() {
+	if d.server == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	d.server.Shutdown(ctx)
+	d.server = nil
+	d.listenAddr = ""
+}
+
+func (d *daemon) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	d.state.mu.Lock()
+	defer d.state.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	writeMetrics(w, d.state.lastHealth, d.state.lastSync, d.state.tempAt)
+}
+
+// runSync syncs the inventory; changedOnly skips drives that haven't changed
+func (d *daemon) runSync(changedOnly bool) {
+	run := syncInventory(d.database, d.cfg, changedOnly, false)
+	fmt.Printf("Sync complete: %d created, %d updated, %d unchanged, %d marked missing\n",
+		run.Created, run.Updated, run.Unchanged, run.Missing)
+
+	d.state.mu.Lock()
+	d.state.lastSync = run
+	d.state.mu.Unlock()
+}
+
+// runHealthcheck runs the healthcheck, which saves its alerts and notifies
+// new critical ones
+func (d *daemon) runHealthcheck() {
+	result := performHealthcheck(healthcheckOptions{
+		tempWarn:   d.cfg.Thresholds.WarningTemp,
+		tempCrit:   d.cfg.Thresholds.CriticalTemp,
+		smartStale: 7 * 24 * time.Hour,
+	}, d.database)
+	fmt.Println(healthcheckSummaryLine(result))
+
+	d.state.mu.Lock()
+	d.state.lastHealth = result
+	d.state.mu.Unlock()
+}
+
+// runTemps records a temperature sample for each active inventory drive
+func (d *daemon) runTemps() {
+	n, err := recordTempSamples(d.database, d.cfg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return
+	}
+	if n == 0 {
+		return
+	}
+
+	d.state.mu.Lock()
+	d.state.tempAt = time.Now()
+	d.state.mu.Unlock()
+}
+
+// runSelfTests records finished self-tests and starts tests on due drives
+func (d *daemon) runSelfTests() {
+	if err := runSelfTestScheduler(d.cfg, d.database, false, false); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+}
+
+// recordTempSamples stores the current temperature of each active drive that
+// is in the inventory (standby drives are not woken) and returns how many
+// were recorded
+func recordTempSamples(database *db.DB, cfg *config.Config) (int, error) {
+	temps := activeTempsBySerial(drive.GetAll(cfg))
+	if len(temps) == 0 {
+		return 0, nil
+	}
+
+	records, err := database.GetAllDrives()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read inventory: %w", err)
+	}
+
+	n := 0
+	for _, rec := range records {
+		temp, ok := temps[strings.ToUpper(rec.Serial)]
+		if !ok && rec.SerialVPD != "" {
+			temp, ok = temps[strings.ToUpper(rec.SerialVPD)]
+		}
+		if !ok {
+			continue
+		}
+		if err := database.RecordTemperature(rec.ID, temp); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
@@ -2,12 +2,18 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sigreer/jbodgod/internal/collector"
 	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/humanize"
+	"github.com/sigreer/jbodgod/internal/identify"
+	"github.com/sigreer/jbodgod/internal/ses"
 	"github.com/spf13/cobra"
 )
 
@@ -21,16 +27,37 @@ Controller queries:
   detail c0 temperature    - Get controller temperature
   detail c0 devices        - List attached devices
   detail c0 enclosures     - List attached enclosures
+  detail c0 phy            - PHY link rates and error counters (invalid dwords
+                             usually mean a failing cable)
+  detail c0 events         - Recent controller events (storcli event log, or
+                             the mpt3sas kernel messages for IT-mode HBAs);
+                             --count sets how many (default 50)
+
+All controllers (multipath shelves and drives listed once, with their paths):
+  detail devices           - List devices across all controllers
+  detail enclosures        - List enclosures across all controllers
+  detail all devices       - Same as 'detail devices' (also 'all enclosures')
+  detail c0 devices --all-controllers
+                           - Same as 'detail devices'
 
 Device queries:
   detail 2:5               - Show device at enclosure 2, slot 5
   detail e2:5              - Same as above (e prefix optional)
-  detail serial:ZA1DKJT7   - Look up device by serial number
+  detail c1:2:5            - Enclosure 2, slot 5 as numbered by controller c1
+                             (needed when two HBAs report the same enclosure)
+  detail 2:5 label         - Enclosure's own bay label (from SES descriptors)
+  detail serial:ZA1DKJT7   - Look up device by serial number, with its SMART
+                             rotation rate and self-test log (active drives only)
+  detail serial:ZA1DKJT7 rpm
+                           - Rotation rate ("Solid State" for SSDs)
+  detail /dev/sdb          - Look up device by path (including /dev/disk/by-*)
+  detail wwn:5000c500d0068 - Look up device by WWN (or just 0x5000c500d0068...)
 
 Examples:
   jbodgod detail c0
   jbodgod detail c0 temp
   jbodgod detail 2:5
+  jbodgod detail all devices
   jbodgod detail c0 --json`,
 	Args: cobra.RangeArgs(1, 2),
 	Run:  runDetail,
@@ -40,6 +67,8 @@ func init() {
 	detailCmd.Flags().Bool("raw", false, "Output raw value only (no formatting)")
 	detailCmd.Flags().Bool("json", false, "Output as JSON")
 	detailCmd.Flags().Bool("refresh", false, "Force refresh cached data")
+	detailCmd.Flags().Bool("all-controllers", false, "List devices or enclosures across all controllers instead of one")
+	detailCmd.Flags().Int("count", 50, "Number of recent events to show (events query)")
 }
 
 func runDetail(cmd *cobra.Command, args []string) {
@@ -52,13 +81,45 @@ func runDetail(cmd *cobra.Command, args []string) {
 	raw, _ := cmd.Flags().GetBool("raw")
 	jsonOut, _ := cmd.Flags().GetBool("json")
 	refresh, _ := cmd.Flags().GetBool("refresh")
+	allControllers, _ := cmd.Flags().GetBool("all-controllers")
+	count, _ := cmd.Flags().GetInt("count")
+
+	// "all devices" and "c0 devices --all-controllers" are the same as
+	// "devices": the controller is only a starting point
+	lowerItem := strings.ToLower(item)
+	if lowerItem == "all" || (allControllers && strings.HasPrefix(lowerItem, "c") && len(item) >= 2) {
+		if query == "" {
+			query = "devices"
+		}
+		item, query = query, ""
+	}
 
 	// Parse item type
-	if strings.HasPrefix(item, "c") && len(item) >= 2 {
+	switch strings.ToLower(item) {
+	case "devices", "disks", "drives":
+		showAllDevices(jsonOut, refresh)
+		return
+	case "enclosures", "enc":
+		showAllEnclosures(jsonOut, refresh)
+		return
+	}
+
+	if allControllers {
+		fmt.Fprintln(os.Stderr, "Error: --all-controllers only applies to devices and enclosures")
+		os.Exit(1)
+	}
+
+	// Paths and WWNs go first: both can contain ':' (by-path links, wwn:)
+	lowerItem = strings.ToLower(item)
+	if strings.HasPrefix(item, "/dev/") || strings.HasPrefix(lowerItem, "0x") {
+		handleDeviceByIdentifier(item, query, raw, jsonOut, refresh)
+	} else if strings.HasPrefix(lowerItem, "wwn:") {
+		handleDeviceByIdentifier(item[4:], query, raw, jsonOut, refresh)
+	} else if strings.HasPrefix(item, "c") && len(item) >= 2 && !strings.Contains(item, ":") {
 		// Controller query (c0, c1, etc.)
-		handleControllerQuery(item, query, raw, jsonOut, refresh)
+		handleControllerQuery(item, query, raw, jsonOut, refresh, count)
 	} else if strings.Contains(item, ":") {
-		// Device by enclosure:slot (e2:5 or 2:5)
+		// Device by enclosure:slot (e2:5, 2:5 or c1:2:5)
 		handleDeviceBySlot(item, query, raw, jsonOut, refresh)
 	} else if strings.HasPrefix(strings.ToLower(item), "serial:") {
 		// Device by serial
@@ -67,13 +128,17 @@ func runDetail(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Unknown item type '%s'\n", item)
 		fmt.Fprintln(os.Stderr, "Supported formats:")
 		fmt.Fprintln(os.Stderr, "  c0, c1, ...     - Controllers")
+		fmt.Fprintln(os.Stderr, "  devices         - Devices across all controllers (or 'all devices')")
+		fmt.Fprintln(os.Stderr, "  enclosures      - Enclosures across all controllers")
 		fmt.Fprintln(os.Stderr, "  2:5, e2:5       - Device by enclosure:slot")
 		fmt.Fprintln(os.Stderr, "  serial:ABC123   - Device by serial number")
+		fmt.Fprintln(os.Stderr, "  /dev/sdb        - Device by path")
+		fmt.Fprintln(os.Stderr, "  wwn:5000c5...   - Device by WWN (or 0x5000c5...)")
 		os.Exit(1)
 	}
 }
 
-func handleControllerQuery(controller, query string, raw, jsonOut, refresh bool) {
+func handleControllerQuery(controller, query string, raw, jsonOut, refresh bool, count int) {
 	switch query {
 	case "":
 		// Show all controller info
@@ -84,9 +149,13 @@ func handleControllerQuery(controller, query string, raw, jsonOut, refresh bool)
 		showControllerDevices(controller, jsonOut, refresh)
 	case "enclosures", "enc":
 		showControllerEnclosures(controller, jsonOut, refresh)
+	case "phy", "phys":
+		showControllerPhys(controller, jsonOut)
+	case "events", "log":
+		showControllerEvents(controller, count, jsonOut)
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown query '%s' for controller\n", query)
-		fmt.Fprintln(os.Stderr, "Supported queries: temperature, devices, enclosures (or none for all info)")
+		fmt.Fprintln(os.Stderr, "Supported queries: temperature, devices, enclosures, phy, events (or none for all info)")
 		os.Exit(1)
 	}
 }
@@ -200,6 +269,74 @@ func showControllerTemperature(controllerID string, raw, jsonOut bool) {
 	}
 }
 
+func showControllerPhys(controllerID string, jsonOut bool) {
+	ctrl, err := hba.GetControllerPhys(controllerID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(ctrl)
+		return
+	}
+
+	counter := func(n *int64) string {
+		if n == nil {
+			return "-"
+		}
+		return strconv.FormatInt(*n, 10)
+	}
+	fmt.Printf("Controller %s PHYs\n\n", controllerID)
+	fmt.Printf("%-4s %-12s %-14s %-10s %-10s %s\n", "PHY", "LINK RATE", "INVALID DWORD", "DISPARITY", "LOSS SYNC", "RESET PROB")
+	fmt.Println(strings.Repeat("-", 66))
+	suspect := 0
+	for _, p := range ctrl.Phys {
+		rate := p.LinkRate
+		if rate == "" {
+			rate = "-"
+		}
+		mark := ""
+		if p.InvalidDwords != nil && *p.InvalidDwords > 0 {
+			mark = "  <- check cable"
+			suspect++
+		}
+		fmt.Printf("%-4d %-12s %-14s %-10s %-10s %s%s\n", p.ID, rate,
+			counter(p.InvalidDwords), counter(p.DisparityErrors), counter(p.LossOfSync), counter(p.ResetProblems), mark)
+	}
+	if suspect > 0 {
+		fmt.Printf("\n%d PHY(s) with invalid dwords: usually a failing cable or connector\n", suspect)
+	}
+}
+
+func showControllerEvents(controllerID string, count int, jsonOut bool) {
+	events, err := hba.GetControllerEvents(controllerID, count)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(events)
+		return
+	}
+
+	if len(events) == 0 {
+		fmt.Printf("No events for controller %s\n", controllerID)
+		return
+	}
+	fmt.Printf("Controller %s events (%s, oldest first)\n\n", controllerID, events[0].Source)
+	fmt.Printf("%-26s %-9s %s\n", "TIME", "SEVERITY", "DESCRIPTION")
+	fmt.Println(strings.Repeat("-", 80))
+	for _, e := range events {
+		fmt.Printf("%-26s %-9s %s\n", e.Time, e.Severity, e.Description)
+	}
+}
+
 func showControllerDevices(controllerID string, jsonOut, refresh bool) {
 	_, _, devices, err := hba.GetFullControllerInfo(controllerID, refresh)
 	if err != nil {
@@ -221,16 +358,70 @@ func showControllerDevices(controllerID string, jsonOut, refresh bool) {
 	fmt.Println(strings.Repeat("-", 80))
 
 	for _, d := range devices {
-		size := fmt.Sprintf("%d GB", d.SizeMB/1024)
-		if d.SizeMB/1024 >= 1000 {
-			size = fmt.Sprintf("%.1f TB", float64(d.SizeMB)/1024/1024)
-		}
+		size := humanize.Bytes(d.SizeMB * humanize.MiB)
 		fmt.Printf("%-6d %-6d %-12s %-18s %-10s %s\n",
 			d.EnclosureID, d.Slot, d.Serial, d.Model, size, d.State)
 	}
 	fmt.Printf("\nTotal: %d devices\n", len(devices))
 }
 
+func showAllDevices(jsonOut, refresh bool) {
+	_, _, devices, err := hba.GetAllControllerData(refresh)
+	if err != nil && len(devices) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(devices)
+		return
+	}
+
+	fmt.Println("Devices on all controllers")
+	fmt.Println(strings.Repeat("=", 90))
+	fmt.Printf("%-6s %-6s %-12s %-18s %-10s %-10s %s\n",
+		"ENC", "SLOT", "SERIAL", "MODEL", "SIZE", "STATE", "PATHS")
+	fmt.Println(strings.Repeat("-", 90))
+
+	multipath := 0
+	for _, d := range devices {
+		size := humanize.Bytes(d.SizeMB * humanize.MiB)
+		if d.IsMultipath() {
+			multipath++
+		}
+		fmt.Printf("%-6d %-6d %-12s %-18s %-10s %-10s %s\n",
+			d.EnclosureID, d.Slot, d.Serial, d.Model, size, d.State, strings.Join(d.Paths, ","))
+	}
+	fmt.Printf("\nTotal: %d devices (%d multipath)\n", len(devices), multipath)
+}
+
+func showAllEnclosures(jsonOut, refresh bool) {
+	_, enclosures, _, err := hba.GetAllControllerData(refresh)
+	if err != nil && len(enclosures) == 0 {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(enclosures)
+		return
+	}
+
+	fmt.Println("Enclosures on all controllers")
+	fmt.Println(strings.Repeat("=", 60))
+	fmt.Printf("%-6s %-20s %-8s %-6s %s\n", "ID", "LOGICAL ID", "SLOTS", "START", "PATHS")
+	fmt.Println(strings.Repeat("-", 60))
+
+	for _, e := range enclosures {
+		fmt.Printf("%-6d %-20s %-8d %-6d %s\n",
+			e.ID, e.LogicalID, e.NumSlots, e.StartSlot, strings.Join(e.Paths, ","))
+	}
+}
+
 func showControllerEnclosures(controllerID string, jsonOut, refresh bool) {
 	_, enclosures, _, err := hba.GetFullControllerInfo(controllerID, refresh)
 	if err != nil {
@@ -257,51 +448,167 @@ func showControllerEnclosures(controllerID string, jsonOut, refresh bool) {
 }
 
 func handleDeviceBySlot(item, query string, raw, jsonOut, refresh bool) {
-	// Parse enclosure:slot (e2:5 or 2:5)
-	item = strings.TrimPrefix(strings.ToLower(item), "e")
-	parts := strings.Split(item, ":")
-	if len(parts) != 2 {
-		fmt.Fprintf(os.Stderr, "Invalid slot format '%s', use enclosure:slot (e.g., 2:5)\n", item)
+	// Parse enclosure:slot (e2:5 or 2:5), optionally prefixed with the
+	// controller whose enclosure numbering it uses (c1:2:5)
+	normalized := strings.ToLower(item)
+	if ctrl, rest, ok := strings.Cut(normalized, ":"); ok && strings.HasPrefix(ctrl, "c") {
+		normalized = ctrl + ":" + strings.TrimPrefix(rest, "e")
+	} else {
+		normalized = strings.TrimPrefix(normalized, "e")
+	}
+	controller, enclosure, slot, ok := ses.ParseEnclosureSlot(normalized)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid slot format '%s', use enclosure:slot (e.g., 2:5 or c1:2:5)\n", item)
 		os.Exit(1)
 	}
 
-	enclosure, err1 := strconv.Atoi(parts[0])
-	slot, err2 := strconv.Atoi(parts[1])
-	if err1 != nil || err2 != nil {
-		fmt.Fprintf(os.Stderr, "Invalid slot format '%s'\n", item)
+	dev, err := hba.FindDeviceBySlot(controller, enclosure, slot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	dev := hba.GetDeviceBySlot(enclosure, slot)
 	if dev == nil {
 		fmt.Fprintf(os.Stderr, "No device found at enclosure %d, slot %d\n", enclosure, slot)
 		os.Exit(1)
 	}
 
-	printDevice(dev, query, raw, jsonOut)
+	printDevice(newDeviceDetail(dev, query), query, raw, jsonOut)
+}
+
+// deviceController returns a controller an HBA device was seen through, so
+// its enclosure number can be resolved unambiguously ("" if unknown)
+func deviceController(dev *hba.PhysicalDevice) string {
+	if len(dev.Paths) > 0 {
+		return dev.Paths[0]
+	}
+	return ""
 }
 
 func handleDeviceBySerial(serial, query string, raw, jsonOut, refresh bool) {
-	dev := hba.GetDeviceBySerial(serial)
+	dev, err := hba.FindDeviceBySerial(serial)
+	if err != nil && errors.Is(err, hba.ErrAmbiguousSerial) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
 	if dev == nil {
 		fmt.Fprintf(os.Stderr, "No device found with serial '%s'\n", serial)
 		os.Exit(1)
 	}
 
-	printDevice(dev, query, raw, jsonOut)
+	printDevice(newDeviceDetail(dev, query), query, raw, jsonOut)
+}
+
+// newDeviceDetail adds the SMART details a query needs to an HBA device:
+// the rotation rate, and the last SMART read and self-test log for a full
+// listing
+func newDeviceDetail(dev *hba.PhysicalDevice, query string) *DeviceDetail {
+	detail := &DeviceDetail{PhysicalDevice: dev}
+	if query != "" && !isRotationQuery(query) {
+		return detail
+	}
+	sysData := collector.CollectSystemData(false)
+	if device := deviceBlockPath(dev, sysData); device != "" {
+		data := collector.GetDriveData(device, sysData)
+		detail.RotationRate = data.RotationRate
+		if query == "" {
+			detail.LastProbed = data.LastProbed
+			detail.SelfTests = deviceSelfTests(device)
+		}
+	}
+	return detail
+}
+
+// deviceBlockPath finds the block device of an HBA device by serial.
+// Returns "" if the drive has no device node.
+func deviceBlockPath(dev *hba.PhysicalDevice, sysData *collector.SystemData) string {
+	for _, l := range sysData.LsblkDevices {
+		if l.Serial == nil {
+			continue
+		}
+		if strings.EqualFold(*l.Serial, dev.Serial) || (dev.SerialVPD != "" && strings.EqualFold(*l.Serial, dev.SerialVPD)) {
+			return l.Path
+		}
+	}
+	return ""
+}
+
+// deviceSelfTests reads the SMART self-test log of a block device. Returns
+// nil if the drive isn't active.
+func deviceSelfTests(device string) []collector.SelfTestEntry {
+	entries, err := collector.GetSelfTestLog(device)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return nil
+	}
+	return entries
+}
+
+// DeviceDetail is an HBA device with its SMART self-test log and the
+// details only SMART reports
+type DeviceDetail struct {
+	*hba.PhysicalDevice
+	RotationRate *int                      `json:"rotation_rate,omitempty"` // rpm, 0 for SSDs
+	LastProbed   *time.Time                `json:"last_probed,omitempty"`   // last successful SMART read
+	SelfTests    []collector.SelfTestEntry `json:"self_tests,omitempty"`
+}
+
+// isRotationQuery reports whether a field query asks for the rotation rate
+func isRotationQuery(query string) bool {
+	switch strings.ToLower(query) {
+	case "rpm", "rotation_rate":
+		return true
+	}
+	return false
+}
+
+// formatRotationRate renders a SMART rotation rate for display
+func formatRotationRate(rpm *int) string {
+	switch {
+	case rpm == nil:
+		return ""
+	case *rpm == 0:
+		return "Solid State"
+	}
+	return fmt.Sprintf("%d rpm", *rpm)
+}
+
+// handleDeviceByIdentifier resolves a device path or WWN to a serial through
+// the identify index, then looks the drive up on the HBA
+func handleDeviceByIdentifier(id, query string, raw, jsonOut, refresh bool) {
+	idx, err := identify.BuildIndex()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to build device index: %v\n", err)
+		os.Exit(1)
+	}
+
+	entity, _, err := idx.Lookup(id)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if entity.Serial == nil || *entity.Serial == "" {
+		fmt.Fprintf(os.Stderr, "Error: device %s has no serial number (needed for HBA lookup)\n", id)
+		os.Exit(1)
+	}
+
+	handleDeviceBySerial(*entity.Serial, query, raw, jsonOut, refresh)
 }
 
-func printDevice(dev *hba.PhysicalDevice, query string, raw, jsonOut bool) {
+func printDevice(detail *DeviceDetail, query string, raw, jsonOut bool) {
+	dev, selfTests := detail.PhysicalDevice, detail.SelfTests
 	if jsonOut {
 		enc := json.NewEncoder(os.Stdout)
 		enc.SetIndent("", "  ")
-		enc.Encode(dev)
+		enc.Encode(detail)
 		return
 	}
 
 	// If specific query, return just that field
 	if query != "" {
 		val := getDeviceField(dev, query)
+		if isRotationQuery(query) {
+			val = formatRotationRate(detail.RotationRate)
+		}
 		if val == "" {
 			fmt.Fprintf(os.Stderr, "Unknown field '%s'\n", query)
 			os.Exit(1)
@@ -314,8 +621,12 @@ func printDevice(dev *hba.PhysicalDevice, query string, raw, jsonOut bool) {
 		return
 	}
 
-	// Full device info
-	fmt.Printf("Device at Enclosure %d, Slot %d\n", dev.EnclosureID, dev.Slot)
+	// Full device info, with the enclosure's own bay label when it has one
+	if label := ses.LookupSlotLabel(deviceController(dev), dev.EnclosureID, dev.Slot); label != "" {
+		fmt.Printf("Device at Enclosure %d, Slot %d (%s)\n", dev.EnclosureID, dev.Slot, label)
+	} else {
+		fmt.Printf("Device at Enclosure %d, Slot %d\n", dev.EnclosureID, dev.Slot)
+	}
 	fmt.Println(strings.Repeat("=", 50))
 
 	fmt.Println("\nIdentification:")
@@ -336,16 +647,34 @@ func printDevice(dev *hba.PhysicalDevice, query string, raw, jsonOut bool) {
 	fmt.Printf("  Drive Type:     %s\n", dev.DriveType)
 
 	fmt.Println("\nCapacity:")
-	sizeGB := dev.SizeMB / 1024
-	if sizeGB >= 1000 {
-		fmt.Printf("  Size:           %.2f TB\n", float64(dev.SizeMB)/1024/1024)
-	} else {
-		fmt.Printf("  Size:           %d GB\n", sizeGB)
-	}
+	fmt.Printf("  Size:           %s\n", humanize.Bytes(dev.SizeMB*humanize.MiB))
 	fmt.Printf("  Sectors:        %d\n", dev.Sectors)
+	if rate := formatRotationRate(detail.RotationRate); rate != "" {
+		fmt.Printf("  Rotation Rate:  %s\n", rate)
+	}
 
 	fmt.Println("\nStatus:")
 	fmt.Printf("  State:          %s\n", dev.State)
+	if detail.LastProbed != nil {
+		fmt.Printf("  Last Probed:    %s\n", detail.LastProbed.Format("2006-01-02 15:04:05"))
+	}
+
+	if selfTests == nil {
+		return
+	}
+	fmt.Println("\nSelf-Test Log:")
+	if len(selfTests) == 0 {
+		fmt.Println("  No self-tests logged")
+		return
+	}
+	fmt.Printf("  %-3s %-20s %-32s %-8s %s\n", "#", "TYPE", "STATUS", "HOURS", "FIRST ERROR LBA")
+	for _, t := range selfTests {
+		hours := "-"
+		if t.LifetimeHours != nil {
+			hours = strconv.Itoa(*t.LifetimeHours)
+		}
+		fmt.Printf("  %-3d %-20s %-32s %-8s %s\n", t.Num, t.Type, t.Status, hours, derefOr(t.FirstErrorLBA, "-"))
+	}
 }
 
 func getDeviceField(dev *hba.PhysicalDevice, field string) string {
@@ -370,10 +699,15 @@ func getDeviceField(dev *hba.PhysicalDevice, field string) string {
 		return dev.State
 	case "slot":
 		return strconv.Itoa(dev.Slot)
+	case "label", "slot_label":
+		if label := ses.LookupSlotLabel(deviceController(dev), dev.EnclosureID, dev.Slot); label != "" {
+			return label
+		}
+		return strconv.Itoa(dev.Slot)
 	case "enclosure", "enc":
 		return strconv.Itoa(dev.EnclosureID)
 	case "size":
-		return fmt.Sprintf("%d MB", dev.SizeMB)
+		return humanize.Bytes(dev.SizeMB * humanize.MiB)
 	default:
 		return ""
 	}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/drive"
+	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/ses"
+	"github.com/spf13/cobra"
+)
+
+var enclosureCmd = &cobra.Command{
+	Use:   "enclosure",
+	Short: "Enclosure views",
+	Long:  `Commands that present drives in the context of their physical enclosure.`,
+}
+
+var enclosureHeatmapCmd = &cobra.Command{
+	Use:   "heatmap <enclosure>",
+	Short: "Show a temperature heatmap of an enclosure's bays",
+	Long: `Render the enclosure's bays as a grid matching the physical layout, with
+each bay colored by drive temperature.
+
+The layout (rows, columns, fill order) is taken from the 'layouts' section of
+the config, matched by enclosure model. Without a configured layout, bays are
+shown four wide, filled row by row.
+
+Color bands are derived from thresholds.warning_temp and critical_temp.
+Standby drives are not woken and are shown in grey.
+
+Enclosures are numbered per controller; prefix the controller (c1:2) when
+two HBAs report the same enclosure number.
+
+Examples:
+  jbodgod enclosure heatmap 2
+  jbodgod enclosure heatmap e2 --no-color
+  jbodgod enclosure heatmap c1:2`,
+	Args: cobra.ExactArgs(1),
+	Run:  runEnclosureHeatmap,
+}
+
+var enclosureSlotsCmd = &cobra.Command{
+	Use:   "slots <sg-device>",
+	Short: "Show every slot of an enclosure",
+	Long: `List every device slot of the enclosure behind an SES device: whether a
+drive is present, the attached device's HCTL, and the locate/fault LED state.
+
+Slot state is read from 'sg_ses --page=es --join' and from the kernel's
+/sys/class/enclosure entries; either one is enough. Use it to find the empty
+bays before installing a drive. Slot numbers are SES element indexes, as used
+by sg_ses --dev-slot-num.
+
+Examples:
+  jbodgod enclosure slots /dev/sg3
+  jbodgod enclosure slots sg3 --json`,
+	Args: cobra.ExactArgs(1),
+	Run:  runEnclosureSlots,
+}
+
+func init() {
+	enclosureHeatmapCmd.Flags().Bool("no-color", false, "Disable ANSI colors (also honours NO_COLOR)")
+	enclosureCmd.AddCommand(enclosureHeatmapCmd)
+
+	enclosureSlotsCmd.Flags().Bool("json", false, "Output as JSON")
+	enclosureCmd.AddCommand(enclosureSlotsCmd)
+}
+
+func runEnclosureHeatmap(cmd *cobra.Command, args []string) {
+	noColor, _ := cmd.Flags().GetBool("no-color")
+	if os.Getenv("NO_COLOR") != "" {
+		noColor = true
+	}
+
+	controller, encID, err := parseEnclosureArg(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	enclosures, err := hba.AllEnclosures()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to fetch HBA enclosure data: %v\n", err)
+		os.Exit(1)
+	}
+	enc, err := hba.FindEnclosure(enclosures, controller, encID)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if enc == nil {
+		fmt.Fprintf(os.Stderr, "Error: enclosure %d not found\n", encID)
+		os.Exit(1)
+	}
+
+	layout := drive.DefaultLayout(enc.NumSlots)
+	if l := cfg.LayoutFor(enc.Model); l != nil {
+		layout = *l
+	}
+
+	drives := drive.GetAll(cfg)
+	drive.PrintHeatmap(*enc, layout, drives, cfg.Thresholds, !noColor)
+}
+
+func runEnclosureSlots(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+
+	slots, err := ses.GetSlotStatuses(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(slots)
+		return
+	}
+
+	onOff := func(b bool) string {
+		if b {
+			return "on"
+		}
+		return "off"
+	}
+	fmt.Printf("%-5s %-12s %-16s %-14s %-7s %s\n", "SLOT", "LABEL", "STATUS", "DEVICE HCTL", "LOCATE", "FAULT")
+	fmt.Println(strings.Repeat("-", 64))
+	empty := 0
+	for _, s := range slots {
+		label, status, hctl := s.Label, s.Status, s.DeviceHCTL
+		if label == "" {
+			label = "-"
+		}
+		if status == "" {
+			status = "-"
+		}
+		if !s.Populated {
+			status = "empty"
+			empty++
+		}
+		if hctl == "" {
+			hctl = "-"
+		}
+		fmt.Printf("%-5d %-12s %-16s %-14s %-7s %s\n", s.Index, label, status, hctl, onOff(s.Locate), onOff(s.Fault))
+	}
+	fmt.Printf("\n%d of %d slots empty\n", empty, len(slots))
+}
+
+// parseEnclosureArg parses an enclosure number (2 or e2), optionally
+// prefixed with the controller whose numbering it uses (c1:2)
+func parseEnclosureArg(arg string) (controller string, id int, err error) {
+	value := strings.ToLower(arg)
+	if ctrl, rest, ok := strings.Cut(value, ":"); ok {
+		if _, err := strconv.Atoi(strings.TrimPrefix(ctrl, "c")); err != nil || !strings.HasPrefix(ctrl, "c") {
+			return "", 0, fmt.Errorf("invalid controller in '%s', use e.g. c1:2", arg)
+		}
+		controller, value = ctrl, rest
+	}
+	id, err = strconv.Atoi(strings.TrimPrefix(value, "e"))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid enclosure '%s', use the enclosure number (e.g., 2 or c1:2)", arg)
+	}
+	return controller, id, nil
+}
@@ -1,41 +1,75 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
 
+	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/collector"
 	"github.com/sigreer/jbodgod/internal/config"
 	"github.com/sigreer/jbodgod/internal/db"
 	"github.com/sigreer/jbodgod/internal/drive"
 	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/humanize"
+	"github.com/sigreer/jbodgod/internal/notify"
 	"github.com/sigreer/jbodgod/internal/zfs"
 	"github.com/spf13/cobra"
 )
 
+// healthcheckSchemaVersion is the schema_version of 'healthcheck --json'
+// output. Bump it when fields are renamed, removed or change meaning.
+const healthcheckSchemaVersion = 1
+
 // HealthcheckResult contains the complete health check output
 type HealthcheckResult struct {
-	Timestamp     time.Time           `json:"timestamp"`
-	Status        string              `json:"status"` // healthy, warning, critical
-	Drives        DriveHealthSummary  `json:"drives"`
-	Pools         []PoolHealthSummary `json:"pools"`
-	Alerts        []HealthAlert       `json:"alerts"`
-	ScanDurationMs int64              `json:"scan_duration_ms"`
+	SchemaVersion  int                 `json:"schema_version"`
+	Timestamp      time.Time           `json:"timestamp"`
+	Status         string              `json:"status"` // healthy, warning, critical
+	Drives         DriveHealthSummary  `json:"drives"`
+	Pools          []PoolHealthSummary `json:"pools"`
+	Alerts         []HealthAlert       `json:"alerts"`
+	ScanDurationMs int64               `json:"scan_duration_ms"`
 }
 
 // DriveHealthSummary contains drive health statistics
 type DriveHealthSummary struct {
-	Expected  int      `json:"expected"`
-	Present   int      `json:"present"`
-	Active    int      `json:"active"`
-	Standby   int      `json:"standby"`
-	Missing   []string `json:"missing,omitempty"`
-	Failed    []string `json:"failed,omitempty"`
-	New       []string `json:"new,omitempty"`
-	TempWarn  []string `json:"temp_warn,omitempty"`
+	Expected int      `json:"expected"`
+	Present  int      `json:"present"`
+	Active   int      `json:"active"`
+	Standby  int      `json:"standby"`
+	Missing  []string `json:"missing,omitempty"`
+	Failed   []string `json:"failed,omitempty"`
+
+	// Present but not answering (I/O errors or smartctl timing out)
+	Unresponsive []string `json:"unresponsive,omitempty"`
+
+	New      []string `json:"new,omitempty"`
+	TempWarn []string `json:"temp_warn,omitempty"`
+
+	// Inventory drives whose SMART data hasn't been read within --smart-stale
+	SmartStale []string `json:"smart_stale,omitempty"`
+
+	// Current temperature of each active drive by device (°C)
+	Temps map[string]int `json:"temps,omitempty"`
+
+	// Slot occupancy vs. the expected set from config ("enclosure:slot")
+	EmptySlots      []string `json:"empty_slots,omitempty"`
+	UnexpectedSlots []string `json:"unexpected_slots,omitempty"`
+
+	// Drive roster in config vs. the HBA, with --compare-config (serials,
+	// or the configured name when a missing drive has no serial)
+	ConfigMissing    []string `json:"config_missing,omitempty"`
+	ConfigUnexpected []string `json:"config_unexpected,omitempty"`
 }
 
 // PoolHealthSummary contains ZFS pool health
@@ -45,6 +79,17 @@ type PoolHealthSummary struct {
 	ScanState    string   `json:"scan_state,omitempty"`
 	FaultedVdevs []string `json:"faulted_vdevs,omitempty"`
 	ErrorCount   int64    `json:"error_count"`
+	SlowIOs      int64    `json:"slow_ios,omitempty"`
+	CapacityPct  *int     `json:"capacity_percent,omitempty"`
+	FragPct      *int     `json:"fragmentation_percent,omitempty"`
+}
+
+// HealthBadge is a shields.io endpoint badge (https://shields.io/badges/endpoint-badge)
+type HealthBadge struct {
+	SchemaVersion int    `json:"schemaVersion"`
+	Label         string `json:"label"`
+	Message       string `json:"message"`
+	Color         string `json:"color"`
 }
 
 // HealthAlert represents a health check alert
@@ -61,40 +106,205 @@ var healthcheckCmd = &cobra.Command{
 	Long: `Perform a comprehensive health check:
   - Verify all expected drives are present
   - Check ZFS pool status for degraded/faulted states
+  - Warn when a pool is nearly full (thresholds.pool_capacity_warn/_crit
+    in config, default 80%/90%)
   - Compare HBA roster against inventory
-  - Report temperature warnings
-  - Update inventory database (with --update)`,
+  - Warn about mirror/raidz members sized differently from the rest of
+    their vdev
+  - Audit pool properties: vdevs created with different ashifts, autotrim
+    off on all-SSD pools, failmode=wait on pools listed in critical_pools
+  - Compare SES slot occupancy against the expected set in config
+  - Compare the HBA roster against the drives listed in the config file
+    (with --compare-config)
+  - Report temperature warnings (per-drive temp_warn/temp_crit in config
+    override --temp-warn/--temp-crit)
+  - Warn about drives whose SMART data hasn't been read within --smart-stale
+  - Update inventory database (with --update)
+  - Rescan instead of using cached data (with --refresh), e.g. after
+    swapping drives
+  - Send new critical alerts to the webhook/email configured under 'alerts'
+    (the same problem is not re-sent within alerts.renotify_after)
+
+Use 'jbodgod healthcheck diff <old.json> <new.json>' to compare two saved
+--json captures.
+
+With --watch, the check repeats every --interval (default 60s) and prints a
+one-line summary per cycle; combined with --json it emits one JSON object per
+line (JSONL). Every cycle rescans the HBAs and drive state, and only alerts
+not raised by the previous cycle are stored. Stop with Ctrl+C.
+
+The --badge flag prints only a shields.io endpoint badge derived from the
+overall status, for status pages:
+  {"schemaVersion":1,"label":"storage","message":"healthy","color":"green"}
+
+With --exit-code, the command exits 1 when the status is warning and 2 when
+it is critical (after printing its output), for cron and monitoring checks.`,
 	Run: runHealthcheck,
 }
 
 func init() {
 	healthcheckCmd.Flags().Bool("json", false, "Output as JSON")
+	healthcheckCmd.Flags().Bool("badge", false, "Output a shields.io endpoint badge")
 	healthcheckCmd.Flags().Bool("update", false, "Update inventory database with current state")
 	healthcheckCmd.Flags().Int("temp-warn", 55, "Temperature warning threshold (°C)")
 	healthcheckCmd.Flags().Int("temp-crit", 60, "Temperature critical threshold (°C)")
+	healthcheckCmd.Flags().Duration("smart-stale", 7*24*time.Hour, "Warn when a drive's SMART data hasn't been read for this long")
+	healthcheckCmd.Flags().Bool("watch", false, "Re-run the check every --interval until interrupted")
+	healthcheckCmd.Flags().Duration("interval", time.Minute, "Time between checks in --watch mode")
+	healthcheckCmd.Flags().Bool("refresh", false, "Bypass cached data (e.g. after swapping drives)")
+	healthcheckCmd.Flags().Bool("exit-code", false, "Exit 1 on warning and 2 on critical status")
+	healthcheckCmd.Flags().Bool("compare-config", false, "Compare HBA drives against the drive roster in the config file")
+
+	healthcheckDiffCmd.Flags().Bool("json", false, "Output as JSON")
+	healthcheckDiffCmd.Flags().Int("temp-delta", 5, "Report temperature changes of at least this many °C")
+	healthcheckCmd.AddCommand(healthcheckDiffCmd)
+}
+
+// healthcheckOptions holds the flag values that affect a check
+type healthcheckOptions struct {
+	updateDB   bool
+	tempWarn   int
+	tempCrit   int
+	smartStale time.Duration
+	refresh    bool
+
+	compareConfig bool
+
+	// previousAlerts are the alerts of the last watch cycle, which are
+	// already stored; nil stores every alert
+	previousAlerts map[string]bool
+}
+
+// healthAlertKey identifies an alert across watch cycles
+func healthAlertKey(a HealthAlert) string {
+	return a.Severity + "|" + a.Category + "|" + a.Message
 }
 
 func runHealthcheck(cmd *cobra.Command, args []string) {
-	start := time.Now()
 	jsonOut, _ := cmd.Flags().GetBool("json")
-	updateDB, _ := cmd.Flags().GetBool("update")
-	tempWarn, _ := cmd.Flags().GetInt("temp-warn")
-	tempCrit, _ := cmd.Flags().GetInt("temp-crit")
+	badge, _ := cmd.Flags().GetBool("badge")
+	watch, _ := cmd.Flags().GetBool("watch")
+	interval, _ := cmd.Flags().GetDuration("interval")
+	useExitCode, _ := cmd.Flags().GetBool("exit-code")
 
-	result := &HealthcheckResult{
-		Timestamp: start,
-		Status:    "healthy",
-	}
+	var opts healthcheckOptions
+	opts.updateDB, _ = cmd.Flags().GetBool("update")
+	opts.tempWarn, _ = cmd.Flags().GetInt("temp-warn")
+	opts.tempCrit, _ = cmd.Flags().GetInt("temp-crit")
+	opts.smartStale, _ = cmd.Flags().GetDuration("smart-stale")
+	opts.refresh, _ = cmd.Flags().GetBool("refresh")
+	opts.compareConfig, _ = cmd.Flags().GetBool("compare-config")
 
 	// Open database (optional - we still run checks without it)
-	database, dbErr := db.New(db.DefaultPath)
-	if dbErr != nil && updateDB {
+	database, dbErr := openDB()
+	if dbErr != nil && opts.updateDB {
 		fmt.Fprintf(os.Stderr, "Warning: could not open database: %v\n", dbErr)
 	}
 	if database != nil {
 		defer database.Close()
 	}
 
+	if watch {
+		if interval <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: --interval must be positive\n")
+			os.Exit(1)
+		}
+		watchHealthcheck(opts, database, interval, jsonOut)
+		return
+	}
+
+	result := performHealthcheck(opts, database)
+
+	// Output
+	switch {
+	case badge:
+		json.NewEncoder(os.Stdout).Encode(healthBadge(result.Status))
+	case jsonOut:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(result)
+	default:
+		printHealthcheckText(result)
+	}
+
+	if useExitCode {
+		exitCode = healthcheckExitCode(result.Status)
+	}
+}
+
+// healthcheckExitCode maps a status to the --exit-code exit status
+func healthcheckExitCode(status string) int {
+	switch status {
+	case "critical":
+		return 2
+	case "warning":
+		return 1
+	default:
+		return 0
+	}
+}
+
+// watchHealthcheck re-runs the check every interval until interrupted,
+// printing a one-line summary (or one JSON object per line) per cycle
+func watchHealthcheck(opts healthcheckOptions, database *db.DB, interval time.Duration, jsonOut bool) {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Drives can be pulled between cycles, so every cycle rescans the HBAs
+	// and drive state rather than reading the cached roster
+	opts.refresh = true
+
+	enc := json.NewEncoder(os.Stdout)
+	for {
+		result := performHealthcheck(opts, database)
+		opts.previousAlerts = make(map[string]bool, len(result.Alerts))
+		for _, alert := range result.Alerts {
+			opts.previousAlerts[healthAlertKey(alert)] = true
+		}
+		if jsonOut {
+			enc.Encode(result)
+		} else {
+			fmt.Println(healthcheckSummaryLine(result))
+		}
+
+		// Persist cache between cycles so a killed watch leaves it warm
+		cache.Flush()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// healthcheckSummaryLine formats a result as a compact single line
+func healthcheckSummaryLine(result *HealthcheckResult) string {
+	return fmt.Sprintf("%s %-8s present=%d missing=%d failed=%d temp_warn=%d alerts=%d",
+		result.Timestamp.Format("2006-01-02 15:04:05"),
+		strings.ToUpper(result.Status),
+		result.Drives.Present,
+		len(result.Drives.Missing),
+		len(result.Drives.Failed),
+		len(result.Drives.TempWarn),
+		len(result.Alerts))
+}
+
+// performHealthcheck runs all checks once, records alerts (and inventory
+// updates with opts.updateDB) and returns the result
+func performHealthcheck(opts healthcheckOptions, database *db.DB) *HealthcheckResult {
+	start := time.Now()
+	tempWarn, tempCrit, smartStale := opts.tempWarn, opts.tempCrit, opts.smartStale
+
+	result := &HealthcheckResult{
+		SchemaVersion: healthcheckSchemaVersion,
+		Timestamp:     start,
+		Status:        "healthy",
+	}
+
 	// Load config
 	cfg, err := config.Load(cfgFile)
 	if err != nil {
@@ -108,21 +318,22 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 	}
 	result.Drives.Expected = len(expectedDrives)
 
+	// Index configured drives for per-drive temperature thresholds
+	configDrives := make(map[string]config.Drive)
+	for _, d := range expectedDrives {
+		configDrives[d.Device] = d
+	}
+
 	// Get current drive states
 	var driveInfos []drive.DriveInfo
 	if cfg != nil {
-		driveInfos = drive.GetAll(cfg)
+		driveInfos = drive.GetAllParallel(cfg, collector.DefaultConcurrency(), opts.refresh)
+		// Paths of a multipath drive are configured separately but merged
+		result.Drives.Expected = len(driveInfos)
 	}
 
-	// Get HBA data
-	var hbaDevices []hba.PhysicalDevice
-	controllers := hba.ListControllers()
-	for _, ctrlNum := range controllers {
-		_, _, devices, err := hba.GetFullControllerInfo(fmt.Sprintf("c%d", ctrlNum), false)
-		if err == nil {
-			hbaDevices = append(hbaDevices, devices...)
-		}
-	}
+	// Get HBA data (drives on a multipath shelf are merged into one record)
+	_, _, hbaDevices, _ := hba.GetAllControllerData(opts.refresh)
 
 	// Analyze drives
 	hbaSerials := make(map[string]hba.PhysicalDevice)
@@ -138,10 +349,11 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 
 	// Track known serials from inventory
 	var inventorySerials map[string]bool
+	var inventoryDrives []*db.DriveRecord
 	if database != nil {
 		inventorySerials = make(map[string]bool)
-		allDrives, _ := database.GetAllDrives()
-		for _, d := range allDrives {
+		inventoryDrives, _ = database.GetAllDrives()
+		for _, d := range inventoryDrives {
 			inventorySerials[d.Serial] = true
 		}
 	}
@@ -153,28 +365,18 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 			result.Drives.Active++
 			result.Drives.Present++
 
-			// Check temperature
-			if d.Temp != nil {
-				if *d.Temp >= tempCrit {
-					result.Alerts = append(result.Alerts, HealthAlert{
-						Severity: "critical",
-						Category: "temperature",
-						Message:  fmt.Sprintf("Drive %s temperature critical: %d°C", d.Device, *d.Temp),
-						Details:  map[string]any{"device": d.Device, "temp": *d.Temp},
-					})
-					result.Drives.TempWarn = append(result.Drives.TempWarn, d.Device)
-					result.Status = "critical"
-				} else if *d.Temp >= tempWarn {
-					result.Alerts = append(result.Alerts, HealthAlert{
-						Severity: "warning",
-						Category: "temperature",
-						Message:  fmt.Sprintf("Drive %s temperature warning: %d°C", d.Device, *d.Temp),
-						Details:  map[string]any{"device": d.Device, "temp": *d.Temp},
-					})
-					result.Drives.TempWarn = append(result.Drives.TempWarn, d.Device)
-					if result.Status == "healthy" {
-						result.Status = "warning"
-					}
+			checkDriveTemp(d, configDriveFor(configDrives, d), tempWarn, tempCrit, result)
+
+			// Interface CRC errors point at a bad cable, backplane or connector
+			if d.CRCErrors != nil && *d.CRCErrors > 0 {
+				result.Alerts = append(result.Alerts, HealthAlert{
+					Severity: "warning",
+					Category: "crc_errors",
+					Message:  fmt.Sprintf("Drive %s has %d interface CRC errors (check cable/connector)", d.Device, *d.CRCErrors),
+					Details:  map[string]any{"device": d.Device, "crc_errors": *d.CRCErrors},
+				})
+				if result.Status == "healthy" {
+					result.Status = "warning"
 				}
 			}
 
@@ -182,6 +384,9 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 			result.Drives.Standby++
 			result.Drives.Present++
 
+			// Only set when the enclosure reports the bay's temperature
+			checkDriveTemp(d, configDriveFor(configDrives, d), tempWarn, tempCrit, result)
+
 		case "missing":
 			serial := "unknown"
 			if d.Serial != nil {
@@ -209,6 +414,22 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 				Details:  map[string]any{"device": d.Device, "serial": serial},
 			})
 			result.Status = "critical"
+
+		case "unresponsive":
+			// Still attached, so counted as present rather than removed
+			serial := "unknown"
+			if d.Serial != nil {
+				serial = *d.Serial
+			}
+			result.Drives.Present++
+			result.Drives.Unresponsive = append(result.Drives.Unresponsive, d.Device)
+			result.Alerts = append(result.Alerts, HealthAlert{
+				Severity: "critical",
+				Category: "drive_unresponsive",
+				Message:  fmt.Sprintf("Drive %s is not responding (serial: %s)", d.Device, serial),
+				Details:  map[string]any{"device": d.Device, "serial": serial},
+			})
+			result.Status = "critical"
 		}
 	}
 
@@ -227,7 +448,60 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Flag drives sharing a serial, which serial-keyed lookups can't tell apart
+	checkDuplicateSerials(hbaDevices, result)
+
+	// Flag drives whose SMART data hasn't been read in a long time - a drive
+	// that stays present but never answers may be failing silently
+	if smartStale > 0 && len(inventoryDrives) > 0 {
+		checkSmartStale(inventoryDrives, lastProbedBySerial(driveInfos), smartStale, result)
+	}
+
+	// Flag drives whose grown defect list grew since the last inventory update
+	if len(inventoryDrives) > 0 {
+		checkGrownDefects(inventoryDrives, grownDefectsBySerial(driveInfos), result)
+	}
+
+	// Flag SMR drives in ZFS pools, which stall resilvers
+	checkSMRInPools(driveInfos, result)
+	checkLinkSpeed(driveInfos, result)
+	checkVdevSizes(driveInfos, result)
+
+	// Check physical slot occupancy against the expected set
+	if cfg != nil && len(cfg.Expected) > 0 {
+		checkExpectedSlots(cfg.Expected, result)
+	}
+
+	// Check the HBA roster against the drives listed in the config file.
+	// Discovery replaces those in cfg, so the file is read again as written.
+	if opts.compareConfig {
+		fileCfg, err := config.LoadFile(cfgFile)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: could not load config: %v\n", err)
+		case len(fileCfg.GetAllDrives()) == 0:
+			fmt.Fprintf(os.Stderr, "Warning: --compare-config: no drives listed in the config file\n")
+		case len(hbaDevices) == 0:
+			fmt.Fprintf(os.Stderr, "Warning: --compare-config: no drives reported by the HBA\n")
+		default:
+			checkConfigRoster(fileCfg.GetAllDrives(), driveInfos, hbaDevices, result)
+		}
+	}
+
+	// Flag pools mixing firmware revisions of the same drive model
+	if database != nil {
+		if groups, err := database.GetFirmwareDistributionByPool(); err == nil {
+			checkFirmwareMismatch(groups, result)
+		}
+	}
+
 	// Check ZFS pools
+	capWarn, capCrit := 80, 90
+	var criticalPools []string
+	if cfg != nil {
+		capWarn, capCrit = cfg.Thresholds.PoolCapacityWarn, cfg.Thresholds.PoolCapacityCrit
+		criticalPools = cfg.CriticalPools
+	}
 	poolHealths, err := zfs.GetAllPoolHealth()
 	if err == nil {
 		for _, pool := range poolHealths {
@@ -236,6 +510,7 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 				State:      pool.State,
 				ScanState:  pool.ScanState,
 				ErrorCount: pool.TotalErrors,
+				SlowIOs:    pool.TotalSlowIOs,
 			}
 
 			// Get faulted devices
@@ -243,6 +518,17 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 				summary.FaultedVdevs = append(summary.FaultedVdevs, faulted.Name)
 			}
 
+			// A nearly full pool slows down badly, more so when fragmented
+			if capacity, err := zfs.GetPoolCapacity(pool.Name); err == nil {
+				summary.CapacityPct = &capacity.CapacityPct
+				summary.FragPct = capacity.FragPct
+				checkPoolCapacity(capacity, capWarn, capCrit, result)
+			}
+
+			if props, err := zfs.GetPoolProperties(pool.Name); err == nil {
+				checkPoolProperties(props, driveInfos, criticalPools, result)
+			}
+
 			result.Pools = append(result.Pools, summary)
 
 			// Generate alerts for pool issues
@@ -269,33 +555,75 @@ func runHealthcheck(cmd *cobra.Command, args []string) {
 					result.Status = "warning"
 				}
 			}
+
+			// Slow I/Os point at a struggling drive or path before it errors
+			if pool.HasSlowIOs() {
+				result.Alerts = append(result.Alerts, HealthAlert{
+					Severity: "warning",
+					Category: "pool_slow_ios",
+					Message:  fmt.Sprintf("ZFS pool %s has %d slow I/Os", pool.Name, pool.TotalSlowIOs),
+					Details:  map[string]any{"pool": pool.Name, "slow_ios": pool.TotalSlowIOs},
+				})
+				if result.Status == "healthy" {
+					result.Status = "warning"
+				}
+			}
 		}
 	}
 
+	// A vdev whose GUID maps to no device has lost its disk, whether or
+	// not ZFS has noticed yet
+	checkUnmappedVdevs(collector.CollectSystemData(false).UnmappedZpoolVdevs(), result)
+
 	result.ScanDurationMs = time.Since(start).Milliseconds()
 
 	// Update database if requested
-	if updateDB && database != nil {
+	if opts.updateDB && database != nil {
 		updateInventoryFromHealthcheck(database, hbaDevices, driveInfos)
 	}
 
-	// Save alerts to database
+	// Save alerts to database, notifying about new critical ones. A watch
+	// only stores alerts that weren't raised by its previous cycle.
 	if database != nil {
+		var dispatcher *notify.Dispatcher
+		if cfg != nil {
+			dispatcher = notify.NewDispatcher(cfg.Alerts, database)
+		}
 		for _, alert := range result.Alerts {
-			database.CreateAlertWithDetails(alert.Severity, alert.Category, alert.Message, nil)
+			if opts.previousAlerts[healthAlertKey(alert)] {
+				continue
+			}
+			details, _ := alert.Details.(map[string]any)
+			stored, err := database.CreateAlertWithDetails(alert.Severity, alert.Category, alert.Message, details)
+			if err != nil || dispatcher == nil || alert.Severity != db.SeverityCritical {
+				continue
+			}
+			if _, err := dispatcher.Notify(stored); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
 		}
 	}
 
-	// Output
-	if jsonOut {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		enc.Encode(result)
-		return
-	}
+	return result
+}
 
-	// Text output
-	printHealthcheckText(result)
+// healthBadge maps an overall healthcheck status to a shields.io badge
+func healthBadge(status string) HealthBadge {
+	color := "lightgrey"
+	switch status {
+	case "healthy":
+		color = "green"
+	case "warning":
+		color = "yellow"
+	case "critical":
+		color = "red"
+	}
+	return HealthBadge{
+		SchemaVersion: 1,
+		Label:         "storage",
+		Message:       status,
+		Color:         color,
+	}
 }
 
 func printHealthcheckText(result *HealthcheckResult) {
@@ -321,12 +649,30 @@ func printHealthcheckText(result *HealthcheckResult) {
 	if len(result.Drives.Failed) > 0 {
 		fmt.Printf("  ✗ Failed: %s\n", strings.Join(result.Drives.Failed, ", "))
 	}
+	if len(result.Drives.Unresponsive) > 0 {
+		fmt.Printf("  ✗ Unresponsive: %s\n", strings.Join(result.Drives.Unresponsive, ", "))
+	}
 	if len(result.Drives.TempWarn) > 0 {
 		fmt.Printf("  ⚠ Temperature warnings: %s\n", strings.Join(result.Drives.TempWarn, ", "))
 	}
+	if len(result.Drives.SmartStale) > 0 {
+		fmt.Printf("  ⚠ SMART data stale: %s\n", strings.Join(result.Drives.SmartStale, ", "))
+	}
 	if len(result.Drives.New) > 0 {
 		fmt.Printf("  + New drives: %s\n", strings.Join(result.Drives.New, ", "))
 	}
+	if len(result.Drives.EmptySlots) > 0 {
+		fmt.Printf("  ✗ Unexpectedly empty slots: %s\n", strings.Join(result.Drives.EmptySlots, ", "))
+	}
+	if len(result.Drives.UnexpectedSlots) > 0 {
+		fmt.Printf("  ⚠ Unexpectedly occupied slots: %s\n", strings.Join(result.Drives.UnexpectedSlots, ", "))
+	}
+	if len(result.Drives.ConfigMissing) > 0 {
+		fmt.Printf("  ✗ In config but not on HBA: %s\n", strings.Join(result.Drives.ConfigMissing, ", "))
+	}
+	if len(result.Drives.ConfigUnexpected) > 0 {
+		fmt.Printf("  ⚠ On HBA but not in config: %s\n", strings.Join(result.Drives.ConfigUnexpected, ", "))
+	}
 	fmt.Println()
 
 	// Pools
@@ -347,6 +693,12 @@ func printHealthcheckText(result *HealthcheckResult) {
 			if pool.ScanState != "" && pool.ScanState != "none" {
 				fmt.Printf(" [%s]", pool.ScanState)
 			}
+			if pool.CapacityPct != nil {
+				fmt.Printf(" %d%% full", *pool.CapacityPct)
+				if pool.FragPct != nil {
+					fmt.Printf(", %d%% frag", *pool.FragPct)
+				}
+			}
 			fmt.Println()
 
 			if len(pool.FaultedVdevs) > 0 {
@@ -371,12 +723,664 @@ func printHealthcheckText(result *HealthcheckResult) {
 	}
 }
 
+// checkExpectedSlots compares live SES slot occupancy from sysfs against the
+// expected drive set declared in config
+func checkExpectedSlots(expected []config.ExpectedEnclosure, result *HealthcheckResult) {
+	enclosures := collector.CollectSysfsEnclosures()
+	devices := collector.CollectSysfsDevices()
+
+	// Map device HCTL -> serial for serial checks
+	serialByHCTL := make(map[string]string)
+	for _, dev := range devices {
+		if dev.HCTL != nil && dev.Serial != nil {
+			serialByHCTL[*dev.HCTL] = *dev.Serial
+		}
+	}
+
+	for _, exp := range expected {
+		var enc *collector.SysfsEnclosure
+		for _, e := range enclosures {
+			if e.HCTL == exp.Enclosure || normalizeEnclosureID(e.ID) == normalizeEnclosureID(exp.Enclosure) {
+				enc = e
+				break
+			}
+		}
+
+		if enc == nil {
+			result.Alerts = append(result.Alerts, HealthAlert{
+				Severity: "critical",
+				Category: "enclosure_missing",
+				Message:  fmt.Sprintf("Expected enclosure %s not found", exp.Enclosure),
+				Details:  map[string]any{"enclosure": exp.Enclosure},
+			})
+			result.Status = "critical"
+			continue
+		}
+
+		occupied := 0
+		for _, slot := range enc.Slots {
+			loc := fmt.Sprintf("%s:%d", exp.Enclosure, slot.Number)
+			expectedSerial, listed := exp.Slots[slot.Number]
+
+			if !slot.Occupied() {
+				if listed {
+					result.Drives.EmptySlots = append(result.Drives.EmptySlots, loc)
+					result.Alerts = append(result.Alerts, HealthAlert{
+						Severity: "critical",
+						Category: "slot_empty",
+						Message:  fmt.Sprintf("Slot %s is empty but should contain a drive", loc),
+						Details:  map[string]any{"enclosure": exp.Enclosure, "slot": slot.Number, "expected_serial": expectedSerial},
+					})
+					result.Status = "critical"
+				}
+				continue
+			}
+			occupied++
+
+			if len(exp.Slots) > 0 && !listed {
+				result.Drives.UnexpectedSlots = append(result.Drives.UnexpectedSlots, loc)
+				result.Alerts = append(result.Alerts, HealthAlert{
+					Severity: "warning",
+					Category: "slot_unexpected",
+					Message:  fmt.Sprintf("Slot %s is occupied but not in the expected set", loc),
+					Details:  map[string]any{"enclosure": exp.Enclosure, "slot": slot.Number},
+				})
+				if result.Status == "healthy" {
+					result.Status = "warning"
+				}
+				continue
+			}
+
+			// Verify the serial when one is declared and the OS can see the drive
+			if expectedSerial != "" && slot.DeviceHCTL != nil {
+				if serial := serialByHCTL[*slot.DeviceHCTL]; serial != "" && serial != expectedSerial {
+					result.Alerts = append(result.Alerts, HealthAlert{
+						Severity: "warning",
+						Category: "slot_mismatch",
+						Message:  fmt.Sprintf("Slot %s contains %s, expected %s", loc, serial, expectedSerial),
+						Details:  map[string]any{"enclosure": exp.Enclosure, "slot": slot.Number, "serial": serial, "expected_serial": expectedSerial},
+					})
+					if result.Status == "healthy" {
+						result.Status = "warning"
+					}
+				}
+			}
+		}
+
+		if exp.Count > 0 && occupied != exp.Count {
+			severity := "warning"
+			if occupied < exp.Count {
+				severity = "critical"
+				result.Status = "critical"
+			} else if result.Status == "healthy" {
+				result.Status = "warning"
+			}
+			result.Alerts = append(result.Alerts, HealthAlert{
+				Severity: severity,
+				Category: "slot_count",
+				Message:  fmt.Sprintf("Enclosure %s has %d populated slots, expected %d", exp.Enclosure, occupied, exp.Count),
+				Details:  map[string]any{"enclosure": exp.Enclosure, "occupied": occupied, "expected": exp.Count},
+			})
+		}
+	}
+}
+
+// checkConfigRoster compares the drives the HBA reports against the roster
+// in the config file by serial. Roster drives without a serial are matched
+// by the serial of the drive currently at their device.
+func checkConfigRoster(roster []config.Drive, drives []drive.DriveInfo, hbaDevices []hba.PhysicalDevice, result *HealthcheckResult) {
+	serialByDevice := make(map[string]string)
+	for _, d := range drives {
+		if d.Serial != nil {
+			serialByDevice[d.Device] = *d.Serial
+		}
+	}
+
+	// Serial of each roster drive, "" when it can't be determined
+	serials := make([]string, len(roster))
+	rosterSerials := make(map[string]bool)
+	for i, d := range roster {
+		serial := d.Serial
+		if serial == "" {
+			serial = serialByDevice[d.Device]
+		}
+		if serial == "" {
+			if resolved, err := filepath.EvalSymlinks(d.Device); err == nil {
+				serial = serialByDevice[resolved]
+			}
+		}
+		serials[i] = strings.ToUpper(strings.TrimSpace(serial))
+		if serials[i] != "" {
+			rosterSerials[serials[i]] = true
+		}
+	}
+
+	hbaSerials := make(map[string]bool)
+	for _, dev := range hbaDevices {
+		for _, serial := range []string{dev.Serial, dev.SerialVPD} {
+			if serial = strings.ToUpper(strings.TrimSpace(serial)); serial != "" {
+				hbaSerials[serial] = true
+			}
+		}
+	}
+
+	// Drives the HBA sees that nobody listed, e.g. one put in the wrong bay
+	for _, dev := range hbaDevices {
+		serial := strings.ToUpper(strings.TrimSpace(dev.Serial))
+		vpd := strings.ToUpper(strings.TrimSpace(dev.SerialVPD))
+		if serial == "" && vpd == "" || rosterSerials[serial] || rosterSerials[vpd] {
+			continue
+		}
+		if serial == "" {
+			serial = vpd
+		}
+		loc := fmt.Sprintf("%d:%d", dev.EnclosureID, dev.Slot)
+		result.Drives.ConfigUnexpected = append(result.Drives.ConfigUnexpected, serial)
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "config_unexpected",
+			Message:  fmt.Sprintf("Drive %s in slot %s is not in the config roster", serial, loc),
+			Details:  map[string]any{"serial": serial, "enclosure": dev.EnclosureID, "slot": dev.Slot, "model": dev.Model},
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+
+	// Listed drives the HBA doesn't see
+	for i, d := range roster {
+		serial := serials[i]
+		if serial != "" && hbaSerials[serial] {
+			continue
+		}
+
+		// A roster drive without a serial whose device is gone can't be
+		// anywhere on the HBA either
+		if serial == "" {
+			if _, err := os.Stat(d.Device); err == nil {
+				continue
+			}
+		}
+
+		id := serial
+		if id == "" {
+			id = d.Name
+		}
+		result.Drives.ConfigMissing = append(result.Drives.ConfigMissing, id)
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "critical",
+			Category: "config_missing",
+			Message:  fmt.Sprintf("Drive %s (%s) is in the config roster but not on the HBA", d.Name, derefOr(&serial, d.Device)),
+			Details:  map[string]any{"name": d.Name, "device": d.Device, "serial": serial},
+		})
+		result.Status = "critical"
+	}
+}
+
+// checkDriveTemp records a drive's temperature and alerts when it crosses
+// the drive's warning or critical threshold
+func checkDriveTemp(d drive.DriveInfo, cfgDrive config.Drive, tempWarn, tempCrit int, result *HealthcheckResult) {
+	if d.Temp == nil {
+		return
+	}
+	if result.Drives.Temps == nil {
+		result.Drives.Temps = make(map[string]int)
+	}
+	result.Drives.Temps[d.Device] = *d.Temp
+
+	warn, warnSource, crit, critSource := tempThresholds(cfgDrive, tempWarn, tempCrit)
+	if *d.Temp >= crit {
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "critical",
+			Category: "temperature",
+			Message:  fmt.Sprintf("Drive %s temperature critical: %d°C (%s threshold %d°C)", d.Device, *d.Temp, critSource, crit),
+			Details:  map[string]any{"device": d.Device, "temp": *d.Temp, "threshold": crit, "threshold_source": critSource},
+		})
+		result.Drives.TempWarn = append(result.Drives.TempWarn, d.Device)
+		result.Status = "critical"
+	} else if *d.Temp >= warn {
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "temperature",
+			Message:  fmt.Sprintf("Drive %s temperature warning: %d°C (%s threshold %d°C)", d.Device, *d.Temp, warnSource, warn),
+			Details:  map[string]any{"device": d.Device, "temp": *d.Temp, "threshold": warn, "threshold_source": warnSource},
+		})
+		result.Drives.TempWarn = append(result.Drives.TempWarn, d.Device)
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// configDriveFor returns the configured drive for a drive, looking up each
+// path of a multipath drive
+func configDriveFor(configDrives map[string]config.Drive, d drive.DriveInfo) config.Drive {
+	if c, ok := configDrives[d.Device]; ok {
+		return c
+	}
+	for _, p := range d.Paths {
+		if c, ok := configDrives[p]; ok {
+			return c
+		}
+	}
+	return config.Drive{}
+}
+
+// tempThresholds returns the warning and critical temperatures for a drive,
+// preferring its config entry over the global flags, and which was used for
+// each ("drive" or "global"), since a drive may override only one of them
+func tempThresholds(d config.Drive, globalWarn, globalCrit int) (warn int, warnSource string, crit int, critSource string) {
+	warn, warnSource = globalWarn, "global"
+	if d.TempWarn > 0 {
+		warn, warnSource = d.TempWarn, "drive"
+	}
+	crit, critSource = globalCrit, "global"
+	if d.TempCrit > 0 {
+		crit, critSource = d.TempCrit, "drive"
+	}
+	return warn, warnSource, crit, critSource
+}
+
+// checkSmartStale warns about present inventory drives whose last successful
+// SMART read (from this run or the inventory) is older than maxAge
+func checkSmartStale(drives []*db.DriveRecord, probed map[string]time.Time, maxAge time.Duration, result *HealthcheckResult) {
+	for _, d := range drives {
+		// Standby drives are deliberately not probed, so staleness is expected
+		switch d.CurrentState {
+		case db.StateMissing, db.StateUnknown, db.StateStandby, db.StateReplaced:
+			continue
+		}
+
+		last := d.LastSmartOK
+		if at, ok := probed[strings.ToUpper(d.Serial)]; ok && (last == nil || at.After(*last)) {
+			last = &at
+		}
+		if last == nil || time.Since(*last) <= maxAge {
+			continue
+		}
+
+		age := time.Since(*last).Round(time.Hour)
+		result.Drives.SmartStale = append(result.Drives.SmartStale, d.Serial)
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "smart_stale",
+			Message:  fmt.Sprintf("Drive %s SMART data not read successfully for %s", d.Serial, age),
+			Details:  map[string]any{"serial": d.Serial, "last_smart_ok": *last},
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// checkGrownDefects warns about drives whose SCSI grown defect list is larger
+// than the count recorded in the inventory. The new count is stored by the
+// next inventory update, so a growth keeps alerting until then.
+func checkGrownDefects(drives []*db.DriveRecord, current map[string]int, result *HealthcheckResult) {
+	for _, d := range drives {
+		if d.GrownDefects == nil {
+			continue
+		}
+		count, ok := current[strings.ToUpper(d.Serial)]
+		if !ok || count <= *d.GrownDefects {
+			continue
+		}
+
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "grown_defects",
+			Message:  fmt.Sprintf("Drive %s grown defect list increased from %d to %d", d.Serial, *d.GrownDefects, count),
+			Details:  map[string]any{"serial": d.Serial, "previous": *d.GrownDefects, "current": count},
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// checkSMRInPools warns about SMR drives that are members of a ZFS pool
+func checkSMRInPools(drives []drive.DriveInfo, result *HealthcheckResult) {
+	for _, d := range drives {
+		if d.IsSMR == nil || !*d.IsSMR || d.Zpool == nil {
+			continue
+		}
+
+		model := derefOr(d.Model, "unknown model")
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "smr_in_pool",
+			Message:  fmt.Sprintf("Drive %s (%s) in pool %s is SMR; resilvers may stall", d.Device, model, *d.Zpool),
+			Details:  map[string]any{"device": d.Device, "serial": derefOr(d.Serial, ""), "model": model, "pool": *d.Zpool},
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// checkLinkSpeed warns about drives that negotiated a slower link than they
+// are capable of, which usually means a bad backplane, cable or expander
+// port. A drive's capability is the maximum it reports (SATA) or, failing
+// that, the fastest link negotiated by another drive of the same model.
+func checkLinkSpeed(drives []drive.DriveInfo, result *HealthcheckResult) {
+	bestByModel := make(map[string]float64)
+	for _, d := range drives {
+		if d.Model == nil || d.LinkSpeed == nil {
+			continue
+		}
+		if rate := collector.ParseLinkRate(*d.LinkSpeed); rate > bestByModel[*d.Model] {
+			bestByModel[*d.Model] = rate
+		}
+	}
+
+	for _, d := range drives {
+		if d.LinkSpeed == nil {
+			continue
+		}
+		rate := collector.ParseLinkRate(*d.LinkSpeed)
+		if rate <= 0 {
+			continue
+		}
+		capable := 0.0
+		if d.LinkSpeedMax != nil {
+			capable = collector.ParseLinkRate(*d.LinkSpeedMax)
+		} else if d.Model != nil {
+			capable = bestByModel[*d.Model]
+		}
+		if rate >= capable {
+			continue
+		}
+
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "link_speed",
+			Message:  fmt.Sprintf("Drive %s linked at %.1f Gb/s but is capable of %.1f Gb/s (check backplane/cable)", d.Device, rate, capable),
+			Details:  map[string]any{"device": d.Device, "serial": derefOr(d.Serial, ""), "link_speed": *d.LinkSpeed, "capable": capable},
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// checkPoolCapacity warns when a pool is at least warnPct full and goes
+// critical at critPct
+func checkPoolCapacity(c *zfs.PoolCapacity, warnPct, critPct int, result *HealthcheckResult) {
+	if c.CapacityPct < warnPct {
+		return
+	}
+	severity := "warning"
+	if c.CapacityPct >= critPct {
+		severity = "critical"
+	}
+
+	message := fmt.Sprintf("ZFS pool %s is %d%% full", c.Name, c.CapacityPct)
+	details := map[string]any{
+		"pool":             c.Name,
+		"capacity_percent": c.CapacityPct,
+		"free_bytes":       c.Free,
+	}
+	if c.FragPct != nil {
+		message += fmt.Sprintf(" (%d%% fragmented)", *c.FragPct)
+		details["fragmentation_percent"] = *c.FragPct
+	}
+
+	result.Alerts = append(result.Alerts, HealthAlert{
+		Severity: severity,
+		Category: "pool_capacity",
+		Message:  message,
+		Details:  details,
+	})
+	if severity == "critical" {
+		result.Status = "critical"
+	} else if result.Status == "healthy" {
+		result.Status = "warning"
+	}
+}
+
+// vdevSizeTolerance is how far (as a fraction) a vdev member's size may be
+// from the other members'. Drives of the same nominal capacity have the same
+// LBA count whatever the vendor, so any real difference is a different model.
+const vdevSizeTolerance = 0.001
+
+// checkPoolProperties warns about pool settings that are costly to leave
+// wrong: top-level vdevs created with different ashifts, autotrim off on a
+// pool of SSDs, and failmode=wait on a pool listed in critical_pools
+func checkPoolProperties(props *zfs.PoolProperties, drives []drive.DriveInfo, criticalPools []string, result *HealthcheckResult) {
+	warn := func(category, message string, details map[string]any) {
+		details["pool"] = props.Name
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: category,
+			Message:  message,
+			Details:  details,
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+
+	if props.AshiftMismatch() {
+		warn("pool_ashift",
+			fmt.Sprintf("ZFS pool %s has vdevs with different ashifts (%s)", props.Name, props.VdevAshiftSummary()),
+			map[string]any{"vdev_ashift": props.VdevAshift})
+	}
+
+	if props.Get("autotrim") == "off" {
+		members, ssds := 0, 0
+		for _, d := range drives {
+			if d.Zpool == nil || *d.Zpool != props.Name {
+				continue
+			}
+			members++
+			if isSSD(d) {
+				ssds++
+			}
+		}
+		if members > 0 && ssds == members {
+			warn("pool_autotrim",
+				fmt.Sprintf("ZFS pool %s is all SSD but has autotrim=off", props.Name),
+				map[string]any{"autotrim": "off", "drives": members})
+		}
+	}
+
+	critical := false
+	for _, name := range criticalPools {
+		if name == props.Name {
+			critical = true
+		}
+	}
+	if critical && props.Get("failmode") == "wait" {
+		warn("pool_failmode",
+			fmt.Sprintf("Critical ZFS pool %s has failmode=wait; I/O will hang if the pool fails", props.Name),
+			map[string]any{"failmode": "wait"})
+	}
+}
+
+// isSSD reports whether a drive is solid state, by rotation rate or, when
+// smartctl didn't report one, the HBA's media type
+func isSSD(d drive.DriveInfo) bool {
+	if d.RotationRate != nil {
+		return *d.RotationRate == 0
+	}
+	return d.DriveType != nil && strings.Contains(strings.ToUpper(*d.DriveType), "SSD")
+}
+
+// checkVdevSizes warns about mirror/raidz members whose size differs from
+// the rest of their vdev; ZFS only uses the smallest member's capacity
+func checkVdevSizes(drives []drive.DriveInfo, result *HealthcheckResult) {
+	members := make(map[string][]drive.DriveInfo)
+	var vdevs []string
+	for _, d := range drives {
+		if d.Zpool == nil || d.Vdev == nil || d.SizeBytes == nil || *d.SizeBytes <= 0 {
+			continue
+		}
+		key := *d.Zpool + "/" + *d.Vdev
+		if _, ok := members[key]; !ok {
+			vdevs = append(vdevs, key)
+		}
+		members[key] = append(members[key], d)
+	}
+	sort.Strings(vdevs)
+
+	for _, key := range vdevs {
+		group := members[key]
+		if len(group) < 2 {
+			continue
+		}
+
+		// The most common size is the vdev's intended size (the larger on a tie)
+		counts := make(map[int64]int)
+		var common int64
+		for _, d := range group {
+			size := *d.SizeBytes
+			counts[size]++
+			if counts[size] > counts[common] || counts[size] == counts[common] && size > common {
+				common = size
+			}
+		}
+
+		for _, d := range group {
+			size := *d.SizeBytes
+			diff := float64(size-common) / float64(common)
+			if diff >= -vdevSizeTolerance && diff <= vdevSizeTolerance {
+				continue
+			}
+
+			bay := "unknown bay"
+			if d.Enclosure != nil && d.Slot != nil {
+				bay = fmt.Sprintf("%s:%d", derefOr(d.EnclosureLabel, strconv.Itoa(*d.Enclosure)), *d.Slot)
+			}
+			serial := derefOr(d.Serial, "unknown")
+			have, want := humanize.Bytes(size), humanize.Bytes(common)
+			if have == want {
+				// Within a rounding step; show the exact sizes
+				have = fmt.Sprintf("%s (%d bytes)", have, size)
+				want = fmt.Sprintf("%s (%d bytes)", want, common)
+			}
+			result.Alerts = append(result.Alerts, HealthAlert{
+				Severity: "warning",
+				Category: "vdev_size_mismatch",
+				Message: fmt.Sprintf("Drive %s (serial %s, bay %s) in %s is %s, other members are %s",
+					d.Device, serial, bay, key, have, want),
+				Details: map[string]any{
+					"device": d.Device, "serial": serial, "bay": bay, "pool": *d.Zpool, "vdev": *d.Vdev,
+					"size_bytes": size, "expected_size_bytes": common,
+				},
+			})
+			if result.Status == "healthy" {
+				result.Status = "warning"
+			}
+		}
+	}
+}
+
+// checkUnmappedVdevs warns about pool vdevs whose GUID no longer maps to
+// any device on the system
+func checkUnmappedVdevs(vdevs []*collector.ZpoolVdev, result *HealthcheckResult) {
+	for _, vdev := range vdevs {
+		details := map[string]any{"pool": vdev.PoolName, "vdev_guid": vdev.VdevGUID, "state": vdev.State}
+		if vdev.DevicePath != nil {
+			details["device_path"] = *vdev.DevicePath
+		}
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "vdev_unmapped",
+			Message:  fmt.Sprintf("ZFS pool %s vdev %s (%s) does not map to any device", vdev.PoolName, vdev.VdevGUID, vdev.State),
+			Details:  details,
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// checkFirmwareMismatch warns when a pool holds one drive model on more than
+// one firmware revision, which can cause compatibility problems within a vdev
+func checkFirmwareMismatch(groups []*db.FirmwareGroup, result *HealthcheckResult) {
+	type poolModel struct{ pool, model string }
+	var order []poolModel
+	revisions := make(map[poolModel][]*db.FirmwareGroup)
+	for _, g := range groups {
+		// Unknown model or firmware can't be compared
+		if g.Model == "" || g.Firmware == "" {
+			continue
+		}
+		key := poolModel{g.Pool, g.Model}
+		if _, ok := revisions[key]; !ok {
+			order = append(order, key)
+		}
+		revisions[key] = append(revisions[key], g)
+	}
+
+	for _, key := range order {
+		revs := revisions[key]
+		if len(revs) < 2 {
+			continue
+		}
+
+		serialsByRev := make(map[string][]string)
+		names := make([]string, len(revs))
+		for i, g := range revs {
+			serialsByRev[g.Firmware] = g.Serials
+			names[i] = fmt.Sprintf("%s (%d)", g.Firmware, len(g.Serials))
+		}
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "firmware_mismatch",
+			Message: fmt.Sprintf("ZFS pool %s has %s drives on %d firmware revisions: %s",
+				key.pool, key.model, len(revs), strings.Join(names, ", ")),
+			Details: map[string]any{"pool": key.pool, "model": key.model, "firmware": serialsByRev},
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// checkDuplicateSerials warns when several drives on the HBA report the same
+// serial. Lookups by serial refuse to pick one, so such drives can't be
+// located or matched to their bay by serial.
+func checkDuplicateSerials(devices []hba.PhysicalDevice, result *HealthcheckResult) {
+	dups := hba.DuplicateSerials(devices)
+	serials := make([]string, 0, len(dups))
+	for serial := range dups {
+		serials = append(serials, serial)
+	}
+	sort.Strings(serials)
+
+	for _, serial := range serials {
+		slots := make([]string, len(dups[serial]))
+		for i, d := range dups[serial] {
+			slots[i] = fmt.Sprintf("%d:%d", d.EnclosureID, d.Slot)
+		}
+		result.Alerts = append(result.Alerts, HealthAlert{
+			Severity: "warning",
+			Category: "duplicate_serial",
+			Message: fmt.Sprintf("Serial %s is reported by %d drives (slots %s)",
+				serial, len(slots), strings.Join(slots, ", ")),
+			Details: map[string]any{"serial": serial, "slots": slots},
+		})
+		if result.Status == "healthy" {
+			result.Status = "warning"
+		}
+	}
+}
+
+// normalizeEnclosureID lowercases an enclosure SAS address and strips 0x
+func normalizeEnclosureID(id string) string {
+	return strings.TrimPrefix(strings.ToLower(strings.TrimSpace(id)), "0x")
+}
+
 func updateInventoryFromHealthcheck(database *db.DB, hbaDevices []hba.PhysicalDevice, driveInfos []drive.DriveInfo) {
 	// Build map of drive info by serial
 	driveByDevice := make(map[string]drive.DriveInfo)
 	for _, d := range driveInfos {
 		driveByDevice[d.Device] = d
 	}
+	temps := activeTempsBySerial(driveInfos)
+	probed := lastProbedBySerial(driveInfos)
+	defects := grownDefectsBySerial(driveInfos)
 
 	var wg sync.WaitGroup
 	for _, dev := range hbaDevices {
@@ -413,7 +1417,21 @@ func updateInventoryFromHealthcheck(database *db.DB, hbaDevices []hba.PhysicalDe
 				record.Slot = &sl
 			}
 
-			database.UpsertDrive(record)
+			if err := database.UpsertDrive(record); err != nil {
+				return
+			}
+
+			if temp, ok := lookupBySerial(temps, device); ok {
+				if existing, _ := database.GetDriveBySerial(serial); existing != nil {
+					database.RecordTemperature(existing.ID, temp)
+				}
+			}
+			if at, ok := lookupBySerial(probed, device); ok {
+				database.UpdateLastSmartOK(serial, at)
+			}
+			if count, ok := lookupBySerial(defects, device); ok {
+				database.UpdateGrownDefects(serial, count)
+			}
 		}(dev)
 	}
 	wg.Wait()
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// HealthcheckDiff summarizes what changed between two healthcheck captures
+type HealthcheckDiff struct {
+	OldTimestamp time.Time `json:"old_timestamp"`
+	NewTimestamp time.Time `json:"new_timestamp"`
+	OldStatus    string    `json:"old_status"`
+	NewStatus    string    `json:"new_status"`
+
+	NewlyMissing []string `json:"newly_missing,omitempty"`
+	NewlyFailed  []string `json:"newly_failed,omitempty"`
+	Recovered    []string `json:"recovered,omitempty"` // previously missing/failed/unresponsive, now none
+
+	NewlyUnresponsive []string `json:"newly_unresponsive,omitempty"`
+
+	NewTempWarn []string `json:"new_temp_warn,omitempty"`
+	TempCleared []string `json:"temp_cleared,omitempty"`
+	NewEmpty    []string `json:"new_empty_slots,omitempty"`
+	Refilled    []string `json:"refilled_slots,omitempty"`
+
+	TempChanges []TempChange `json:"temp_changes,omitempty"`
+	PoolChanges []PoolChange `json:"pool_changes,omitempty"`
+
+	NewAlerts      []HealthAlert `json:"new_alerts,omitempty"`
+	ResolvedAlerts []HealthAlert `json:"resolved_alerts,omitempty"`
+}
+
+// TempChange is a drive temperature change beyond the reporting threshold
+type TempChange struct {
+	Device string `json:"device"`
+	Old    int    `json:"old"`
+	New    int    `json:"new"`
+	Delta  int    `json:"delta"`
+}
+
+// PoolChange is a pool state or error count transition. A pool that
+// appeared or disappeared has an empty old or new state.
+type PoolChange struct {
+	Name      string `json:"name"`
+	OldState  string `json:"old_state,omitempty"`
+	NewState  string `json:"new_state,omitempty"`
+	OldErrors int64  `json:"old_errors"`
+	NewErrors int64  `json:"new_errors"`
+}
+
+var healthcheckDiffCmd = &cobra.Command{
+	Use:   "diff <old.json> <new.json>",
+	Short: "Compare two healthcheck JSON captures",
+	Long: `Compare two saved 'healthcheck --json' captures and report what changed:
+newly missing or failed drives, recovered drives, temperature changes of at
+least --temp-delta °C, slot occupancy changes, pool state transitions, and
+new or resolved alerts.
+
+Examples:
+  jbodgod healthcheck --json > $(date +%F).json
+  jbodgod healthcheck diff 2024-06-01.json 2024-06-02.json
+  jbodgod healthcheck diff old.json new.json --json`,
+	Args: cobra.ExactArgs(2),
+	Run:  runHealthcheckDiff,
+}
+
+func runHealthcheckDiff(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	tempDelta, _ := cmd.Flags().GetInt("temp-delta")
+
+	oldResult, err := readHealthcheckResult(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	newResult, err := readHealthcheckResult(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	diff := diffHealthcheck(oldResult, newResult, tempDelta)
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(diff)
+		return
+	}
+
+	printHealthcheckDiff(diff)
+}
+
+// readHealthcheckResult loads a saved 'healthcheck --json' capture
+func readHealthcheckResult(path string) (*HealthcheckResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var result HealthcheckResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &result, nil
+}
+
+// diffHealthcheck compares two captures
+func diffHealthcheck(old, cur *HealthcheckResult, tempDelta int) *HealthcheckDiff {
+	diff := &HealthcheckDiff{
+		OldTimestamp: old.Timestamp,
+		NewTimestamp: cur.Timestamp,
+		OldStatus:    old.Status,
+		NewStatus:    cur.Status,
+	}
+
+	diff.NewlyMissing = stringsAdded(old.Drives.Missing, cur.Drives.Missing)
+	diff.NewlyFailed = stringsAdded(old.Drives.Failed, cur.Drives.Failed)
+	diff.NewlyUnresponsive = stringsAdded(old.Drives.Unresponsive, cur.Drives.Unresponsive)
+	diff.Recovered = stringsAdded(
+		append(append(append([]string{}, cur.Drives.Missing...), cur.Drives.Failed...), cur.Drives.Unresponsive...),
+		append(append(append([]string{}, old.Drives.Missing...), old.Drives.Failed...), old.Drives.Unresponsive...),
+	)
+	diff.NewTempWarn = stringsAdded(old.Drives.TempWarn, cur.Drives.TempWarn)
+	diff.TempCleared = stringsAdded(cur.Drives.TempWarn, old.Drives.TempWarn)
+	diff.NewEmpty = stringsAdded(old.Drives.EmptySlots, cur.Drives.EmptySlots)
+	diff.Refilled = stringsAdded(cur.Drives.EmptySlots, old.Drives.EmptySlots)
+
+	// Temperature changes
+	oldTemps, curTemps := captureTemps(old), captureTemps(cur)
+	for dev, t := range curTemps {
+		prev, ok := oldTemps[dev]
+		if !ok {
+			continue
+		}
+		delta := t - prev
+		if delta >= tempDelta || -delta >= tempDelta {
+			diff.TempChanges = append(diff.TempChanges, TempChange{Device: dev, Old: prev, New: t, Delta: delta})
+		}
+	}
+	sort.Slice(diff.TempChanges, func(i, j int) bool {
+		return diff.TempChanges[i].Device < diff.TempChanges[j].Device
+	})
+
+	// Pool transitions
+	oldPools := make(map[string]PoolHealthSummary)
+	for _, p := range old.Pools {
+		oldPools[p.Name] = p
+	}
+	seen := make(map[string]bool)
+	for _, p := range cur.Pools {
+		seen[p.Name] = true
+		prev, ok := oldPools[p.Name]
+		if !ok {
+			diff.PoolChanges = append(diff.PoolChanges, PoolChange{Name: p.Name, NewState: p.State, NewErrors: p.ErrorCount})
+			continue
+		}
+		if prev.State != p.State || prev.ErrorCount != p.ErrorCount {
+			diff.PoolChanges = append(diff.PoolChanges, PoolChange{
+				Name: p.Name, OldState: prev.State, NewState: p.State,
+				OldErrors: prev.ErrorCount, NewErrors: p.ErrorCount,
+			})
+		}
+	}
+	for _, p := range old.Pools {
+		if !seen[p.Name] {
+			diff.PoolChanges = append(diff.PoolChanges, PoolChange{Name: p.Name, OldState: p.State, OldErrors: p.ErrorCount})
+		}
+	}
+
+	// Alerts, matched by category and message
+	alertKey := func(a HealthAlert) string { return a.Category + "|" + a.Message }
+	oldAlerts := make(map[string]bool)
+	for _, a := range old.Alerts {
+		oldAlerts[alertKey(a)] = true
+	}
+	curAlerts := make(map[string]bool)
+	for _, a := range cur.Alerts {
+		curAlerts[alertKey(a)] = true
+		if !oldAlerts[alertKey(a)] {
+			diff.NewAlerts = append(diff.NewAlerts, a)
+		}
+	}
+	for _, a := range old.Alerts {
+		if !curAlerts[alertKey(a)] {
+			diff.ResolvedAlerts = append(diff.ResolvedAlerts, a)
+		}
+	}
+
+	return diff
+}
+
+// captureTemps returns per-device temperatures from a capture. Older
+// captures without the temps map fall back to temperature alert details.
+func captureTemps(r *HealthcheckResult) map[string]int {
+	if len(r.Drives.Temps) > 0 {
+		return r.Drives.Temps
+	}
+	temps := make(map[string]int)
+	for _, a := range r.Alerts {
+		if a.Category != "temperature" {
+			continue
+		}
+		details, ok := a.Details.(map[string]any)
+		if !ok {
+			continue
+		}
+		dev, _ := details["device"].(string)
+		temp, ok := details["temp"].(float64) // JSON numbers decode as float64
+		if dev != "" && ok {
+			temps[dev] = int(temp)
+		}
+	}
+	return temps
+}
+
+// stringsAdded returns entries in cur that aren't in old, sorted
+func stringsAdded(old, cur []string) []string {
+	had := make(map[string]bool, len(old))
+	for _, s := range old {
+		had[s] = true
+	}
+	var added []string
+	for _, s := range cur {
+		if !had[s] {
+			added = append(added, s)
+			had[s] = true
+		}
+	}
+	sort.Strings(added)
+	return added
+}
+
+func printHealthcheckDiff(diff *HealthcheckDiff) {
+	fmt.Printf("Healthcheck diff: %s -> %s\n",
+		diff.OldTimestamp.Format("2006-01-02 15:04:05"),
+		diff.NewTimestamp.Format("2006-01-02 15:04:05"))
+	fmt.Println(strings.Repeat("=", 50))
+
+	if diff.OldStatus != diff.NewStatus {
+		fmt.Printf("Status: %s -> %s\n", strings.ToUpper(diff.OldStatus), strings.ToUpper(diff.NewStatus))
+	} else {
+		fmt.Printf("Status: %s (unchanged)\n", strings.ToUpper(diff.NewStatus))
+	}
+
+	changes := 0
+	printList := func(label string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		changes++
+		fmt.Printf("\n%s (%d):\n", label, len(items))
+		for _, item := range items {
+			fmt.Printf("  - %s\n", item)
+		}
+	}
+
+	printList("Newly missing drives", diff.NewlyMissing)
+	printList("Newly failed drives", diff.NewlyFailed)
+	printList("Newly unresponsive drives", diff.NewlyUnresponsive)
+	printList("Recovered drives", diff.Recovered)
+	printList("New temperature warnings", diff.NewTempWarn)
+	printList("Temperature warnings cleared", diff.TempCleared)
+	printList("Newly empty slots", diff.NewEmpty)
+	printList("Refilled slots", diff.Refilled)
+
+	if len(diff.TempChanges) > 0 {
+		changes++
+		fmt.Printf("\nTemperature changes (%d):\n", len(diff.TempChanges))
+		for _, t := range diff.TempChanges {
+			fmt.Printf("  - %s: %d°C -> %d°C (%+d)\n", t.Device, t.Old, t.New, t.Delta)
+		}
+	}
+
+	if len(diff.PoolChanges) > 0 {
+		changes++
+		fmt.Printf("\nPool changes (%d):\n", len(diff.PoolChanges))
+		for _, p := range diff.PoolChanges {
+			switch {
+			case p.OldState == "":
+				fmt.Printf("  - %s: appeared (%s)\n", p.Name, p.NewState)
+			case p.NewState == "":
+				fmt.Printf("  - %s: disappeared (was %s)\n", p.Name, p.OldState)
+			default:
+				fmt.Printf("  - %s: %s -> %s, errors %d -> %d\n", p.Name, p.OldState, p.NewState, p.OldErrors, p.NewErrors)
+			}
+		}
+	}
+
+	if len(diff.NewAlerts) > 0 {
+		changes++
+		fmt.Printf("\nNew alerts (%d):\n", len(diff.NewAlerts))
+		for _, a := range diff.NewAlerts {
+			fmt.Printf("  [%s] %s\n", strings.ToUpper(a.Severity), a.Message)
+		}
+	}
+	if len(diff.ResolvedAlerts) > 0 {
+		changes++
+		fmt.Printf("\nResolved alerts (%d):\n", len(diff.ResolvedAlerts))
+		for _, a := range diff.ResolvedAlerts {
+			fmt.Printf("  [%s] %s\n", strings.ToUpper(a.Severity), a.Message)
+		}
+	}
+
+	if changes == 0 {
+		fmt.Println("\nNo changes.")
+	}
+}
@@ -1,8 +1,11 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/sigreer/jbodgod/internal/identify"
 	"github.com/spf13/cobra"
@@ -22,20 +25,36 @@ Examples:
   jbodgod identify 0x5000c500d006891c          # WWN
   jbodgod identify 14707061191158689053        # ZFS pool GUID
   jbodgod identify tank                        # ZFS pool name
-  jbodgod identify 2f4ca112-c476-...           # GPT Partition UUID`,
-	Args: cobra.ExactArgs(1),
-	Run:  runIdentify,
+  jbodgod identify 2f4ca112-c476-...           # GPT Partition UUID
+  jbodgod identify --conflicts                 # Report shared identifiers (e.g. duplicate WWNs)
+  jbodgod identify --all                       # Dump every indexed device and what it's reachable by`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		conflicts, _ := cmd.Flags().GetBool("conflicts")
+		all, _ := cmd.Flags().GetBool("all")
+		if conflicts || all {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
+	Run: runIdentify,
 }
 
 func init() {
 	identifyCmd.Flags().StringP("output", "o", "json", "Output format: json, table")
+	identifyCmd.Flags().Bool("json", false, "Output as JSON (same as --output json)")
 	identifyCmd.Flags().BoolP("quiet", "q", false, "Only output device path")
+	identifyCmd.Flags().Bool("conflicts", false, "Report identifiers shared by multiple devices")
+	identifyCmd.Flags().Bool("all", false, "Dump every entity in the device index as JSON, with the identifiers each is reachable by")
 }
 
 func runIdentify(cmd *cobra.Command, args []string) {
-	query := args[0]
 	outputFmt, _ := cmd.Flags().GetString("output")
 	quiet, _ := cmd.Flags().GetBool("quiet")
+	conflicts, _ := cmd.Flags().GetBool("conflicts")
+	all, _ := cmd.Flags().GetBool("all")
+	if jsonOut, _ := cmd.Flags().GetBool("json"); jsonOut {
+		outputFmt = "json"
+	}
 
 	// Build the device index
 	idx, err := identify.BuildIndex()
@@ -44,18 +63,39 @@ func runIdentify(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
+	if conflicts {
+		printConflicts(idx.Conflicts, outputFmt)
+		return
+	}
+
+	if all {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(idx.Dump()); err != nil {
+			fmt.Fprintf(os.Stderr, "Error encoding output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Look up the query
-	entity, matchedAs, err := idx.Lookup(query)
+	query := args[0]
+	entity, matchedAs, confidence, err := idx.LookupWithConfidence(query)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Not found: %s\n", query)
+		if errors.Is(err, identify.ErrAmbiguous) {
+			fmt.Fprintf(os.Stderr, "Ambiguous: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "Not found: %s\n", query)
+		}
 		os.Exit(1)
 	}
 
 	// Create result
 	result := &identify.LookupResult{
-		Query:     query,
-		MatchedAs: matchedAs,
-		Device:    entity,
+		Query:      query,
+		MatchedAs:  matchedAs,
+		Confidence: confidence,
+		Device:     entity,
 	}
 
 	// Output based on format
@@ -74,3 +114,27 @@ func runIdentify(cmd *cobra.Command, args []string) {
 		}
 	}
 }
+
+// printConflicts reports identifiers that map to more than one device
+func printConflicts(conflicts []identify.Conflict, outputFmt string) {
+	if outputFmt != "table" {
+		if conflicts == nil {
+			conflicts = []identify.Conflict{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(conflicts)
+		return
+	}
+
+	if len(conflicts) == 0 {
+		fmt.Println("No identifier conflicts found.")
+		return
+	}
+
+	fmt.Printf("%-10s %-24s %s\n", "TYPE", "VALUE", "DEVICES")
+	fmt.Println(strings.Repeat("-", 70))
+	for _, c := range conflicts {
+		fmt.Printf("%-10s %-24s %s\n", c.Type, c.Value, strings.Join(c.Devices, ", "))
+	}
+}
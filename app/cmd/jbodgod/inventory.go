@@ -1,14 +1,25 @@
 package main
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/sigreer/jbodgod/internal/collector"
 	"github.com/sigreer/jbodgod/internal/config"
 	"github.com/sigreer/jbodgod/internal/db"
+	"github.com/sigreer/jbodgod/internal/drive"
 	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/humanize"
+	"github.com/sigreer/jbodgod/internal/ses"
+	"github.com/sigreer/jbodgod/internal/zfs"
 	"github.com/spf13/cobra"
 )
 
@@ -36,7 +47,13 @@ This command:
   - Queries the HBA for all connected drives
   - Gets drive info via smartctl
   - Updates or creates inventory records
-  - Records state change events`,
+  - Records state change events
+
+With --changed-only, drives whose location, device path, pool and firmware
+match the last sync and that are still active in the inventory are not
+re-written; only new, moved, renamed, re-flashed and returning drives are
+upserted. Temperatures are recorded for all active
+drives either way. The first sync (no previous roster) is always full.`,
 	Run: runInventorySync,
 }
 
@@ -50,7 +67,17 @@ var inventoryShowCmd = &cobra.Command{
 var inventoryEventsCmd = &cobra.Command{
 	Use:   "events",
 	Short: "Show recent drive events",
-	Run:   runInventoryEvents,
+	Long: `Show drive events, newest first.
+
+By default the last --limit events are shown. --since (a duration such as 6h
+or 7d) and --after (a date, 2024-01-01, or an RFC 3339 time) instead show
+every event in that window; --limit then only applies if given explicitly.
+Both combine with --type.
+
+Examples:
+  jbodgod inventory events --since 6h
+  jbodgod inventory events --after 2024-01-01 --type removed`,
+	Run: runInventoryEvents,
 }
 
 var inventoryAlertsCmd = &cobra.Command{
@@ -59,31 +86,148 @@ var inventoryAlertsCmd = &cobra.Command{
 	Run:   runInventoryAlerts,
 }
 
+var inventorySeedCmd = &cobra.Command{
+	Use:   "seed <file.csv|file.json>",
+	Short: "Pre-seed inventory from purchase records",
+	Long: `Pre-create drive records from a CSV or JSON file of purchase records.
+
+Seeded drives start in state 'unknown'. When they are installed and synced,
+the existing record is updated and the purchase/warranty data is preserved.
+
+Recognised columns (case-insensitive): serial (required), model,
+manufacturer (or vendor), size_bytes, purchase_date, warranty_expires
+(or warranty). Any other column (e.g. asset_tag, supplier) is stored as
+metadata on the drive.
+
+CSV files must have a header row. JSON files contain an array of objects.
+
+Examples:
+  jbodgod inventory seed purchases.csv
+  jbodgod inventory seed purchases.json`,
+	Args: cobra.ExactArgs(1),
+	Run:  runInventorySeed,
+}
+
+var inventoryPruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "Delete old events and compact the database",
+	Long: `Delete drive events older than --events and/or compact the database with
+--vacuum, which reclaims the space of deleted rows and truncates the WAL file.
+
+Examples:
+  jbodgod inventory prune --events 180d
+  jbodgod inventory prune --events 180d --vacuum
+  jbodgod inventory prune --vacuum`,
+	Run: runInventoryPrune,
+}
+
+var inventoryLocateMissingCmd = &cobra.Command{
+	Use:   "locate-missing",
+	Short: "List the last-known bays of missing and failed drives",
+	Long: `List drives the inventory records as missing or failed, with the
+enclosure:slot they were last seen in, so you know which bay to check.
+
+With --led the fault LED of each of those bays is turned on (using the
+stored location, so it works after the drive has disappeared). Turn them
+off again with --led-off once the drives have been dealt with.
+
+Examples:
+  jbodgod inventory locate-missing
+  sudo jbodgod inventory locate-missing --led
+  sudo jbodgod inventory locate-missing --led-off`,
+	Run: runInventoryLocateMissing,
+}
+
+var inventoryReplaceCmd = &cobra.Command{
+	Use:   "replace <old-serial> <new-serial>",
+	Short: "Record that a drive was replaced by another",
+	Long: `Record a drive replacement in the inventory. The new drive takes over the
+old drive's ZFS pool, vdev type and enclosure slot, the old drive is marked
+'replaced', and a 'replaced' event naming the other drive is recorded on
+both. The new drive is added to the inventory if it isn't there yet.
+
+With --zpool-replace, 'zpool replace' is also run to resilver the old drive's
+vdev onto the new drive. The new drive must be attached and the old drive's
+pool and vdev GUID known to the inventory (run 'inventory sync' first).
+--dry-run makes the same checks and prints what would be recorded and run.
+
+Examples:
+  jbodgod inventory replace ZA1DKJT7 ZA1FQ0P2
+  sudo jbodgod inventory replace ZA1DKJT7 ZA1FQ0P2 --zpool-replace`,
+	Args: cobra.ExactArgs(2),
+	Run:  runInventoryReplace,
+}
+
+var inventoryNoteCmd = &cobra.Command{
+	Use:   "note <serial> <text>",
+	Short: "Attach a note to a drive",
+	Long: `Set a free-text note on a drive, shown by 'inventory show'. Syncs never
+change it. An empty note ("") clears it.
+
+Examples:
+  jbodgod inventory note ZA1DKJT7 "RMA pending, case 48213"
+  jbodgod inventory note ZA1DKJT7 ""`,
+	Args: cobra.ExactArgs(2),
+	Run:  runInventoryNote,
+}
+
 func init() {
 	inventoryCmd.AddCommand(inventoryListCmd)
 	inventoryCmd.AddCommand(inventorySyncCmd)
 	inventoryCmd.AddCommand(inventoryShowCmd)
 	inventoryCmd.AddCommand(inventoryEventsCmd)
 	inventoryCmd.AddCommand(inventoryAlertsCmd)
+	inventoryCmd.AddCommand(inventorySeedCmd)
+	inventoryCmd.AddCommand(inventoryPruneCmd)
+	inventoryCmd.AddCommand(inventoryLocateMissingCmd)
+	inventoryCmd.AddCommand(inventoryReplaceCmd)
+	inventoryCmd.AddCommand(inventoryNoteCmd)
 
 	// Add flags
 	inventoryListCmd.Flags().Bool("json", false, "Output as JSON")
+	inventoryListCmd.Flags().Bool("csv", false, "Output as CSV (serial, enclosure, slot, state, device, pool, model, size, firmware)")
 	inventoryListCmd.Flags().String("state", "", "Filter by state (active, missing, failed)")
 	inventoryListCmd.Flags().String("pool", "", "Filter by ZFS pool name")
+	inventoryListCmd.Flags().String("stale", "", "Only drives not seen for this long, any state (e.g. 30d, 72h)")
 
 	inventorySyncCmd.Flags().Bool("verbose", false, "Show detailed sync progress")
+	inventorySyncCmd.Flags().Bool("changed-only", false, "Only upsert drives that are new, moved or changed state since the last sync")
 
 	inventoryEventsCmd.Flags().Int("limit", 50, "Maximum number of events to show")
 	inventoryEventsCmd.Flags().String("type", "", "Filter by event type")
+	inventoryEventsCmd.Flags().String("since", "", "Show events within this long ago (e.g. 6h, 7d)")
+	inventoryEventsCmd.Flags().String("after", "", "Show events after this date (2024-01-01 or RFC 3339)")
 
 	inventoryAlertsCmd.Flags().Bool("ack-all", false, "Acknowledge all alerts")
 	inventoryAlertsCmd.Flags().Int64("ack", 0, "Acknowledge specific alert by ID")
+
+	inventoryPruneCmd.Flags().String("events", "", "Delete events older than this (e.g. 180d, 720h)")
+	inventoryPruneCmd.Flags().Bool("vacuum", false, "Compact the database and truncate the WAL")
+
+	inventoryLocateMissingCmd.Flags().Bool("json", false, "Output as JSON")
+	inventoryLocateMissingCmd.Flags().Bool("led", false, "Turn on the fault LED of each bay")
+	inventoryLocateMissingCmd.Flags().Bool("led-off", false, "Turn off the fault LED of each bay")
+
+	inventoryReplaceCmd.Flags().Bool("zpool-replace", false, "Also run 'zpool replace' onto the new drive")
+	inventoryReplaceCmd.Flags().Bool("dry-run", false, "Print what would be recorded and run without changing anything")
 }
 
 func openDB() (*db.DB, error) {
-	dbPath := db.DefaultPath
-	// Could add config option for custom path
-	return db.New(dbPath)
+	// Only the config file is needed for db_path; skip drive discovery
+	cfg, _ := config.LoadFile(cfgFile)
+	return db.New(resolveDBPath(cfg))
+}
+
+// parseAge parses a duration that may also be given in days (e.g. "30d")
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("bad day count %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
 }
 
 func runInventoryList(cmd *cobra.Command, args []string) {
@@ -95,12 +239,26 @@ func runInventoryList(cmd *cobra.Command, args []string) {
 	defer database.Close()
 
 	jsonOut, _ := cmd.Flags().GetBool("json")
+	csvOut, _ := cmd.Flags().GetBool("csv")
 	stateFilter, _ := cmd.Flags().GetString("state")
 	poolFilter, _ := cmd.Flags().GetString("pool")
+	staleFlag, _ := cmd.Flags().GetString("stale")
+
+	if jsonOut && csvOut {
+		fmt.Fprintln(os.Stderr, "Error: --json and --csv are mutually exclusive")
+		os.Exit(1)
+	}
 
 	var drives []*db.DriveRecord
 
-	if stateFilter != "" {
+	if staleFlag != "" {
+		age, perr := parseAge(staleFlag)
+		if perr != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --stale value: %v\n", perr)
+			os.Exit(1)
+		}
+		drives, err = database.GetStaleDrives(age)
+	} else if stateFilter != "" {
 		drives, err = database.GetDrivesByState(stateFilter)
 	} else if poolFilter != "" {
 		drives, err = database.GetDrivesByPool(poolFilter)
@@ -113,7 +271,12 @@ func runInventoryList(cmd *cobra.Command, args []string) {
 		os.Exit(1)
 	}
 
-	if len(drives) == 0 {
+	// An empty CSV still gets its header row
+	if len(drives) == 0 && !csvOut {
+		if staleFlag != "" {
+			fmt.Printf("No drives unseen for %s.\n", staleFlag)
+			return
+		}
 		fmt.Println("No drives in inventory. Run 'jbodgod inventory sync' to populate.")
 		return
 	}
@@ -125,9 +288,31 @@ func runInventoryList(cmd *cobra.Command, args []string) {
 		return
 	}
 
-	// Table output
-	fmt.Printf("%-20s %-8s %-10s %-12s %-15s %s\n", "SERIAL", "ENC:SLOT", "STATE", "DEVICE", "ZPOOL", "MODEL")
-	fmt.Println(strings.Repeat("-", 85))
+	if csvOut {
+		w := csv.NewWriter(os.Stdout)
+		w.Write(drive.CSVColumns)
+		for _, d := range drives {
+			record := make([]string, len(drive.CSVColumns))
+			for i, col := range drive.CSVColumns {
+				record[i] = cmdbColumnValue(d, col)
+			}
+			w.Write(record)
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Table output; stale listings show when each drive was last seen
+	lastHeader := "MODEL"
+	if staleFlag != "" {
+		lastHeader = "LAST SEEN"
+	}
+	fmt.Printf("%-20s %-8s %-10s %-12s %-15s %-9s %s\n", "SERIAL", "ENC:SLOT", "STATE", "DEVICE", "ZPOOL", "SIZE", lastHeader)
+	fmt.Println(strings.Repeat("-", 95))
 
 	for _, d := range drives {
 		slot := "-"
@@ -147,23 +332,32 @@ func runInventoryList(cmd *cobra.Command, args []string) {
 			pool = "-"
 		}
 
+		size := "-"
+		if d.SizeBytes > 0 {
+			size = humanize.Bytes(d.SizeBytes)
+		}
+
 		model := d.Model
 		if len(model) > 20 {
 			model = model[:20] + "..."
 		}
+		if staleFlag != "" {
+			model = fmt.Sprintf("%s (%dd ago)", d.LastSeen.Format("2006-01-02"), int(time.Since(d.LastSeen).Hours()/24))
+		}
 
-		fmt.Printf("%-20s %-8s %-10s %-12s %-15s %s\n",
-			d.Serial, slot, strings.ToUpper(d.CurrentState), device, pool, model)
+		fmt.Printf("%-20s %-8s %-10s %-12s %-15s %-9s %s\n",
+			d.Serial, slot, strings.ToUpper(d.CurrentState), device, pool, size, model)
 	}
 
 	// Summary
 	total, active, missing, failed, _ := database.DriveCount()
-	fmt.Println(strings.Repeat("-", 85))
+	fmt.Println(strings.Repeat("-", 95))
 	fmt.Printf("Total: %d | Active: %d | Missing: %d | Failed: %d\n", total, active, missing, failed)
 }
 
 func runInventorySync(cmd *cobra.Command, args []string) {
 	verbose, _ := cmd.Flags().GetBool("verbose")
+	changedOnly, _ := cmd.Flags().GetBool("changed-only")
 
 	database, err := openDB()
 	if err != nil {
@@ -177,23 +371,29 @@ func runInventorySync(cmd *cobra.Command, args []string) {
 		fmt.Fprintf(os.Stderr, "Warning: could not load config: %v\n", err)
 	}
 
+	run := syncInventory(database, cfg, changedOnly, verbose)
+	if run.Mode == db.SyncChanged {
+		fmt.Printf("Sync complete: %d created, %d updated, %d unchanged, %d marked missing\n", run.Created, run.Updated, run.Unchanged, run.Missing)
+		return
+	}
+	fmt.Printf("Sync complete: %d created, %d updated, %d marked missing\n", run.Created, run.Updated, run.Missing)
+}
+
+// syncInventory records the drives the HBA reports in the inventory, marks
+// drives it no longer reports as missing and stores a summary of the run.
+// With changedOnly, drives still active where the last sync saw them are
+// skipped (all drives are synced when there is no previous roster).
+func syncInventory(database *db.DB, cfg *config.Config, changedOnly, verbose bool) *db.SyncRun {
+	start := time.Now()
+
 	if verbose {
 		fmt.Println("Scanning HBA controllers...")
 	}
 
-	// Get HBA data
-	controllers := hba.ListControllers()
-	var allDevices []hba.PhysicalDevice
-
-	for _, ctrlNum := range controllers {
-		_, _, devices, err := hba.GetFullControllerInfo(fmt.Sprintf("c%d", ctrlNum), true)
-		if err != nil {
-			if verbose {
-				fmt.Printf("  Warning: controller %d: %v\n", ctrlNum, err)
-			}
-			continue
-		}
-		allDevices = append(allDevices, devices...)
+	// Get HBA data (drives on a multipath shelf are merged into one record)
+	_, _, allDevices, err := hba.GetAllControllerData(true)
+	if err != nil && verbose {
+		fmt.Printf("  Warning: %v\n", err)
 	}
 
 	if verbose {
@@ -209,8 +409,60 @@ func runInventorySync(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Current temperatures, SMART read times and grown defect counts of
+	// active drives (standby drives are not woken)
+	var temps, defects map[string]int
+	var probed map[string]time.Time
+	var devicePaths, pools map[string]string
+	if cfg != nil {
+		infos := drive.GetAll(cfg)
+		devicePaths = devicePathsBySerial(infos)
+		pools = poolsBySerial(infos)
+		temps = activeTempsBySerial(infos)
+		probed = lastProbedBySerial(infos)
+		defects = grownDefectsBySerial(infos)
+	}
+
+	// With --changed-only, drives the last sync saw the same way (see
+	// rosterEntry) and still active in the inventory are skipped
+	mode := db.SyncFull
+	var lastRoster map[string]string
+	known := make(map[string]*db.DriveRecord)
+	if changedOnly {
+		last, err := database.GetLastSync()
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "Warning: %v; doing a full sync\n", err)
+		case last == nil || len(last.Roster) == 0:
+			if verbose {
+				fmt.Println("No previous sync roster; doing a full sync")
+			}
+		default:
+			mode = db.SyncChanged
+			lastRoster = last.Roster
+			records, _ := database.GetAllDrives()
+			for _, r := range records {
+				known[r.Serial] = r
+			}
+		}
+	}
+
+	recordMetrics := func(driveID int64, serial string, device hba.PhysicalDevice) {
+		if temp, ok := lookupBySerial(temps, device); ok {
+			database.RecordTemperature(driveID, temp)
+		}
+		if at, ok := lookupBySerial(probed, device); ok {
+			database.UpdateLastSmartOK(serial, at)
+		}
+		if count, ok := lookupBySerial(defects, device); ok {
+			database.UpdateGrownDefects(serial, count)
+		}
+	}
+
 	// Sync each device (sequential to avoid SQLite lock issues)
-	var updated, created int
+	var updated, created, unchanged int
+	var skipped []string
+	roster := make(map[string]string)
 
 	for _, device := range allDevices {
 		serial := device.Serial
@@ -221,6 +473,19 @@ func runInventorySync(cmd *cobra.Command, args []string) {
 			continue // Skip devices without serial
 		}
 
+		devicePath, _ := lookupBySerial(devicePaths, device)
+		pool, _ := lookupBySerial(pools, device)
+		entry := rosterEntry(device, devicePath, pool)
+		roster[serial] = entry
+		if lastRoster != nil {
+			if rec := known[serial]; rec != nil && rec.CurrentState == db.StateActive && lastRoster[serial] == entry {
+				recordMetrics(rec.ID, serial, device)
+				skipped = append(skipped, serial)
+				unchanged++
+				continue
+			}
+		}
+
 		// Check if exists
 		existing, _ := database.GetDriveBySerial(serial)
 		isNew := existing == nil
@@ -235,6 +500,8 @@ func runInventorySync(cmd *cobra.Command, args []string) {
 			Protocol:     device.Protocol,
 			DriveType:    device.DriveType,
 			SASAddress:   device.SASAddress,
+			DevicePath:   devicePath, // the multipath map when the drive has one
+			ZpoolName:    pool,
 			CurrentState: db.StateActive, // Device is present in HBA
 		}
 
@@ -255,14 +522,18 @@ func runInventorySync(cmd *cobra.Command, args []string) {
 			continue
 		}
 
+		recordMetrics(record.ID, serial, device)
+
 		if isNew {
 			created++
 			// Record discovery event
 			database.RecordEvent(record.ID, db.EventDiscovered, "", db.StateActive, "", nil)
 		} else {
 			updated++
-			// Check for state change
-			if existing.CurrentState != db.StateActive {
+			// Check for state change (seeded drives are discovered on first sync)
+			if existing.CurrentState == db.StateUnknown {
+				database.RecordEvent(record.ID, db.EventDiscovered, existing.CurrentState, db.StateActive, "", nil)
+			} else if existing.CurrentState != db.StateActive {
 				database.RecordEvent(record.ID, db.EventOnline, existing.CurrentState, db.StateActive, "", nil)
 			}
 		}
@@ -276,6 +547,11 @@ func runInventorySync(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	// Skipped drives were still seen; without this --stale would list them
+	if err := database.TouchDrives(skipped); err != nil && verbose {
+		fmt.Printf("  Warning: %v\n", err)
+	}
+
 	// Check for missing drives (in DB but not in HBA)
 	allDrives, _ := database.GetAllDrives()
 	hbaSerials := make(map[string]bool)
@@ -301,7 +577,21 @@ func runInventorySync(cmd *cobra.Command, args []string) {
 		}
 	}
 
-	fmt.Printf("Sync complete: %d created, %d updated, %d marked missing\n", created, updated, missing)
+	run := &db.SyncRun{
+		Mode:       mode,
+		Devices:    len(roster),
+		Created:    created,
+		Updated:    updated,
+		Unchanged:  unchanged,
+		Missing:    missing,
+		DurationMs: time.Since(start).Milliseconds(),
+		Roster:     roster,
+		SyncedAt:   time.Now(),
+	}
+	if err := database.RecordSync(run); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return run
 }
 
 func runInventoryShow(cmd *cobra.Command, args []string) {
@@ -331,6 +621,9 @@ func runInventoryShow(cmd *cobra.Command, args []string) {
 	fmt.Printf("  Firmware:     %s\n", drive.Firmware)
 	fmt.Printf("  Protocol:     %s\n", drive.Protocol)
 	fmt.Printf("  Type:         %s\n", drive.DriveType)
+	if drive.SizeBytes > 0 {
+		fmt.Printf("  Size:         %s\n", humanize.Bytes(drive.SizeBytes))
+	}
 	fmt.Println()
 
 	if drive.EnclosureID != nil && drive.Slot != nil {
@@ -346,9 +639,53 @@ func runInventoryShow(cmd *cobra.Command, args []string) {
 	}
 	fmt.Println()
 
+	if drive.PurchaseDate != "" || drive.WarrantyExpires != "" || len(drive.Metadata) > 0 {
+		if drive.PurchaseDate != "" {
+			fmt.Printf("  Purchased:    %s\n", drive.PurchaseDate)
+		}
+		if drive.WarrantyExpires != "" {
+			fmt.Printf("  Warranty:     %s\n", drive.WarrantyExpires)
+		}
+		keys := make([]string, 0, len(drive.Metadata))
+		for k := range drive.Metadata {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  %-13s %s\n", k+":", drive.Metadata[k])
+		}
+		fmt.Println()
+	}
+
+	if drive.Notes != "" {
+		fmt.Printf("  Notes:        %s\n", drive.Notes)
+		fmt.Println()
+	}
+
 	fmt.Printf("  State:        %s\n", strings.ToUpper(drive.CurrentState))
 	fmt.Printf("  First Seen:   %s\n", drive.FirstSeen.Format("2006-01-02 15:04:05"))
 	fmt.Printf("  Last Seen:    %s\n", drive.LastSeen.Format("2006-01-02 15:04:05"))
+	if drive.LastSmartOK != nil {
+		fmt.Printf("  SMART Read:   %s\n", drive.LastSmartOK.Format("2006-01-02 15:04:05"))
+	}
+
+	// Temperature summary over the last 7 days
+	samples, err := database.GetTemperatureHistory(drive.Serial, time.Now().AddDate(0, 0, -7))
+	if err == nil && len(samples) > 0 {
+		min, max, sum := samples[0].TempC, samples[0].TempC, 0
+		for _, s := range samples {
+			if s.TempC < min {
+				min = s.TempC
+			}
+			if s.TempC > max {
+				max = s.TempC
+			}
+			sum += s.TempC
+		}
+		fmt.Println()
+		fmt.Printf("  Temp (7d):    min %d°C / max %d°C / avg %d°C (%d samples)\n",
+			min, max, sum/len(samples), len(samples))
+	}
 
 	// Show recent events
 	events, err := database.GetDriveEvents(drive.ID, 10)
@@ -375,10 +712,47 @@ func runInventoryEvents(cmd *cobra.Command, args []string) {
 
 	limit, _ := cmd.Flags().GetInt("limit")
 	eventType, _ := cmd.Flags().GetString("type")
+	sinceFlag, _ := cmd.Flags().GetString("since")
+	afterFlag, _ := cmd.Flags().GetString("after")
+
+	if sinceFlag != "" && afterFlag != "" {
+		fmt.Fprintln(os.Stderr, "Error: --since and --after cannot be used together")
+		os.Exit(1)
+	}
+
+	var since time.Time
+	if sinceFlag != "" {
+		age, err := parseAge(sinceFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --since value: %v\n", err)
+			os.Exit(1)
+		}
+		since = time.Now().Add(-age)
+	} else if afterFlag != "" {
+		since, err = parseEventTime(afterFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid --after value: %v\n", err)
+			os.Exit(1)
+		}
+	}
 
 	var events []*db.DriveEvent
 
-	if eventType != "" {
+	if !since.IsZero() {
+		events, err = database.GetEventsSince(since)
+		if err == nil && eventType != "" {
+			filtered := events[:0]
+			for _, e := range events {
+				if e.EventType == eventType {
+					filtered = append(filtered, e)
+				}
+			}
+			events = filtered
+		}
+		if cmd.Flags().Changed("limit") && limit > 0 && len(events) > limit {
+			events = events[:limit]
+		}
+	} else if eventType != "" {
 		events, err = database.GetEventsByType(eventType, limit)
 	} else {
 		events, err = database.GetRecentEvents(limit)
@@ -415,6 +789,20 @@ func runInventoryEvents(cmd *cobra.Command, args []string) {
 	}
 }
 
+// parseEventTime parses an --after value: a local date (2024-01-01), a local
+// date and time (2024-01-01 15:04), or an RFC 3339 timestamp
+func parseEventTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	for _, layout := range []string{"2006-01-02", "2006-01-02 15:04", "2006-01-02T15:04"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("expected a date like 2024-01-01, got %q", s)
+}
+
 func runInventoryAlerts(cmd *cobra.Command, args []string) {
 	database, err := openDB()
 	if err != nil {
@@ -472,3 +860,492 @@ func runInventoryAlerts(cmd *cobra.Command, args []string) {
 			a.ID, strings.ToUpper(a.Severity), a.Category, slot, a.Message)
 	}
 }
+
+func runInventoryPrune(cmd *cobra.Command, args []string) {
+	eventsAge, _ := cmd.Flags().GetString("events")
+	vacuum, _ := cmd.Flags().GetBool("vacuum")
+
+	if eventsAge == "" && !vacuum {
+		fmt.Fprintln(os.Stderr, "Error: specify --events <age> and/or --vacuum")
+		os.Exit(1)
+	}
+
+	var olderThan time.Duration
+	if eventsAge != "" {
+		var err error
+		olderThan, err = parseAge(eventsAge)
+		if err != nil || olderThan <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: invalid --events value %q (use e.g. 180d or 720h)\n", eventsAge)
+			os.Exit(1)
+		}
+	}
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	if eventsAge != "" {
+		n, err := database.PruneEvents(olderThan)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Deleted %d event(s) older than %s\n", n, eventsAge)
+	}
+
+	if vacuum {
+		before := dbFileSize(database.Path())
+		if err := database.Maintenance(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		after := dbFileSize(database.Path())
+		fmt.Printf("Database compacted: %s -> %s\n", humanize.Bytes(before), humanize.Bytes(after))
+	}
+}
+
+// MissingDriveLocation is a missing or failed drive with its last-known bay
+type MissingDriveLocation struct {
+	Serial      string    `json:"serial"`
+	Model       string    `json:"model,omitempty"`
+	State       string    `json:"state"`
+	EnclosureID *int      `json:"enclosure_id,omitempty"`
+	Slot        *int      `json:"slot,omitempty"`
+	DevicePath  string    `json:"device_path,omitempty"`
+	Zpool       string    `json:"zpool,omitempty"`
+	LastSeen    time.Time `json:"last_seen"`
+	LED         string    `json:"led,omitempty"` // "on", "off" or the error
+}
+
+func runInventoryLocateMissing(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	ledOn, _ := cmd.Flags().GetBool("led")
+	ledOff, _ := cmd.Flags().GetBool("led-off")
+
+	if ledOn && ledOff {
+		fmt.Fprintln(os.Stderr, "Error: --led and --led-off are mutually exclusive")
+		os.Exit(1)
+	}
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	var drives []*db.DriveRecord
+	for _, state := range []string{db.StateMissing, db.StateFailed} {
+		found, err := database.GetDrivesByState(state)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error querying drives: %v\n", err)
+			os.Exit(1)
+		}
+		drives = append(drives, found...)
+	}
+
+	locations := make([]MissingDriveLocation, 0, len(drives))
+	for _, d := range drives {
+		loc := MissingDriveLocation{
+			Serial:      d.Serial,
+			Model:       d.Model,
+			State:       d.CurrentState,
+			EnclosureID: d.EnclosureID,
+			Slot:        d.Slot,
+			DevicePath:  d.DevicePath,
+			Zpool:       d.ZpoolName,
+			LastSeen:    d.LastSeen,
+		}
+		if (ledOn || ledOff) && d.EnclosureID != nil && d.Slot != nil {
+			loc.LED = setMissingDriveFaultLED(d.Serial, database, ledOn)
+		}
+		locations = append(locations, loc)
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(locations)
+		return
+	}
+
+	if len(locations) == 0 {
+		fmt.Println("No missing or failed drives in inventory.")
+		return
+	}
+
+	fmt.Printf("%-20s %-8s %-8s %-22s %-12s %s\n", "SERIAL", "STATE", "ENC:SLOT", "MODEL", "ZPOOL", "LAST SEEN")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, l := range locations {
+		slot := "-"
+		if l.EnclosureID != nil && l.Slot != nil {
+			slot = fmt.Sprintf("%d:%d", *l.EnclosureID, *l.Slot)
+		}
+		pool := l.Zpool
+		if pool == "" {
+			pool = "-"
+		}
+		model := l.Model
+		if len(model) > 19 {
+			model = model[:19] + "..."
+		}
+		fmt.Printf("%-20s %-8s %-8s %-22s %-12s %s\n",
+			l.Serial, strings.ToUpper(l.State), slot, model, pool, l.LastSeen.Format("2006-01-02 15:04"))
+		if l.LED != "" {
+			fmt.Printf("  fault LED: %s\n", l.LED)
+		}
+	}
+}
+
+// setMissingDriveFaultLED switches the fault LED of a drive's last-known bay
+// and returns "on", "off" or the error
+func setMissingDriveFaultLED(serial string, database *db.DB, on bool) string {
+	info, err := ses.GetLocateInfoFromDB(serial, database)
+	if err != nil {
+		return err.Error()
+	}
+	if err := ses.SetSlotFaultLED(info.SGDevice, info.DevSlotNum(), on); err != nil {
+		return err.Error()
+	}
+	if on {
+		return "on"
+	}
+	return "off"
+}
+
+// dbFileSize returns the combined size of the database and its WAL file
+func dbFileSize(path string) int64 {
+	var size int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			size += info.Size()
+		}
+	}
+	return size
+}
+
+func runInventorySeed(cmd *cobra.Command, args []string) {
+	rows, err := readSeedFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	var created, updated, skipped int
+	for i, row := range rows {
+		record := seedRowToRecord(row)
+		if record.Serial == "" {
+			fmt.Fprintf(os.Stderr, "Warning: row %d has no serial, skipping\n", i+1)
+			skipped++
+			continue
+		}
+
+		isNew, err := database.SeedDrive(record)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: %v\n", record.Serial, err)
+			skipped++
+			continue
+		}
+		if isNew {
+			created++
+		} else {
+			updated++
+		}
+	}
+
+	fmt.Printf("Seed complete: %d created, %d updated, %d skipped\n", created, updated, skipped)
+}
+
+// readSeedFile reads purchase records from a CSV (with header) or JSON file
+// into rows of lowercase column name -> value
+func readSeedFile(path string) ([]map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") || strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		var raw []map[string]any
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		rows := make([]map[string]string, 0, len(raw))
+		for _, obj := range raw {
+			row := make(map[string]string)
+			for k, v := range obj {
+				if v == nil {
+					continue
+				}
+				row[seedColumnName(k)] = strings.TrimSpace(fmt.Sprint(v))
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+
+	r := csv.NewReader(strings.NewReader(string(data)))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing CSV header: %w", err)
+	}
+	for i := range header {
+		header[i] = seedColumnName(header[i])
+	}
+
+	var rows []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CSV: %w", err)
+		}
+		row := make(map[string]string)
+		for i, val := range record {
+			if i < len(header) && strings.TrimSpace(val) != "" {
+				row[header[i]] = strings.TrimSpace(val)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// seedColumnName normalises a column name ("Warranty Expires" -> "warranty_expires")
+func seedColumnName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	return strings.Join(strings.Fields(name), "_")
+}
+
+// seedRowToRecord maps known columns onto a DriveRecord; the rest become metadata
+func seedRowToRecord(row map[string]string) *db.DriveRecord {
+	record := &db.DriveRecord{Metadata: make(map[string]string)}
+
+	for key, val := range row {
+		switch key {
+		case "serial", "serial_number":
+			record.Serial = val
+		case "model":
+			record.Model = val
+		case "manufacturer", "vendor":
+			record.Manufacturer = val
+		case "size_bytes":
+			record.SizeBytes, _ = strconv.ParseInt(val, 10, 64)
+		case "purchase_date", "purchased":
+			record.PurchaseDate = val
+		case "warranty_expires", "warranty", "warranty_until":
+			record.WarrantyExpires = val
+		default:
+			record.Metadata[key] = val
+		}
+	}
+
+	return record
+}
+
+// activeTempsBySerial maps the serials (short and VPD, uppercased) of active
+// drives with a temperature reading to that reading
+func activeTempsBySerial(infos []drive.DriveInfo) map[string]int {
+	temps := make(map[string]int)
+	for _, d := range infos {
+		if d.State != "active" || d.Temp == nil {
+			continue
+		}
+		if d.Serial != nil && *d.Serial != "" {
+			temps[strings.ToUpper(*d.Serial)] = *d.Temp
+		}
+		if d.SerialVPD != nil && *d.SerialVPD != "" {
+			temps[strings.ToUpper(*d.SerialVPD)] = *d.Temp
+		}
+	}
+	return temps
+}
+
+// devicePathsBySerial maps the serials (short and VPD, uppercased) of drives
+// to their device path
+func devicePathsBySerial(infos []drive.DriveInfo) map[string]string {
+	paths := make(map[string]string)
+	for _, d := range infos {
+		for _, serial := range []*string{d.Serial, d.SerialVPD} {
+			if serial != nil && *serial != "" {
+				paths[strings.ToUpper(*serial)] = d.Device
+			}
+		}
+	}
+	return paths
+}
+
+// poolsBySerial maps the serials (short and VPD, uppercased) of drives in a
+// ZFS pool to the pool's name
+func poolsBySerial(infos []drive.DriveInfo) map[string]string {
+	pools := make(map[string]string)
+	for _, d := range infos {
+		if d.Zpool == nil || *d.Zpool == "" {
+			continue
+		}
+		for _, serial := range []*string{d.Serial, d.SerialVPD} {
+			if serial != nil && *serial != "" {
+				pools[strings.ToUpper(*serial)] = *d.Zpool
+			}
+		}
+	}
+	return pools
+}
+
+// rosterEntry is what a sync records about a drive to tell, next time,
+// whether its inventory record needs rewriting: its location, device path
+// (sdX names move across reboots), pool and firmware
+func rosterEntry(device hba.PhysicalDevice, devicePath, pool string) string {
+	return strings.Join([]string{
+		fmt.Sprintf("%d:%d", device.EnclosureID, device.Slot),
+		devicePath, pool, device.Firmware,
+	}, "|")
+}
+
+// grownDefectsBySerial maps the serials (short and VPD, uppercased) of
+// drives reporting a SCSI grown defect list to its size
+func grownDefectsBySerial(infos []drive.DriveInfo) map[string]int {
+	defects := make(map[string]int)
+	for _, d := range infos {
+		if d.GrownDefects == nil {
+			continue
+		}
+		if d.Serial != nil && *d.Serial != "" {
+			defects[strings.ToUpper(*d.Serial)] = *d.GrownDefects
+		}
+		if d.SerialVPD != nil && *d.SerialVPD != "" {
+			defects[strings.ToUpper(*d.SerialVPD)] = *d.GrownDefects
+		}
+	}
+	return defects
+}
+
+// lastProbedBySerial maps the serials (short and VPD, uppercased) of drives
+// with a successful SMART read to when it happened
+func lastProbedBySerial(infos []drive.DriveInfo) map[string]time.Time {
+	probed := make(map[string]time.Time)
+	for _, d := range infos {
+		if d.LastProbed == nil {
+			continue
+		}
+		if d.Serial != nil && *d.Serial != "" {
+			probed[strings.ToUpper(*d.Serial)] = *d.LastProbed
+		}
+		if d.SerialVPD != nil && *d.SerialVPD != "" {
+			probed[strings.ToUpper(*d.SerialVPD)] = *d.LastProbed
+		}
+	}
+	return probed
+}
+
+// lookupBySerial finds the value for an HBA device by either serial form
+func lookupBySerial[T any](m map[string]T, device hba.PhysicalDevice) (T, bool) {
+	for _, serial := range []string{device.Serial, device.SerialVPD} {
+		if serial == "" {
+			continue
+		}
+		if v, ok := m[strings.ToUpper(serial)]; ok {
+			return v, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+func runInventoryReplace(cmd *cobra.Command, args []string) {
+	oldSerial, newSerial := args[0], args[1]
+	zpoolReplace, _ := cmd.Flags().GetBool("zpool-replace")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	// Capture the old drive's vdev before ReplaceDrive clears it
+	old, err := database.GetDriveBySerial(oldSerial)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if old == nil {
+		fmt.Fprintf(os.Stderr, "Drive not found: %s\n", oldSerial)
+		os.Exit(1)
+	}
+
+	// Resolve everything zpool replace needs before changing the inventory
+	var newDevice string
+	if zpoolReplace {
+		if old.ZpoolName == "" || old.ZFSVdevGUID == "" {
+			fmt.Fprintf(os.Stderr, "Error: inventory has no pool/vdev for %s, can't run zpool replace\n", oldSerial)
+			os.Exit(1)
+		}
+		newDevice = collector.CollectSystemData(true).DevicesBySerial()[strings.ToUpper(newSerial)]
+		if newDevice == "" {
+			fmt.Fprintf(os.Stderr, "Error: drive %s is not attached, can't run zpool replace\n", newSerial)
+			os.Exit(1)
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Would record %s as replaced by %s\n", oldSerial, newSerial)
+	} else {
+		if err := database.ReplaceDrive(oldSerial, newSerial); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Recorded %s as replaced by %s\n", oldSerial, newSerial)
+	}
+	if old.ZpoolName != "" {
+		fmt.Printf("  pool: %s\n", old.ZpoolName)
+	}
+	if old.EnclosureID != nil && old.Slot != nil {
+		fmt.Printf("  slot: %d:%d\n", *old.EnclosureID, *old.Slot)
+	}
+
+	if zpoolReplace && dryRun {
+		fmt.Printf("Would run zpool replace %s %s %s\n", old.ZpoolName, old.ZFSVdevGUID, newDevice)
+	} else if zpoolReplace {
+		fmt.Printf("Running zpool replace %s %s %s...\n", old.ZpoolName, old.ZFSVdevGUID, newDevice)
+		if err := zfs.ReplaceDevice(old.ZpoolName, old.ZFSVdevGUID, newDevice); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Resilver started; follow it with 'zpool status %s'\n", old.ZpoolName)
+	}
+}
+
+func runInventoryNote(cmd *cobra.Command, args []string) {
+	serial, note := args[0], strings.TrimSpace(args[1])
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	if err := database.SetDriveNote(serial, note); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if note == "" {
+		fmt.Printf("Cleared note on %s\n", serial)
+	} else {
+		fmt.Printf("Set note on %s\n", serial)
+	}
+}
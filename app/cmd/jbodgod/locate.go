@@ -14,33 +14,45 @@ import (
 	"github.com/spf13/cobra"
 )
 
+// locateSchemaVersion is the schema_version of 'locate --json' output.
+// Bump it when fields are renamed, removed or change meaning.
+const locateSchemaVersion = 1
+
 // LocateResponse is the JSON response structure for application integration
 type LocateResponse struct {
-	Success     bool    `json:"success"`
-	Action      string  `json:"action"`                 // "on", "off", "timed", "info"
-	LEDState    string  `json:"led_state"`              // "on", "off"
-	Device      string  `json:"device"`
-	Serial      string  `json:"serial"`
-	Model       string  `json:"model,omitempty"`
-	Enclosure   int     `json:"enclosure"`
-	Slot        int     `json:"slot"`
-	SGDevice    string  `json:"sg_device"`
-	MatchedAs   string  `json:"matched_as,omitempty"`
-	Duration    float64 `json:"duration_seconds,omitempty"` // How long LED was on
-	StopReason  string  `json:"stop_reason,omitempty"`      // "timeout", "interrupted", "manual"
-	Timestamp   string  `json:"timestamp"`
-	Error       string  `json:"error,omitempty"`
+	SchemaVersion int              `json:"schema_version"`
+	Success       bool             `json:"success"`
+	Action        string           `json:"action"`    // "on", "off", "timed", "info", "enclosure", "reset"
+	LEDState      string           `json:"led_state"` // "on", "off"
+	Device        string           `json:"device"`
+	Serial        string           `json:"serial"`
+	Model         string           `json:"model,omitempty"`
+	Enclosure     int              `json:"enclosure"`
+	Slot          int              `json:"slot"`
+	SlotLabel     string           `json:"slot_label,omitempty"` // Enclosure's own bay label
+	SGDevice      string           `json:"sg_device"`
+	Slots         []int            `json:"slots,omitempty"`     // Slots flashed by --enclosure
+	Cleared       []*ses.LEDMarker `json:"cleared,omitempty"`   // LEDs turned off by --reset-all
+	Mechanism     string           `json:"mechanism,omitempty"` // "sysfs" or "sg_ses"
+	MatchedAs     string           `json:"matched_as,omitempty"`
+	Duration      float64          `json:"duration_seconds,omitempty"` // How long LED was on
+	StopReason    string           `json:"stop_reason,omitempty"`      // "timeout", "interrupted", "manual"
+	DryRun        bool             `json:"dry_run,omitempty"`
+	Commands      []string         `json:"commands,omitempty"` // What --dry-run would have run
+	Timestamp     string           `json:"timestamp"`
+	Error         string           `json:"error,omitempty"`
 }
 
 var locateCmd = &cobra.Command{
-	Use:   "locate <identifier>",
+	Use:   "locate <identifier> | --enclosure <id>",
 	Short: "Flash the enclosure bay LED for a drive",
 	Long: `Flash the identify LED on a drive's enclosure bay to help locate it physically.
 
 The identifier can be any unique device identifier:
   - Device path: /dev/sda, /dev/disk/by-id/...
   - Serial number: WCK5NWKQ
-  - Enclosure:Slot: 2:5 (directly specify bay location)
+  - Enclosure:Slot: 2:5 (directly specify bay location), or c1:2:5 when
+    two HBAs report the same enclosure number
   - WWN: 0x5000c500d006891c
   - LUID: 5000c500d006891c
   - ZFS pool/vdev GUID
@@ -57,8 +69,18 @@ Modes:
   --on         Turn LED on and exit (for external app control)
   --off        Turn LED off
   --info-only  Show device location without changing LED
+  --enclosure  Flash every populated bay of an enclosure for --timeout, to
+               confirm you're at the right shelf; LEDs that were already on
+               stay on afterwards
+  --reset-all  Turn off LEDs left on by a locate that was killed (SIGKILL,
+               power loss), found from the marker files in
+               /var/lib/jbodgod/leds; LEDs turned on with --on are left
+               alone
 
 The --json flag provides machine-readable output for application integration.
+With --dry-run, the LED commands (sysfs write or sg_ses invocation) are
+printed instead of run; --enclosure and --reset-all list the bays they would
+change.
 
 Examples:
   jbodgod locate /dev/sda                    # Flash for 30s
@@ -66,8 +88,16 @@ Examples:
   jbodgod locate 2:5                         # Locate by enclosure 2, slot 5
   jbodgod locate --on --json /dev/sda        # Turn on, output JSON
   jbodgod locate --off --json /dev/sda       # Turn off, output JSON
-  jbodgod locate --info-only --json /dev/sda # Get location info as JSON`,
-	Args: cobra.ExactArgs(1),
+  jbodgod locate --info-only --json /dev/sda # Get location info as JSON
+  jbodgod locate --enclosure 2               # Flash all drives in enclosure 2
+  jbodgod locate --reset-all                 # Clear orphaned locate LEDs
+  jbodgod locate --on --dry-run 2:5          # Show the command that would light bay 2:5`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if cmd.Flags().Changed("enclosure") || cmd.Flags().Changed("reset-all") {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(1)(cmd, args)
+	},
 	Run:  runLocate,
 }
 
@@ -78,9 +108,21 @@ func init() {
 	locateCmd.Flags().Bool("info-only", false, "Only show device location info, don't change LED")
 	locateCmd.Flags().Bool("on", false, "Turn LED on and exit immediately (for external control)")
 	locateCmd.Flags().Bool("off", false, "Turn LED off")
+	locateCmd.Flags().String("enclosure", "", "Flash every populated bay of this enclosure (2, or c1:2 to pick the controller)")
+	locateCmd.Flags().Bool("reset-all", false, "Turn off locate LEDs left on by killed locates")
+	locateCmd.Flags().Bool("dry-run", false, "Print the LED commands without running them")
 }
 
 func runLocate(cmd *cobra.Command, args []string) {
+	if cmd.Flags().Changed("enclosure") {
+		runLocateEnclosure(cmd)
+		return
+	}
+	if reset, _ := cmd.Flags().GetBool("reset-all"); reset {
+		runLocateResetAll(cmd)
+		return
+	}
+
 	query := args[0]
 	timeout, _ := cmd.Flags().GetDuration("timeout")
 	verbose, _ := cmd.Flags().GetBool("verbose")
@@ -88,22 +130,11 @@ func runLocate(cmd *cobra.Command, args []string) {
 	infoOnly, _ := cmd.Flags().GetBool("info-only")
 	turnOn, _ := cmd.Flags().GetBool("on")
 	turnOff, _ := cmd.Flags().GetBool("off")
-
-	// Check for sg_ses before doing anything
-	if err := ses.CheckSgSesInstalled(); err != nil {
-		if jsonOut {
-			outputError("sg_ses not found - install sg3_utils package", nil)
-		} else {
-			fmt.Fprintf(os.Stderr, "Error: sg_ses not found.\n")
-			fmt.Fprintf(os.Stderr, "Install: sudo pacman -S sg3_utils lsscsi  (Arch)\n")
-			fmt.Fprintf(os.Stderr, "     or: sudo apt install sg3-utils lsscsi  (Debian/Ubuntu)\n")
-		}
-		os.Exit(1)
-	}
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
 
 	// Try to open database for fallback lookups (optional - don't fail if unavailable)
 	var database *db.DB
-	database, _ = db.New(db.DefaultPath)
+	database, _ = openDB()
 	if database != nil {
 		defer database.Close()
 	}
@@ -132,11 +163,25 @@ func runLocate(cmd *cobra.Command, args []string) {
 		} else {
 			fmt.Fprintf(os.Stderr, "Error: %s\n", errMsg)
 			fmt.Fprintf(os.Stderr, "Device: %s (serial: %s)\n", info.DevicePath, info.Serial)
-			fmt.Fprintf(os.Stderr, "Enclosure: %d, Slot: %d\n", info.EnclosureID, info.Slot)
+			fmt.Fprintf(os.Stderr, "Enclosure: %d, Slot: %s\n", info.EnclosureID, slotText(info))
 		}
 		os.Exit(1)
 	}
 
+	// sg_ses is only needed when the kernel doesn't expose the LED in sysfs
+	if !infoOnly && !ses.SysfsLocateAvailable(info) {
+		if err := ses.CheckSgSesInstalled(); err != nil {
+			if jsonOut {
+				outputError("sg_ses not found - install sg3_utils package", info)
+			} else {
+				fmt.Fprintf(os.Stderr, "Error: sg_ses not found and no writable sysfs locate LED for this slot.\n")
+				fmt.Fprintf(os.Stderr, "Install: sudo pacman -S sg3_utils lsscsi  (Arch)\n")
+				fmt.Fprintf(os.Stderr, "     or: sudo apt install sg3-utils lsscsi  (Debian/Ubuntu)\n")
+			}
+			os.Exit(1)
+		}
+	}
+
 	// Info-only mode: just display location and exit
 	if infoOnly {
 		resp := buildResponse(info, "info", "unknown", "", 0)
@@ -150,20 +195,27 @@ func runLocate(cmd *cobra.Command, args []string) {
 				fmt.Printf("Model:      %s\n", info.Model)
 			}
 			fmt.Printf("Enclosure:  %d\n", info.EnclosureID)
-			fmt.Printf("Slot:       %d\n", info.Slot)
+			fmt.Printf("Slot:       %s\n", slotText(info))
 			fmt.Printf("SG Device:  %s\n", info.SGDevice)
 		}
 		return
 	}
 
+	if dryRun {
+		dryRunLocate(info, turnOn, turnOff, timeout, jsonOut)
+		return
+	}
+
 	// Turn off mode
 	if turnOff {
 		if verbose {
 			fmt.Printf("Turning off LED for enclosure %d, slot %d...\n", info.EnclosureID, info.Slot)
 		}
-		if err := ses.SetSlotIdentLED(info.SGDevice, info.Slot, false); err != nil {
+		mechanism, err := ses.SetLocateLED(info, false)
+		if err != nil {
 			if jsonOut {
 				resp := buildResponse(info, "off", "off", "", 0)
+				resp.Mechanism = mechanism
 				resp.Success = false
 				resp.Error = err.Error()
 				outputJSON(resp)
@@ -172,7 +224,11 @@ func runLocate(cmd *cobra.Command, args []string) {
 			}
 			os.Exit(1)
 		}
+		if err := ses.RemoveLEDMarker(info); err != nil && !jsonOut {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
 		resp := buildResponse(info, "off", "off", "manual", 0)
+		resp.Mechanism = mechanism
 		if jsonOut {
 			outputJSON(resp)
 		} else {
@@ -186,9 +242,11 @@ func runLocate(cmd *cobra.Command, args []string) {
 		if verbose {
 			fmt.Printf("Turning on LED for enclosure %d, slot %d...\n", info.EnclosureID, info.Slot)
 		}
-		if err := ses.SetSlotIdentLED(info.SGDevice, info.Slot, true); err != nil {
+		mechanism, err := ses.SetLocateLED(info, true)
+		if err != nil {
 			if jsonOut {
 				resp := buildResponse(info, "on", "off", "", 0)
+				resp.Mechanism = mechanism
 				resp.Success = false
 				resp.Error = err.Error()
 				outputJSON(resp)
@@ -197,7 +255,11 @@ func runLocate(cmd *cobra.Command, args []string) {
 			}
 			os.Exit(1)
 		}
+		if err := ses.WriteLEDMarker(info, 0); err != nil && !jsonOut {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
 		resp := buildResponse(info, "on", "on", "", 0)
+		resp.Mechanism = mechanism
 		if jsonOut {
 			outputJSON(resp)
 		} else {
@@ -211,16 +273,21 @@ func runLocate(cmd *cobra.Command, args []string) {
 		fmt.Printf("Locating: %s\n", query)
 		fmt.Printf("  Device:    %s\n", info.DevicePath)
 		fmt.Printf("  Serial:    %s\n", info.Serial)
-		fmt.Printf("  Enclosure: %d, Slot: %d\n", info.EnclosureID, info.Slot)
+		fmt.Printf("  Enclosure: %d, Slot: %s\n", info.EnclosureID, slotText(info))
 		fmt.Printf("  SG Device: %s\n", info.SGDevice)
+		if ses.SysfsLocateAvailable(info) {
+			fmt.Printf("  LED via:   sysfs (/sys/class/enclosure/%s)\n", info.EnclosureHCTL)
+		}
 		fmt.Printf("  Duration:  %v\n", timeout)
 		fmt.Println()
 	}
 
 	// Turn on LED
-	if err := ses.SetSlotIdentLED(info.SGDevice, info.Slot, true); err != nil {
+	mechanism, err := ses.SetLocateLED(info, true)
+	if err != nil {
 		if jsonOut {
 			resp := buildResponse(info, "timed", "off", "", 0)
+			resp.Mechanism = mechanism
 			resp.Success = false
 			resp.Error = "failed to turn on LED: " + err.Error()
 			outputJSON(resp)
@@ -232,9 +299,16 @@ func runLocate(cmd *cobra.Command, args []string) {
 
 	startTime := time.Now()
 
+	// If this process is killed the marker is how 'locate --reset-all'
+	// finds the LED; it's removed once the LED is off
+	if err := ses.WriteLEDMarker(info, timeout); err != nil && !jsonOut {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+
 	if jsonOut {
 		// Output initial "on" state
 		resp := buildResponse(info, "timed", "on", "", 0)
+		resp.Mechanism = mechanism
 		outputJSON(resp)
 	} else {
 		fmt.Printf("LED ON for %s (enc:%d slot:%d) - will turn off in %v\n",
@@ -262,9 +336,10 @@ func runLocate(cmd *cobra.Command, args []string) {
 	}
 
 	// Turn off LED
-	if err := ses.SetSlotIdentLED(info.SGDevice, info.Slot, false); err != nil {
+	if mechanism, err = ses.SetLocateLED(info, false); err != nil {
 		if jsonOut {
 			resp := buildResponse(info, "timed", "on", stopReason, time.Since(startTime).Seconds())
+			resp.Mechanism = mechanism
 			resp.Success = false
 			resp.Error = "failed to turn off LED: " + err.Error()
 			outputJSON(resp)
@@ -275,21 +350,222 @@ func runLocate(cmd *cobra.Command, args []string) {
 	}
 
 	duration := time.Since(startTime)
+	if err := ses.RemoveLEDMarker(info); err != nil && !jsonOut {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
 
 	if jsonOut {
 		resp := buildResponse(info, "timed", "off", stopReason, duration.Seconds())
+		resp.Mechanism = mechanism
 		outputJSON(resp)
 	} else {
 		fmt.Printf("LED OFF (was on for %v)\n", duration.Round(time.Second))
 	}
 }
 
+// runLocateResetAll turns off the LEDs of orphaned markers
+func runLocateResetAll(cmd *cobra.Command) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	cleared, err := ses.ResetOrphanedLEDs(dryRun)
+	if jsonOut {
+		resp := &LocateResponse{
+			SchemaVersion: locateSchemaVersion,
+			Success:       err == nil,
+			Action:        "reset",
+			LEDState:      "off",
+			Cleared:       cleared,
+			DryRun:        dryRun,
+			Timestamp:     time.Now().UTC().Format(time.RFC3339),
+		}
+		if err != nil {
+			resp.Error = err.Error()
+		}
+		outputJSON(resp)
+	} else {
+		for _, m := range cleared {
+			device := m.DevicePath
+			if device == "" {
+				device = "-"
+			}
+			verb := "LED OFF"
+			if dryRun {
+				verb = "Would turn LED off"
+			}
+			fmt.Printf("%s for enc:%d slot:%d (%s, on since %s)\n",
+				verb, m.EnclosureID, m.Slot, device, m.Since.Format("2006-01-02 15:04:05"))
+		}
+		if len(cleared) == 0 && err == nil {
+			fmt.Println("No orphaned locate LEDs")
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+	}
+	if err != nil {
+		os.Exit(1)
+	}
+}
+
+// runLocateEnclosure flashes every populated bay of an enclosure
+func runLocateEnclosure(cmd *cobra.Command) {
+	enclosureArg, _ := cmd.Flags().GetString("enclosure")
+	timeout, _ := cmd.Flags().GetDuration("timeout")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	controller, enclosure, err := parseEnclosureArg(enclosureArg)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	resp := &LocateResponse{
+		SchemaVersion: locateSchemaVersion,
+		Action:        "enclosure",
+		LEDState:      "off",
+		Enclosure:     enclosure,
+		Mechanism:     ses.LEDMechanismSgSes,
+	}
+	fail := func(err error) {
+		if jsonOut {
+			resp.Error = err.Error()
+			resp.Timestamp = time.Now().UTC().Format(time.RFC3339)
+			outputJSON(resp)
+		} else {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+		os.Exit(1)
+	}
+
+	if err := ses.CheckSgSesInstalled(); err != nil {
+		fail(err)
+	}
+	sesEnc, startSlot, err := ses.GetEnclosureSES(controller, enclosure)
+	if err != nil {
+		fail(err)
+	}
+	resp.SGDevice = sesEnc.SGDevice
+	enc := &ses.LocateInfo{
+		Controller:  controller,
+		EnclosureID: enclosure,
+		StartSlot:   startSlot,
+		SGDevice:    sesEnc.SGDevice,
+	}
+
+	if dryRun {
+		slots, err := ses.BlinkAllSlotsWithContext(context.Background(), enc, timeout, true)
+		if err != nil {
+			fail(err)
+		}
+		resp.Slots = slots
+		resp.DryRun = true
+		if jsonOut {
+			resp.Success = true
+			resp.Timestamp = time.Now().UTC().Format(time.RFC3339)
+			outputJSON(resp)
+		} else {
+			fmt.Printf("Would flash %d populated bays of enclosure %d (%s) for %v: slots %v\n",
+				len(slots), enclosure, sesEnc.SGDevice, timeout, slots)
+		}
+		return
+	}
+
+	if !jsonOut {
+		fmt.Printf("Flashing all populated bays of enclosure %d (%s) for %v...\n", enclosure, sesEnc.SGDevice, timeout)
+	}
+
+	// Ctrl+C ends the flash early; the LEDs are still restored
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		if _, ok := <-sigChan; ok {
+			cancel()
+		}
+	}()
+
+	startTime := time.Now()
+	slots, err := ses.BlinkAllSlotsWithContext(ctx, enc, timeout, false)
+	signal.Stop(sigChan)
+	close(sigChan)
+	resp.Slots = slots
+	stopReason := "timeout"
+	if ctx.Err() != nil {
+		stopReason = "interrupted"
+	}
+	if err != nil {
+		fail(err)
+	}
+
+	duration := time.Since(startTime)
+	if jsonOut {
+		resp.Success = true
+		resp.StopReason = stopReason
+		resp.Duration = duration.Seconds()
+		resp.Timestamp = time.Now().UTC().Format(time.RFC3339)
+		outputJSON(resp)
+	} else {
+		fmt.Printf("Flashed %d bays for %v; LEDs restored\n", len(slots), duration.Round(time.Second))
+	}
+}
+
+// dryRunLocate reports the LED commands a locate would run, without running
+// them
+func dryRunLocate(info *ses.LocateInfo, turnOn, turnOff bool, timeout time.Duration, jsonOut bool) {
+	action := "timed"
+	switch {
+	case turnOff:
+		action = "off"
+	case turnOn:
+		action = "on"
+	}
+
+	var mechanism string
+	var commands []string
+	if action != "off" {
+		var on string
+		mechanism, on = ses.LocateLEDCommand(info, true)
+		commands = append(commands, on)
+	}
+	if action != "on" {
+		var off string
+		mechanism, off = ses.LocateLEDCommand(info, false)
+		commands = append(commands, off)
+	}
+
+	if jsonOut {
+		resp := buildResponse(info, action, "unknown", "", 0)
+		resp.Mechanism = mechanism
+		resp.DryRun = true
+		resp.Commands = commands
+		outputJSON(resp)
+		return
+	}
+
+	switch action {
+	case "on":
+		fmt.Printf("Would turn LED on for %s (enc:%d slot:%s)\n", info.DevicePath, info.EnclosureID, slotText(info))
+	case "off":
+		fmt.Printf("Would turn LED off for %s (enc:%d slot:%s)\n", info.DevicePath, info.EnclosureID, slotText(info))
+	default:
+		fmt.Printf("Would turn LED on for %s (enc:%d slot:%s) for %v, then off\n",
+			info.DevicePath, info.EnclosureID, slotText(info), timeout)
+	}
+	for _, c := range commands {
+		fmt.Printf("  %s\n", c)
+	}
+}
+
 func buildResponse(info *ses.LocateInfo, action, ledState, stopReason string, duration float64) *LocateResponse {
 	resp := &LocateResponse{
-		Success:   true,
-		Action:    action,
-		LEDState:  ledState,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		SchemaVersion: locateSchemaVersion,
+		Success:       true,
+		Action:        action,
+		LEDState:      ledState,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
 	}
 	if info != nil {
 		resp.Device = info.DevicePath
@@ -297,6 +573,7 @@ func buildResponse(info *ses.LocateInfo, action, ledState, stopReason string, du
 		resp.Model = info.Model
 		resp.Enclosure = info.EnclosureID
 		resp.Slot = info.Slot
+		resp.SlotLabel = info.SlotLabel
 		resp.SGDevice = info.SGDevice
 		resp.MatchedAs = info.MatchedAs
 	}
@@ -309,6 +586,15 @@ func buildResponse(info *ses.LocateInfo, action, ledState, stopReason string, du
 	return resp
 }
 
+// slotText formats a slot number with the enclosure's bay label, if any,
+// since the label is what's printed on the chassis
+func slotText(info *ses.LocateInfo) string {
+	if info.SlotLabel != "" {
+		return fmt.Sprintf("%d (%s)", info.Slot, info.SlotLabel)
+	}
+	return fmt.Sprintf("%d", info.Slot)
+}
+
 func outputJSON(resp *LocateResponse) {
 	enc := json.NewEncoder(os.Stdout)
 	enc.SetIndent("", "  ")
@@ -317,11 +603,12 @@ func outputJSON(resp *LocateResponse) {
 
 func outputError(errMsg string, info *ses.LocateInfo) {
 	resp := &LocateResponse{
-		Success:   false,
-		Action:    "error",
-		LEDState:  "unknown",
-		Error:     errMsg,
-		Timestamp: time.Now().UTC().Format(time.RFC3339),
+		SchemaVersion: locateSchemaVersion,
+		Success:       false,
+		Action:        "error",
+		LEDState:      "unknown",
+		Error:         errMsg,
+		Timestamp:     time.Now().UTC().Format(time.RFC3339),
 	}
 	if info != nil {
 		resp.Device = info.DevicePath
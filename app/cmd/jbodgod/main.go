@@ -1,17 +1,26 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
+	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/collector"
 	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/db"
 	"github.com/sigreer/jbodgod/internal/drive"
 	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/privexec"
 	"github.com/sigreer/jbodgod/internal/version"
 	"github.com/spf13/cobra"
 )
 
 var cfgFile string
+var cacheFile string
+var dbPath string
 
 var rootCmd = &cobra.Command{
 	Use:   "jbodgod",
@@ -19,6 +28,23 @@ var rootCmd = &cobra.Command{
 	Long: `JBODgod is a CLI tool for managing JBOD enclosures, SAS/SATA drives,
 and storage pools (ZFS, LVM). It provides monitoring, power management,
 and alerting capabilities.`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// Best effort: an unreadable cache file just means a cold cache
+		if cacheFile != "" {
+			cache.EnablePersistence(cacheFile)
+		}
+		// Commands with their own --verbose (e.g. locate) enable it too
+		if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+			privexec.SetVerbose(true)
+		}
+		if include, _ := cmd.Flags().GetBool("include-excluded"); include {
+			config.SetIncludeExcluded(true)
+		}
+	},
+	PersistentPostRun: func(cmd *cobra.Command, args []string) {
+		// Best effort: non-root users typically can't write under /var/lib
+		cache.Flush()
+	},
 }
 
 var versionCmd = &cobra.Command{
@@ -40,27 +66,86 @@ Use --detail to include additional information like model, serial, and more.
 The --json flag changes the output format without affecting the data shown.
 Combine --json with --detail for comprehensive JSON output.
 
+The --prewarm flag refreshes bulk and per-drive cache entries in parallel
+before collection, and keeps them warm in the background while the command
+runs.
+
+The --refresh flag bypasses cached data, including the HBA data that is
+otherwise kept for 24 hours, e.g. after physically swapping drives.
+
+The --parallelism flag limits how many drives are probed at once (default
+2x the CPU count), avoiding a smartctl storm on large enclosures.
+
+The --pool and --state flags limit the output to drives in one ZFS pool
+and/or in the given states (comma-separated: active, standby, missing,
+failed, unresponsive, unknown). The summary counts only the shown drives.
+
 Examples:
   jbodgod status              # Core data in table format
   jbodgod status --json       # Core data in JSON format
+  jbodgod status --csv        # One row per drive, for spreadsheets
   jbodgod status --detail     # Detailed data in table format
-  jbodgod status --json --detail  # Full data in JSON format`,
+  jbodgod status --json --detail  # Full data in JSON format
+  jbodgod status --prewarm    # Warm caches in parallel before collecting
+  jbodgod status --refresh    # Rescan after swapping drives
+  jbodgod status --parallelism 8  # Probe at most 8 drives at once
+  jbodgod status --pool tank      # Only drives in pool 'tank'
+  jbodgod status --state standby,missing  # Only standby or missing drives`,
 	Run: func(cmd *cobra.Command, args []string) {
 		jsonOut, _ := cmd.Flags().GetBool("json")
+		csvOut, _ := cmd.Flags().GetBool("csv")
 		detail, _ := cmd.Flags().GetBool("detail")
+		prewarm, _ := cmd.Flags().GetBool("prewarm")
+		parallelism, _ := cmd.Flags().GetInt("parallelism")
+		refresh, _ := cmd.Flags().GetBool("refresh")
+		pool, _ := cmd.Flags().GetString("pool")
+		states, _ := cmd.Flags().GetStringSlice("state")
+		for _, s := range states {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "active", "standby", "missing", "failed", "unresponsive", "unknown":
+			default:
+				fmt.Fprintf(os.Stderr, "Error: invalid state %q (use active, standby, missing, failed, unresponsive or unknown)\n", s)
+				os.Exit(1)
+			}
+		}
+		if jsonOut && csvOut {
+			fmt.Fprintln(os.Stderr, "Error: --json and --csv are mutually exclusive")
+			os.Exit(1)
+		}
 		cfg, err := config.Load(cfgFile)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
-		drives := drive.GetAll(cfg)
+		if prewarm {
+			var devices []string
+			for _, d := range cfg.GetAllDrives() {
+				devices = append(devices, d.Device)
+			}
+			warmer := cache.NewWarmer(cache.Global())
+			collector.RegisterWarmers(warmer, devices)
+			warmer.WarmDue()
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			go warmer.Run(ctx, time.Second)
+		}
+		drives := drive.GetAllParallel(cfg, parallelism, refresh)
+		if pool != "" || len(states) > 0 {
+			drives = drive.FilterDrives(drives, pool, states)
+		}
 		if jsonOut {
 			var controllers []hba.ControllerInfo
 			var enclosures []hba.EnclosureInfo
 			if detail {
-				controllers, enclosures, _ = drive.FetchHBAData(false)
+				controllers, enclosures, _ = drive.FetchHBAData(refresh)
 			}
 			drive.PrintJSON(drives, controllers, enclosures, detail)
+		} else if csvOut {
+			if err := drive.PrintCSV(drives); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing CSV: %v\n", err)
+				os.Exit(1)
+			}
 		} else {
 			drive.PrintStatus(drives, detail)
 		}
@@ -68,36 +153,53 @@ Examples:
 }
 
 var spindownCmd = &cobra.Command{
-	Use:   "spindown [-c controller] [devices...]",
+	Use:   "spindown [-c controller] [--pool name] [devices...]",
 	Short: "Spin down drives",
 	Long: `Spin down drives to standby mode.
 
-You MUST specify either a controller (-c) or specific device paths.
-This is a safety measure to prevent accidental spindown of all drives.
+You MUST specify a controller (-c), a ZFS pool (--pool) or specific device
+paths. This is a safety measure to prevent accidental spindown of all drives.
+--pool limits the spindown to the drives of that pool (repeatable); combined
+with -c, only the pool's drives on that controller.
 
 ZFS pools are handled gracefully: if any target drives are part of a ZFS pool,
 you will be prompted to export the pool before spindown. This ensures data
 integrity and allows automatic re-import when drives are spun back up.
+Without a terminal to prompt on (cron), drives in imported pools are skipped
+unless --force-all or --force is given. Pools with a scrub or resilver in
+progress are never exported; their drives are skipped and the scan progress
+and ETA are reported.
 
 Flags:
   --force      Skip all ZFS checks and prompts (dangerous!)
   --force-all  Export all affected pools without individual prompts
+  --dry-run    Show which pools would be exported and which drives stopped
+               (the sdparm commands), without changing anything
 
 Examples:
   jbodgod spindown -c c0              # Spin down all drives on controller c0
   jbodgod spindown /dev/sda           # Spin down a specific drive
   jbodgod spindown /dev/sda /dev/sdb  # Spin down multiple specific drives
-  jbodgod spindown --force-all -c c0  # Export all pools and spin down without prompts`,
+  jbodgod spindown --pool archive     # Export 'archive' and spin down its drives
+  jbodgod spindown --force-all -c c0  # Export all pools and spin down without prompts
+  jbodgod spindown --pool archive --dry-run  # Check the drive selection first`,
 	Run: func(cmd *cobra.Command, args []string) {
 		controller, _ := cmd.Flags().GetString("controller")
 		force, _ := cmd.Flags().GetBool("force")
 		forceAll, _ := cmd.Flags().GetBool("force-all")
+		pools, _ := cmd.Flags().GetStringSlice("pool")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-		if controller == "" && len(args) == 0 {
-			fmt.Fprintln(os.Stderr, "Error: specify -c <controller> or device path(s)")
+		if len(pools) > 0 && len(args) > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --pool cannot be combined with device paths")
+			os.Exit(1)
+		}
+		if controller == "" && len(pools) == 0 && len(args) == 0 {
+			fmt.Fprintln(os.Stderr, "Error: specify -c <controller>, --pool <name> or device path(s)")
 			fmt.Fprintln(os.Stderr, "This prevents accidental spindown of all drives.")
 			fmt.Fprintln(os.Stderr, "Examples:")
 			fmt.Fprintln(os.Stderr, "  jbodgod spindown -c c0")
+			fmt.Fprintln(os.Stderr, "  jbodgod spindown --pool archive")
 			fmt.Fprintln(os.Stderr, "  jbodgod spindown /dev/sda /dev/sdb")
 			os.Exit(1)
 		}
@@ -106,43 +208,62 @@ Examples:
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
+		cfg.DBPath = resolveDBPath(cfg)
 		drive.SpindownWithZFS(cfg, controller, args, drive.SpindownOptions{
 			Force:    force,
 			ForceAll: forceAll,
+			Pools:    pools,
+			DryRun:   dryRun,
 		})
 	},
 }
 
 var spinupCmd = &cobra.Command{
-	Use:   "spinup [-c controller] [devices...]",
+	Use:   "spinup [-c controller] [--pool name] [devices...]",
 	Short: "Spin up drives",
 	Long: `Spin up drives from standby mode.
 
 Specify a controller (-c), specific device paths, or both.
 If no arguments provided, spins up all discovered drives.
 
+--pool spins up only the drives of a ZFS pool (repeatable). A pool exported
+by 'jbodgod spindown' is found through the export recorded in the database.
+
 After spinning up drives, any ZFS pools that were exported during spindown
 will be automatically re-imported.
 
 Flags:
   --no-import  Skip automatic ZFS pool re-import
+  --dry-run    Show the drives (by spinup group) and pools that would be
+               started and imported, without changing anything
 
 Examples:
   jbodgod spinup                      # Spin up all drives
   jbodgod spinup -c c0                # Spin up all drives on controller c0
   jbodgod spinup /dev/sda             # Spin up a specific drive
+  jbodgod spinup --pool archive       # Spin up and re-import pool 'archive'
   jbodgod spinup --no-import -c c0    # Spin up without pool re-import`,
 	Run: func(cmd *cobra.Command, args []string) {
 		controller, _ := cmd.Flags().GetString("controller")
 		noImport, _ := cmd.Flags().GetBool("no-import")
+		pools, _ := cmd.Flags().GetStringSlice("pool")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		if len(pools) > 0 && len(args) > 0 {
+			fmt.Fprintln(os.Stderr, "Error: --pool cannot be combined with device paths")
+			os.Exit(1)
+		}
 
 		cfg, err := config.Load(cfgFile)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
+		cfg.DBPath = resolveDBPath(cfg)
 		drive.SpinupWithZFS(cfg, controller, args, drive.SpinupOptions{
 			NoImport: noImport,
+			Pools:    pools,
+			DryRun:   dryRun,
 		})
 	},
 }
@@ -153,40 +274,64 @@ var monitorCmd = &cobra.Command{
 	Long: `Live monitoring with efficient in-place updates.
 
 The monitor uses ANSI escape sequences to update values in-place without
-clearing the screen, providing smooth real-time updates.
+clearing the screen, providing smooth real-time updates. When stdout isn't a
+terminal (piped to a file, run under systemd) or with --plain, it appends a
+timestamped plain-text block per refresh instead.
 
 Drive states are checked every interval, while temperatures are fetched
-less frequently to reduce drive load. Controller temperature (if specified)
-is updated every 30 seconds.`,
+less frequently (--temp-interval) to reduce drive load. The temperature of
+the controller given with -c is refreshed on the same interval and flagged
+WARM at 70°C and HOT at 80°C.
+
+Each drive's temperature trend is tracked across samples. A drive rising
+faster than thresholds.thermal_rate (°C/minute) for thresholds.thermal_samples
+consecutive readings is flagged as THERMAL RUNAWAY, even below the absolute
+temperature thresholds. Set thermal_rate to 0 to turn the check off.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		interval, _ := cmd.Flags().GetInt("interval")
 		tempInterval, _ := cmd.Flags().GetInt("temp-interval")
 		controller, _ := cmd.Flags().GetString("controller")
+		plain, _ := cmd.Flags().GetBool("plain")
 		cfg, err := config.Load(cfgFile)
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
 			os.Exit(1)
 		}
-		drive.Monitor(cfg, interval, tempInterval, controller)
+		drive.Monitor(cfg, interval, tempInterval, controller, plain)
 	},
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is /etc/jbodgod/config.yaml)")
+	rootCmd.PersistentFlags().StringVar(&dbPath, "db", "", "inventory database path (default: db_path in config, $JBODGOD_DB, or "+db.DefaultPath+")")
+	rootCmd.PersistentFlags().StringVar(&cacheFile, "cache-file", "", "persist static controller info across runs in this file (e.g. "+cache.DefaultPersistPath+")")
+	rootCmd.PersistentFlags().Bool("verbose", false, "log retried HBA tool commands to stderr")
+	rootCmd.PersistentFlags().Bool("include-excluded", false, "include drives in the config's exclude list and the drive holding /")
 
 	statusCmd.Flags().Bool("json", false, "Output as JSON")
+	statusCmd.Flags().Bool("csv", false, "Output as CSV (serial, enclosure, slot, state, device, pool, model, size, firmware)")
 	statusCmd.Flags().BoolP("detail", "d", false, "Include detailed drive information")
+	statusCmd.Flags().Bool("prewarm", false, "Refresh caches in parallel before collecting")
+	statusCmd.Flags().Bool("refresh", false, "Bypass cached data (e.g. after swapping drives)")
+	statusCmd.Flags().Int("parallelism", 0, "Maximum drives probed concurrently (default: 2x CPU count)")
+	statusCmd.Flags().String("pool", "", "Only show drives in this ZFS pool")
+	statusCmd.Flags().StringSlice("state", nil, "Only show drives in these states (comma-separated)")
 
 	spindownCmd.Flags().StringP("controller", "c", "", "target specific controller (e.g., c0)")
 	spindownCmd.Flags().Bool("force", false, "skip ZFS pool checks (dangerous)")
 	spindownCmd.Flags().Bool("force-all", false, "export all affected pools without prompts")
+	spindownCmd.Flags().StringSlice("pool", nil, "only drives in this ZFS pool (repeatable)")
+	spindownCmd.Flags().Bool("dry-run", false, "print what would be exported and spun down without doing it")
 
 	spinupCmd.Flags().StringP("controller", "c", "", "target specific controller (e.g., c0)")
 	spinupCmd.Flags().Bool("no-import", false, "skip automatic ZFS pool re-import")
+	spinupCmd.Flags().StringSlice("pool", nil, "only drives in this ZFS pool (repeatable)")
+	spinupCmd.Flags().Bool("dry-run", false, "print what would be spun up and imported without doing it")
 
 	monitorCmd.Flags().IntP("interval", "i", 2, "state refresh interval in seconds")
 	monitorCmd.Flags().IntP("temp-interval", "t", 30, "temperature refresh interval in seconds")
 	monitorCmd.Flags().StringP("controller", "c", "", "controller to monitor (e.g., c0)")
+	monitorCmd.Flags().Bool("plain", false, "append plain-text blocks instead of updating in place")
 
 	rootCmd.AddCommand(versionCmd)
 	rootCmd.AddCommand(statusCmd)
@@ -194,15 +339,45 @@ func init() {
 	rootCmd.AddCommand(spinupCmd)
 	rootCmd.AddCommand(monitorCmd)
 	rootCmd.AddCommand(identifyCmd)
+	rootCmd.AddCommand(searchCmd)
 	rootCmd.AddCommand(detailCmd)
 	rootCmd.AddCommand(locateCmd)
 	rootCmd.AddCommand(inventoryCmd)
 	rootCmd.AddCommand(healthcheckCmd)
+	rootCmd.AddCommand(enclosureCmd)
+	rootCmd.AddCommand(selftestCmd)
+	rootCmd.AddCommand(poolCmd)
+	rootCmd.AddCommand(cacheCmd)
+	rootCmd.AddCommand(mapCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(daemonCmd)
 }
 
+// resolveDBPath returns the inventory database path: the --db flag, then
+// db_path from config (which itself falls back to $JBODGOD_DB), then the
+// default location
+func resolveDBPath(cfg *config.Config) string {
+	if dbPath != "" {
+		return dbPath
+	}
+	if cfg != nil && cfg.DBPath != "" {
+		return cfg.DBPath
+	}
+	if env := os.Getenv("JBODGOD_DB"); env != "" {
+		return env
+	}
+	return db.DefaultPath
+}
+
+// exitCode is set by commands that report a result through the exit status
+// (e.g. healthcheck --exit-code). It's applied once the command has returned
+// so deferred cleanup and the cache flush still run.
+var exitCode int
+
 func main() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
+	os.Exit(exitCode)
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/collector"
+	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/spf13/cobra"
+)
+
+var mapCmd = &cobra.Command{
+	Use:   "map",
+	Short: "Show which device and pool is in each bay",
+	Long: `Print every occupied bay with its device path, serial and ZFS pool.
+
+Bays come from the HBA; device paths are found by matching the drive's
+serial against sysfs and udev, so no drive is woken. A bay shows '-' as its
+device when the drive has no device node (e.g. it failed or is being
+rebuilt).
+
+Examples:
+  jbodgod map          # Bay table
+  jbodgod map --json   # For scripting`,
+	Run: runMap,
+}
+
+func init() {
+	mapCmd.Flags().Bool("json", false, "Output as JSON")
+	mapCmd.Flags().Bool("refresh", false, "Bypass cached data (e.g. after swapping drives)")
+}
+
+// BayMapping is one occupied bay for 'map'
+type BayMapping struct {
+	Bay         string `json:"bay"` // "enclosure:slot"
+	EnclosureID int    `json:"enclosure_id"`
+	Slot        int    `json:"slot"`
+	Device      string `json:"device,omitempty"`
+	Serial      string `json:"serial"`
+	Model       string `json:"model,omitempty"`
+	Pool        string `json:"pool,omitempty"`
+}
+
+func runMap(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	refresh, _ := cmd.Flags().GetBool("refresh")
+
+	sysData := collector.CollectSystemData(refresh)
+	_, _, devices, err := hba.GetAllControllerData(refresh)
+	if len(devices) == 0 {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		} else {
+			fmt.Fprintln(os.Stderr, "Error: no drives reported by the HBA")
+		}
+		os.Exit(1)
+	}
+
+	// Each drive is matched by its own serial: enclosure numbers repeat
+	// across controllers, so an enclosure:slot map could mix up bays
+	devicesBySerial := sysData.DevicesBySerial()
+	bays := make([]BayMapping, 0, len(devices))
+	for _, dev := range devices {
+		b := BayMapping{
+			Bay:         fmt.Sprintf("%d:%d", dev.EnclosureID, dev.Slot),
+			EnclosureID: dev.EnclosureID,
+			Slot:        dev.Slot,
+			Device:      hba.DevicePathForSerial(dev, devicesBySerial),
+			Serial:      dev.Serial,
+			Model:       dev.Model,
+		}
+		if b.Device != "" {
+			if vdev := sysData.ZpoolVdevForDevice(strings.TrimPrefix(b.Device, "/dev/")); vdev != nil {
+				b.Pool = vdev.PoolName
+			}
+		}
+		bays = append(bays, b)
+	}
+	sort.Slice(bays, func(i, j int) bool {
+		if bays[i].EnclosureID != bays[j].EnclosureID {
+			return bays[i].EnclosureID < bays[j].EnclosureID
+		}
+		return bays[i].Slot < bays[j].Slot
+	})
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(bays)
+		return
+	}
+
+	dash := func(s string) string {
+		if s == "" {
+			return "-"
+		}
+		return s
+	}
+	fmt.Printf("%-8s %-10s %-22s %-24s %s\n", "BAY", "DEVICE", "SERIAL", "MODEL", "POOL")
+	fmt.Println(strings.Repeat("-", 75))
+	for _, b := range bays {
+		fmt.Printf("%-8s %-10s %-22s %-24s %s\n",
+			b.Bay, dash(strings.TrimPrefix(b.Device, "/dev/")), dash(b.Serial), dash(b.Model), dash(b.Pool))
+	}
+}
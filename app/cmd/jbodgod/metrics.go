@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/db"
+	"github.com/sigreer/jbodgod/internal/version"
+)
+
+// metricWriter writes gauges in the Prometheus text exposition format,
+// emitting HELP/TYPE once per metric name
+type metricWriter struct {
+	w    io.Writer
+	seen map[string]bool
+}
+
+// gauge writes one sample; labels are name/value pairs
+func (m *metricWriter) gauge(name, help string, value float64, labels ...string) {
+	if !m.seen[name] {
+		m.seen[name] = true
+		fmt.Fprintf(m.w, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+	}
+
+	var pairs []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	if len(pairs) > 0 {
+		name += "{" + strings.Join(pairs, ",") + "}"
+	}
+	fmt.Fprintf(m.w, "%s %s\n", name, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeMetrics renders the daemon's latest healthcheck, sync and temperature
+// sample as Prometheus metrics. Results that haven't been produced yet are
+// left out.
+func writeMetrics(w io.Writer, health *HealthcheckResult, run *db.SyncRun, tempAt time.Time) {
+	m := &metricWriter{w: w, seen: make(map[string]bool)}
+
+	m.gauge("jbodgod_info", "jbodgod version", 1, "version", version.Version)
+
+	if health != nil {
+		m.gauge("jbodgod_health_status", "Overall health (0 healthy, 1 warning, 2 critical)", float64(healthcheckExitCode(health.Status)))
+		m.gauge("jbodgod_healthcheck_timestamp_seconds", "Time of the last healthcheck", float64(health.Timestamp.Unix()))
+
+		m.gauge("jbodgod_drives", "Drives by healthcheck state", float64(health.Drives.Expected), "state", "expected")
+		m.gauge("jbodgod_drives", "Drives by healthcheck state", float64(health.Drives.Present), "state", "present")
+		m.gauge("jbodgod_drives", "Drives by healthcheck state", float64(health.Drives.Active), "state", "active")
+		m.gauge("jbodgod_drives", "Drives by healthcheck state", float64(health.Drives.Standby), "state", "standby")
+		m.gauge("jbodgod_drives", "Drives by healthcheck state", float64(len(health.Drives.Missing)), "state", "missing")
+		m.gauge("jbodgod_drives", "Drives by healthcheck state", float64(len(health.Drives.Failed)), "state", "failed")
+		m.gauge("jbodgod_drives", "Drives by healthcheck state", float64(len(health.Drives.Unresponsive)), "state", "unresponsive")
+
+		devices := make([]string, 0, len(health.Drives.Temps))
+		for device := range health.Drives.Temps {
+			devices = append(devices, device)
+		}
+		sort.Strings(devices)
+		for _, device := range devices {
+			m.gauge("jbodgod_drive_temperature_celsius", "Drive temperature", float64(health.Drives.Temps[device]), "device", device)
+		}
+
+		for _, pool := range health.Pools {
+			online := 0.0
+			if pool.State == "ONLINE" {
+				online = 1
+			}
+			m.gauge("jbodgod_pool_online", "Whether the ZFS pool is ONLINE", online, "pool", pool.Name, "state", pool.State)
+			m.gauge("jbodgod_pool_errors", "ZFS pool read/write/checksum errors", float64(pool.ErrorCount), "pool", pool.Name)
+			if pool.CapacityPct != nil {
+				m.gauge("jbodgod_pool_capacity_percent", "ZFS pool capacity used", float64(*pool.CapacityPct), "pool", pool.Name)
+			}
+		}
+
+		counts := map[string]int{"info": 0, "warning": 0, "critical": 0}
+		for _, alert := range health.Alerts {
+			counts[alert.Severity]++
+		}
+		for _, severity := range []string{"info", "warning", "critical"} {
+			m.gauge("jbodgod_alerts", "Alerts raised by the last healthcheck", float64(counts[severity]), "severity", severity)
+		}
+	}
+
+	if run != nil {
+		m.gauge("jbodgod_sync_timestamp_seconds", "Time of the last inventory sync", float64(run.SyncedAt.Unix()))
+		m.gauge("jbodgod_sync_duration_seconds", "Duration of the last inventory sync", float64(run.DurationMs)/1000)
+		m.gauge("jbodgod_sync_devices", "Drives seen by the last inventory sync", float64(run.Devices))
+		m.gauge("jbodgod_sync_missing", "Drives marked missing by the last inventory sync", float64(run.Missing))
+	}
+
+	if !tempAt.IsZero() {
+		m.gauge("jbodgod_temp_sample_timestamp_seconds", "Time of the last temperature sample", float64(tempAt.Unix()))
+	}
+}
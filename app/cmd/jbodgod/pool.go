@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/zfs"
+	"github.com/spf13/cobra"
+)
+
+var poolCmd = &cobra.Command{
+	Use:   "pool",
+	Short: "Manage ZFS pools",
+}
+
+var poolScrubCmd = &cobra.Command{
+	Use:   "scrub <pool>",
+	Short: "Start or stop a scrub of a ZFS pool",
+	Long: `Start a scrub of a ZFS pool, or stop a running one with --stop.
+
+A scrub is not started if the pool already has a scrub or resilver in
+progress; its progress and ETA are reported instead. --dry-run makes the
+same check and prints the zpool command without running it.
+
+Examples:
+  jbodgod pool scrub tank            # Start a scrub
+  jbodgod pool scrub tank --stop     # Stop the running scrub
+  jbodgod pool scrub tank --dry-run  # Check whether a scrub would start`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPoolScrub,
+}
+
+var poolHistoryCmd = &cobra.Command{
+	Use:   "history <pool>",
+	Short: "Show ZFS events for a pool (device faults, resilvers, scrubs)",
+	Long: `Show the events ZFS has logged for a pool, from 'zpool events -v'.
+
+By default only device events (faults, state changes, I/O and checksum
+error reports) and scrub/resilver starts and finishes are shown; --all
+shows every event class. ZFS keeps events in memory, so history only goes
+back to the last boot.
+
+Examples:
+  jbodgod pool history tank
+  jbodgod pool history tank --limit 20 --json`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPoolHistory,
+}
+
+func init() {
+	poolScrubCmd.Flags().Bool("stop", false, "Stop a running scrub")
+	poolScrubCmd.Flags().Bool("dry-run", false, "Print the zpool command without running it")
+
+	poolHistoryCmd.Flags().Int("limit", 50, "Maximum number of events to show (0 = all)")
+	poolHistoryCmd.Flags().Bool("all", false, "Show all event classes")
+	poolHistoryCmd.Flags().Bool("json", false, "Output as JSON")
+
+	poolCmd.AddCommand(poolScrubCmd)
+	poolCmd.AddCommand(poolHistoryCmd)
+}
+
+func runPoolScrub(cmd *cobra.Command, args []string) {
+	stop, _ := cmd.Flags().GetBool("stop")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	poolName := args[0]
+
+	if stop && dryRun {
+		fmt.Printf("Would stop scrub of pool '%s' (zpool scrub -s %s)\n", poolName, poolName)
+		return
+	}
+	if stop {
+		if err := zfs.CancelScrub(poolName); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Printf("Stopped scrub of pool '%s'\n", poolName)
+		return
+	}
+
+	if err := zfs.TriggerScrub(poolName, dryRun); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if health, herr := zfs.GetPoolHealth(poolName); herr == nil && health.ScanRate != "" {
+			fmt.Fprintf(os.Stderr, "Current rate: %s\n", health.ScanRate)
+		}
+		os.Exit(1)
+	}
+	if dryRun {
+		fmt.Printf("Would start scrub of pool '%s' (zpool scrub %s)\n", poolName, poolName)
+		return
+	}
+	fmt.Printf("Started scrub of pool '%s'\n", poolName)
+	fmt.Printf("Follow progress with 'zpool status %s'\n", poolName)
+}
+
+func runPoolHistory(cmd *cobra.Command, args []string) {
+	limit, _ := cmd.Flags().GetInt("limit")
+	all, _ := cmd.Flags().GetBool("all")
+	jsonOut, _ := cmd.Flags().GetBool("json")
+	poolName := args[0]
+
+	events, err := zfs.GetPoolEvents(poolName, 0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if !all {
+		var filtered []zfs.PoolEvent
+		for _, e := range events {
+			if e.IsDeviceOrScan() {
+				filtered = append(filtered, e)
+			}
+		}
+		events = filtered
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+
+	if jsonOut {
+		if events == nil {
+			events = []zfs.PoolEvent{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(events)
+		return
+	}
+
+	if len(events) == 0 {
+		fmt.Printf("No events for pool '%s'\n", poolName)
+		return
+	}
+
+	fmt.Printf("%-19s  %-18s  %-40s  %s\n", "TIME", "EVENT", "DEVICE", "DETAILS")
+	for _, e := range events {
+		device := "-"
+		if e.Vdev != "" {
+			device = filepath.Base(e.Vdev)
+		}
+		fmt.Printf("%-19s  %-18s  %-40s  %s\n", e.Time.Format("2006-01-02 15:04:05"), e.ShortClass(), device, poolEventDetails(e))
+	}
+}
+
+// poolEventDetails summarizes the fields of an event worth a glance
+func poolEventDetails(e zfs.PoolEvent) string {
+	var parts []string
+	if state, ok := e.Details["vdev_state"]; ok {
+		s := zfs.VdevStateName(state)
+		if last, ok := e.Details["vdev_laststate"]; ok {
+			s += " (was " + zfs.VdevStateName(last) + ")"
+		}
+		parts = append(parts, s)
+	}
+	if errno, ok := e.Details["zio_err"]; ok && errno != "0x0" {
+		parts = append(parts, "errno "+errno)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/identify"
+	"github.com/spf13/cobra"
+)
+
+// SearchResult is a search match with the drive's bay, if known
+type SearchResult struct {
+	identify.SearchMatch
+	Enclosure *int `json:"enclosure,omitempty"`
+	Slot      *int `json:"slot,omitempty"`
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search <partial>",
+	Short: "Find drives by a partial serial, WWN, model, by-id name or pool",
+	Long: `List every drive with an identifier containing the given text.
+
+Unlike 'identify', which needs one exact identifier, search matches partial
+values case-insensitively across serials, WWNs, LUIDs, models, by-id names,
+kernel names and ZFS pool names. Exact matches are listed first, then
+prefix, substring and fuzzy (characters in order) matches.
+
+Examples:
+  jbodgod search WCK5        # Part of a serial
+  jbodgod search ST8000      # All drives of a model
+  jbodgod search tank        # Drives in a pool`,
+	Args: cobra.ExactArgs(1),
+	Run:  runSearch,
+}
+
+func init() {
+	searchCmd.Flags().Bool("json", false, "Output as JSON")
+}
+
+func runSearch(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+
+	idx, err := identify.BuildIndex()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error building device index: %v\n", err)
+		os.Exit(1)
+	}
+
+	matches := idx.Search(args[0])
+	results := make([]SearchResult, 0, len(matches))
+	for _, m := range matches {
+		r := SearchResult{SearchMatch: m}
+		if m.Device.Serial != nil {
+			if dev := hba.GetDeviceBySerial(*m.Device.Serial); dev != nil {
+				r.Enclosure = &dev.EnclosureID
+				r.Slot = &dev.Slot
+			}
+		}
+		results = append(results, r)
+	}
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+		return
+	}
+
+	if len(results) == 0 {
+		fmt.Fprintf(os.Stderr, "No drives match '%s'\n", args[0])
+		os.Exit(1)
+	}
+
+	fmt.Printf("%-12s %-20s %-22s %-8s %s\n", "DEVICE", "SERIAL", "MODEL", "BAY", "MATCHED")
+	fmt.Println(strings.Repeat("-", 90))
+	for _, r := range results {
+		bay := "-"
+		if r.Enclosure != nil && r.Slot != nil {
+			bay = fmt.Sprintf("%d:%d", *r.Enclosure, *r.Slot)
+		}
+		fmt.Printf("%-12s %-20s %-22s %-8s %s=%s\n",
+			r.Device.DevicePath, derefOr(r.Device.Serial, "-"), derefOr(r.Device.Model, "-"),
+			bay, r.MatchedAs, r.Value)
+	}
+}
+
+// derefOr returns the string a pointer refers to, or def if it is nil
+func derefOr(s *string, def string) string {
+	if s == nil || *s == "" {
+		return def
+	}
+	return *s
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/db"
+	"github.com/sigreer/jbodgod/internal/drive"
+	"github.com/sigreer/jbodgod/internal/notify"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// minSelfTestDuration is how long a started test is assumed to be
+	// running regardless of the log, which may still show the previous test
+	minSelfTestDuration = 10 * time.Minute
+
+	// selfTestTimeout marks a test aborted if it never reports a result
+	// (e.g. the drive was pulled or power cycled mid-test)
+	selfTestTimeout = 48 * time.Hour
+)
+
+var selftestCmd = &cobra.Command{
+	Use:   "selftest",
+	Short: "Schedule rotating SMART long self-tests",
+	Long: `Run SMART long self-tests on a rotating schedule so every drive is tested
+once per cadence without the whole shelf testing at once.
+
+'selftest run' is meant to be called regularly (e.g. hourly from cron or a
+systemd timer); 'jbodgod daemon' runs it every daemon.selftest_interval.
+Each run:
+  - checks tests it started earlier and records their results, raising a
+    critical alert when a test fails
+  - starts 'smartctl -t long' on the drives most overdue for a test, up to
+    selftest.max_concurrent, during selftest.hours only
+
+Drives in standby are never woken for a test. Results are kept in the
+inventory database.
+
+Config:
+  selftest:
+    cadence: 720h        # test each drive every 30 days
+    max_concurrent: 1    # drives testing at once
+    hours: [1, 2, 3, 4]  # start tests only between 01:00 and 04:59`,
+}
+
+var selftestRunCmd = &cobra.Command{
+	Use:   "run",
+	Short: "Record finished tests and start tests on due drives",
+	Run:   runSelftestRun,
+}
+
+var selftestStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the latest self-test of each drive",
+	Run:   runSelftestStatus,
+}
+
+func init() {
+	selftestRunCmd.Flags().Bool("dry-run", false, "Show which drives would be tested without starting tests")
+	selftestRunCmd.Flags().Bool("ignore-hours", false, "Start tests outside the configured hours")
+	selftestStatusCmd.Flags().Bool("json", false, "Output as JSON")
+
+	selftestCmd.AddCommand(selftestRunCmd)
+	selftestCmd.AddCommand(selftestStatusCmd)
+}
+
+func runSelftestRun(cmd *cobra.Command, args []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	ignoreHours, _ := cmd.Flags().GetBool("ignore-hours")
+
+	cfg, err := config.Load(cfgFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	if err := runSelfTestScheduler(cfg, database, dryRun, ignoreHours); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// runSelfTestScheduler records finished tests and starts tests on the drives
+// most overdue for one. Shared by 'selftest run' and the daemon.
+func runSelfTestScheduler(cfg *config.Config, database *db.DB, dryRun, ignoreHours bool) error {
+	// Check on tests started by earlier runs
+	running, err := database.GetRunningSelfTests()
+	if err != nil {
+		return fmt.Errorf("failed to query self-tests: %w", err)
+	}
+
+	testing := make(map[string]bool)
+	for _, t := range running {
+		if !dryRun && checkSelfTest(cfg, database, t) {
+			continue
+		}
+		testing[t.Serial] = true
+	}
+
+	if !ignoreHours && !cfg.SelfTest.AllowsHour(time.Now().Hour()) {
+		fmt.Printf("Outside scheduled hours %v; not starting new tests (%d running)\n", cfg.SelfTest.Hours, len(testing))
+		return nil
+	}
+
+	slots := cfg.SelfTest.MaxConcurrent - len(testing)
+	if slots <= 0 {
+		fmt.Printf("%d test(s) running (max %d); not starting new tests\n", len(testing), cfg.SelfTest.MaxConcurrent)
+		return nil
+	}
+
+	latest, err := database.GetLatestSelfTests()
+	if err != nil {
+		return fmt.Errorf("failed to query self-tests: %w", err)
+	}
+
+	// Pick due drives: never tested first, then the longest since a test
+	type candidate struct {
+		device, serial string
+		last           *time.Time
+	}
+	var due []candidate
+	for _, d := range drive.GetAll(cfg) {
+		if d.State != "active" || d.Serial == nil || *d.Serial == "" || testing[*d.Serial] {
+			continue
+		}
+		c := candidate{device: d.ProbeDevice(), serial: *d.Serial}
+		if t, ok := latest[*d.Serial]; ok {
+			if time.Since(t.StartedAt) < cfg.SelfTest.Cadence {
+				continue
+			}
+			c.last = &t.StartedAt
+		}
+		due = append(due, c)
+	}
+	sort.SliceStable(due, func(i, j int) bool {
+		if (due[i].last == nil) != (due[j].last == nil) {
+			return due[i].last == nil
+		}
+		return due[i].last != nil && due[i].last.Before(*due[j].last)
+	})
+
+	if len(due) == 0 {
+		fmt.Println("No drives due for a self-test")
+		return nil
+	}
+
+	started := 0
+	for _, c := range due {
+		if started >= slots {
+			break
+		}
+		if dryRun {
+			fmt.Printf("Would start long self-test on %s (%s)\n", c.device, c.serial)
+			started++
+			continue
+		}
+		if err := drive.StartLongSelfTest(c.device); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		}
+		if _, err := database.StartSelfTest(c.serial, c.device, "long"); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		}
+		fmt.Printf("Started long self-test on %s (%s)\n", c.device, c.serial)
+		started++
+	}
+
+	if remaining := len(due) - started; remaining > 0 {
+		fmt.Printf("%d more drive(s) due\n", remaining)
+	}
+	return nil
+}
+
+// checkSelfTest reads the result of a running test and records it once
+// finished. Returns true if the test is no longer running.
+func checkSelfTest(cfg *config.Config, database *db.DB, t *db.SelfTest) bool {
+	if time.Since(t.StartedAt) < minSelfTestDuration {
+		return false
+	}
+
+	result, err := drive.GetSelfTestResult(t.DevicePath)
+	if err != nil || result == nil || result.InProgress {
+		if time.Since(t.StartedAt) < selfTestTimeout {
+			return false
+		}
+		database.CompleteSelfTest(t.ID, db.SelfTestAborted, fmt.Sprintf("no result after %s", selfTestTimeout))
+		fmt.Printf("Self-test on %s (%s) gave no result; marked aborted\n", t.DevicePath, t.Serial)
+		return true
+	}
+
+	status := db.SelfTestFailed
+	switch {
+	case result.Passed:
+		status = db.SelfTestPassed
+	case result.Aborted:
+		status = db.SelfTestAborted
+	}
+
+	if err := database.CompleteSelfTest(t.ID, status, result.Raw); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		return false
+	}
+	fmt.Printf("Self-test on %s (%s) %s: %s\n", t.DevicePath, t.Serial, status, result.Status)
+
+	if status == db.SelfTestFailed {
+		alert, err := database.CreateAlertWithDetails(db.SeverityCritical, "selftest_failed",
+			fmt.Sprintf("SMART long self-test failed on %s (serial: %s): %s", t.DevicePath, t.Serial, result.Status),
+			map[string]interface{}{"serial": t.Serial, "device": t.DevicePath, "result": result.Raw})
+		if err == nil {
+			if _, err := notify.NewDispatcher(cfg.Alerts, database).Notify(alert); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+	return true
+}
+
+// SelftestStatus is the latest self-test of a drive for 'selftest status'
+type SelftestStatus struct {
+	Serial      string     `json:"serial"`
+	Device      string     `json:"device,omitempty"`
+	Status      string     `json:"status"`
+	Result      string     `json:"result,omitempty"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	NextDue     time.Time  `json:"next_due"`
+}
+
+func runSelftestStatus(cmd *cobra.Command, args []string) {
+	jsonOut, _ := cmd.Flags().GetBool("json")
+
+	cfg, err := config.LoadFile(cfgFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading config: %v\n", err)
+		os.Exit(1)
+	}
+
+	database, err := openDB()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening database: %v\n", err)
+		os.Exit(1)
+	}
+	defer database.Close()
+
+	latest, err := database.GetLatestSelfTests()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error querying self-tests: %v\n", err)
+		os.Exit(1)
+	}
+
+	statuses := make([]SelftestStatus, 0, len(latest))
+	for _, t := range latest {
+		statuses = append(statuses, SelftestStatus{
+			Serial:      t.Serial,
+			Device:      t.DevicePath,
+			Status:      t.Status,
+			Result:      t.Result,
+			StartedAt:   t.StartedAt,
+			CompletedAt: t.CompletedAt,
+			NextDue:     t.StartedAt.Add(cfg.SelfTest.Cadence),
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].NextDue.Before(statuses[j].NextDue)
+	})
+
+	if jsonOut {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(statuses)
+		return
+	}
+
+	if len(statuses) == 0 {
+		fmt.Println("No self-tests recorded. Run 'jbodgod selftest run' to start the schedule.")
+		return
+	}
+
+	fmt.Printf("%-20s %-10s %-9s %-17s %s\n", "SERIAL", "DEVICE", "STATUS", "STARTED", "NEXT DUE")
+	fmt.Println(strings.Repeat("-", 75))
+	for _, s := range statuses {
+		fmt.Printf("%-20s %-10s %-9s %-17s %s\n",
+			s.Serial, strings.TrimPrefix(s.Device, "/dev/"), strings.ToUpper(s.Status),
+			s.StartedAt.Format("2006-01-02 15:04"), s.NextDue.Format("2006-01-02"))
+	}
+}
@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"strings"
 	"sync"
 	"time"
 )
@@ -121,6 +122,22 @@ func (c *Cache) Delete(key string) {
 	delete(c.entries, key)
 }
 
+// DeletePrefix removes all entries whose key starts with prefix and returns
+// how many were removed
+func (c *Cache) DeletePrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	n := 0
+	for k := range c.entries {
+		if strings.HasPrefix(k, prefix) {
+			delete(c.entries, k)
+			n++
+		}
+	}
+	return n
+}
+
 // Clear removes all entries from cache
 func (c *Cache) Clear() {
 	c.mu.Lock()
@@ -0,0 +1,203 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultPersistPath is the suggested on-disk cache location
+const DefaultPersistPath = "/var/lib/jbodgod/cache"
+
+// bootIDPath changes on every boot; a cache file written under another boot
+// is discarded, since device names and SCSI addresses may have moved
+var bootIDPath = "/proc/sys/kernel/random/boot_id"
+
+// persistMinTTL is the shortest remaining lifetime worth writing to disk;
+// fast-changing entries (drive state, temperatures) are never persisted
+const persistMinTTL = time.Minute
+
+// persistedEntry is the on-disk form of a CacheEntry. The value is encoded
+// separately so one unserializable entry doesn't fail the whole file.
+type persistedEntry struct {
+	Data      []byte
+	ExpiresAt time.Time
+	FetchedAt time.Time
+}
+
+// persistedCache is the on-disk cache file
+type persistedCache struct {
+	BootID  string
+	Entries map[string]persistedEntry
+}
+
+// valueBox wraps a value so gob records its concrete type
+type valueBox struct {
+	Value interface{}
+}
+
+var (
+	prefixMu        sync.RWMutex
+	persistPrefixes []string
+)
+
+// Register makes a value's concrete type persistable. Packages call this in
+// init() for the types they cache; unregistered types are skipped on save.
+func Register(value interface{}) {
+	gob.Register(value)
+}
+
+// PersistPrefix allows keys starting with prefix to be written to disk.
+// Only static hardware facts belong here: drive rosters, drive state and
+// entries keyed or valued by /dev names go stale across runs and reboots.
+func PersistPrefix(prefix string) {
+	prefixMu.Lock()
+	persistPrefixes = append(persistPrefixes, prefix)
+	prefixMu.Unlock()
+}
+
+// persistable reports whether key matches a PersistPrefix
+func persistable(key string) bool {
+	prefixMu.RLock()
+	defer prefixMu.RUnlock()
+	for _, p := range persistPrefixes {
+		if strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// bootID returns the current boot's ID ("" if unreadable)
+func bootID() string {
+	data, err := os.ReadFile(bootIDPath)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// SaveToFile writes the unexpired entries under a PersistPrefix with at
+// least persistMinTTL remaining to path. Entries whose values can't be
+// encoded are skipped.
+func (c *Cache) SaveToFile(path string) error {
+	c.mu.RLock()
+	entries := make(map[string]persistedEntry, len(c.entries))
+	for key, entry := range c.entries {
+		if !persistable(key) || time.Until(entry.ExpiresAt) < persistMinTTL {
+			continue
+		}
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(valueBox{Value: entry.Value}); err != nil {
+			continue
+		}
+		entries[key] = persistedEntry{
+			Data:      buf.Bytes(),
+			ExpiresAt: entry.ExpiresAt,
+			FetchedAt: entry.FetchedAt,
+		}
+	}
+	c.mu.RUnlock()
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// Write to a temp file and rename so concurrent readers never see a
+	// partially written cache
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cache-*")
+	if err != nil {
+		return fmt.Errorf("failed to create cache file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(persistedCache{BootID: bootID(), Entries: entries}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to encode cache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write cache file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace cache file: %w", err)
+	}
+	return nil
+}
+
+// LoadFromFile merges unexpired entries from path into the cache. Existing
+// in-memory entries take precedence. A missing file, or one written before
+// the last reboot, is not an error; the latter is just ignored.
+func (c *Cache) LoadFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to open cache file: %w", err)
+	}
+	defer f.Close()
+
+	var file persistedCache
+	if err := gob.NewDecoder(f).Decode(&file); err != nil {
+		return fmt.Errorf("failed to decode cache file: %w", err)
+	}
+	if file.BootID == "" || file.BootID != bootID() {
+		return nil
+	}
+
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for key, pe := range file.Entries {
+		if now.After(pe.ExpiresAt) || !persistable(key) {
+			continue
+		}
+		if _, ok := c.entries[key]; ok {
+			continue
+		}
+		var box valueBox
+		if err := gob.NewDecoder(bytes.NewReader(pe.Data)).Decode(&box); err != nil {
+			// Type no longer registered or changed shape - refetch instead
+			continue
+		}
+		c.entries[key] = &CacheEntry{
+			Value:     box.Value,
+			ExpiresAt: pe.ExpiresAt,
+			FetchedAt: pe.FetchedAt,
+		}
+	}
+	return nil
+}
+
+var (
+	persistMu   sync.Mutex
+	persistPath string
+)
+
+// EnablePersistence hydrates the global cache from path and remembers the
+// path for Flush
+func EnablePersistence(path string) error {
+	persistMu.Lock()
+	persistPath = path
+	persistMu.Unlock()
+
+	return Global().LoadFromFile(path)
+}
+
+// Flush saves the global cache to disk if persistence is enabled
+func Flush() error {
+	persistMu.Lock()
+	path := persistPath
+	persistMu.Unlock()
+
+	if path == "" {
+		return nil
+	}
+	return Global().SaveToFile(path)
+}
@@ -0,0 +1,45 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPersistOnlyAllowedPrefixes(t *testing.T) {
+	dir := t.TempDir()
+	bootIDPath = filepath.Join(dir, "boot_id")
+	os.WriteFile(bootIDPath, []byte("boot-a\n"), 0644)
+	PersistPrefix("test:static:")
+
+	c := New()
+	c.Set("test:static:c0", "firmware", time.Hour)
+	c.Set("drive:serial:/dev/sda", "ABC123", 24*time.Hour)
+
+	path := filepath.Join(dir, "cache")
+	if err := c.SaveToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := New()
+	if err := loaded.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Get("test:static:c0"); got != "firmware" {
+		t.Errorf("test:static:c0 = %v, want firmware", got)
+	}
+	if got := loaded.Get("drive:serial:/dev/sda"); got != nil {
+		t.Errorf("drive:serial:/dev/sda was persisted: %v", got)
+	}
+
+	// After a reboot the file is ignored
+	os.WriteFile(bootIDPath, []byte("boot-b\n"), 0644)
+	rebooted := New()
+	if err := rebooted.LoadFromFile(path); err != nil {
+		t.Fatal(err)
+	}
+	if got := rebooted.Get("test:static:c0"); got != nil {
+		t.Errorf("entry survived a reboot: %v", got)
+	}
+}
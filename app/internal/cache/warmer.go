@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RefreshFunc re-fetches the data for a cache key and stores it in the cache
+type RefreshFunc func()
+
+// warmTask is a registered refresh for a single cache key
+type warmTask struct {
+	ttl     time.Duration
+	refresh RefreshFunc
+	running bool
+}
+
+// Warmer refreshes registered cache entries shortly before their TTL expires,
+// so foreground lookups nearly always hit a warm cache
+type Warmer struct {
+	cache *Cache
+	mu    sync.Mutex
+	tasks map[string]*warmTask
+}
+
+// NewWarmer creates a warmer for the given cache
+func NewWarmer(c *Cache) *Warmer {
+	return &Warmer{
+		cache: c,
+		tasks: make(map[string]*warmTask),
+	}
+}
+
+// Register adds a cache key to keep warm. The ttl must match the TTL the
+// refresh function stores the entry with.
+func (w *Warmer) Register(key string, ttl time.Duration, refresh RefreshFunc) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.tasks[key] = &warmTask{ttl: ttl, refresh: refresh}
+}
+
+// leadTime returns how long before expiry an entry should be refreshed
+// (10% of the TTL, between 1s and 1m)
+func leadTime(ttl time.Duration) time.Duration {
+	lead := ttl / 10
+	if lead < time.Second {
+		lead = time.Second
+	}
+	if lead > time.Minute {
+		lead = time.Minute
+	}
+	return lead
+}
+
+// isDue returns true if the entry is missing or about to expire
+func (w *Warmer) isDue(key string, ttl time.Duration) bool {
+	entry := w.cache.GetEntry(key)
+	if entry == nil {
+		return true
+	}
+	return entry.Age() >= ttl-leadTime(ttl)
+}
+
+// WarmDue refreshes all entries that are missing or close to expiry in
+// parallel and waits for them to complete. Returns the number refreshed.
+func (w *Warmer) WarmDue() int {
+	var wg sync.WaitGroup
+	refreshed := 0
+
+	w.mu.Lock()
+	for key, task := range w.tasks {
+		if task.running || !w.isDue(key, task.ttl) {
+			continue
+		}
+		task.running = true
+		refreshed++
+
+		wg.Add(1)
+		go func(t *warmTask) {
+			defer wg.Done()
+			t.refresh()
+
+			w.mu.Lock()
+			t.running = false
+			w.mu.Unlock()
+		}(task)
+	}
+	w.mu.Unlock()
+
+	wg.Wait()
+	return refreshed
+}
+
+// Run checks registered entries every interval and refreshes those nearing
+// expiry until the context is cancelled
+func (w *Warmer) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.WarmDue()
+		}
+	}
+}
@@ -2,15 +2,25 @@ package collector
 
 import (
 	"encoding/json"
+	"fmt"
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/privexec"
+	"github.com/sigreer/jbodgod/internal/wwn"
 )
 
+// refreshPrefixes are the cache keys dropped by a forced refresh: every
+// layer of the bulk data plus per-drive SMART reads, so a swapped drive's
+// new identity is picked up
+var refreshPrefixes = []string{"system:", "sysfs:", "udev:", "smart:"}
+
 // CollectSystemData gathers data from all bulk sources
 func CollectSystemData(forceRefresh bool) *SystemData {
 	c := cache.Global()
@@ -20,6 +30,12 @@ func CollectSystemData(forceRefresh bool) *SystemData {
 		if cached := c.Get(cacheKey); cached != nil {
 			return cached.(*SystemData)
 		}
+	} else {
+		// The layers below keep their own cache entries, which would
+		// otherwise rebuild the bulk data from stale results
+		for _, prefix := range refreshPrefixes {
+			c.DeletePrefix(prefix)
+		}
 	}
 
 	data := &SystemData{
@@ -114,7 +130,7 @@ func collectLsblk(data *SystemData) {
 			Name:      bd.Name,
 			Path:      bd.Path,
 			Serial:    trimPtr(bd.Serial),
-			WWN:       trimPtr(bd.WWN),
+			WWN:       normalizedWWN(bd.WWN),
 			Model:     trimPtr(bd.Model),
 			Vendor:    trimPtr(bd.Vendor),
 			Rev:       trimPtr(bd.Rev),
@@ -152,7 +168,7 @@ func collectBlkid(data *SystemData) {
 		return
 	}
 
-	out, err := exec.Command("sudo", "blkid", "-o", "export").CombinedOutput()
+	out, err := privexec.RunSudo("blkid", "-o", "export")
 	if err != nil {
 		return
 	}
@@ -274,7 +290,10 @@ func collectLsscsi(data *SystemData) {
 	c.SetFast(cacheKey, devices)
 }
 
-// collectZpool parses zpool status -gLP output
+// collectZpool parses zpool status output. Vdevs are identified by GUID
+// (-g) and mapped to disks through their on-disk label, so pools built from
+// by-id links or whole disks match as well as those using /dev/sdX
+// partitions. A second status without -g supplies the vdev names.
 func collectZpool(data *SystemData) {
 	c := cache.Global()
 	cacheKey := "system:zpool"
@@ -286,106 +305,83 @@ func collectZpool(data *SystemData) {
 		return
 	}
 
-	out, err := exec.Command("sudo", "zpool", "status", "-gLP").CombinedOutput()
+	out, err := privexec.RunSudo("zpool", "status", "-gP")
 	if err != nil {
 		return
 	}
+	rows := parseZpoolConfig(string(out))
 
+	// Names line up with the GUID rows unless a pool changed in between,
+	// in which case fall back to the GUIDs
+	var nameRows []zpoolConfigRow
+	if out, err := privexec.RunSudo("zpool", "status", "-LP"); err == nil {
+		nameRows = parseZpoolConfig(string(out))
+	}
+	if len(nameRows) != len(rows) {
+		nameRows = rows
+	}
+
+	memberDisks := zfsMemberDisks()
 	vdevs := make(map[string]*ZpoolVdev)
-	var currentPool string
-	var poolState string
 	var currentVdevType string
 
-	lines := strings.Split(string(out), "\n")
-	inConfig := false
-
-	for _, line := range lines {
-		trimmed := strings.TrimSpace(line)
+	for i, row := range rows {
+		guid := row.Fields[0]
+		name := nameRows[i].Fields[0]
+		read, _ := strconv.Atoi(row.Fields[2])
+		write, _ := strconv.Atoi(row.Fields[3])
+		cksum, _ := strconv.Atoi(row.Fields[4])
 
-		// Pool name
-		if strings.HasPrefix(trimmed, "pool:") {
-			currentPool = strings.TrimSpace(strings.TrimPrefix(trimmed, "pool:"))
+		// Pool name line (indent ~2)
+		if row.Indent <= 2 {
 			currentVdevType = ""
 			continue
 		}
 
-		// Pool state
-		if strings.HasPrefix(trimmed, "state:") {
-			poolState = strings.TrimSpace(strings.TrimPrefix(trimmed, "state:"))
-			continue
-		}
-
-		// Config section start
-		if strings.HasPrefix(trimmed, "config:") {
-			inConfig = true
-			continue
-		}
-
-		// End of config
-		if strings.HasPrefix(trimmed, "errors:") {
-			inConfig = false
+		// Vdev type line (mirror, raidz, etc.) - typically indent 4
+		if row.Indent <= 4 && isVdevGroup(name) {
+			currentVdevType = name
 			continue
 		}
-
-		if !inConfig || currentPool == "" {
+		if isVdevGroup(name) {
 			continue
 		}
 
-		// Skip header
-		if strings.HasPrefix(trimmed, "NAME") {
-			continue
+		// Top-level single-disk vdevs sit at the same depth as groups
+		vdevType := currentVdevType
+		if row.Indent <= 4 {
+			vdevType = ""
 		}
 
-		// Parse vdev lines
-		fields := strings.Fields(line)
-		if len(fields) < 5 {
-			continue
-		}
-
-		name := fields[0]
-		state := fields[1]
-		read, _ := strconv.Atoi(fields[2])
-		write, _ := strconv.Atoi(fields[3])
-		cksum, _ := strconv.Atoi(fields[4])
-
-		// Determine if this is a vdev type or leaf device
-		indent := len(line) - len(strings.TrimLeft(line, " \t"))
-
-		// Pool name line (indent ~2)
-		if indent <= 2 && name == currentPool {
-			continue
-		}
-
-		// Vdev type line (mirror, raidz, etc.) - typically indent 4
-		if indent <= 4 && (strings.HasPrefix(name, "mirror") ||
-			strings.HasPrefix(name, "raidz") ||
-			strings.HasPrefix(name, "spare") ||
-			strings.HasPrefix(name, "cache") ||
-			strings.HasPrefix(name, "log")) {
-			currentVdevType = name
-			continue
-		}
-
-		// This is a leaf device (GUID or path)
 		vdev := &ZpoolVdev{
-			PoolName:    currentPool,
-			PoolState:   poolState,
-			VdevGUID:    name, // Could be GUID or path
-			VdevType:    currentVdevType,
-			State:       state,
+			PoolName:    row.Pool,
+			PoolState:   row.PoolState,
+			VdevGUID:    guid,
+			VdevType:    vdevType,
+			State:       row.Fields[1],
 			ReadErrors:  read,
 			WriteErrors: write,
 			CksumErrors: cksum,
 		}
 
-		// If name starts with / it's a path
-		if strings.HasPrefix(name, "/") {
-			vdev.DevicePath = &name
-			// Extract base device for GUID lookup
+		// by-id and by-vdev names are stored as the kernel device
+		// they point at, so they compare against sdX names downstream
+		if path := resolveVdevPath(name); path != "" {
+			vdev.DevicePath = &path
 		}
 
-		vdevs[name] = vdev
-		data.ZpoolVdevs[name] = vdev
+		// The label is authoritative; the path is only a fallback when
+		// udev has no record of it
+		if disk, ok := memberDisks[guid]; ok {
+			vdev.Disk = &disk
+		} else if vdev.DevicePath != nil {
+			if disk := diskForPath(*vdev.DevicePath); disk != "" {
+				vdev.Disk = &disk
+			}
+		}
+
+		vdevs[guid] = vdev
+		data.ZpoolVdevs[guid] = vdev
 	}
 
 	c.SetFast(cacheKey, vdevs)
@@ -404,8 +400,8 @@ func collectLVM(data *SystemData) {
 	}
 
 	// Use pvs with specific output format
-	out, err := exec.Command("sudo", "pvs", "--noheadings", "--nosuffix", "--units", "b",
-		"-o", "pv_name,pv_uuid,vg_name,pv_size,pv_free", "--separator", "|").CombinedOutput()
+	out, err := privexec.RunSudo("pvs", "--noheadings", "--nosuffix", "--units", "b",
+		"-o", "pv_name,pv_uuid,vg_name,pv_size,pv_free", "--separator", "|")
 	if err != nil {
 		return
 	}
@@ -527,14 +523,184 @@ func collectHBA(data *SystemData) {
 		collectSas3ircu(data)
 	}
 
-	// Cache combined result with static TTL (24h)
+	// Cache combined result with static TTL (24h). The maps are copied so
+	// the cache never shares them with a SystemData handed to callers.
 	combinedCache := &hbaCombinedCache{
-		Devices:     data.HBADevices,
-		Controllers: data.Controllers,
+		Devices:     make(map[string]*HBADevice, len(data.HBADevices)),
+		Controllers: make(map[string]*ControllerData, len(data.Controllers)),
+	}
+	for k, v := range data.HBADevices {
+		combinedCache.Devices[k] = v
+	}
+	for k, v := range data.Controllers {
+		combinedCache.Controllers[k] = v
 	}
 	c.SetStatic(cacheKey, combinedCache)
 }
 
+// addHBADevice stores an HBA device keyed by upper-case serial. Some cheap
+// drives report duplicate serials: when a device in another slot already
+// holds the serial, both are re-keyed by serial and slot (see hbaSlotKey)
+// rather than one overwriting the other, and the bare serial is left unset
+// so lookups by serial alone can't return the wrong bay.
+//
+// A drive on a multipath shelf is reported by each controller; a view from
+// another controller with the same SAS address or WWN is the same drive, so
+// the first controller's view is kept.
+func addHBADevice(devices map[string]*HBADevice, dev *HBADevice) {
+	serial := strings.ToUpper(dev.Serial)
+
+	if existing, ok := devices[serial]; ok {
+		if existing.EnclosureID == dev.EnclosureID && existing.Slot == dev.Slot && existing.ControllerID == dev.ControllerID {
+			devices[serial] = dev
+			return
+		}
+		if sameHBADrive(existing, dev) {
+			return
+		}
+		delete(devices, serial)
+		devices[hbaSlotKey(existing)] = existing
+		devices[hbaSlotKey(dev)] = dev
+		return
+	}
+
+	dups := duplicateHBADevices(devices, serial)
+	for _, d := range dups {
+		if sameHBADrive(d, dev) {
+			return
+		}
+	}
+	if len(dups) > 0 {
+		devices[hbaSlotKey(dev)] = dev
+		return
+	}
+	devices[serial] = dev
+}
+
+// sameHBADrive reports whether two devices from different controllers are
+// views of one multipath drive: they share a SAS address or a WWN
+func sameHBADrive(a, b *HBADevice) bool {
+	if a.ControllerID == b.ControllerID {
+		return false
+	}
+	if a.SASAddress != nil && b.SASAddress != nil &&
+		normalizeSASAddress(*a.SASAddress) == normalizeSASAddress(*b.SASAddress) {
+		return true
+	}
+	return a.WWN != nil && b.WWN != nil && wwn.Normalize(*a.WWN) == wwn.Normalize(*b.WWN)
+}
+
+// hbaSlotKey keys a device whose serial is shared with another drive:
+// "SERIAL@enclosure:slot"
+func hbaSlotKey(dev *HBADevice) string {
+	return fmt.Sprintf("%s@%d:%d", strings.ToUpper(dev.Serial), dev.EnclosureID, dev.Slot)
+}
+
+// duplicateHBADevices returns the devices stored under slot keys for a
+// serial, i.e. the drives sharing that serial
+func duplicateHBADevices(devices map[string]*HBADevice, serial string) []*HBADevice {
+	var dups []*HBADevice
+	for k, v := range devices {
+		if strings.HasPrefix(k, serial+"@") {
+			dups = append(dups, v)
+		}
+	}
+	return dups
+}
+
+// lookupHBADevice finds the HBA device for a drive. A serial shared by
+// several drives is only resolved when the drive's SAS address picks out
+// exactly one of them; otherwise nil is returned.
+func lookupHBADevice(devices map[string]*HBADevice, serial string, sasAddress *string) *HBADevice {
+	serial = strings.ToUpper(serial)
+	if dev, ok := devices[serial]; ok {
+		return dev
+	}
+	if sasAddress == nil {
+		return nil
+	}
+
+	var match *HBADevice
+	for _, dev := range duplicateHBADevices(devices, serial) {
+		if dev.SASAddress != nil && normalizeSASAddress(*dev.SASAddress) == normalizeSASAddress(*sasAddress) {
+			if match != nil {
+				return nil
+			}
+			match = dev
+		}
+	}
+	return match
+}
+
+// DuplicateHBASerials returns the serials reported by more than one drive
+// on the HBA, sorted
+func (d *SystemData) DuplicateHBASerials() []string {
+	seen := make(map[string]bool)
+	var serials []string
+	for k := range d.HBADevices {
+		if serial, _, ok := strings.Cut(k, "@"); ok && !seen[serial] {
+			seen[serial] = true
+			serials = append(serials, serial)
+		}
+	}
+	sort.Strings(serials)
+	return serials
+}
+
+// DevicesBySerial maps the upper-cased serials of whole disks (VPD serial
+// from sysfs plus the udev short and SCSI serials) to their /dev path
+func (d *SystemData) DevicesBySerial() map[string]string {
+	devices := make(map[string]string)
+	add := func(serial, name string) {
+		if serial = strings.ToUpper(strings.TrimSpace(serial)); serial != "" {
+			devices[serial] = "/dev/" + name
+		}
+	}
+
+	for name, dev := range d.SysfsDevices {
+		if dev.Serial != nil {
+			add(*dev.Serial, name)
+		}
+	}
+	for name, dev := range d.UdevDevices {
+		if dev.DevType == "partition" {
+			continue
+		}
+		add(dev.IDSerialShort, name)
+		add(dev.IDSCSISerial, name)
+	}
+	return devices
+}
+
+// ZpoolVdevForDevice returns the zpool vdev backed by a device (or one of
+// its partitions), or nil if the device isn't in an imported pool
+func (d *SystemData) ZpoolVdevForDevice(devName string) *ZpoolVdev {
+	for _, vdev := range d.ZpoolVdevs {
+		if vdev.Disk != nil && *vdev.Disk == devName {
+			return vdev
+		}
+	}
+	return nil
+}
+
+// normalizeSASAddress lowercases a SAS address and strips 0x and dashes
+func normalizeSASAddress(addr string) string {
+	addr = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(addr), "-", ""))
+	return strings.TrimPrefix(addr, "0x")
+}
+
+// normalizedWWN returns a normalized copy of a WWN, or nil if it's empty
+func normalizedWWN(value *string) *string {
+	if value == nil {
+		return nil
+	}
+	n := wwn.Normalize(*value)
+	if n == "" {
+		return nil
+	}
+	return &n
+}
+
 type hbaCombinedCache struct {
 	Devices     map[string]*HBADevice
 	Controllers map[string]*ControllerData
@@ -557,7 +723,9 @@ func collectStorcli(data *SystemData) {
 	}
 
 	// First get controller list
-	out, err := exec.Command("sudo", "storcli", "show").CombinedOutput()
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", "show")
+	})
 	if err != nil {
 		return
 	}
@@ -579,10 +747,11 @@ func collectStorcli(data *SystemData) {
 		}
 
 		// Get drive details
-		devices := collectStorcliDrives(ctrlID)
-		for serial, dev := range devices {
-			data.HBADevices[serial] = dev
-			cachedData.Devices[serial] = dev
+		// Combined like one controller's duplicates, so drives sharing a
+		// serial across controllers aren't overwritten
+		for _, dev := range collectStorcliDrives(ctrlID) {
+			addHBADevice(data.HBADevices, dev)
+			addHBADevice(cachedData.Devices, dev)
 		}
 	}
 
@@ -610,7 +779,9 @@ func parseStorcliControllers(output string) []string {
 }
 
 func collectStorcliController(ctrlID string) *ControllerData {
-	out, err := exec.Command("sudo", "storcli", "/"+ctrlID, "show").CombinedOutput()
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", "/"+ctrlID, "show")
+	})
 	if err != nil {
 		return nil
 	}
@@ -661,10 +832,18 @@ func collectStorcliController(ctrlID string) *ControllerData {
 	return ctrl
 }
 
+// collectStorcliDrives reads drive details via storcli, preferring its JSON
+// output and falling back to parsing the text layout on older versions
 func collectStorcliDrives(ctrlID string) map[string]*HBADevice {
+	if devices, err := collectStorcliDrivesJSON(ctrlID); err == nil {
+		return devices
+	}
+
 	devices := make(map[string]*HBADevice)
 
-	out, err := exec.Command("sudo", "storcli", "/"+ctrlID+"/eall/sall", "show", "all").CombinedOutput()
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", "/"+ctrlID+"/eall/sall", "show", "all")
+	})
 	if err != nil {
 		return devices
 	}
@@ -676,7 +855,7 @@ func collectStorcliDrives(ctrlID string) map[string]*HBADevice {
 	for _, section := range driveSections[1:] { // Skip first empty section
 		dev := parseStorcliDriveSection(ctrlID, section)
 		if dev != nil && dev.Serial != "" {
-			devices[strings.ToUpper(dev.Serial)] = dev
+			addHBADevice(devices, dev)
 		}
 	}
 
@@ -694,18 +873,14 @@ func parseStorcliDriveSection(ctrlID, section string) *HBADevice {
 	}
 
 	// Parse device attributes
+	var rawSize string
 	patterns := map[string]func(string){
 		`SN = (\S+)`:                    func(v string) { dev.Serial = v },
-		`WWN = (\S+)`:                   func(v string) { dev.WWN = &v },
+		`WWN = (\S+)`:                   func(v string) { dev.WWN = normalizedWWN(&v) },
 		`Model Number = (.+)`:           func(v string) { v = strings.TrimSpace(v); dev.Model = &v },
 		`Manufacturer Id = (.+)`:        func(v string) { v = strings.TrimSpace(v); dev.Vendor = &v },
 		`Firmware Revision = (\S+)`:     func(v string) { dev.Firmware = &v },
-		`Raw size = ([0-9.]+) TB`:       func(v string) {
-			if f, err := strconv.ParseFloat(v, 64); err == nil {
-				size := int64(f * 1024 * 1024 * 1024 * 1024)
-				dev.SizeBytes = &size
-			}
-		},
+		`Raw size = (.+)`:               func(v string) { rawSize = v },
 		`Sector Size = (\d+)`:           func(v string) {
 			if i, err := strconv.Atoi(v); err == nil {
 				dev.SectorSize = &i
@@ -736,6 +911,13 @@ func parseStorcliDriveSection(ctrlID, section string) *HBADevice {
 		}
 	}
 
+	// Size depends on the sector size, so parse it once both are known
+	sectorSize := 0
+	if dev.SectorSize != nil {
+		sectorSize = *dev.SectorSize
+	}
+	dev.SizeBytes = parseStorcliSize(rawSize, sectorSize)
+
 	// Parse interface and media type from summary line
 	// Format: EID:Slt DID State DG Size Intf Med ...
 	summaryRe := regexp.MustCompile(`\d+:\d+\s+(\d+)\s+\S+\s+\S+\s+[\d.]+\s+\S+\s+(SAS|SATA)\s+(HDD|SSD)`)
@@ -756,7 +938,8 @@ func parseStorcliDriveSection(ctrlID, section string) *HBADevice {
 	return dev
 }
 
-// collectSas3ircu is fallback if storcli isn't available
+// collectSas3ircu is fallback if storcli isn't available (uses sas2ircu on
+// older HBAs)
 func collectSas3ircu(data *SystemData) {
 	c := cache.Global()
 	cacheKey := "system:sas3ircu"
@@ -768,7 +951,7 @@ func collectSas3ircu(data *SystemData) {
 		return
 	}
 
-	out, err := exec.Command("sudo", "sas3ircu", "0", "display").CombinedOutput()
+	out, err := hba.IrcuDisplay(0)
 	if err != nil {
 		return
 	}
@@ -794,8 +977,7 @@ func collectSas3ircu(data *SystemData) {
 
 		if strings.HasPrefix(line, "Device is a") {
 			if current != nil && current.Serial != "" {
-				devices[strings.ToUpper(current.Serial)] = current
-				data.HBADevices[strings.ToUpper(current.Serial)] = current
+				addHBADevice(devices, current)
 			}
 			current = &HBADevice{ControllerID: "c0"}
 			if strings.Contains(line, "Enclosure") {
@@ -852,8 +1034,10 @@ func collectSas3ircu(data *SystemData) {
 
 	// Don't forget last device
 	if current != nil && current.Serial != "" {
-		devices[strings.ToUpper(current.Serial)] = current
-		data.HBADevices[strings.ToUpper(current.Serial)] = current
+		addHBADevice(devices, current)
+	}
+	for k, v := range devices {
+		data.HBADevices[k] = v
 	}
 
 	c.SetSlow(cacheKey, devices)
@@ -1,13 +1,15 @@
 package collector
 
 import (
-	"os/exec"
+	"errors"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
 
 	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/privexec"
 )
 
 // GetDriveData collects comprehensive data for a single drive using layered approach
@@ -117,7 +119,7 @@ func mergeSysfsData(data *DriveData, sysfs *SysfsDevice) {
 		data.Serial = sysfs.Serial
 	}
 	if sysfs.WWN != nil && data.WWN == nil {
-		data.WWN = sysfs.WWN
+		data.WWN = normalizedWWN(sysfs.WWN)
 	}
 	if sysfs.SASAddress != nil && data.SASAddress == nil {
 		data.SASAddress = sysfs.SASAddress
@@ -142,6 +144,9 @@ func mergeSysfsData(data *DriveData, sysfs *SysfsDevice) {
 	if sysfs.EnclosureID != nil {
 		data.ControllerID = sysfs.EnclosureID
 	}
+	if sysfs.LinkRate != nil && data.LinkSpeed == nil {
+		data.LinkSpeed = normalizeLinkRate(sysfs.LinkRate)
+	}
 
 	// Map sysfs state to our state model
 	if sysfs.State != nil {
@@ -163,8 +168,7 @@ func mergeUdevData(data *DriveData, udev *UdevDevice) {
 		data.Serial = &udev.IDSCSISerial
 	}
 	if udev.IDWWN != "" && data.WWN == nil {
-		wwn := strings.TrimPrefix(udev.IDWWN, "0x")
-		data.WWN = &wwn
+		data.WWN = normalizedWWN(&udev.IDWWN)
 	}
 	if udev.IDModel != "" && data.Model == nil {
 		data.Model = &udev.IDModel
@@ -194,7 +198,7 @@ func mergeLsblkData(data *DriveData, lsblk *LsblkDevice) {
 		data.Serial = lsblk.Serial
 	}
 	if lsblk.WWN != nil && data.WWN == nil {
-		data.WWN = lsblk.WWN
+		data.WWN = normalizedWWN(lsblk.WWN)
 	}
 	if lsblk.Model != nil && data.Model == nil {
 		data.Model = lsblk.Model
@@ -251,27 +255,20 @@ func mergeLsscsiData(data *DriveData, lsscsi *LsscsiDevice) {
 // mergeZFSData merges ZFS pool membership from zpool status
 // Uses vdev GUID matching against imported pools only
 func mergeZFSData(data *DriveData, devName string, sysData *SystemData) {
-	// Try to find this device in zpool vdevs
-	// zpool status -gLP shows device paths, we can match
-	for _, vdev := range sysData.ZpoolVdevs {
-		if vdev.DevicePath != nil {
-			vdevDev := strings.TrimPrefix(*vdev.DevicePath, "/dev/")
-			// Handle partition suffix (e.g., sda1 -> sda)
-			vdevDev = strings.TrimRight(vdevDev, "0123456789")
-			if vdevDev == devName {
-				data.Zpool = &vdev.PoolName
-				if vdev.VdevType != "" {
-					data.Vdev = &vdev.VdevType
-				}
-				data.VdevGUID = &vdev.VdevGUID
-				data.ZfsErrors = &ZfsErrors{
-					Read:  vdev.ReadErrors,
-					Write: vdev.WriteErrors,
-					Cksum: vdev.CksumErrors,
-				}
-				break
-			}
-		}
+	// Vdev GUIDs are resolved to disks when zpool status is collected
+	vdev := sysData.ZpoolVdevForDevice(devName)
+	if vdev == nil {
+		return
+	}
+	data.Zpool = &vdev.PoolName
+	if vdev.VdevType != "" {
+		data.Vdev = &vdev.VdevType
+	}
+	data.VdevGUID = &vdev.VdevGUID
+	data.ZfsErrors = &ZfsErrors{
+		Read:  vdev.ReadErrors,
+		Write: vdev.WriteErrors,
+		Cksum: vdev.CksumErrors,
 	}
 }
 
@@ -292,11 +289,25 @@ func mergeSmartData(data *DriveData, device string) {
 	}
 
 	data.State = smartData.State
+	if smartData.State == "active" {
+		// Cached reads report when the data was actually fetched
+		if entry := cache.Global().GetEntry("smart:info:" + device); entry != nil {
+			fetched := entry.FetchedAt
+			data.LastProbed = &fetched
+		}
+	}
 	data.Temp = smartData.Temp
 	data.SmartHealth = smartData.SmartHealth
 	data.PowerOnHours = smartData.PowerOnHours
 	data.Reallocated = smartData.Reallocated
 	data.PendingSectors = smartData.PendingSectors
+	data.ReportedUncorrect = smartData.ReportedUncorrect
+	data.CommandTimeouts = smartData.CommandTimeouts
+	data.CRCErrors = smartData.CRCErrors
+	data.GrownDefects = smartData.GrownDefects
+	if rate := normalizeLinkRate(smartData.LinkSpeedMax); rate != nil {
+		data.LinkSpeedMax = rate
+	}
 
 	// Fill in any missing identity data
 	if smartData.Serial != nil && data.Serial == nil {
@@ -306,28 +317,41 @@ func mergeSmartData(data *DriveData, device string) {
 		data.LUID = smartData.LUID
 	}
 	if smartData.WWN != nil && data.WWN == nil {
-		data.WWN = smartData.WWN
+		data.WWN = normalizedWWN(smartData.WWN)
 	}
 	if smartData.FormFactor != nil {
 		data.FormFactor = smartData.FormFactor
 	}
+	if smartData.RotationRate != nil {
+		data.RotationRate = smartData.RotationRate
+	}
+	if smartData.Zoned != nil {
+		smr := isZonedSMR(*smartData.Zoned)
+		data.IsSMR = &smr
+	}
+}
+
+// isZonedSMR reports whether smartctl's zoned description is an SMR drive:
+// host-aware, host-managed or device-managed zones (not "Not zoned")
+func isZonedSMR(zoned string) bool {
+	zoned = strings.ToLower(zoned)
+	return strings.Contains(zoned, "aware") || strings.Contains(zoned, "managed")
 }
 
 // mergeHBAData merges HBA controller data (cached 24h)
 func mergeHBAData(data *DriveData, serial string, sysData *SystemData) {
-	serialUpper := strings.ToUpper(serial)
-	hba, ok := sysData.HBADevices[serialUpper]
-	if !ok {
+	hba := lookupHBADevice(sysData.HBADevices, serial, data.SASAddress)
+	if hba == nil {
 		return
 	}
 
 	if data.ControllerID == nil {
 		data.ControllerID = &hba.ControllerID
 	}
+	// Enclosure and slot are always taken as a pair: a sysfs slot number
+	// without its enclosure would be ambiguous across enclosures
 	if data.Enclosure == nil {
 		data.Enclosure = &hba.EnclosureID
-	}
-	if data.Slot == nil {
 		data.Slot = &hba.Slot
 	}
 	data.DeviceID = hba.DeviceID
@@ -340,7 +364,7 @@ func mergeHBAData(data *DriveData, serial string, sysData *SystemData) {
 		data.SerialVPD = hba.SerialVPD
 	}
 	if hba.WWN != nil && data.WWN == nil {
-		data.WWN = hba.WWN
+		data.WWN = normalizedWWN(hba.WWN)
 	}
 	if hba.SectorSize != nil {
 		data.SectorSize = hba.SectorSize
@@ -348,28 +372,55 @@ func mergeHBAData(data *DriveData, serial string, sysData *SystemData) {
 	if hba.MediaType != nil {
 		data.DriveType = hba.MediaType
 	}
-	if hba.LinkSpeed != nil {
-		data.LinkSpeed = hba.LinkSpeed
+	if rate := normalizeLinkRate(hba.LinkSpeed); rate != nil {
+		data.LinkSpeed = rate
 	}
 	if hba.MediaErrors != nil {
 		data.MediaErrors = hba.MediaErrors
 	}
 }
 
+// DefaultConcurrency returns the default number of drives probed at once
+func DefaultConcurrency() int {
+	return runtime.NumCPU() * 2
+}
+
 // GetAllDriveData collects data for all drives
 func GetAllDriveData(devices []string, forceRefresh bool) []*DriveData {
+	return GetAllDriveDataParallel(devices, forceRefresh, DefaultConcurrency())
+}
+
+// GetAllDriveDataParallel collects data for all drives using at most
+// maxConcurrency workers, so large enclosures don't launch one smartctl per
+// bay at once. Values below 1 use DefaultConcurrency.
+func GetAllDriveDataParallel(devices []string, forceRefresh bool, maxConcurrency int) []*DriveData {
 	sysData := CollectSystemData(forceRefresh)
 
+	if maxConcurrency < 1 {
+		maxConcurrency = DefaultConcurrency()
+	}
+	if maxConcurrency > len(devices) {
+		maxConcurrency = len(devices)
+	}
+
 	results := make([]*DriveData, len(devices))
+	jobs := make(chan int, maxConcurrency)
 	var wg sync.WaitGroup
 
-	for i, dev := range devices {
+	for w := 0; w < maxConcurrency; w++ {
 		wg.Add(1)
-		go func(idx int, device string) {
+		go func() {
 			defer wg.Done()
-			results[idx] = GetDriveData(device, sysData)
-		}(i, dev)
+			for idx := range jobs {
+				results[idx] = GetDriveData(devices[idx], sysData)
+			}
+		}()
+	}
+
+	for i := range devices {
+		jobs <- i
 	}
+	close(jobs)
 
 	wg.Wait()
 	return results
@@ -385,13 +436,22 @@ type smartInfo struct {
 	Firmware       *string
 	SizeBytes      *int64
 	FormFactor     *string
+	RotationRate   *int    // rpm, 0 for SSDs
+	Zoned          *string // zoned (SMR) capability as reported by smartctl
 	Protocol       *string
+	LinkSpeedMax   *string // fastest interface speed the drive supports (SATA)
 	State          string
 	Temp           *int
 	SmartHealth    *string
 	PowerOnHours   *int
 	Reallocated    *int
 	PendingSectors *int
+
+	// Failure predictors
+	ReportedUncorrect *int
+	CommandTimeouts   *int
+	CRCErrors         *int // interface CRC errors, usually a bad cable or connector
+	GrownDefects      *int // SCSI grown defect list length
 }
 
 // getSmartStateOnly does minimal smartctl probe to determine state without waking standby drives
@@ -404,7 +464,7 @@ func getSmartStateOnly(device string) *smartInfo {
 	}
 
 	// Use -n standby to check state without waking
-	out, err := exec.Command("smartctl", "-i", "-n", "standby", device).CombinedOutput()
+	out, err := privexec.Run("smartctl", "-i", "-n", "standby", device)
 	output := string(out)
 
 	info := &smartInfo{State: "unknown"}
@@ -415,8 +475,8 @@ func getSmartStateOnly(device string) *smartInfo {
 	} else if err != nil {
 		if strings.Contains(output, "No such device") || strings.Contains(output, "No such file") {
 			info.State = "missing"
-		} else if strings.Contains(output, "I/O error") {
-			info.State = "failed"
+		} else if IsUnresponsive(output, err) {
+			info.State = "unresponsive"
 		} else {
 			info.State = "failed"
 		}
@@ -428,6 +488,13 @@ func getSmartStateOnly(device string) *smartInfo {
 	return info
 }
 
+// IsUnresponsive reports whether a failed smartctl call means the drive is
+// present but not answering - an I/O error or smartctl hanging past the
+// command timeout - rather than gone or failed outright
+func IsUnresponsive(output string, err error) bool {
+	return errors.Is(err, privexec.ErrTimeout) || strings.Contains(output, "I/O error")
+}
+
 // getSmartInfo gets comprehensive info from smartctl (only call for active drives!)
 func getSmartInfo(device string) *smartInfo {
 	c := cache.Global()
@@ -437,13 +504,19 @@ func getSmartInfo(device string) *smartInfo {
 		return cached.(*smartInfo)
 	}
 
-	// Full smartctl call - only for active drives
-	out, err := exec.Command("smartctl", "-i", "-A", "-H", device).CombinedOutput()
+	// Full smartctl call - only for active drives. JSON output is stable
+	// across smartmontools versions and drive types; smartctl older than 7.0
+	// doesn't support -j, so fall back to parsing the text
+	out, err := privexec.Run("smartctl", "-i", "-A", "-H", "-j", device)
+	info, jsonErr := parseSmartJSON(out)
+	if jsonErr != nil && !errors.Is(err, privexec.ErrTimeout) {
+		out, err = privexec.Run("smartctl", "-i", "-A", "-H", device)
+		info = parseSmartText(string(out))
+	}
 	output := string(out)
 
-	info := &smartInfo{State: "active"}
-
 	if err != nil {
+		info = &smartInfo{}
 		// Device might have gone to standby between state check and this call
 		if strings.Contains(output, "STANDBY") || strings.Contains(output, "NOT READY") {
 			info.State = "standby"
@@ -451,14 +524,58 @@ func getSmartInfo(device string) *smartInfo {
 			return info
 		}
 		info.State = "failed"
+		if IsUnresponsive(output, err) {
+			info.State = "unresponsive"
+		}
 		c.SetFast(cacheKey, info)
 		return info
 	}
 
+	c.SetDynamic(cacheKey, info)
+	return info
+}
+
+// smartTempPatterns match a drive's current temperature in smartctl text
+// output, in order of preference. Each captures the value and its unit (the
+// SATA attribute is always Celsius). SAS drives also print a "Drive Trip
+// Temperature" and SATA drives may have an Airflow_Temperature_Cel
+// attribute next to Temperature_Celsius; the drive's own temperature wins.
+// Attributes are read from the raw value column, not the normalized one.
+var smartTempPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`(?m)^Current Drive Temperature:\s+(\d+)\s+([CF])\b`),
+	regexp.MustCompile(`Temperature_Celsius(?:[ \t]+\S+){7}[ \t]+(\d+)()`),
+	regexp.MustCompile(`(?m)^Temperature:\s+(\d+)\s+(Celsius|Fahrenheit)`),
+	regexp.MustCompile(`Airflow_Temperature_Cel(?:[ \t]+\S+){7}[ \t]+(\d+)()`),
+}
+
+// ParseSmartTemp returns the current drive temperature in °C from smartctl
+// text output (-A), or nil if none is reported
+func ParseSmartTemp(output string) *int {
+	for _, re := range smartTempPatterns {
+		matches := re.FindStringSubmatch(output)
+		if len(matches) < 3 {
+			continue
+		}
+		temp, err := strconv.Atoi(matches[1])
+		if err != nil {
+			continue
+		}
+		if strings.HasPrefix(matches[2], "F") {
+			temp = (temp - 32) * 5 / 9
+		}
+		return &temp
+	}
+	return nil
+}
+
+// parseSmartText parses 'smartctl -i -A -H' text output
+func parseSmartText(output string) *smartInfo {
+	info := &smartInfo{State: "active"}
+
 	// Parse info section
 	patterns := map[string]func(string){
 		`Serial [Nn]umber:\s+(\S+)`:        func(v string) { info.Serial = &v },
-		`LU WWN Device Id:\s+(\S.+)`:       func(v string) { v = strings.ReplaceAll(v, " ", ""); info.WWN = &v },
+		`LU WWN Device Id:\s+(\S.+)`:       func(v string) { info.WWN = normalizedWWN(&v) },
 		`Logical Unit id:\s+(\S+)`:         func(v string) { info.LUID = &v },
 		`(?:Product|Device Model):\s+(.+)`: func(v string) { v = strings.TrimSpace(v); info.Model = &v },
 		`Vendor:\s+(\S+)`:                  func(v string) { info.Vendor = &v },
@@ -470,7 +587,19 @@ func getSmartInfo(device string) *smartInfo {
 			}
 		},
 		`Form Factor:\s+(.+)`:         func(v string) { v = strings.TrimSpace(v); info.FormFactor = &v },
+		`Rotation Rate:\s+(.+)`: func(v string) {
+			// "7200 rpm" or "Solid State Device"
+			rate := 0
+			if fields := strings.Fields(v); len(fields) > 0 {
+				rate, _ = strconv.Atoi(fields[0])
+			}
+			info.RotationRate = &rate
+		},
 		`Transport protocol:\s+(\S+)`: func(v string) { info.Protocol = &v },
+		// "SATA Version is:  SATA 3.3, 6.0 Gb/s (current: 3.0 Gb/s)"
+		`SATA Version is:.*?,\s*([\d.]+ Gb/s)`: func(v string) { info.LinkSpeedMax = &v },
+		// ATA "Zoned Device:", SCSI "Zoned model:"
+		`Zoned (?:Device|model):\s+(.+)`: func(v string) { v = strings.TrimSpace(v); info.Zoned = &v },
 	}
 
 	for pattern, setter := range patterns {
@@ -490,25 +619,11 @@ func getSmartInfo(device string) *smartInfo {
 		info.SmartHealth = &health
 	}
 
-	// Temperature
-	tempPatterns := []string{
-		`Current Drive Temperature:\s+(\d+)`,
-		`Temperature_Celsius\s+\S+\s+(\d+)`,
-		`Temperature:\s+(\d+)\s+Celsius`,
-	}
-	for _, pattern := range tempPatterns {
-		re := regexp.MustCompile(pattern)
-		if matches := re.FindStringSubmatch(output); len(matches) > 1 {
-			if temp, err := strconv.Atoi(matches[1]); err == nil {
-				info.Temp = &temp
-				break
-			}
-		}
-	}
+	info.Temp = ParseSmartTemp(output)
 
 	// Power on hours
 	pohPatterns := []string{
-		`Power_On_Hours\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+(\d+)`,
+		`Power_On_Hours(?:[ \t]+\S+){7}[ \t]+(\d+)`,
 		`Accumulated power on time[^:]*:\s+(\d+)`,
 	}
 	for _, pattern := range pohPatterns {
@@ -522,7 +637,7 @@ func getSmartInfo(device string) *smartInfo {
 	}
 
 	// Reallocated sectors
-	re := regexp.MustCompile(`Reallocated_Sector_Ct\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+(\d+)`)
+	re := regexp.MustCompile(`Reallocated_Sector_Ct(?:[ \t]+\S+){7}[ \t]+(\d+)`)
 	if matches := re.FindStringSubmatch(output); len(matches) > 1 {
 		if count, err := strconv.Atoi(matches[1]); err == nil && count > 0 {
 			info.Reallocated = &count
@@ -530,13 +645,28 @@ func getSmartInfo(device string) *smartInfo {
 	}
 
 	// Pending sectors
-	re = regexp.MustCompile(`Current_Pending_Sector\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+\S+\s+(\d+)`)
+	re = regexp.MustCompile(`Current_Pending_Sector(?:[ \t]+\S+){7}[ \t]+(\d+)`)
 	if matches := re.FindStringSubmatch(output); len(matches) > 1 {
 		if count, err := strconv.Atoi(matches[1]); err == nil && count > 0 {
 			info.PendingSectors = &count
 		}
 	}
 
-	c.SetDynamic(cacheKey, info)
+	// Failure predictors are kept even when zero, so a change can be seen
+	counters := map[string]**int{
+		`Reported_Uncorrect(?:[ \t]+\S+){7}[ \t]+(\d+)`:   &info.ReportedUncorrect,
+		`Command_Timeout(?:[ \t]+\S+){7}[ \t]+(\d+)`:      &info.CommandTimeouts,
+		`UDMA_CRC_Error_Count(?:[ \t]+\S+){7}[ \t]+(\d+)`: &info.CRCErrors,
+		`Elements in grown defect list:\s+(\d+)`:          &info.GrownDefects,
+	}
+	for pattern, dst := range counters {
+		re := regexp.MustCompile(pattern)
+		if matches := re.FindStringSubmatch(output); len(matches) > 1 {
+			if count, err := strconv.Atoi(matches[1]); err == nil {
+				*dst = &count
+			}
+		}
+	}
+
 	return info
 }
@@ -0,0 +1,184 @@
+package collector
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/sigreer/jbodgod/internal/cache"
+)
+
+func TestParseSmartTemp(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   int // -1 for no temperature
+	}{
+		{
+			name: "SAS current and trip temperature",
+			output: `=== START OF READ SMART DATA SECTION ===
+SMART Health Status: OK
+
+Grown defects during certification <not available>
+Total blocks reassigned during format <not available>
+Total new blocks reassigned <not available>
+Power on minutes since format <not available>
+Current Drive Temperature:     38 C
+Drive Trip Temperature:        65 C
+
+Accumulated power on time, hours:minutes 31785:12
+`,
+			want: 38,
+		},
+		{
+			name: "SATA Temperature_Celsius and Airflow_Temperature_Cel",
+			output: `=== START OF READ SMART DATA SECTION ===
+SMART Attributes Data Structure revision number: 10
+Vendor Specific SMART Attributes with Thresholds:
+ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+  1 Raw_Read_Error_Rate     0x000f   083   064   044    Pre-fail  Always       -       191627528
+  9 Power_On_Hours          0x0032   069   069   000    Old_age   Always       -       27540
+190 Airflow_Temperature_Cel 0x0022   069   052   040    Old_age   Always       -       31 (Min/Max 27/41)
+194 Temperature_Celsius     0x0022   036   048   000    Old_age   Always       -       36 (0 17 0 0 0)
+197 Current_Pending_Sector  0x0012   100   100   000    Old_age   Always       -       0
+`,
+			want: 36,
+		},
+		{
+			name: "SATA Airflow_Temperature_Cel only",
+			output: `ID# ATTRIBUTE_NAME          FLAG     VALUE WORST THRESH TYPE      UPDATED  WHEN_FAILED RAW_VALUE
+190 Airflow_Temperature_Cel 0x0032   067   045   000    Old_age   Always       -       33
+`,
+			want: 33,
+		},
+		{
+			name: "NVMe",
+			output: `=== START OF SMART DATA SECTION ===
+SMART/Health Information (NVMe Log 0x02)
+Critical Warning:                   0x00
+Temperature:                        41 Celsius
+Available Spare:                    100%
+Available Spare Threshold:          10%
+Percentage Used:                    2%
+Warning  Comp. Temperature Time:    0
+Critical Comp. Temperature Time:    0
+Temperature Sensor 1:               41 Celsius
+Temperature Sensor 2:               47 Celsius
+`,
+			want: 41,
+		},
+		{
+			name:   "SAS in Fahrenheit",
+			output: "Current Drive Temperature:     104 F\nDrive Trip Temperature:        149 F\n",
+			want:   40,
+		},
+		{
+			name:   "no temperature",
+			output: "SMART Health Status: OK\n",
+			want:   -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseSmartTemp(tt.output)
+			switch {
+			case got == nil && tt.want != -1:
+				t.Errorf("got nil, want %d", tt.want)
+			case got != nil && *got != tt.want:
+				t.Errorf("got %d, want %d", *got, tt.want)
+			}
+		})
+	}
+}
+
+// fixtureSystemData caches a SystemData for n drives, with SMART state
+// cached so no smartctl runs: even drives are in standby, odd ones active
+func fixtureSystemData(n int) []string {
+	c := cache.Global()
+	sysData := &SystemData{
+		SysfsDevices:    make(map[string]*SysfsDevice),
+		SysfsEnclosures: make(map[string]*SysfsEnclosure),
+		UdevDevices:     make(map[string]*UdevDevice),
+		LsblkDevices:    make(map[string]*LsblkDevice),
+		LsscsiDevices:   make(map[string]*LsscsiDevice),
+		ByIDLinks:       make(map[string]string),
+		ZpoolVdevs:      make(map[string]*ZpoolVdev),
+		LvmPVs:          make(map[string]*LvmPV),
+		Controllers:     make(map[string]*ControllerData),
+		HBADevices:      make(map[string]*HBADevice),
+		BlkidDevices:    make(map[string]*BlkidDevice),
+	}
+
+	devices := make([]string, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("sd%c%c", 'a'+i/26, 'a'+i%26)
+		device := "/dev/" + name
+		devices[i] = device
+		serial := fmt.Sprintf("SER%04d", i)
+		state := "running"
+		disk := name
+
+		sysData.SysfsDevices[name] = &SysfsDevice{Name: name, Path: device, Serial: &serial, State: &state}
+		sysData.ByIDLinks[device] = "/dev/disk/by-id/scsi-" + serial
+		sysData.ZpoolVdevs[fmt.Sprintf("%d", 1000+i)] = &ZpoolVdev{PoolName: "tank", VdevGUID: fmt.Sprintf("%d", 1000+i), Disk: &disk}
+		sysData.HBADevices[serial] = &HBADevice{ControllerID: "c0", EnclosureID: 2, Slot: i, Serial: serial}
+
+		if i%2 == 0 {
+			c.SetDynamic("smart:state:"+device, &smartInfo{State: "standby"})
+			continue
+		}
+		temp := 30 + i%10
+		c.SetDynamic("smart:state:"+device, &smartInfo{State: "active"})
+		c.SetDynamic("smart:info:"+device, &smartInfo{State: "active", Serial: &serial, Temp: &temp})
+	}
+
+	c.SetDynamic("system:bulk", sysData)
+	return devices
+}
+
+// TestGetAllDriveDataParallelConcurrent collects the same drives from
+// several goroutines at once; run with -race to check SystemData and the
+// cache are only read concurrently
+func TestGetAllDriveDataParallelConcurrent(t *testing.T) {
+	devices := fixtureSystemData(60)
+	defer cache.Global().Delete("system:bulk")
+
+	var wg sync.WaitGroup
+	results := make([][]*DriveData, 8)
+	for g := range results {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			results[g] = GetAllDriveDataParallel(devices, false, 4)
+		}(g)
+	}
+	wg.Wait()
+
+	for g, drives := range results {
+		if len(drives) != len(devices) {
+			t.Fatalf("goroutine %d: got %d drives, want %d", g, len(drives), len(devices))
+		}
+		for i, d := range drives {
+			wantState := "standby"
+			if i%2 == 1 {
+				wantState = "active"
+			}
+			if d == nil || d.Device != devices[i] {
+				t.Fatalf("goroutine %d: drive %d is %v, want %s", g, i, d, devices[i])
+			}
+			if d.State != wantState {
+				t.Errorf("%s: state %q, want %q", d.Device, d.State, wantState)
+			}
+			if d.Zpool == nil || *d.Zpool != "tank" {
+				t.Errorf("%s: zpool not merged", d.Device)
+			}
+			if d.Slot == nil || *d.Slot != i || d.Enclosure == nil || *d.Enclosure != 2 {
+				t.Errorf("%s: enclosure/slot not merged", d.Device)
+			}
+			if wantState == "active" && (d.Temp == nil || *d.Temp != 30+i%10) {
+				t.Errorf("%s: temperature not merged", d.Device)
+			}
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/cache"
+)
+
+// MultipathDevice is a dm-multipath map and the SCSI path devices under it
+type MultipathDevice struct {
+	Name   string   // mpatha, or the WWID without user_friendly_names
+	Device string   // /dev/mapper/<name>
+	Paths  []string // /dev/sdX of each path
+}
+
+// CollectMultipathDevices maps SCSI path device names (sdc) to the
+// dm-multipath map they belong to, read from sysfs (no process spawning)
+func CollectMultipathDevices() map[string]*MultipathDevice {
+	c := cache.Global()
+	cacheKey := "sysfs:multipath"
+
+	if cached := c.Get(cacheKey); cached != nil {
+		return cached.(map[string]*MultipathDevice)
+	}
+
+	byPath := make(map[string]*MultipathDevice)
+	dirs, _ := filepath.Glob("/sys/block/dm-*")
+	for _, dir := range dirs {
+		uuid := readSysfsAttr(filepath.Join(dir, "dm", "uuid"))
+		if !strings.HasPrefix(uuid, "mpath-") {
+			continue
+		}
+		name := readSysfsAttr(filepath.Join(dir, "dm", "name"))
+		if name == "" {
+			continue
+		}
+
+		mp := &MultipathDevice{Name: name, Device: "/dev/mapper/" + name}
+		slaves, _ := os.ReadDir(filepath.Join(dir, "slaves"))
+		for _, s := range slaves {
+			mp.Paths = append(mp.Paths, "/dev/"+s.Name())
+			byPath[s.Name()] = mp
+		}
+		sort.Strings(mp.Paths)
+	}
+
+	c.SetSlow(cacheKey, byPath)
+	return byPath
+}
+
+// readSysfsAttr reads a sysfs attribute, returning "" if it can't be read
+func readSysfsAttr(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
@@ -0,0 +1,196 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// smartctlJSON is the subset of 'smartctl -i -A -H -j' output that is used.
+// ATA, SCSI and NVMe drives fill in different fields.
+type smartctlJSON struct {
+	Smartctl struct {
+		Version []int `json:"version"`
+	} `json:"smartctl"`
+	Device struct {
+		Protocol string `json:"protocol"` // "ATA", "SCSI", "NVMe"
+	} `json:"device"`
+
+	ModelName       string `json:"model_name"`
+	SerialNumber    string `json:"serial_number"`
+	FirmwareVersion string `json:"firmware_version"`
+	LogicalUnitID   string `json:"logical_unit_id"`
+
+	// SCSI inquiry data (smartctl 7.3+ prefixes these with scsi_)
+	Vendor       string `json:"vendor"`
+	Product      string `json:"product"`
+	Revision     string `json:"revision"`
+	SCSIVendor   string `json:"scsi_vendor"`
+	SCSIProduct  string `json:"scsi_product"`
+	SCSIRevision string `json:"scsi_revision"`
+
+	WWN *struct {
+		NAA uint64 `json:"naa"`
+		OUI uint64 `json:"oui"`
+		ID  uint64 `json:"id"`
+	} `json:"wwn"`
+	UserCapacity struct {
+		Bytes int64 `json:"bytes"`
+	} `json:"user_capacity"`
+	FormFactor struct {
+		Name string `json:"name"`
+	} `json:"form_factor"`
+	RotationRate *int `json:"rotation_rate"` // 0 for SSDs
+	ZonedDevice  *struct {
+		Capabilities string `json:"capabilities"` // e.g. "Device managed zones"
+	} `json:"zoned_device"`
+	InterfaceSpeed *struct {
+		Max struct {
+			String string `json:"string"` // e.g. "6.0 Gb/s"
+		} `json:"max"`
+	} `json:"interface_speed"` // SATA only
+	SCSITransportProtocol struct {
+		Name string `json:"name"` // e.g. "SAS (SPL-4)"
+	} `json:"scsi_transport_protocol"`
+
+	SmartStatus *struct {
+		Passed bool `json:"passed"`
+	} `json:"smart_status"`
+	Temperature *struct {
+		Current int `json:"current"`
+	} `json:"temperature"`
+	PowerOnTime *struct {
+		Hours int `json:"hours"`
+	} `json:"power_on_time"`
+
+	ATASmartAttributes struct {
+		Table []struct {
+			ID  int `json:"id"`
+			Raw struct {
+				Value int `json:"value"`
+			} `json:"raw"`
+		} `json:"table"`
+	} `json:"ata_smart_attributes"`
+
+	SCSIGrownDefectList *int `json:"scsi_grown_defect_list"`
+
+	NVMeHealth *struct {
+		Temperature  int `json:"temperature"`
+		PowerOnHours int `json:"power_on_hours"`
+	} `json:"nvme_smart_health_information_log"`
+}
+
+// ATA attribute ids read from the attribute table
+const (
+	ataAttrReallocated       = 5
+	ataAttrReportedUncorrect = 187
+	ataAttrCommandTimeout    = 188
+	ataAttrPending           = 197
+	ataAttrCRCErrors         = 199
+)
+
+// parseSmartJSON parses 'smartctl -i -A -H -j' output. An error means the
+// output isn't smartctl JSON (e.g. smartctl is too old for -j) and the text
+// output should be parsed instead.
+func parseSmartJSON(data []byte) (*smartInfo, error) {
+	var sj smartctlJSON
+	if err := json.Unmarshal(data, &sj); err != nil {
+		return nil, fmt.Errorf("failed to parse smartctl JSON: %w", err)
+	}
+	if len(sj.Smartctl.Version) == 0 {
+		return nil, errors.New("smartctl JSON has no version")
+	}
+
+	info := &smartInfo{State: "active"}
+	setString := func(dst **string, values ...string) {
+		for _, v := range values {
+			if v = strings.TrimSpace(v); v != "" {
+				*dst = &v
+				return
+			}
+		}
+	}
+
+	setString(&info.Serial, sj.SerialNumber)
+	setString(&info.LUID, sj.LogicalUnitID)
+	setString(&info.Model, sj.SCSIProduct, sj.Product, sj.ModelName)
+	setString(&info.Vendor, sj.SCSIVendor, sj.Vendor)
+	setString(&info.Firmware, sj.SCSIRevision, sj.Revision, sj.FirmwareVersion)
+	setString(&info.FormFactor, sj.FormFactor.Name)
+	switch {
+	case sj.RotationRate != nil:
+		info.RotationRate = sj.RotationRate
+	case sj.Device.Protocol == "NVMe":
+		ssd := 0
+		info.RotationRate = &ssd
+	}
+	if sj.ZonedDevice != nil {
+		setString(&info.Zoned, sj.ZonedDevice.Capabilities)
+	}
+	if sj.InterfaceSpeed != nil {
+		setString(&info.LinkSpeedMax, sj.InterfaceSpeed.Max.String)
+	}
+	if name := strings.Fields(sj.SCSITransportProtocol.Name); len(name) > 0 {
+		info.Protocol = &name[0]
+	}
+
+	// Same form as the text output's "LU WWN Device Id" with spaces removed
+	if sj.WWN != nil && sj.WWN.NAA != 0 {
+		wwn := fmt.Sprintf("%x%06x%09x", sj.WWN.NAA, sj.WWN.OUI, sj.WWN.ID)
+		info.WWN = &wwn
+	}
+	if sj.UserCapacity.Bytes > 0 {
+		size := sj.UserCapacity.Bytes
+		info.SizeBytes = &size
+	}
+
+	if sj.SmartStatus != nil {
+		health := "FAILED"
+		if sj.SmartStatus.Passed {
+			health = "PASSED"
+		}
+		info.SmartHealth = &health
+	}
+
+	switch {
+	case sj.Temperature != nil && sj.Temperature.Current > 0:
+		temp := sj.Temperature.Current
+		info.Temp = &temp
+	case sj.NVMeHealth != nil && sj.NVMeHealth.Temperature > 0:
+		temp := sj.NVMeHealth.Temperature
+		info.Temp = &temp
+	}
+
+	switch {
+	case sj.PowerOnTime != nil:
+		hours := sj.PowerOnTime.Hours
+		info.PowerOnHours = &hours
+	case sj.NVMeHealth != nil:
+		hours := sj.NVMeHealth.PowerOnHours
+		info.PowerOnHours = &hours
+	}
+
+	for _, attr := range sj.ATASmartAttributes.Table {
+		count := attr.Raw.Value
+		switch attr.ID {
+		case ataAttrReallocated:
+			if count > 0 {
+				info.Reallocated = &count
+			}
+		case ataAttrPending:
+			if count > 0 {
+				info.PendingSectors = &count
+			}
+		case ataAttrReportedUncorrect:
+			info.ReportedUncorrect = &count
+		case ataAttrCommandTimeout:
+			info.CommandTimeouts = &count
+		case ataAttrCRCErrors:
+			info.CRCErrors = &count
+		}
+	}
+	info.GrownDefects = sj.SCSIGrownDefectList
+
+	return info, nil
+}
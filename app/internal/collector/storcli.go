@@ -0,0 +1,258 @@
+package collector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// storcliJSON is the envelope of storcli's JSON output ("... show all J")
+type storcliJSON struct {
+	Controllers []struct {
+		CommandStatus struct {
+			Status      string `json:"Status"`
+			Description string `json:"Description"`
+		} `json:"Command Status"`
+		ResponseData map[string]json.RawMessage `json:"Response Data"`
+	} `json:"Controllers"`
+}
+
+var (
+	// "Drive /c0/e252/s5" and "Drive /c0/e252/s5 - Detailed Information"
+	storcliDriveKeyRe = regexp.MustCompile(`^Drive /c\d+/e(\d+)/s(\d+)( - Detailed Information)?$`)
+
+	// "3.638 TB [0x1d1c0beb0 Sectors]"
+	storcliSectorsRe = regexp.MustCompile(`\[0x([0-9a-fA-F]+) Sectors\]`)
+	storcliSizeRe    = regexp.MustCompile(`([0-9.]+)\s*(TB|GB|MB)`)
+)
+
+// collectStorcliDrivesJSON reads drives using storcli's JSON output, which
+// is stable across storcli versions unlike the text layout
+func collectStorcliDrivesJSON(ctrlID string) (map[string]*HBADevice, error) {
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", "/"+ctrlID+"/eall/sall", "show", "all", "J")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storcli failed: %w", err)
+	}
+	return parseStorcliDrivesJSON(ctrlID, out)
+}
+
+// parseStorcliDrivesJSON parses "storcli /cX/eall/sall show all J" output
+// into devices keyed by upper-case serial
+func parseStorcliDrivesJSON(ctrlID string, data []byte) (map[string]*HBADevice, error) {
+	var resp storcliJSON
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse storcli JSON: %w", err)
+	}
+	if len(resp.Controllers) == 0 {
+		return nil, errors.New("storcli JSON has no controllers")
+	}
+
+	bySlot := make(map[string]*HBADevice)
+	get := func(enc, slot string) *HBADevice {
+		key := enc + ":" + slot
+		if dev, ok := bySlot[key]; ok {
+			return dev
+		}
+		dev := &HBADevice{ControllerID: ctrlID}
+		dev.EnclosureID, _ = strconv.Atoi(enc)
+		dev.Slot, _ = strconv.Atoi(slot)
+		bySlot[key] = dev
+		return dev
+	}
+
+	for _, ctrl := range resp.Controllers {
+		if ctrl.CommandStatus.Status != "Success" && len(ctrl.ResponseData) == 0 {
+			return nil, fmt.Errorf("storcli: %s", ctrl.CommandStatus.Description)
+		}
+
+		for key, raw := range ctrl.ResponseData {
+			m := storcliDriveKeyRe.FindStringSubmatch(key)
+			if m == nil {
+				continue
+			}
+			dev := get(m[1], m[2])
+
+			if m[3] == "" {
+				// Summary row: EID:Slt DID State DG Size Intf Med ...
+				var rows []map[string]any
+				if err := json.Unmarshal(raw, &rows); err == nil && len(rows) > 0 {
+					applyStorcliSummary(dev, rows[0])
+				}
+				continue
+			}
+
+			var detail map[string]json.RawMessage
+			if err := json.Unmarshal(raw, &detail); err != nil {
+				continue
+			}
+			applyStorcliDetail(dev, detail)
+		}
+	}
+
+	devices := make(map[string]*HBADevice)
+	for _, dev := range bySlot {
+		if dev.Serial != "" {
+			addHBADevice(devices, dev)
+		}
+	}
+	if len(devices) == 0 {
+		return nil, errors.New("storcli JSON has no drives")
+	}
+	return devices, nil
+}
+
+func applyStorcliSummary(dev *HBADevice, row map[string]any) {
+	if did, ok := storcliInt(row["DID"]); ok {
+		dev.DeviceID = &did
+	}
+	if v := storcliString(row["State"]); v != "" {
+		dev.State = &v
+	}
+	if v := storcliString(row["Intf"]); v != "" {
+		dev.Protocol = &v
+	}
+	if v := storcliString(row["Med"]); v != "" {
+		dev.MediaType = &v
+	}
+	if dev.Model == nil {
+		if v := storcliString(row["Model"]); v != "" {
+			dev.Model = &v
+		}
+	}
+}
+
+func applyStorcliDetail(dev *HBADevice, detail map[string]json.RawMessage) {
+	for key, raw := range detail {
+		var section map[string]any
+		if err := json.Unmarshal(raw, &section); err != nil {
+			continue
+		}
+
+		switch {
+		case strings.HasSuffix(key, " State"):
+			setCount := func(field string, dst **int) {
+				if i, ok := storcliInt(section[field]); ok && i > 0 {
+					*dst = &i
+				}
+			}
+			setCount("Media Error Count", &dev.MediaErrors)
+			setCount("Other Error Count", &dev.OtherErrors)
+			setCount("Predictive Failure Count", &dev.PredFailure)
+			if v := storcliString(section["S.M.A.R.T alert flagged by drive"]); v != "" {
+				alert := strings.EqualFold(v, "Yes")
+				dev.SmartAlert = &alert
+			}
+
+		case strings.HasSuffix(key, " Device attributes"):
+			if v := storcliString(section["SN"]); v != "" {
+				dev.Serial = v
+			}
+			if v := storcliString(section["WWN"]); v != "" {
+				dev.WWN = normalizedWWN(&v)
+			}
+			if v := storcliString(section["Model Number"]); v != "" {
+				dev.Model = &v
+			}
+			if v := storcliString(section["Manufacturer Id"]); v != "" {
+				dev.Vendor = &v
+			}
+			if v := storcliString(section["Firmware Revision"]); v != "" {
+				dev.Firmware = &v
+			}
+			if v := storcliString(section["Link Speed"]); v != "" {
+				dev.LinkSpeed = &v
+			}
+
+			sectorSize := 0
+			for _, field := range []string{"Logical Sector Size", "Sector Size"} {
+				if v := storcliString(section[field]); v != "" {
+					if i, err := strconv.Atoi(strings.TrimSuffix(v, "B")); err == nil {
+						sectorSize = i
+						break
+					}
+				}
+			}
+			if sectorSize > 0 {
+				dev.SectorSize = &sectorSize
+			}
+			dev.SizeBytes = parseStorcliSize(storcliString(section["Raw size"]), sectorSize)
+
+		case strings.HasSuffix(key, " Policies/Settings"):
+			ports, _ := section["Port Information"].([]any)
+			for _, p := range ports {
+				port, _ := p.(map[string]any)
+				if storcliString(port["Status"]) != "Active" {
+					continue
+				}
+				if v := storcliString(port["SAS address"]); v != "" {
+					dev.SASAddress = &v
+					break
+				}
+			}
+		}
+	}
+}
+
+// parseStorcliSize converts a storcli size such as "3.638 TB [0x1d1c0beb0
+// Sectors]" to bytes, preferring the exact sector count over the rounded
+// figure. Returns nil if the size can't be parsed
+func parseStorcliSize(s string, sectorSize int) *int64 {
+	if m := storcliSectorsRe.FindStringSubmatch(s); m != nil {
+		if sectors, err := strconv.ParseInt(m[1], 16, 64); err == nil && sectors > 0 {
+			if sectorSize <= 0 {
+				sectorSize = 512
+			}
+			size := sectors * int64(sectorSize)
+			return &size
+		}
+	}
+
+	m := storcliSizeRe.FindStringSubmatch(s)
+	if m == nil {
+		return nil
+	}
+	f, err := strconv.ParseFloat(m[1], 64)
+	if err != nil || f <= 0 {
+		return nil
+	}
+	mult := float64(1024 * 1024)
+	switch m[2] {
+	case "TB":
+		mult *= 1024 * 1024
+	case "GB":
+		mult *= 1024
+	}
+	size := int64(f * mult)
+	return &size
+}
+
+// storcliString returns a JSON value as trimmed text (storcli pads many
+// strings and mixes numbers and strings for the same field across versions)
+func storcliString(v any) string {
+	switch val := v.(type) {
+	case string:
+		return strings.TrimSpace(val)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	}
+	return ""
+}
+
+// storcliInt returns a JSON value as an int, accepting numeric strings
+func storcliInt(v any) (int, bool) {
+	switch val := v.(type) {
+	case float64:
+		return int(val), true
+	case string:
+		i, err := strconv.Atoi(strings.TrimSpace(val))
+		return i, err == nil
+	}
+	return 0, false
+}
@@ -1,12 +1,16 @@
 package collector
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/wwn"
 )
 
 // SysfsDevice represents device data collected from sysfs (no process spawning, no drive wake)
@@ -31,7 +35,8 @@ type SysfsDevice struct {
 	EnclosurePath *string // sysfs path to enclosure
 
 	// State
-	State *string // from state (running, offline, etc.)
+	State    *string // from state (running, offline, etc.)
+	LinkRate *string // negotiated_linkrate of the SAS phy the drive is attached to
 }
 
 // SysfsEnclosure represents enclosure data from sysfs
@@ -46,6 +51,7 @@ type SysfsEnclosure struct {
 
 // SysfsSlot represents a slot in an enclosure
 type SysfsSlot struct {
+	Name        string // component directory, e.g. Slot01
 	Number      int
 	Status      string // OK, not installed, etc.
 	Locate      bool   // LED state
@@ -55,6 +61,24 @@ type SysfsSlot struct {
 	PowerStatus *string
 }
 
+// ElementSlots returns the enclosure's slots in SES element order. The
+// kernel creates the components in element order, so the n-th slot is
+// element n whatever number (0- or 1-based, zero-padded) its name carries.
+func (e *SysfsEnclosure) ElementSlots() []SysfsSlot {
+	slots := append([]SysfsSlot(nil), e.Slots...)
+	sort.Slice(slots, func(i, j int) bool { return slots[i].Number < slots[j].Number })
+	return slots
+}
+
+// Occupied returns true if SES reports a drive physically present in the slot
+func (s SysfsSlot) Occupied() bool {
+	if s.DeviceHCTL != nil {
+		return true
+	}
+	status := strings.ToLower(s.Status)
+	return status != "" && status != "not installed"
+}
+
 // CollectSysfsDevices gathers device info purely from sysfs (no process spawning)
 // This does NOT wake sleeping drives
 func CollectSysfsDevices() map[string]*SysfsDevice {
@@ -132,11 +156,8 @@ func collectSysfsDevice(name string) *SysfsDevice {
 
 	// WWN/WWID
 	if data, err := os.ReadFile(filepath.Join(devicePath, "wwid")); err == nil {
-		wwid := strings.TrimSpace(string(data))
 		// Format: naa.XXXXXXXX or t10.XXXXX etc
-		wwid = strings.TrimPrefix(wwid, "naa.")
-		wwid = strings.TrimPrefix(wwid, "t10.")
-		if wwid != "" {
+		if wwid := wwn.Normalize(string(data)); wwid != "" {
 			dev.WWN = &wwid
 		}
 	}
@@ -217,13 +238,84 @@ func collectSysfsDevice(name string) *SysfsDevice {
 		}
 	}
 
+	if rate := sasLinkRate(devicePath); rate != "" {
+		dev.LinkRate = &rate
+	}
+
 	return dev
 }
 
+// sasLinkRate returns the negotiated link rate (e.g. "12.0 Gbit") of the SAS
+// phy a drive is attached to, or "" if it isn't behind a SAS HBA. The device
+// path resolves to .../port-H:E:N/end_device-H:E:N/targetH:C:T/H:C:T:L and
+// the port holds the phy (an expander phy, or an HBA phy for direct attach).
+// SATA drives behind SAS are covered too, as they hang off the same phys.
+func sasLinkRate(devicePath string) string {
+	resolved, err := filepath.EvalSymlinks(devicePath)
+	if err != nil {
+		return ""
+	}
+	for dir := resolved; dir != "/" && dir != "."; dir = filepath.Dir(dir) {
+		if !strings.HasPrefix(filepath.Base(dir), "end_device-") {
+			continue
+		}
+		phys, _ := filepath.Glob(filepath.Join(filepath.Dir(dir), "phy-*"))
+		for _, phy := range phys {
+			data, err := os.ReadFile(filepath.Join("/sys/class/sas_phy", filepath.Base(phy), "negotiated_linkrate"))
+			if err != nil {
+				continue
+			}
+			// Unattached phys of a wide port read "Unknown" or "Phy disabled"
+			if rate := strings.TrimSpace(string(data)); ParseLinkRate(rate) > 0 {
+				return rate
+			}
+		}
+		break
+	}
+	return ""
+}
+
+// ParseLinkRate returns a link rate in Gbit/s from any of the forms the
+// tools report ("12.0 Gbit", "12.0Gb/s", "6.0 Gb/s", "12G"), or 0 if the
+// string has no rate
+func ParseLinkRate(s string) float64 {
+	m := linkRatePattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0
+	}
+	rate, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0
+	}
+	return rate
+}
+
+// normalizeLinkRate rewrites a link rate in the form used for display,
+// e.g. "12.0 Gb/s"; strings without a rate are returned as nil
+func normalizeLinkRate(s *string) *string {
+	if s == nil {
+		return nil
+	}
+	rate := ParseLinkRate(*s)
+	if rate <= 0 {
+		return nil
+	}
+	v := fmt.Sprintf("%.1f Gb/s", rate)
+	return &v
+}
+
+// linkRatePattern matches the number of a link rate in Gbit/s
+var linkRatePattern = regexp.MustCompile(`(?i)(\d+(?:\.\d+)?)\s*G`)
+
+// SysfsEnclosuresCacheKey is the cache entry holding the enclosure slots
+// (and their LED state) read by CollectSysfsEnclosures. Anything that sets a
+// slot LED deletes it so the next read shows the new state.
+const SysfsEnclosuresCacheKey = "sysfs:enclosures"
+
 // CollectSysfsEnclosures gathers enclosure info from sysfs
 func CollectSysfsEnclosures() map[string]*SysfsEnclosure {
 	c := cache.Global()
-	cacheKey := "sysfs:enclosures"
+	cacheKey := SysfsEnclosuresCacheKey
 
 	if cached := c.Get(cacheKey); cached != nil {
 		return cached.(map[string]*SysfsEnclosure)
@@ -269,7 +361,7 @@ func CollectSysfsEnclosures() map[string]*SysfsEnclosure {
 			slotNumStr := strings.TrimPrefix(slotEntry.Name(), "Slot")
 			slotNum, _ := strconv.Atoi(slotNumStr)
 
-			slot := SysfsSlot{Number: slotNum}
+			slot := SysfsSlot{Name: slotEntry.Name(), Number: slotNum}
 
 			// Status
 			if data, err := os.ReadFile(filepath.Join(slotPath, "status")); err == nil {
@@ -323,26 +415,61 @@ func CollectSysfsEnclosures() map[string]*SysfsEnclosure {
 // SetSlotLocateLED sets the locate LED for a slot via sysfs (no sg_ses needed)
 // Returns nil on success, error otherwise
 func SetSlotLocateLED(enclosureHCTL string, slotNum int, on bool) error {
-	slotPath := filepath.Join("/sys/class/enclosure", enclosureHCTL,
-		"Slot"+strconv.Itoa(slotNum), "locate")
+	slotPath := SlotLocatePath(enclosureHCTL, slotNum)
 
 	value := "0"
 	if on {
 		value = "1"
 	}
 
-	return os.WriteFile(slotPath, []byte(value), 0644)
+	if err := os.WriteFile(slotPath, []byte(value), 0644); err != nil {
+		return err
+	}
+	cache.Global().Delete(SysfsEnclosuresCacheKey)
+	return nil
 }
 
 // SetSlotFaultLED sets the fault LED for a slot via sysfs
 func SetSlotFaultLED(enclosureHCTL string, slotNum int, on bool) error {
-	slotPath := filepath.Join("/sys/class/enclosure", enclosureHCTL,
-		"Slot"+strconv.Itoa(slotNum), "fault")
+	slotPath := filepath.Join(slotDir(enclosureHCTL, slotNum), "fault")
 
 	value := "0"
 	if on {
 		value = "1"
 	}
 
-	return os.WriteFile(slotPath, []byte(value), 0644)
+	if err := os.WriteFile(slotPath, []byte(value), 0644); err != nil {
+		return err
+	}
+	cache.Global().Delete(SysfsEnclosuresCacheKey)
+	return nil
+}
+
+// SlotLocatePath returns the sysfs file that controls a slot's locate LED
+func SlotLocatePath(enclosureHCTL string, slotNum int) string {
+	return filepath.Join(slotDir(enclosureHCTL, slotNum), "locate")
+}
+
+// SlotLocateWritable reports whether a slot's locate LED can be set via sysfs
+// by the current user
+func SlotLocateWritable(enclosureHCTL string, slotNum int) bool {
+	f, err := os.OpenFile(SlotLocatePath(enclosureHCTL, slotNum), os.O_WRONLY, 0)
+	if err != nil {
+		return false
+	}
+	f.Close()
+	return true
+}
+
+// slotDir returns the sysfs directory of the slot with SES element index
+// slotNum. Slot names may be 1-based (Slot01 is element 0), so the slot is
+// picked by element order rather than by the number in its name.
+func slotDir(enclosureHCTL string, slotNum int) string {
+	encPath := filepath.Join("/sys/class/enclosure", enclosureHCTL)
+	if enc := CollectSysfsEnclosures()[enclosureHCTL]; enc != nil {
+		if slots := enc.ElementSlots(); slotNum >= 0 && slotNum < len(slots) {
+			return filepath.Join(encPath, slots[slotNum].Name)
+		}
+	}
+	return filepath.Join(encPath, "Slot"+strconv.Itoa(slotNum))
 }
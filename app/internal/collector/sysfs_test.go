@@ -0,0 +1,26 @@
+package collector
+
+import "testing"
+
+func TestElementSlots(t *testing.T) {
+	// A 1-based enclosure, listed in directory (not element) order
+	enc := &SysfsEnclosure{Slots: []SysfsSlot{
+		{Name: "Slot10", Number: 10},
+		{Name: "Slot02", Number: 2},
+		{Name: "Slot01", Number: 1},
+	}}
+
+	want := []string{"Slot01", "Slot02", "Slot10"}
+	got := enc.ElementSlots()
+	if len(got) != len(want) {
+		t.Fatalf("got %d slots, want %d", len(got), len(want))
+	}
+	for i, name := range want {
+		if got[i].Name != name {
+			t.Errorf("element %d = %s, want %s", i, got[i].Name, name)
+		}
+	}
+	if enc.Slots[0].Name != "Slot10" {
+		t.Error("ElementSlots reordered the enclosure's slots")
+	}
+}
@@ -1,5 +1,7 @@
 package collector
 
+import "time"
+
 // DriveData represents comprehensive drive information from all sources
 type DriveData struct {
 	// === Identifiers ===
@@ -14,15 +16,18 @@ type DriveData struct {
 	ByIDPath  *string `json:"by_id_path,omitempty"`
 
 	// === Hardware ===
-	Model      *string `json:"model,omitempty"`
-	Vendor     *string `json:"vendor,omitempty"`
-	Firmware   *string `json:"firmware,omitempty"`
-	SizeBytes  *int64  `json:"size_bytes,omitempty"`
-	Protocol   *string `json:"protocol,omitempty"`   // SAS, SATA, NVMe
-	DriveType  *string `json:"drive_type,omitempty"` // HDD, SSD
-	FormFactor *string `json:"form_factor,omitempty"`
-	SectorSize *int    `json:"sector_size,omitempty"`
-	LinkSpeed  *string `json:"link_speed,omitempty"`
+	Model        *string `json:"model,omitempty"`
+	Vendor       *string `json:"vendor,omitempty"`
+	Firmware     *string `json:"firmware,omitempty"`
+	SizeBytes    *int64  `json:"size_bytes,omitempty"`
+	Protocol     *string `json:"protocol,omitempty"`      // SAS, SATA, NVMe
+	DriveType    *string `json:"drive_type,omitempty"`    // HDD, SSD
+	FormFactor   *string `json:"form_factor,omitempty"`
+	RotationRate *int    `json:"rotation_rate,omitempty"` // rpm, 0 for SSDs
+	IsSMR        *bool   `json:"is_smr,omitempty"`        // shingled recording
+	SectorSize   *int    `json:"sector_size,omitempty"`
+	LinkSpeed    *string `json:"link_speed,omitempty"`     // negotiated, e.g. "12.0 Gb/s"
+	LinkSpeedMax *string `json:"link_speed_max,omitempty"` // drive's maximum, when it reports one
 
 	// === Physical Location ===
 	ControllerID *string `json:"controller_id,omitempty"`
@@ -56,10 +61,18 @@ type DriveData struct {
 	PartLabel *string `json:"part_label,omitempty"`
 
 	// === SMART Metrics ===
+	// When SMART data was last read successfully (cache entry FetchedAt)
+	LastProbed   *time.Time `json:"last_probed,omitempty"`
 	PowerOnHours *int `json:"power_on_hours,omitempty"`
 	Reallocated  *int `json:"reallocated_sectors,omitempty"`
 	PendingSectors *int `json:"pending_sectors,omitempty"`
 	MediaErrors  *int `json:"media_errors,omitempty"`
+
+	// Failure predictors
+	ReportedUncorrect *int `json:"reported_uncorrect,omitempty"`
+	CommandTimeouts   *int `json:"command_timeouts,omitempty"`
+	CRCErrors         *int `json:"crc_errors,omitempty"`    // interface CRC errors (cabling)
+	GrownDefects      *int `json:"grown_defects,omitempty"` // SCSI grown defect list
 }
 
 // ZfsErrors holds ZFS vdev error counts
@@ -93,6 +106,10 @@ type EnclosureData struct {
 }
 
 // SystemData holds bulk-collected system information
+//
+// A SystemData is read-only once CollectSystemData returns it: the same
+// instance is cached and shared by every goroutine collecting drive data,
+// so callers must not modify its maps or the values they point to.
 type SystemData struct {
 	// Layer 1: Safe sources (no drive wake, fast)
 	SysfsDevices    map[string]*SysfsDevice    // keyed by device name (sda, sdb)
@@ -108,7 +125,7 @@ type SystemData struct {
 
 	// Layer 3: HBA data (cached 24h, may wake on first call)
 	Controllers map[string]*ControllerData
-	HBADevices  map[string]*HBADevice // keyed by serial
+	HBADevices  map[string]*HBADevice // keyed by serial, or "SERIAL@enc:slot" for duplicate serials
 
 	// DEPRECATED: BlkidDevices removed - wakes sleeping drives
 	BlkidDevices map[string]*BlkidDevice // kept for compatibility, not populated
@@ -168,6 +185,7 @@ type ZpoolVdev struct {
 	WriteErrors int   `json:"write_errors"`
 	CksumErrors int   `json:"cksum_errors"`
 	DevicePath *string `json:"device_path,omitempty"` // for leaf vdevs
+	Disk       *string `json:"disk,omitempty"`        // disk the vdev GUID maps to (sda), nil if none
 }
 
 // LvmPV represents an LVM physical volume
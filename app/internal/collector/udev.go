@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/wwn"
 )
 
 // UdevDevice represents device data from udev database (no process spawning needed)
@@ -125,9 +126,9 @@ func collectUdevDevice(name string) *UdevDevice {
 		case "ID_SERIAL_SHORT":
 			dev.IDSerialShort = value
 		case "ID_WWN":
-			dev.IDWWN = strings.TrimPrefix(value, "0x")
+			dev.IDWWN = wwn.Normalize(value)
 		case "ID_WWN_WITH_EXTENSION":
-			dev.IDWWNExt = strings.TrimPrefix(value, "0x")
+			dev.IDWWNExt = wwn.Normalize(value)
 		case "ID_SCSI_SERIAL":
 			dev.IDSCSISerial = value
 		case "ID_BUS":
@@ -176,9 +177,7 @@ func collectFromSymlinks(name string) *UdevDevice {
 
 		// WWN link: wwn-0x5000c500a6e7b82b
 		if strings.HasPrefix(linkName, "wwn-") {
-			wwn := strings.TrimPrefix(linkName, "wwn-")
-			wwn = strings.TrimPrefix(wwn, "0x")
-			dev.IDWWN = wwn
+			dev.IDWWN = wwn.Normalize(strings.TrimPrefix(linkName, "wwn-"))
 		}
 
 		// SCSI link: scsi-35000c500a6e7b82b (3 = NAA designator)
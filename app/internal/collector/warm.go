@@ -0,0 +1,40 @@
+package collector
+
+import (
+	"github.com/sigreer/jbodgod/internal/cache"
+)
+
+// RegisterWarmers registers the bulk and per-device cache entries used by
+// GetAllDriveData so they can be refreshed ahead of expiry
+func RegisterWarmers(w *cache.Warmer, devices []string) {
+	c := cache.Global()
+
+	// Only the bulk entry is rebuilt: a forced refresh would also purge the
+	// HBA and SMART layers, re-running storcli/sas3ircu (and racing the
+	// per-device tasks below) on every cycle
+	w.Register("system:bulk", cache.TTLMedium, func() {
+		c.Delete("system:bulk")
+		CollectSystemData(false)
+	})
+
+	for _, dev := range devices {
+		device := dev
+		stateKey := "smart:state:" + device
+		infoKey := "smart:info:" + device
+
+		w.Register(stateKey, cache.TTLFast, func() {
+			c.Delete(stateKey)
+			getSmartStateOnly(device)
+		})
+
+		// Full SMART info is only refreshed for active drives so the
+		// warmer never wakes a drive in standby
+		w.Register(infoKey, cache.TTLDynamic, func() {
+			if getSmartStateOnly(device).State != "active" {
+				return
+			}
+			c.Delete(infoKey)
+			getSmartInfo(device)
+		})
+	}
+}
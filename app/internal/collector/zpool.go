@@ -0,0 +1,208 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// zpoolConfigRow is a vdev row from the config section of zpool status
+type zpoolConfigRow struct {
+	Pool      string
+	PoolState string
+	Indent    int
+	Fields    []string
+}
+
+// parseZpoolConfig extracts the vdev rows of every pool in zpool status
+// output. Rows without error counters (section headers like "logs", spares)
+// are skipped, so the output of the same status with different name flags
+// lines up row for row.
+func parseZpoolConfig(out string) []zpoolConfigRow {
+	var rows []zpoolConfigRow
+	var currentPool, poolState string
+	inConfig := false
+
+	for _, line := range strings.Split(out, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case strings.HasPrefix(trimmed, "pool:"):
+			currentPool = strings.TrimSpace(strings.TrimPrefix(trimmed, "pool:"))
+			continue
+		case strings.HasPrefix(trimmed, "state:"):
+			poolState = strings.TrimSpace(strings.TrimPrefix(trimmed, "state:"))
+			continue
+		case strings.HasPrefix(trimmed, "config:"):
+			inConfig = true
+			continue
+		case strings.HasPrefix(trimmed, "errors:"):
+			inConfig = false
+			continue
+		}
+
+		if !inConfig || currentPool == "" || strings.HasPrefix(trimmed, "NAME") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 5 {
+			continue
+		}
+
+		rows = append(rows, zpoolConfigRow{
+			Pool:      currentPool,
+			PoolState: poolState,
+			Indent:    len(line) - len(strings.TrimLeft(line, " \t")),
+			Fields:    fields,
+		})
+	}
+	return rows
+}
+
+// isVdevGroup reports whether a vdev name is a grouping vdev (mirror, raidz,
+// etc.) rather than a leaf device
+func isVdevGroup(name string) bool {
+	for _, prefix := range []string{"mirror", "raidz", "draid", "spare", "replacing", "cache", "log"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// zfsMemberDisks maps ZFS vdev GUIDs to the disk that carries their label,
+// using the zfs_member signature udev records for each disk and partition
+// (ID_FS_UUID_SUB is the vdev GUID). The disk is returned by kernel name
+// (sda) even when the label lives on a partition.
+func zfsMemberDisks() map[string]string {
+	disks := make(map[string]string)
+
+	blockDevs, err := os.ReadDir("/sys/block")
+	if err != nil {
+		return disks
+	}
+
+	for _, entry := range blockDevs {
+		disk := entry.Name()
+		if !strings.HasPrefix(disk, "sd") {
+			continue
+		}
+
+		// Whole-disk vdevs created by hand carry the label on the disk
+		// itself, zpool-created ones on the first partition
+		sysDirs := []string{filepath.Join("/sys/block", disk)}
+		parts, _ := filepath.Glob(filepath.Join("/sys/block", disk, disk+"*"))
+		sysDirs = append(sysDirs, parts...)
+
+		for _, dir := range sysDirs {
+			if guid := udevZFSVdevGUID(dir); guid != "" {
+				disks[guid] = disk
+			}
+		}
+	}
+	return disks
+}
+
+// udevZFSVdevGUID returns the ZFS vdev GUID udev recorded for a block device
+// sysfs directory, or "" if it doesn't carry a zfs_member label
+func udevZFSVdevGUID(sysDir string) string {
+	data, err := os.ReadFile(filepath.Join(sysDir, "dev"))
+	if err != nil {
+		return ""
+	}
+
+	file, err := os.Open(filepath.Join("/run/udev/data", "b"+strings.TrimSpace(string(data))))
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+
+	var fsType, uuidSub string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "E:ID_FS_TYPE="):
+			fsType = strings.TrimPrefix(line, "E:ID_FS_TYPE=")
+		case strings.HasPrefix(line, "E:ID_FS_UUID_SUB="):
+			uuidSub = strings.TrimPrefix(line, "E:ID_FS_UUID_SUB=")
+		}
+	}
+
+	if fsType != "zfs_member" {
+		return ""
+	}
+	return uuidSub
+}
+
+// vdevAliasDirs are where zpool looks up leaf names given without a path
+var vdevAliasDirs = []string{"/dev/disk/by-vdev", "/dev/disk/by-id", "/dev/disk/by-path", "/dev"}
+
+// resolveVdevPath turns a leaf vdev name as zpool prints it (/dev/sdb1,
+// /dev/disk/by-id/..., a by-vdev alias like A0) into the kernel device path
+// behind it. Paths that no longer resolve are returned as printed; bare
+// names that match nothing (such as the GUID of a missing vdev) give "".
+func resolveVdevPath(name string) string {
+	if strings.HasPrefix(name, "/") {
+		if resolved, err := filepath.EvalSymlinks(name); err == nil {
+			return resolved
+		}
+		return name
+	}
+
+	for _, dir := range vdevAliasDirs {
+		if resolved, err := filepath.EvalSymlinks(filepath.Join(dir, name)); err == nil {
+			return resolved
+		}
+	}
+	return ""
+}
+
+// diskForPath resolves a device path (by-id link, partition) to the kernel
+// name of the disk behind it, or "" if the path no longer resolves
+func diskForPath(path string) string {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return ""
+	}
+
+	sysPath, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", filepath.Base(resolved)))
+	if err != nil {
+		return ""
+	}
+
+	// Partitions live under their parent disk in sysfs
+	if _, err := os.Stat(filepath.Join(sysPath, "partition")); err == nil {
+		return filepath.Base(filepath.Dir(sysPath))
+	}
+	return filepath.Base(sysPath)
+}
+
+// CollectZpoolVdevs returns the leaf vdevs of all imported pools keyed by
+// vdev GUID, without collecting the rest of the system data
+func CollectZpoolVdevs() map[string]*ZpoolVdev {
+	data := &SystemData{ZpoolVdevs: make(map[string]*ZpoolVdev)}
+	collectZpool(data)
+	return data.ZpoolVdevs
+}
+
+// UnmappedZpoolVdevs returns leaf vdevs whose GUID no longer maps to any
+// device on the system, sorted by pool and GUID
+func (d *SystemData) UnmappedZpoolVdevs() []*ZpoolVdev {
+	var vdevs []*ZpoolVdev
+	for _, vdev := range d.ZpoolVdevs {
+		if vdev.Disk == nil {
+			vdevs = append(vdevs, vdev)
+		}
+	}
+	sort.Slice(vdevs, func(i, j int) bool {
+		if vdevs[i].PoolName != vdevs[j].PoolName {
+			return vdevs[i].PoolName < vdevs[j].PoolName
+		}
+		return vdevs[i].VdevGUID < vdevs[j].VdevGUID
+	})
+	return vdevs
+}
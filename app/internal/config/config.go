@@ -3,8 +3,10 @@ package config
 import (
 	"fmt"
 	"os"
-	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/sigreer/jbodgod/internal/privexec"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,6 +16,40 @@ type Config struct {
 	Enclosures []Enclosure `yaml:"enclosures"`
 	Thresholds Thresholds  `yaml:"thresholds"`
 	Alerts     Alerts      `yaml:"alerts"`
+	// Expected slot occupancy per enclosure, checked by healthcheck
+	Expected []ExpectedEnclosure `yaml:"expected,omitempty"`
+	// Physical bay layouts per enclosure model, used by the heatmap
+	Layouts []BayLayout `yaml:"layouts,omitempty"`
+	// Inventory database location (overridden by --db; falls back to the
+	// JBODGOD_DB environment variable, then /var/lib/jbodgod/inventory.db)
+	DBPath string `yaml:"db_path,omitempty"`
+	// Maximum privileged commands (smartctl, storcli, ...) per second across
+	// all goroutines; 0 disables the limit
+	RateLimit float64 `yaml:"rate_limit,omitempty"`
+	// How long smartctl, sg_ses, zpool, ... may run before being killed, so
+	// a hung drive can't block collection (default 15s)
+	CommandTimeout time.Duration `yaml:"command_timeout,omitempty"`
+	// Rotating SMART long self-test schedule (jbodgod selftest run)
+	SelfTest SelfTestSchedule `yaml:"selftest,omitempty"`
+	// Pools the system can't run without (root, VM storage). healthcheck
+	// warns when one has failmode=wait, which hangs all I/O to it on failure
+	CriticalPools []string `yaml:"critical_pools,omitempty"`
+	// Model substrings of SMR drives that don't report themselves as zoned
+	// (drive-managed SMR), in addition to the built-in list
+	SMRModels []string `yaml:"smr_models,omitempty"`
+	// Staged spinup, so a full shelf doesn't trip the PSU's inrush protection
+	Spinup SpinupSchedule `yaml:"spinup,omitempty"`
+	// Friendly names for enclosures and drives shown in status output
+	Labels Labels `yaml:"labels,omitempty"`
+	// What 'jbodgod daemon' runs and how often
+	Daemon DaemonSchedule `yaml:"daemon,omitempty"`
+	// Drives jbodgod leaves alone (boot/OS disks), by serial, device path or
+	// WWN. The disk(s) holding / are always excluded; --include-excluded
+	// turns both off
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	// Kernel names of the excluded disks, resolved by Load
+	excluded map[string]bool
 }
 
 type Enclosure struct {
@@ -25,17 +61,156 @@ type Drive struct {
 	Name   string `yaml:"name"`
 	Device string `yaml:"device"`
 	UUID   string `yaml:"uuid,omitempty"`
+	// Expected drive serial, checked by healthcheck --compare-config; when
+	// empty the serial of whatever drive is at Device is used
+	Serial string `yaml:"serial,omitempty"`
+	// Per-drive temperature thresholds (°C) overriding the healthcheck
+	// defaults; 0 = use the default
+	TempWarn int `yaml:"temp_warn,omitempty"`
+	TempCrit int `yaml:"temp_crit,omitempty"`
+}
+
+// Labels gives enclosures and drives human names. They apply whatever the
+// discovery mode, since drives are matched by serial rather than device
+type Labels struct {
+	// HBA enclosure ID -> name (e.g. 2: top-shelf)
+	Enclosures map[int]string `yaml:"enclosures,omitempty"`
+	// Drive serial -> name and pool role
+	Drives map[string]DriveLabel `yaml:"drives,omitempty"`
+}
+
+// DriveLabel is the friendly name of a drive and its role in its pool
+type DriveLabel struct {
+	Name string `yaml:"name"`
+	// Pool role, e.g. data, spare, log, cache, special (free text)
+	Role string `yaml:"role,omitempty"`
+}
+
+// ExpectedEnclosure declares which slots of an enclosure should be populated
+type ExpectedEnclosure struct {
+	// SES enclosure id (SAS address from /sys/class/enclosure/*/id) or H:C:T:L
+	Enclosure string `yaml:"enclosure"`
+	// Number of slots expected to be populated (optional)
+	Count int `yaml:"count,omitempty"`
+	// Expected serial per slot; an empty serial means "any drive"
+	Slots map[int]string `yaml:"slots,omitempty"`
+}
+
+// BayLayout describes the physical arrangement of bays in an enclosure model
+type BayLayout struct {
+	// Enclosure model as reported by the HBA (e.g. "SC846-P"); "*" matches any
+	Model string `yaml:"model"`
+	Rows  int    `yaml:"rows"`
+	Cols  int    `yaml:"cols"`
+	// Fill order of slot numbers: "row" (left to right, then down) or
+	// "column" (top to bottom, then right)
+	Fill string `yaml:"fill,omitempty"`
+	// Number slots from the bottom row upwards
+	BottomUp bool `yaml:"bottom_up,omitempty"`
 }
 
 type Thresholds struct {
 	WarningTemp      int    `yaml:"warning_temp"`
 	CriticalTemp     int    `yaml:"critical_temp"`
 	ActionOnCritical string `yaml:"action_on_critical"`
+	// Temperature rise (°C/minute) sustained over ThermalSamples readings
+	// that indicates a cooling failure (default 1.0; 0 disables the check)
+	ThermalRate    float64 `yaml:"thermal_rate,omitempty"`
+	ThermalSamples int     `yaml:"thermal_samples,omitempty"`
+	// Pool capacity (percent used) at which healthcheck warns / goes critical
+	PoolCapacityWarn int `yaml:"pool_capacity_warn,omitempty"`
+	PoolCapacityCrit int `yaml:"pool_capacity_crit,omitempty"`
+}
+
+// SelfTestSchedule controls rotating SMART long self-tests
+type SelfTestSchedule struct {
+	// How often each drive should be tested (default 720h = 30 days)
+	Cadence time.Duration `yaml:"cadence,omitempty"`
+	// Maximum drives testing at once (default 1)
+	MaxConcurrent int `yaml:"max_concurrent,omitempty"`
+	// Hours of the day (0-23, local time) in which new tests may start;
+	// empty = any time
+	Hours []int `yaml:"hours,omitempty"`
+}
+
+// AllowsHour reports whether new tests may start in the given hour
+func (s SelfTestSchedule) AllowsHour(hour int) bool {
+	if len(s.Hours) == 0 {
+		return true
+	}
+	for _, h := range s.Hours {
+		if h == hour {
+			return true
+		}
+	}
+	return false
+}
+
+// knownSMRModels are common drive-managed SMR models, which report neither
+// zones nor SMR in their SMART data
+var knownSMRModels = []string{
+	"WD20EFAX", "WD30EFAX", "WD40EFAX", "WD60EFAX", // WD Red (pre-Plus)
+	"ST2000DM008", "ST4000DM004", "ST6000DM003", "ST8000DM004", // Seagate BarraCuda
+	"ST8000AS0002", "ST8000AS0022", // Seagate Archive
+	"ST5000LM000", "ST4000LM024", // Seagate 2.5"
+}
+
+// IsSMRModel reports whether a drive model is a known SMR model, from the
+// built-in list or smr_models (case-insensitive substring match)
+func (c *Config) IsSMRModel(model string) bool {
+	model = strings.ToUpper(model)
+	for _, m := range append(knownSMRModels, c.SMRModels...) {
+		if m = strings.ToUpper(strings.TrimSpace(m)); m != "" && strings.Contains(model, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// SpinupSchedule starts drives in ordered groups with a pause between them
+type SpinupSchedule struct {
+	// Drives per group, as device paths or serials. Drives in no group are
+	// started last, as one final group.
+	Groups [][]string `yaml:"groups,omitempty"`
+	// Wait between starting one group and the next (default 10s; 0 starts
+	// the next group straight away)
+	GroupDelay time.Duration `yaml:"group_delay,omitempty"`
+}
+
+// DaemonSchedule controls the periodic jobs of 'jbodgod daemon'
+type DaemonSchedule struct {
+	// Inventory sync interval (default 1h); syncs after the first only
+	// touch drives that changed
+	SyncInterval time.Duration `yaml:"sync_interval,omitempty"`
+	// Healthcheck interval (default 5m); new critical alerts are notified
+	HealthcheckInterval time.Duration `yaml:"healthcheck_interval,omitempty"`
+	// Temperature sample interval (default 5m)
+	TempInterval time.Duration `yaml:"temp_interval,omitempty"`
+	// Self-test scheduler interval (default 1h); see 'jbodgod selftest'
+	SelfTestInterval time.Duration `yaml:"selftest_interval,omitempty"`
+	// Address of the Prometheus /metrics endpoint (default :9586); "off"
+	// disables it
+	Listen string `yaml:"listen,omitempty"`
 }
 
 type Alerts struct {
-	Email   string `yaml:"email,omitempty"`
+	// Recipient for critical alert emails (requires smtp)
+	Email string `yaml:"email,omitempty"`
+	// URL that receives critical alerts as a JSON POST
 	Webhook string `yaml:"webhook,omitempty"`
+	SMTP    SMTP   `yaml:"smtp,omitempty"`
+	// Minimum time before the same alert is sent again (default 24h)
+	RenotifyAfter time.Duration `yaml:"renotify_after,omitempty"`
+}
+
+// SMTP holds mail server settings for email alerts
+type SMTP struct {
+	// Mail server as host:port
+	Server   string `yaml:"server"`
+	Username string `yaml:"username,omitempty"`
+	Password string `yaml:"password,omitempty"`
+	// Sender address (defaults to jbodgod@<hostname>)
+	From string `yaml:"from,omitempty"`
 }
 
 // defaultConfig provides baseline settings; drives are discovered dynamically
@@ -45,26 +220,37 @@ var defaultConfig = Config{
 		WarningTemp:      55,
 		CriticalTemp:     60,
 		ActionOnCritical: "alert",
+		ThermalRate:      1.0,
+		ThermalSamples:   3,
+		PoolCapacityWarn: 80,
+		PoolCapacityCrit: 90,
+	},
+	Alerts: Alerts{
+		RenotifyAfter: 24 * time.Hour,
+	},
+	SelfTest: SelfTestSchedule{
+		Cadence:       30 * 24 * time.Hour,
+		MaxConcurrent: 1,
+	},
+	Spinup: SpinupSchedule{
+		GroupDelay: 10 * time.Second,
+	},
+	Daemon: DaemonSchedule{
+		SyncInterval:        time.Hour,
+		HealthcheckInterval: 5 * time.Minute,
+		TempInterval:        5 * time.Minute,
+		SelfTestInterval:    time.Hour,
+		Listen:              ":9586",
 	},
 }
 
-func Load(path string) (*Config, error) {
-	if path == "" {
-		// Try default locations
-		candidates := []string{
-			"/etc/jbodgod/config.yaml",
-			filepath.Join(os.Getenv("HOME"), ".config/jbodgod/config.yaml"),
-			"config.yaml",
-		}
-		for _, c := range candidates {
-			if _, err := os.Stat(c); err == nil {
-				path = c
-				break
-			}
-		}
-	}
+// LoadFile reads the config file (or defaults) and applies default values,
+// without discovering drives
+func LoadFile(path string) (*Config, error) {
+	path = ResolvePath(path)
 
 	var cfg Config
+	var set map[string]map[string]bool
 	if path == "" {
 		// No config file found - use defaults with auto-discovery
 		cfg = defaultConfig
@@ -76,6 +262,7 @@ func Load(path string) (*Config, error) {
 			if err := yaml.Unmarshal(data, &cfg); err != nil {
 				return nil, err
 			}
+			set = fileKeys(data)
 		}
 	}
 
@@ -89,6 +276,80 @@ func Load(path string) (*Config, error) {
 	if cfg.Thresholds.ActionOnCritical == "" {
 		cfg.Thresholds.ActionOnCritical = defaultConfig.Thresholds.ActionOnCritical
 	}
+	if cfg.Thresholds.ThermalRate == 0 && !set["thresholds"]["thermal_rate"] {
+		cfg.Thresholds.ThermalRate = defaultConfig.Thresholds.ThermalRate
+	}
+	if cfg.Thresholds.ThermalSamples == 0 {
+		cfg.Thresholds.ThermalSamples = defaultConfig.Thresholds.ThermalSamples
+	}
+	if cfg.Thresholds.PoolCapacityWarn == 0 {
+		cfg.Thresholds.PoolCapacityWarn = defaultConfig.Thresholds.PoolCapacityWarn
+	}
+	if cfg.Thresholds.PoolCapacityCrit == 0 {
+		cfg.Thresholds.PoolCapacityCrit = defaultConfig.Thresholds.PoolCapacityCrit
+	}
+	if cfg.SelfTest.Cadence == 0 {
+		cfg.SelfTest.Cadence = defaultConfig.SelfTest.Cadence
+	}
+	if cfg.SelfTest.MaxConcurrent == 0 {
+		cfg.SelfTest.MaxConcurrent = defaultConfig.SelfTest.MaxConcurrent
+	}
+	if cfg.Spinup.GroupDelay == 0 && !set["spinup"]["group_delay"] {
+		cfg.Spinup.GroupDelay = defaultConfig.Spinup.GroupDelay
+	}
+	if cfg.Daemon.SyncInterval <= 0 {
+		cfg.Daemon.SyncInterval = defaultConfig.Daemon.SyncInterval
+	}
+	if cfg.Daemon.HealthcheckInterval <= 0 {
+		cfg.Daemon.HealthcheckInterval = defaultConfig.Daemon.HealthcheckInterval
+	}
+	if cfg.Daemon.TempInterval <= 0 {
+		cfg.Daemon.TempInterval = defaultConfig.Daemon.TempInterval
+	}
+	if cfg.Daemon.SelfTestInterval <= 0 {
+		cfg.Daemon.SelfTestInterval = defaultConfig.Daemon.SelfTestInterval
+	}
+	if cfg.Daemon.Listen == "" {
+		cfg.Daemon.Listen = defaultConfig.Daemon.Listen
+	}
+	if cfg.Alerts.RenotifyAfter == 0 {
+		cfg.Alerts.RenotifyAfter = defaultConfig.Alerts.RenotifyAfter
+	}
+	if cfg.DBPath == "" {
+		cfg.DBPath = os.Getenv("JBODGOD_DB")
+	}
+
+	return &cfg, nil
+}
+
+// fileKeys returns the keys given in each section of a config file, so a
+// setting explicitly set to 0 can be told apart from a missing one
+func fileKeys(data []byte) map[string]map[string]bool {
+	var raw map[string]interface{}
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+	keys := make(map[string]map[string]bool)
+	for section, v := range raw {
+		if m, ok := v.(map[string]interface{}); ok {
+			keys[section] = make(map[string]bool)
+			for k := range m {
+				keys[section][k] = true
+			}
+		}
+	}
+	return keys
+}
+
+func Load(path string) (*Config, error) {
+	cfg, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Throttle privileged commands before discovery starts issuing them
+	privexec.SetRate(cfg.RateLimit)
+	privexec.SetTimeout(cfg.CommandTimeout)
 
 	// Determine discovery mode
 	discoveryMode := cfg.Discovery
@@ -118,7 +379,11 @@ func Load(path string) (*Config, error) {
 		}
 	}
 
-	return &cfg, nil
+	if !includeExcluded {
+		cfg.excluded = resolveExclusions(cfg.Exclude)
+	}
+
+	return cfg, nil
 }
 
 // discoverDrivesWithMode discovers drives using the specified mode
@@ -140,10 +405,55 @@ func discoverDrivesWithMode(mode string) ([]Drive, error) {
 	}
 }
 
+// LayoutFor returns the bay layout configured for an enclosure model, or nil.
+// Exact (case-insensitive) model matches win over a "*" wildcard.
+func (c *Config) LayoutFor(model string) *BayLayout {
+	var wildcard *BayLayout
+	for i := range c.Layouts {
+		l := &c.Layouts[i]
+		if l.Model == "*" {
+			wildcard = l
+			continue
+		}
+		if strings.EqualFold(strings.TrimSpace(l.Model), strings.TrimSpace(model)) {
+			return l
+		}
+	}
+	return wildcard
+}
+
+// EnclosureLabel returns the configured name for an HBA enclosure ID, or ""
+func (c *Config) EnclosureLabel(id int) string {
+	return c.Labels.Enclosures[id]
+}
+
+// DriveLabel returns the configured label for a drive serial (matched
+// case-insensitively), or nil
+func (c *Config) DriveLabel(serial string) *DriveLabel {
+	if serial == "" {
+		return nil
+	}
+	if l, ok := c.Labels.Drives[serial]; ok {
+		return &l
+	}
+	for s, l := range c.Labels.Drives {
+		if strings.EqualFold(s, serial) {
+			return &l
+		}
+	}
+	return nil
+}
+
+// GetAllDrives returns the configured or discovered drives, less any that
+// are excluded
 func (c *Config) GetAllDrives() []Drive {
 	var drives []Drive
 	for _, enc := range c.Enclosures {
-		drives = append(drives, enc.Drives...)
+		for _, d := range enc.Drives {
+			if !c.IsExcluded(d.Device) {
+				drives = append(drives, d)
+			}
+		}
 	}
 	return drives
 }
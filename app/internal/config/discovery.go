@@ -6,6 +6,8 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/sigreer/jbodgod/internal/hba"
 )
 
 // DiscoverDrives dynamically discovers disk drives on the system.
@@ -149,11 +151,11 @@ func isExcludedDevice(name string) bool {
 }
 
 // DiscoverDrivesFromHBA discovers drives via the HBA controller.
-// This requires sas3ircu or storcli to be available.
+// This requires sas3ircu, sas2ircu or storcli to be available.
 // Returns drives with enclosure/slot information populated.
 func DiscoverDrivesFromHBA() ([]Drive, error) {
-	// Try sas3ircu first
-	out, err := exec.Command("sudo", "sas3ircu", "0", "display").CombinedOutput()
+	// Try sas3ircu (or sas2ircu on older HBAs) first
+	out, err := hba.IrcuDisplay(0)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"bufio"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
+	"github.com/sigreer/jbodgod/internal/wwn"
+)
+
+// includeExcluded disables the exclude list and root drive detection
+var includeExcluded bool
+
+// SetIncludeExcluded makes Load keep excluded drives (the global
+// --include-excluded flag). Call before Load.
+func SetIncludeExcluded(include bool) {
+	includeExcluded = include
+}
+
+// IsExcluded reports whether a device (/dev/sda, /dev/disk/by-id/..., or a
+// partition of either) is one of the drives Load excluded
+func (c *Config) IsExcluded(device string) bool {
+	if len(c.excluded) == 0 {
+		return false
+	}
+	return c.excluded[diskName(device)]
+}
+
+// resolveExclusions returns the kernel names (sda) of the disks matching the
+// exclude entries, plus the disks backing the root filesystem
+func resolveExclusions(entries []string) map[string]bool {
+	excluded := make(map[string]bool)
+	for _, name := range rootDisks() {
+		excluded[name] = true
+	}
+	if len(entries) == 0 {
+		return excluded
+	}
+
+	disks := listDiskIDs()
+	for _, entry := range entries {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if strings.HasPrefix(entry, "/dev/") {
+			if name := diskName(entry); name != "" {
+				excluded[name] = true
+			}
+			continue
+		}
+
+		// Serial or WWN; WWNs match with or without the 0x/wwn- prefixes
+		want := normalizeWWN(entry)
+		for _, d := range disks {
+			if d.name == entry || strings.EqualFold(d.serial, entry) || (d.wwn != "" && d.wwn == want) {
+				excluded[d.name] = true
+			}
+		}
+	}
+	return excluded
+}
+
+// normalizeWWN strips the "wwn-" prefix of a by-id name and normalizes the
+// rest like every other WWN (see wwn.Normalize)
+func normalizeWWN(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return wwn.Normalize(strings.TrimPrefix(s, "wwn-"))
+}
+
+// diskID is the identity of one whole disk as lsblk reports it
+type diskID struct {
+	name   string
+	serial string
+	wwn    string
+}
+
+// lsblkPairRe matches one KEY="value" pair of lsblk -P output
+var lsblkPairRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// listDiskIDs lists the serial and WWN of every whole disk
+func listDiskIDs() []diskID {
+	out, err := exec.Command("lsblk", "-d", "-n", "-P", "-o", "NAME,SERIAL,WWN").Output()
+	if err != nil {
+		return nil
+	}
+
+	var disks []diskID
+	for _, line := range strings.Split(string(out), "\n") {
+		var d diskID
+		for _, m := range lsblkPairRe.FindAllStringSubmatch(line, -1) {
+			switch m[1] {
+			case "NAME":
+				d.name = m[2]
+			case "SERIAL":
+				d.serial = strings.TrimSpace(m[2])
+			case "WWN":
+				d.wwn = normalizeWWN(m[2])
+			}
+		}
+		if d.name != "" {
+			disks = append(disks, d)
+		}
+	}
+	return disks
+}
+
+// diskName resolves a device path (following /dev/disk/by-* links) to the
+// kernel name of its whole disk, so /dev/sda1 gives "sda"
+func diskName(device string) string {
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		device = resolved
+	}
+	name := filepath.Base(device)
+	if _, err := os.Stat(filepath.Join("/sys/class/block", name, "partition")); err == nil {
+		// A partition's sysfs entry sits inside its disk's
+		if dir, err := filepath.EvalSymlinks(filepath.Join("/sys/class/block", name)); err == nil {
+			return filepath.Base(filepath.Dir(dir))
+		}
+	}
+	return name
+}
+
+// rootDisks returns the disks holding the root filesystem. Device mapper
+// (LVM, LUKS) and md devices are followed down to their member disks, and
+// a ZFS root to the disks of its pool.
+func rootDisks() []string {
+	fsType, source := rootMount()
+	switch {
+	case source == "":
+		return nil
+	case fsType == "zfs":
+		pool, _, _ := strings.Cut(source, "/")
+		return zfsPoolDisks(pool)
+	case strings.HasPrefix(source, "/dev/"):
+		return backingDisks(diskName(source))
+	}
+	return nil
+}
+
+// rootMount returns the filesystem type and source of the / mount, from
+// /proc/self/mountinfo
+func rootMount() (fsType, source string) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return "", ""
+	}
+	defer f.Close()
+
+	// Format: id parent major:minor root mountpoint options... - fstype source superoptions
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		pre, post, ok := strings.Cut(scanner.Text(), " - ")
+		if !ok {
+			continue
+		}
+		fields := strings.Fields(pre)
+		if len(fields) < 5 || fields[4] != "/" {
+			continue
+		}
+		if fs := strings.Fields(post); len(fs) >= 2 {
+			// The last / mount wins, as it's the one in effect
+			fsType, source = fs[0], fs[1]
+		}
+	}
+	return fsType, source
+}
+
+// backingDisks follows a block device's slaves (dm, md) down to the disks
+// under it; a plain disk returns itself
+func backingDisks(name string) []string {
+	slaves, _ := os.ReadDir(filepath.Join("/sys/class/block", name, "slaves"))
+	if len(slaves) == 0 {
+		return []string{name}
+	}
+	var disks []string
+	for _, s := range slaves {
+		disks = append(disks, backingDisks(diskName("/dev/"+s.Name()))...)
+	}
+	return disks
+}
+
+// zfsDevRe matches a vdev path in 'zpool status -P -L' output
+var zfsDevRe = regexp.MustCompile(`/dev/\S+`)
+
+// zfsPoolDisks returns the disks of a ZFS pool
+func zfsPoolDisks(pool string) []string {
+	out, err := privexec.Run("zpool", "status", "-P", "-L", pool)
+	if err != nil {
+		return nil
+	}
+	var disks []string
+	for _, dev := range zfsDevRe.FindAllString(string(out), -1) {
+		disks = append(disks, backingDisks(diskName(dev))...)
+	}
+	return disks
+}
@@ -0,0 +1,275 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Validation is the result of checking a config file
+type Validation struct {
+	Path     string   `json:"path"`
+	Errors   []string `json:"errors,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+}
+
+func (v *Validation) errorf(format string, args ...any) {
+	v.Errors = append(v.Errors, fmt.Sprintf(format, args...))
+}
+
+func (v *Validation) warnf(format string, args ...any) {
+	v.Warnings = append(v.Warnings, fmt.Sprintf(format, args...))
+}
+
+// ResolvePath returns the config file LoadFile would read: path itself, or
+// the first default location that exists ("" if none does)
+func ResolvePath(path string) string {
+	if path != "" {
+		return path
+	}
+	candidates := []string{
+		"/etc/jbodgod/config.yaml",
+		filepath.Join(os.Getenv("HOME"), ".config/jbodgod/config.yaml"),
+		"config.yaml",
+	}
+	for _, c := range candidates {
+		if _, err := os.Stat(c); err == nil {
+			return c
+		}
+	}
+	return ""
+}
+
+// Validate checks a config file without discovering drives: that it parses,
+// has no unknown keys, that values are in range, that static drive devices
+// exist as block devices, and that names and serials aren't duplicated.
+// The returned error is only for a file that can't be read at all.
+func Validate(path string) (*Validation, error) {
+	path = ResolvePath(path)
+	if path == "" {
+		return nil, fmt.Errorf("no config file found (looked in /etc/jbodgod, ~/.config/jbodgod and the current directory)")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+
+	v := &Validation{Path: path}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		v.errorf("invalid YAML: %v", err)
+		return v, nil
+	}
+	if len(root.Content) > 0 {
+		for _, key := range unknownKeys(root.Content[0], reflect.TypeOf(Config{}), "") {
+			v.errorf("unknown key %s", key)
+		}
+	}
+
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		v.errorf("invalid value: %v", err)
+		return v, nil
+	}
+
+	switch cfg.Discovery {
+	case "", "auto", "lsscsi", "hba", "static":
+	default:
+		v.errorf("discovery: unknown mode %q (auto, lsscsi, hba or static)", cfg.Discovery)
+	}
+
+	validateDrives(&cfg, v)
+	validateThresholds(cfg.Thresholds, v)
+
+	for i, l := range cfg.Layouts {
+		if l.Rows <= 0 || l.Cols <= 0 {
+			v.errorf("layouts[%d] (%s): rows and cols must be positive", i, l.Model)
+		}
+		if l.Fill != "" && l.Fill != "row" && l.Fill != "column" {
+			v.errorf("layouts[%d] (%s): fill must be row or column, not %q", i, l.Model, l.Fill)
+		}
+	}
+	for _, h := range cfg.SelfTest.Hours {
+		if h < 0 || h > 23 {
+			v.errorf("selftest.hours: %d is not an hour (0-23)", h)
+		}
+	}
+	if cfg.Alerts.Email != "" && cfg.Alerts.SMTP.Server == "" {
+		v.errorf("alerts.email is set but alerts.smtp.server is not")
+	}
+	if cfg.RateLimit < 0 {
+		v.errorf("rate_limit must not be negative")
+	}
+	if cfg.Daemon.SyncInterval < 0 || cfg.Daemon.HealthcheckInterval < 0 || cfg.Daemon.TempInterval < 0 || cfg.Daemon.SelfTestInterval < 0 {
+		v.errorf("daemon: intervals must not be negative")
+	}
+
+	// The same drive in two spinup groups would be started twice
+	seen := make(map[string]int)
+	for i, group := range cfg.Spinup.Groups {
+		for _, entry := range group {
+			key := strings.ToUpper(entry)
+			if prev, ok := seen[key]; ok {
+				v.warnf("spinup.groups: %s is in group %d and group %d", entry, prev+1, i+1)
+				continue
+			}
+			seen[key] = i
+		}
+	}
+
+	// Serials are matched case-insensitively
+	serials := make(map[string]string)
+	for serial := range cfg.Labels.Drives {
+		key := strings.ToUpper(serial)
+		if prev, ok := serials[key]; ok {
+			v.warnf("labels.drives: %s and %s are the same serial", prev, serial)
+		}
+		serials[key] = serial
+	}
+	for _, exp := range cfg.Expected {
+		slotOf := make(map[string]int)
+		for slot, serial := range exp.Slots {
+			if serial == "" {
+				continue
+			}
+			key := strings.ToUpper(serial)
+			if prev, ok := slotOf[key]; ok {
+				v.warnf("expected %s: serial %s is expected in slots %d and %d", exp.Enclosure, serial, min(prev, slot), max(prev, slot))
+			}
+			slotOf[key] = slot
+		}
+	}
+
+	sort.Strings(v.Warnings)
+	return v, nil
+}
+
+// validateDrives checks the statically configured drives
+func validateDrives(cfg *Config, v *Validation) {
+	names := make(map[string]string)
+	devices := make(map[string]string)
+	for _, enc := range cfg.Enclosures {
+		for _, d := range enc.Drives {
+			where := fmt.Sprintf("enclosures %s drive %s", enc.Name, d.Name)
+			if d.Name != "" {
+				if prev, ok := names[d.Name]; ok {
+					v.warnf("duplicate drive name %s (in %s and %s)", d.Name, prev, enc.Name)
+				}
+				names[d.Name] = enc.Name
+			}
+
+			if d.Device == "" {
+				v.errorf("%s: no device", where)
+				continue
+			}
+			if prev, ok := devices[d.Device]; ok {
+				v.warnf("device %s is configured twice (%s and %s)", d.Device, prev, d.Name)
+			}
+			devices[d.Device] = d.Name
+
+			info, err := os.Stat(d.Device)
+			switch {
+			case err != nil:
+				v.errorf("%s: %s does not exist", where, d.Device)
+			case info.Mode()&os.ModeDevice == 0 || info.Mode()&os.ModeCharDevice != 0:
+				v.errorf("%s: %s is not a block device", where, d.Device)
+			}
+
+			if d.TempWarn != 0 && d.TempCrit != 0 && d.TempWarn >= d.TempCrit {
+				v.errorf("%s: temp_warn (%d) must be below temp_crit (%d)", where, d.TempWarn, d.TempCrit)
+			}
+		}
+	}
+}
+
+// validateThresholds checks the global thresholds
+func validateThresholds(t Thresholds, v *Validation) {
+	if t.WarningTemp != 0 && t.CriticalTemp != 0 && t.WarningTemp >= t.CriticalTemp {
+		v.errorf("thresholds: warning_temp (%d) must be below critical_temp (%d)", t.WarningTemp, t.CriticalTemp)
+	}
+	switch t.ActionOnCritical {
+	case "", "alert", "spindown", "notify":
+	default:
+		v.errorf("thresholds.action_on_critical: unknown action %q (alert, spindown or notify)", t.ActionOnCritical)
+	}
+	if t.PoolCapacityWarn != 0 && t.PoolCapacityCrit != 0 && t.PoolCapacityWarn > t.PoolCapacityCrit {
+		v.errorf("thresholds: pool_capacity_warn (%d) must not exceed pool_capacity_crit (%d)", t.PoolCapacityWarn, t.PoolCapacityCrit)
+	}
+	for _, pct := range []int{t.PoolCapacityWarn, t.PoolCapacityCrit} {
+		if pct < 0 || pct > 100 {
+			v.errorf("thresholds: pool capacity %d%% is not a percentage", pct)
+		}
+	}
+}
+
+// unknownKeys walks a YAML node against the struct type it decodes into and
+// returns the keys that type has no field for, as dotted paths with their
+// line number
+func unknownKeys(node *yaml.Node, t reflect.Type, path string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var unknown []string
+	switch node.Kind {
+	case yaml.MappingNode:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := yamlFields(t)
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, val := node.Content[i], node.Content[i+1]
+				ft, ok := fields[key.Value]
+				if !ok {
+					unknown = append(unknown, fmt.Sprintf("%s (line %d)", joinKey(path, key.Value), key.Line))
+					continue
+				}
+				unknown = append(unknown, unknownKeys(val, ft, joinKey(path, key.Value))...)
+			}
+		case reflect.Map:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key, val := node.Content[i], node.Content[i+1]
+				unknown = append(unknown, unknownKeys(val, t.Elem(), joinKey(path, key.Value))...)
+			}
+		}
+	case yaml.SequenceNode:
+		if t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+			for i, item := range node.Content {
+				unknown = append(unknown, unknownKeys(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	}
+	return unknown
+}
+
+// yamlFields maps the YAML keys of a struct to their field types
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("yaml"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(f.Name)
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+func joinKey(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -30,8 +30,9 @@ func (d *DB) CreateAlert(alert *Alert) error {
 	return nil
 }
 
-// CreateAlertWithDetails creates a new alert with structured details
-func (d *DB) CreateAlertWithDetails(severity, category, message string, details map[string]interface{}) error {
+// CreateAlertWithDetails creates a new alert with structured details and
+// returns the stored record
+func (d *DB) CreateAlertWithDetails(severity, category, message string, details map[string]interface{}) (*Alert, error) {
 	var detailsJSON string
 	if details != nil {
 		b, err := json.Marshal(details)
@@ -63,7 +64,10 @@ func (d *DB) CreateAlertWithDetails(severity, category, message string, details
 		}
 	}
 
-	return d.CreateAlert(alert)
+	if err := d.CreateAlert(alert); err != nil {
+		return nil, err
+	}
+	return alert, nil
 }
 
 // GetUnacknowledgedAlerts returns all unacknowledged alerts
@@ -52,11 +52,47 @@ func New(path string) (*DB, error) {
 	return db, nil
 }
 
+// OpenReadOnly opens an existing database without creating, configuring or
+// migrating it, for dry runs that must not touch the disk
+func OpenReadOnly(path string) (*DB, error) {
+	if path == "" {
+		path = DefaultPath
+	}
+	if _, err := os.Stat(path); err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?mode=ro")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &DB{conn: conn, path: path}, nil
+}
+
 // Close closes the database connection
 func (d *DB) Close() error {
 	return d.conn.Close()
 }
 
+// Maintenance rebuilds the database file to reclaim space freed by deleted
+// rows, then checkpoints and truncates the WAL. VACUUM can't run inside a
+// transaction, so this must not be called from one.
+func (d *DB) Maintenance() error {
+	if _, err := d.conn.Exec("VACUUM"); err != nil {
+		return fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	// VACUUM itself goes through the WAL, so checkpoint afterwards
+	if _, err := d.conn.Exec("PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+	return nil
+}
+
 // Path returns the database file path
 func (d *DB) Path() string {
 	return d.path
@@ -86,6 +122,14 @@ func (d *DB) migrate() error {
 	migrations := []string{
 		migrationV1,
 		migrationV2,
+		migrationV3,
+		migrationV4,
+		migrationV5,
+		migrationV6,
+		migrationV7,
+		migrationV8,
+		migrationV9,
+		migrationV10,
 	}
 
 	for i, migration := range migrations {
@@ -256,6 +300,20 @@ type DriveRecord struct {
 	CurrentState string
 	FirstSeen    time.Time
 	LastSeen     time.Time
+
+	// Asset data (seeded from purchase records, preserved by sync)
+	PurchaseDate    string
+	WarrantyExpires string
+	Metadata        map[string]string
+
+	// Last time SMART data was read successfully (nil = never)
+	LastSmartOK *time.Time
+
+	// SCSI grown defect list size at the last inventory update (nil = unknown)
+	GrownDefects *int
+
+	// Free-text note set with 'inventory note' (never touched by sync)
+	Notes string
 }
 
 // DriveEvent represents a state change event
@@ -288,15 +346,59 @@ type Alert struct {
 	Timestamp    time.Time
 }
 
+// SelfTest represents a scheduled SMART self-test
+type SelfTest struct {
+	ID          int64
+	Serial      string
+	DevicePath  string
+	TestType    string
+	Status      string
+	Result      string
+	StartedAt   time.Time
+	CompletedAt *time.Time
+}
+
+// SyncRun is the summary of one inventory sync. Roster maps each serial
+// seen to its location ("enclosure:slot") and other details that change
+// without a new drive (device path, pool, firmware), so the next sync can
+// tell which drives are new or changed without re-reading every record.
+type SyncRun struct {
+	ID         int64
+	Mode       string // full, changed
+	Devices    int
+	Created    int
+	Updated    int
+	Unchanged  int
+	Missing    int
+	DurationMs int64
+	Roster     map[string]string
+	SyncedAt   time.Time
+}
+
+// Sync modes
+const (
+	SyncFull    = "full"
+	SyncChanged = "changed"
+)
+
+// Self-test statuses
+const (
+	SelfTestRunning = "running"
+	SelfTestPassed  = "passed"
+	SelfTestFailed  = "failed"
+	SelfTestAborted = "aborted"
+)
+
 // Event types
 const (
-	EventDiscovered = "discovered"
-	EventOnline     = "online"
-	EventOffline    = "offline"
-	EventMissing    = "missing"
-	EventFailed     = "failed"
-	EventReplaced   = "replaced"
-	EventMoved      = "moved"
+	EventDiscovered   = "discovered"
+	EventOnline       = "online"
+	EventOffline      = "offline"
+	EventMissing      = "missing"
+	EventFailed       = "failed"
+	EventUnresponsive = "unresponsive"
+	EventReplaced     = "replaced"
+	EventMoved        = "moved"
 )
 
 // Drive states
@@ -306,6 +408,12 @@ const (
 	StateStandby = "standby"
 	StateMissing = "missing"
 	StateFailed  = "failed"
+
+	// Present but not answering (I/O errors or commands timing out)
+	StateUnresponsive = "unresponsive"
+
+	// Swapped out for another drive (see ReplaceDrive)
+	StateReplaced = "replaced"
 )
 
 // Alert severities
@@ -317,11 +425,12 @@ const (
 
 // Alert categories
 const (
-	CategoryDriveMissing  = "drive_missing"
-	CategoryDriveFailed   = "drive_failed"
-	CategoryPoolDegraded  = "pool_degraded"
-	CategoryTemperature   = "temperature"
-	CategoryDriveNew      = "drive_new"
+	CategoryDriveMissing      = "drive_missing"
+	CategoryDriveFailed       = "drive_failed"
+	CategoryDriveUnresponsive = "drive_unresponsive"
+	CategoryPoolDegraded      = "pool_degraded"
+	CategoryTemperature       = "temperature"
+	CategoryDriveNew          = "drive_new"
 )
 
 // migrationV2 adds exported_pools table for spindown/spinup tracking
@@ -351,3 +460,95 @@ type ExportedPool struct {
 	ImportedTimestamp *time.Time
 	ImportStatus      string
 }
+
+// FirmwareGroup is the set of present drives of one model and firmware
+// revision within a ZFS pool
+type FirmwareGroup struct {
+	Pool     string
+	Model    string
+	Firmware string
+	Serials  []string
+}
+
+// migrationV3 adds asset/purchase metadata to drives for inventory seeding
+const migrationV3 = `
+ALTER TABLE drives ADD COLUMN purchase_date TEXT;
+ALTER TABLE drives ADD COLUMN warranty_expires TEXT;
+ALTER TABLE drives ADD COLUMN metadata TEXT;
+`
+
+// migrationV4 adds per-drive temperature history
+const migrationV4 = `
+CREATE TABLE IF NOT EXISTS drive_temps (
+    id INTEGER PRIMARY KEY,
+    drive_id INTEGER NOT NULL REFERENCES drives(id),
+    temp_c INTEGER NOT NULL,
+    timestamp TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_temps_drive_time ON drive_temps(drive_id, timestamp);
+`
+
+// TempSample is a recorded drive temperature reading
+type TempSample struct {
+	DriveID   int64
+	TempC     int
+	Timestamp time.Time
+}
+
+// migrationV5 tracks when SMART data was last read successfully
+const migrationV5 = `
+ALTER TABLE drives ADD COLUMN last_smart_ok TIMESTAMP;
+`
+
+// migrationV6 records sent alert notifications for deduplication
+const migrationV6 = `
+CREATE TABLE IF NOT EXISTS notifications (
+    key TEXT PRIMARY KEY,
+    alert_id INTEGER REFERENCES alerts(id),
+    sent_at TIMESTAMP NOT NULL
+);
+`
+
+// migrationV7 tracks scheduled SMART self-tests
+const migrationV7 = `
+CREATE TABLE IF NOT EXISTS self_tests (
+    id INTEGER PRIMARY KEY,
+    serial TEXT NOT NULL,
+    device_path TEXT,
+    test_type TEXT NOT NULL DEFAULT 'long',
+    status TEXT NOT NULL,             -- running, passed, failed, aborted
+    result TEXT,                      -- smartctl self-test log entry
+    started_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_self_tests_serial ON self_tests(serial, started_at);
+CREATE INDEX IF NOT EXISTS idx_self_tests_status ON self_tests(status);
+`
+
+// migrationV8 tracks the SCSI grown defect list so growth can be alerted on
+const migrationV8 = `
+ALTER TABLE drives ADD COLUMN grown_defects INTEGER;
+`
+
+// migrationV9 records a summary and device roster for each inventory sync
+const migrationV9 = `
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id INTEGER PRIMARY KEY,
+    mode TEXT NOT NULL,               -- full, changed
+    devices INTEGER NOT NULL,
+    created INTEGER NOT NULL,
+    updated INTEGER NOT NULL,
+    unchanged INTEGER NOT NULL,
+    missing INTEGER NOT NULL,
+    duration_ms INTEGER,
+    roster TEXT,                      -- JSON: serial -> "enclosure:slot"
+    synced_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+`
+
+// migrationV10 adds a free-text note per drive (e.g. "RMA pending")
+const migrationV10 = `
+ALTER TABLE drives ADD COLUMN notes TEXT;
+`
@@ -2,7 +2,9 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -10,7 +12,10 @@ import (
 func (d *DB) UpsertDrive(drive *DriveRecord) error {
 	now := time.Now()
 
-	result, err := d.conn.Exec(`
+	// RETURNING gives the row's id on update too; LastInsertId would be
+	// whatever the connection last inserted
+	var id int64
+	err := d.conn.QueryRow(`
 		INSERT INTO drives (
 			serial, serial_vpd, model, manufacturer, firmware, size_bytes,
 			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
@@ -37,6 +42,7 @@ func (d *DB) UpsertDrive(drive *DriveRecord) error {
 			zfs_vdev_guid = COALESCE(excluded.zfs_vdev_guid, zfs_vdev_guid),
 			current_state = excluded.current_state,
 			last_seen = excluded.last_seen
+		RETURNING id
 	`,
 		drive.Serial, drive.SerialVPD, nullString(drive.Model), nullString(drive.Manufacturer),
 		nullString(drive.Firmware), nullInt64(drive.SizeBytes), nullString(drive.Protocol),
@@ -44,26 +50,54 @@ func (d *DB) UpsertDrive(drive *DriveRecord) error {
 		nullString(drive.ControllerID), nullString(drive.DevicePath), nullString(drive.WWN),
 		nullString(drive.LUID), nullString(drive.ZpoolName), nullString(drive.VdevType),
 		nullString(drive.ZFSVdevGUID), drive.CurrentState, now, now,
-	)
+	).Scan(&id)
 	if err != nil {
 		return fmt.Errorf("failed to upsert drive: %w", err)
 	}
 
-	// Get the ID (either from insert or existing record)
-	if drive.ID == 0 {
-		id, err := result.LastInsertId()
-		if err == nil && id > 0 {
-			drive.ID = id
-		} else {
-			// Was an update, get existing ID
-			existing, _ := d.GetDriveBySerial(drive.Serial)
-			if existing != nil {
-				drive.ID = existing.ID
-			}
+	drive.ID = id
+	return nil
+}
+
+// SeedDrive pre-creates a drive from purchase records before it has been
+// synced. Existing drives keep their observed hardware details; asset fields
+// from the seed take precedence. Returns true if a new record was created.
+func (d *DB) SeedDrive(drive *DriveRecord) (bool, error) {
+	existing, err := d.GetDriveBySerial(drive.Serial)
+	if err != nil {
+		return false, err
+	}
+
+	var metadataJSON sql.NullString
+	if len(drive.Metadata) > 0 {
+		b, err := json.Marshal(drive.Metadata)
+		if err == nil {
+			metadataJSON = sql.NullString{String: string(b), Valid: true}
 		}
 	}
 
-	return nil
+	_, err = d.conn.Exec(`
+		INSERT INTO drives (
+			serial, model, manufacturer, size_bytes, current_state,
+			purchase_date, warranty_expires, metadata
+		) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(serial) DO UPDATE SET
+			model = COALESCE(model, excluded.model),
+			manufacturer = COALESCE(manufacturer, excluded.manufacturer),
+			size_bytes = COALESCE(size_bytes, excluded.size_bytes),
+			purchase_date = COALESCE(excluded.purchase_date, purchase_date),
+			warranty_expires = COALESCE(excluded.warranty_expires, warranty_expires),
+			metadata = COALESCE(excluded.metadata, metadata)
+	`,
+		drive.Serial, nullString(drive.Model), nullString(drive.Manufacturer),
+		nullInt64(drive.SizeBytes), StateUnknown, nullString(drive.PurchaseDate),
+		nullString(drive.WarrantyExpires), metadataJSON,
+	)
+	if err != nil {
+		return false, fmt.Errorf("failed to seed drive: %w", err)
+	}
+
+	return existing == nil, nil
 }
 
 // GetDriveBySerial returns a drive by its serial number
@@ -72,7 +106,8 @@ func (d *DB) GetDriveBySerial(serial string) (*DriveRecord, error) {
 		SELECT id, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
 			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
 			device_path, wwn, luid, zpool_name, vdev_type, zfs_vdev_guid,
-			current_state, first_seen, last_seen
+			current_state, first_seen, last_seen,
+			purchase_date, warranty_expires, metadata, last_smart_ok, grown_defects, notes
 		FROM drives WHERE serial = ?
 	`, serial)
 
@@ -85,7 +120,8 @@ func (d *DB) GetDriveByLocation(enclosure, slot int) (*DriveRecord, error) {
 		SELECT id, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
 			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
 			device_path, wwn, luid, zpool_name, vdev_type, zfs_vdev_guid,
-			current_state, first_seen, last_seen
+			current_state, first_seen, last_seen,
+			purchase_date, warranty_expires, metadata, last_smart_ok, grown_defects, notes
 		FROM drives WHERE enclosure_id = ? AND slot = ?
 		ORDER BY last_seen DESC LIMIT 1
 	`, enclosure, slot)
@@ -99,7 +135,8 @@ func (d *DB) GetDriveByDevicePath(path string) (*DriveRecord, error) {
 		SELECT id, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
 			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
 			device_path, wwn, luid, zpool_name, vdev_type, zfs_vdev_guid,
-			current_state, first_seen, last_seen
+			current_state, first_seen, last_seen,
+			purchase_date, warranty_expires, metadata, last_smart_ok, grown_defects, notes
 		FROM drives WHERE device_path = ?
 	`, path)
 
@@ -112,7 +149,8 @@ func (d *DB) GetAllDrives() ([]*DriveRecord, error) {
 		SELECT id, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
 			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
 			device_path, wwn, luid, zpool_name, vdev_type, zfs_vdev_guid,
-			current_state, first_seen, last_seen
+			current_state, first_seen, last_seen,
+			purchase_date, warranty_expires, metadata, last_smart_ok, grown_defects, notes
 		FROM drives ORDER BY enclosure_id, slot
 	`)
 	if err != nil {
@@ -138,7 +176,8 @@ func (d *DB) GetDrivesByPool(poolName string) ([]*DriveRecord, error) {
 		SELECT id, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
 			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
 			device_path, wwn, luid, zpool_name, vdev_type, zfs_vdev_guid,
-			current_state, first_seen, last_seen
+			current_state, first_seen, last_seen,
+			purchase_date, warranty_expires, metadata, last_smart_ok, grown_defects, notes
 		FROM drives WHERE zpool_name = ?
 		ORDER BY enclosure_id, slot
 	`, poolName)
@@ -165,7 +204,8 @@ func (d *DB) GetDrivesByState(state string) ([]*DriveRecord, error) {
 		SELECT id, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
 			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
 			device_path, wwn, luid, zpool_name, vdev_type, zfs_vdev_guid,
-			current_state, first_seen, last_seen
+			current_state, first_seen, last_seen,
+			purchase_date, warranty_expires, metadata, last_smart_ok, grown_defects, notes
 		FROM drives WHERE current_state = ?
 		ORDER BY last_seen DESC
 	`, state)
@@ -186,6 +226,55 @@ func (d *DB) GetDrivesByState(state string) ([]*DriveRecord, error) {
 	return drives, rows.Err()
 }
 
+// GetStaleDrives returns drives not seen within olderThan, whatever their
+// current state, oldest first. Unlike the missing state this also finds
+// drives that were removed on purpose long ago
+func (d *DB) GetStaleDrives(olderThan time.Duration) ([]*DriveRecord, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, serial, serial_vpd, model, manufacturer, firmware, size_bytes,
+			protocol, drive_type, enclosure_id, slot, sas_address, controller_id,
+			device_path, wwn, luid, zpool_name, vdev_type, zfs_vdev_guid,
+			current_state, first_seen, last_seen,
+			purchase_date, warranty_expires, metadata, last_smart_ok, grown_defects, notes
+		FROM drives WHERE last_seen < ?
+		ORDER BY last_seen ASC
+	`, time.Now().Add(-olderThan))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query stale drives: %w", err)
+	}
+	defer rows.Close()
+
+	var drives []*DriveRecord
+	for rows.Next() {
+		drive, err := scanDriveRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		drives = append(drives, drive)
+	}
+
+	return drives, rows.Err()
+}
+
+// TouchDrives sets last_seen to now for drives seen but not otherwise
+// updated (e.g. skipped by a changed-only sync)
+func (d *DB) TouchDrives(serials []string) error {
+	if len(serials) == 0 {
+		return nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(serials)), ",")
+	args := make([]interface{}, 0, len(serials)+1)
+	args = append(args, time.Now())
+	for _, s := range serials {
+		args = append(args, s)
+	}
+	_, err := d.conn.Exec(`UPDATE drives SET last_seen = ? WHERE serial IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update last seen: %w", err)
+	}
+	return nil
+}
+
 // UpdateDriveState updates a drive's state and optionally records an event
 func (d *DB) UpdateDriveState(serial, newState string, recordEvent bool) error {
 	drive, err := d.GetDriveBySerial(serial)
@@ -209,6 +298,126 @@ func (d *DB) UpdateDriveState(serial, newState string, recordEvent bool) error {
 	return nil
 }
 
+// ReplaceDrive records that newSerial replaced oldSerial: the new drive takes
+// over the old one's pool, vdev type and bay, the old drive is marked
+// replaced, and both get an EventReplaced event naming the other. The new
+// drive is created (state unknown) if it isn't in the inventory yet.
+func (d *DB) ReplaceDrive(oldSerial, newSerial string) error {
+	if oldSerial == newSerial {
+		return fmt.Errorf("old and new serial are the same")
+	}
+	old, err := d.GetDriveBySerial(oldSerial)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return fmt.Errorf("drive %s not found in inventory", oldSerial)
+	}
+	if old.CurrentState == StateReplaced {
+		return fmt.Errorf("drive %s is already marked replaced", oldSerial)
+	}
+
+	repl, err := d.GetDriveBySerial(newSerial)
+	if err != nil {
+		return err
+	}
+
+	// The old drive must not lose its bay unless the replacement gains it
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if repl == nil {
+		now := time.Now()
+		result, err := tx.Exec(`
+			INSERT INTO drives (serial, current_state, first_seen, last_seen)
+			VALUES (?, ?, ?, ?)
+		`, newSerial, StateUnknown, now, now)
+		if err != nil {
+			return fmt.Errorf("failed to add replacement drive: %w", err)
+		}
+		repl = &DriveRecord{Serial: newSerial, CurrentState: StateUnknown}
+		repl.ID, _ = result.LastInsertId()
+	}
+
+	_, err = tx.Exec(`
+		UPDATE drives SET
+			zpool_name = COALESCE(?, zpool_name),
+			vdev_type = COALESCE(?, vdev_type),
+			enclosure_id = COALESCE(?, enclosure_id),
+			slot = COALESCE(?, slot)
+		WHERE serial = ?
+	`, nullString(old.ZpoolName), nullString(old.VdevType), old.EnclosureID, old.Slot, newSerial)
+	if err != nil {
+		return fmt.Errorf("failed to update replacement drive: %w", err)
+	}
+
+	// Events pick up each drive's bay, so record them before the old drive
+	// gives up its own
+	details := map[string]interface{}{"pool": old.ZpoolName, "vdev_type": old.VdevType}
+	oldDetails := map[string]interface{}{"replaced_by": newSerial}
+	newDetails := map[string]interface{}{"replaces": oldSerial}
+	for k, v := range details {
+		oldDetails[k] = v
+		newDetails[k] = v
+	}
+	if err := recordEvent(tx, old.ID, EventReplaced, old.CurrentState, StateReplaced, old.DevicePath, oldDetails); err != nil {
+		return err
+	}
+	if err := recordEvent(tx, repl.ID, EventReplaced, repl.CurrentState, repl.CurrentState, repl.DevicePath, newDetails); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE drives SET current_state = ?, zpool_name = NULL, vdev_type = NULL,
+			zfs_vdev_guid = NULL, enclosure_id = NULL, slot = NULL
+		WHERE serial = ?
+	`, StateReplaced, oldSerial)
+	if err != nil {
+		return fmt.Errorf("failed to mark drive replaced: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit replacement: %w", err)
+	}
+	return nil
+}
+
+// UpdateLastSmartOK records when SMART data was last read successfully for
+// a drive. Older timestamps never overwrite newer ones.
+func (d *DB) UpdateLastSmartOK(serial string, at time.Time) error {
+	_, err := d.conn.Exec(`
+		UPDATE drives SET last_smart_ok = ?
+		WHERE serial = ? AND (last_smart_ok IS NULL OR last_smart_ok < ?)
+	`, at, serial, at)
+	if err != nil {
+		return fmt.Errorf("failed to update last SMART read: %w", err)
+	}
+	return nil
+}
+
+// UpdateGrownDefects records the size of a drive's SCSI grown defect list
+func (d *DB) UpdateGrownDefects(serial string, count int) error {
+	_, err := d.conn.Exec(`UPDATE drives SET grown_defects = ? WHERE serial = ?`, count, serial)
+	if err != nil {
+		return fmt.Errorf("failed to update grown defects: %w", err)
+	}
+	return nil
+}
+
+// SetDriveNote sets a drive's free-text note; an empty note clears it
+func (d *DB) SetDriveNote(serial, note string) error {
+	result, err := d.conn.Exec(`UPDATE drives SET notes = ? WHERE serial = ?`, nullString(note), serial)
+	if err != nil {
+		return fmt.Errorf("failed to set drive note: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return fmt.Errorf("drive not found: %s", serial)
+	}
+	return nil
+}
+
 // DriveCount returns statistics about drives
 func (d *DB) DriveCount() (total, active, missing, failed int, err error) {
 	row := d.conn.QueryRow(`
@@ -229,7 +438,9 @@ func scanDriveRow(row *sql.Row) (*DriveRecord, error) {
 	var serialVPD, model, manufacturer, firmware, protocol, driveType sql.NullString
 	var sasAddress, controllerID, devicePath, wwn, luid sql.NullString
 	var zpoolName, vdevType, zfsVdevGUID sql.NullString
-	var sizeBytes sql.NullInt64
+	var purchaseDate, warrantyExpires, metadata, notes sql.NullString
+	var lastSmartOK sql.NullTime
+	var sizeBytes, grownDefects sql.NullInt64
 	var enclosureID, slot sql.NullInt64
 
 	err := row.Scan(
@@ -237,6 +448,7 @@ func scanDriveRow(row *sql.Row) (*DriveRecord, error) {
 		&protocol, &driveType, &enclosureID, &slot, &sasAddress, &controllerID,
 		&devicePath, &wwn, &luid, &zpoolName, &vdevType, &zfsVdevGUID,
 		&drive.CurrentState, &drive.FirstSeen, &drive.LastSeen,
+		&purchaseDate, &warrantyExpires, &metadata, &lastSmartOK, &grownDefects, &notes,
 	)
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -268,6 +480,20 @@ func scanDriveRow(row *sql.Row) (*DriveRecord, error) {
 	drive.ZpoolName = zpoolName.String
 	drive.VdevType = vdevType.String
 	drive.ZFSVdevGUID = zfsVdevGUID.String
+	drive.PurchaseDate = purchaseDate.String
+	drive.WarrantyExpires = warrantyExpires.String
+	if metadata.Valid && metadata.String != "" {
+		json.Unmarshal([]byte(metadata.String), &drive.Metadata)
+	}
+	if lastSmartOK.Valid {
+		t := lastSmartOK.Time
+		drive.LastSmartOK = &t
+	}
+	if grownDefects.Valid {
+		gd := int(grownDefects.Int64)
+		drive.GrownDefects = &gd
+	}
+	drive.Notes = notes.String
 
 	return &drive, nil
 }
@@ -278,7 +504,9 @@ func scanDriveRows(rows *sql.Rows) (*DriveRecord, error) {
 	var serialVPD, model, manufacturer, firmware, protocol, driveType sql.NullString
 	var sasAddress, controllerID, devicePath, wwn, luid sql.NullString
 	var zpoolName, vdevType, zfsVdevGUID sql.NullString
-	var sizeBytes sql.NullInt64
+	var purchaseDate, warrantyExpires, metadata, notes sql.NullString
+	var lastSmartOK sql.NullTime
+	var sizeBytes, grownDefects sql.NullInt64
 	var enclosureID, slot sql.NullInt64
 
 	err := rows.Scan(
@@ -286,6 +514,7 @@ func scanDriveRows(rows *sql.Rows) (*DriveRecord, error) {
 		&protocol, &driveType, &enclosureID, &slot, &sasAddress, &controllerID,
 		&devicePath, &wwn, &luid, &zpoolName, &vdevType, &zfsVdevGUID,
 		&drive.CurrentState, &drive.FirstSeen, &drive.LastSeen,
+		&purchaseDate, &warrantyExpires, &metadata, &lastSmartOK, &grownDefects, &notes,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to scan drive row: %w", err)
@@ -314,6 +543,20 @@ func scanDriveRows(rows *sql.Rows) (*DriveRecord, error) {
 	drive.ZpoolName = zpoolName.String
 	drive.VdevType = vdevType.String
 	drive.ZFSVdevGUID = zfsVdevGUID.String
+	drive.PurchaseDate = purchaseDate.String
+	drive.WarrantyExpires = warrantyExpires.String
+	if metadata.Valid && metadata.String != "" {
+		json.Unmarshal([]byte(metadata.String), &drive.Metadata)
+	}
+	if lastSmartOK.Valid {
+		t := lastSmartOK.Time
+		drive.LastSmartOK = &t
+	}
+	if grownDefects.Valid {
+		gd := int(grownDefects.Int64)
+		drive.GrownDefects = &gd
+	}
+	drive.Notes = notes.String
 
 	return &drive, nil
 }
@@ -339,8 +582,10 @@ func eventTypeForStateChange(old, new string) string {
 		return EventMissing
 	case StateFailed:
 		return EventFailed
+	case StateUnresponsive:
+		return EventUnresponsive
 	case StateActive:
-		if old == StateMissing || old == StateFailed {
+		if old == StateMissing || old == StateFailed || old == StateUnresponsive {
 			return EventOnline
 		}
 		return EventOnline
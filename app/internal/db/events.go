@@ -9,6 +9,17 @@ import (
 
 // RecordEvent logs a drive state transition event
 func (d *DB) RecordEvent(driveID int64, eventType, oldState, newState, devicePath string, details map[string]interface{}) error {
+	return recordEvent(d.conn, driveID, eventType, oldState, newState, devicePath, details)
+}
+
+// execQuerier is the part of *sql.DB and *sql.Tx that recordEvent uses
+type execQuerier interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// recordEvent is RecordEvent on a connection or within a transaction
+func recordEvent(q execQuerier, driveID int64, eventType, oldState, newState, devicePath string, details map[string]interface{}) error {
 	var detailsJSON string
 	if details != nil {
 		b, err := json.Marshal(details)
@@ -19,9 +30,9 @@ func (d *DB) RecordEvent(driveID int64, eventType, oldState, newState, devicePat
 
 	// Get current enclosure/slot from drive record
 	var enclosureID, slot sql.NullInt64
-	d.conn.QueryRow("SELECT enclosure_id, slot FROM drives WHERE id = ?", driveID).Scan(&enclosureID, &slot)
+	q.QueryRow("SELECT enclosure_id, slot FROM drives WHERE id = ?", driveID).Scan(&enclosureID, &slot)
 
-	_, err := d.conn.Exec(`
+	_, err := q.Exec(`
 		INSERT INTO drive_events (drive_id, event_type, old_state, new_state, device_path, enclosure_id, slot, details)
 		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`, driveID, eventType, oldState, newState, devicePath, enclosureID, slot, detailsJSON)
@@ -33,6 +44,16 @@ func (d *DB) RecordEvent(driveID int64, eventType, oldState, newState, devicePat
 	return nil
 }
 
+// PruneEvents removes drive events older than the given duration
+func (d *DB) PruneEvents(olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-olderThan)
+	result, err := d.conn.Exec("DELETE FROM drive_events WHERE timestamp < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune events: %w", err)
+	}
+	return result.RowsAffected()
+}
+
 // GetDriveEvents returns events for a specific drive
 func (d *DB) GetDriveEvents(driveID int64, limit int) ([]*DriveEvent, error) {
 	if limit <= 0 {
@@ -89,6 +110,10 @@ func (d *DB) GetRecentEvents(limit int) ([]*DriveEvent, error) {
 
 // GetEventsSince returns events since a given timestamp
 func (d *DB) GetEventsSince(since time.Time) ([]*DriveEvent, error) {
+	// Timestamps are stored by SQLite's CURRENT_TIMESTAMP in UTC, and compare
+	// as text, so the bound must be in UTC too
+	since = since.UTC()
+
 	rows, err := d.conn.Query(`
 		SELECT id, drive_id, event_type, old_state, new_state, device_path, enclosure_id, slot, details, timestamp
 		FROM drive_events
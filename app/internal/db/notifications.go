@@ -0,0 +1,33 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// LastNotified returns when a notification with the given key was last sent,
+// or nil if it never was
+func (d *DB) LastNotified(key string) (*time.Time, error) {
+	var sentAt time.Time
+	err := d.conn.QueryRow(`SELECT sent_at FROM notifications WHERE key = ?`, key).Scan(&sentAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification: %w", err)
+	}
+	return &sentAt, nil
+}
+
+// RecordNotification marks a notification key as sent now for an alert
+func (d *DB) RecordNotification(key string, alertID int64) error {
+	_, err := d.conn.Exec(`
+		INSERT INTO notifications (key, alert_id, sent_at) VALUES (?, ?, ?)
+		ON CONFLICT(key) DO UPDATE SET alert_id = excluded.alert_id, sent_at = excluded.sent_at
+	`, key, alertID, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record notification: %w", err)
+	}
+	return nil
+}
@@ -103,3 +103,35 @@ func (p *ExportedPool) GetDriveSerials() []string {
 	json.Unmarshal([]byte(p.DrivesJSON), &serials)
 	return serials
 }
+
+// GetFirmwareDistributionByPool returns present (active or standby) pool
+// members grouped by pool, model and firmware revision, ordered by pool,
+// model, then firmware
+func (d *DB) GetFirmwareDistributionByPool() ([]*FirmwareGroup, error) {
+	rows, err := d.conn.Query(`
+		SELECT zpool_name, COALESCE(model, ''), COALESCE(firmware, ''), serial
+		FROM drives
+		WHERE zpool_name IS NOT NULL AND zpool_name != ''
+			AND current_state IN (?, ?)
+		ORDER BY zpool_name, model, firmware, serial
+	`, StateActive, StateStandby)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var groups []*FirmwareGroup
+	var current *FirmwareGroup
+	for rows.Next() {
+		var pool, model, firmware, serial string
+		if err := rows.Scan(&pool, &model, &firmware, &serial); err != nil {
+			return nil, err
+		}
+		if current == nil || current.Pool != pool || current.Model != model || current.Firmware != firmware {
+			current = &FirmwareGroup{Pool: pool, Model: model, Firmware: firmware}
+			groups = append(groups, current)
+		}
+		current.Serials = append(current.Serials, serial)
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// StartSelfTest records a self-test started on a drive
+func (d *DB) StartSelfTest(serial, devicePath, testType string) (*SelfTest, error) {
+	now := time.Now()
+	result, err := d.conn.Exec(`
+		INSERT INTO self_tests (serial, device_path, test_type, status, started_at)
+		VALUES (?, ?, ?, ?, ?)
+	`, serial, nullString(devicePath), testType, SelfTestRunning, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record self-test: %w", err)
+	}
+
+	id, _ := result.LastInsertId()
+	return &SelfTest{
+		ID:         id,
+		Serial:     serial,
+		DevicePath: devicePath,
+		TestType:   testType,
+		Status:     SelfTestRunning,
+		StartedAt:  now,
+	}, nil
+}
+
+// CompleteSelfTest records the outcome of a running self-test
+func (d *DB) CompleteSelfTest(id int64, status, result string) error {
+	_, err := d.conn.Exec(`
+		UPDATE self_tests SET status = ?, result = ?, completed_at = ? WHERE id = ?
+	`, status, nullString(result), time.Now(), id)
+	if err != nil {
+		return fmt.Errorf("failed to complete self-test: %w", err)
+	}
+	return nil
+}
+
+// GetRunningSelfTests returns self-tests that haven't completed yet
+func (d *DB) GetRunningSelfTests() ([]*SelfTest, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, serial, device_path, test_type, status, result, started_at, completed_at
+		FROM self_tests WHERE status = ? ORDER BY started_at ASC
+	`, SelfTestRunning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query self-tests: %w", err)
+	}
+	defer rows.Close()
+	return scanSelfTests(rows)
+}
+
+// GetLatestSelfTests returns the most recent self-test of each drive
+func (d *DB) GetLatestSelfTests() (map[string]*SelfTest, error) {
+	rows, err := d.conn.Query(`
+		SELECT id, serial, device_path, test_type, status, result, started_at, completed_at
+		FROM self_tests t
+		WHERE started_at = (SELECT MAX(started_at) FROM self_tests WHERE serial = t.serial)
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query self-tests: %w", err)
+	}
+	defer rows.Close()
+
+	tests, err := scanSelfTests(rows)
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]*SelfTest, len(tests))
+	for _, t := range tests {
+		latest[t.Serial] = t
+	}
+	return latest, nil
+}
+
+func scanSelfTests(rows *sql.Rows) ([]*SelfTest, error) {
+	var tests []*SelfTest
+	for rows.Next() {
+		var t SelfTest
+		var devicePath, result sql.NullString
+		var completedAt sql.NullTime
+		if err := rows.Scan(&t.ID, &t.Serial, &devicePath, &t.TestType, &t.Status,
+			&result, &t.StartedAt, &completedAt); err != nil {
+			return nil, err
+		}
+		t.DevicePath = devicePath.String
+		t.Result = result.String
+		if completedAt.Valid {
+			t.CompletedAt = &completedAt.Time
+		}
+		tests = append(tests, &t)
+	}
+	return tests, rows.Err()
+}
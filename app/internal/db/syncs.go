@@ -0,0 +1,66 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+// RecordSync stores the summary of an inventory sync. Only the latest sync
+// keeps its roster; older summaries are kept without one.
+func (d *DB) RecordSync(run *SyncRun) error {
+	roster, err := json.Marshal(run.Roster)
+	if err != nil {
+		return fmt.Errorf("failed to encode sync roster: %w", err)
+	}
+
+	tx, err := d.conn.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`UPDATE sync_runs SET roster = NULL WHERE roster IS NOT NULL`); err != nil {
+		return fmt.Errorf("failed to clear old sync rosters: %w", err)
+	}
+	result, err := tx.Exec(`
+		INSERT INTO sync_runs (mode, devices, created, updated, unchanged, missing, duration_ms, roster)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, run.Mode, run.Devices, run.Created, run.Updated, run.Unchanged, run.Missing, run.DurationMs, string(roster))
+	if err != nil {
+		return fmt.Errorf("failed to record sync: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sync: %w", err)
+	}
+	run.ID, _ = result.LastInsertId()
+	return nil
+}
+
+// GetLastSync returns the most recent inventory sync, or nil if there
+// hasn't been one
+func (d *DB) GetLastSync() (*SyncRun, error) {
+	var run SyncRun
+	var durationMs sql.NullInt64
+	var roster sql.NullString
+	err := d.conn.QueryRow(`
+		SELECT id, mode, devices, created, updated, unchanged, missing, duration_ms, roster, synced_at
+		FROM sync_runs ORDER BY id DESC LIMIT 1
+	`).Scan(&run.ID, &run.Mode, &run.Devices, &run.Created, &run.Updated, &run.Unchanged,
+		&run.Missing, &durationMs, &roster, &run.SyncedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query last sync: %w", err)
+	}
+
+	run.DurationMs = durationMs.Int64
+	run.Roster = make(map[string]string)
+	if roster.Valid && roster.String != "" {
+		if err := json.Unmarshal([]byte(roster.String), &run.Roster); err != nil {
+			return nil, fmt.Errorf("failed to decode sync roster: %w", err)
+		}
+	}
+	return &run, nil
+}
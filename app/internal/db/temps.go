@@ -0,0 +1,53 @@
+package db
+
+import (
+	"fmt"
+	"time"
+)
+
+// RecordTemperature stores a temperature reading for a drive
+func (d *DB) RecordTemperature(driveID int64, temp int) error {
+	_, err := d.conn.Exec(`
+		INSERT INTO drive_temps (drive_id, temp_c, timestamp) VALUES (?, ?, ?)
+	`, driveID, temp, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to record temperature: %w", err)
+	}
+	return nil
+}
+
+// GetTemperatureHistory returns a drive's temperature readings since the
+// given time, oldest first
+func (d *DB) GetTemperatureHistory(serial string, since time.Time) ([]*TempSample, error) {
+	rows, err := d.conn.Query(`
+		SELECT t.drive_id, t.temp_c, t.timestamp
+		FROM drive_temps t
+		JOIN drives d ON d.id = t.drive_id
+		WHERE d.serial = ? AND t.timestamp >= ?
+		ORDER BY t.timestamp ASC
+	`, serial, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query temperature history: %w", err)
+	}
+	defer rows.Close()
+
+	var samples []*TempSample
+	for rows.Next() {
+		s := &TempSample{}
+		if err := rows.Scan(&s.DriveID, &s.TempC, &s.Timestamp); err != nil {
+			return nil, err
+		}
+		samples = append(samples, s)
+	}
+	return samples, rows.Err()
+}
+
+// DeleteOldTemperatures removes temperature readings older than the given duration
+func (d *DB) DeleteOldTemperatures(olderThan time.Duration) (int64, error) {
+	cutoff := time.Now().Add(-olderThan)
+	result, err := d.conn.Exec("DELETE FROM drive_temps WHERE timestamp < ?", cutoff)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete old temperatures: %w", err)
+	}
+	return result.RowsAffected()
+}
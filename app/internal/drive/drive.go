@@ -2,10 +2,11 @@ package drive
 
 import (
 	"bufio"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"os"
-	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -17,41 +18,51 @@ import (
 	"github.com/sigreer/jbodgod/internal/config"
 	"github.com/sigreer/jbodgod/internal/db"
 	"github.com/sigreer/jbodgod/internal/hba"
+	"github.com/sigreer/jbodgod/internal/privexec"
+	"github.com/sigreer/jbodgod/internal/ses"
 	"github.com/sigreer/jbodgod/internal/zfs"
 )
 
 // DriveInfo represents comprehensive drive information
 type DriveInfo struct {
 	// === Identifiers ===
-	Device     string  `json:"device"`
-	Name       string  `json:"name,omitempty"`
+	Device     string   `json:"device"`          // /dev/mapper/<name> for a multipath drive
+	Paths      []string `json:"paths,omitempty"` // the SCSI paths of a multipath drive
+	Name       string   `json:"name,omitempty"`
 	Serial     *string `json:"serial,omitempty"`
 	SerialVPD  *string `json:"serial_vpd,omitempty"`
 	WWN        *string `json:"wwn,omitempty"`
 	LUID       *string `json:"luid,omitempty"`
 	SASAddress *string `json:"sas_address,omitempty"`
 	ByIDPath   *string `json:"by_id_path,omitempty"`
+	Label      *string `json:"label,omitempty"` // Friendly name from config labels
+	Role       *string `json:"role,omitempty"`  // Pool role from config labels
 
 	// === Hardware ===
-	Model      *string `json:"model,omitempty"`
-	Vendor     *string `json:"vendor,omitempty"`
-	Firmware   *string `json:"firmware,omitempty"`
-	SizeBytes  *int64  `json:"size_bytes,omitempty"`
-	Protocol   *string `json:"protocol,omitempty"`
-	DriveType  *string `json:"drive_type,omitempty"`
-	FormFactor *string `json:"form_factor,omitempty"`
-	SectorSize *int    `json:"sector_size,omitempty"`
-	LinkSpeed  *string `json:"link_speed,omitempty"`
+	Model        *string `json:"model,omitempty"`
+	Vendor       *string `json:"vendor,omitempty"`
+	Firmware     *string `json:"firmware,omitempty"`
+	SizeBytes    *int64  `json:"size_bytes,omitempty"`
+	Protocol     *string `json:"protocol,omitempty"`
+	DriveType    *string `json:"drive_type,omitempty"`
+	FormFactor   *string `json:"form_factor,omitempty"`
+	RotationRate *int    `json:"rotation_rate,omitempty"` // rpm, 0 for SSDs
+	IsSMR        *bool   `json:"is_smr,omitempty"`        // shingled recording
+	SectorSize   *int    `json:"sector_size,omitempty"`
+	LinkSpeed    *string `json:"link_speed,omitempty"`
+	LinkSpeedMax *string `json:"link_speed_max,omitempty"`
 
 	// === Physical Location ===
 	ControllerID *string `json:"controller_id,omitempty"`
 	Enclosure    *int    `json:"enclosure,omitempty"`
 	Slot         *int    `json:"slot,omitempty"`
 	SCSIAddr     *string `json:"scsi_addr,omitempty"`
+	EnclosureLabel *string `json:"enclosure_label,omitempty"` // From config labels
 
 	// === Runtime State ===
 	State       string  `json:"state"`
 	Temp        *int    `json:"temp,omitempty"`
+	TempSource  *string `json:"temp_source,omitempty"` // "ses" when read from the enclosure
 	SmartHealth *string `json:"smart_health,omitempty"`
 
 	// === Storage Stack ===
@@ -70,20 +81,28 @@ type DriveInfo struct {
 	PartLabel *string `json:"part_label,omitempty"`
 
 	// === SMART Metrics ===
+	LastProbed     *time.Time `json:"last_probed,omitempty"`
 	PowerOnHours   *int `json:"power_on_hours,omitempty"`
 	Reallocated    *int `json:"reallocated_sectors,omitempty"`
 	PendingSectors *int `json:"pending_sectors,omitempty"`
 	MediaErrors    *int `json:"media_errors,omitempty"`
+
+	// Failure predictors
+	ReportedUncorrect *int `json:"reported_uncorrect,omitempty"`
+	CommandTimeouts   *int `json:"command_timeouts,omitempty"`
+	CRCErrors         *int `json:"crc_errors,omitempty"`
+	GrownDefects      *int `json:"grown_defects,omitempty"`
 }
 
 type Summary struct {
-	Active  int  `json:"active"`
-	Standby int  `json:"standby"`
-	Missing int  `json:"missing"`
-	Failed  int  `json:"failed"`
-	TempMin *int `json:"temp_min,omitempty"`
-	TempMax *int `json:"temp_max,omitempty"`
-	TempAvg *int `json:"temp_avg,omitempty"`
+	Active       int  `json:"active"`
+	Standby      int  `json:"standby"`
+	Missing      int  `json:"missing"`
+	Failed       int  `json:"failed"`
+	Unresponsive int  `json:"unresponsive"`
+	TempMin      *int `json:"temp_min,omitempty"`
+	TempMax      *int `json:"temp_max,omitempty"`
+	TempAvg      *int `json:"temp_avg,omitempty"`
 }
 
 // CoreDriveInfo contains essential realtime data (default output)
@@ -94,26 +113,42 @@ type CoreDriveInfo struct {
 	Temp    *int    `json:"temp,omitempty"`
 	Zpool   *string `json:"zpool,omitempty"`
 	Slot    string  `json:"slot,omitempty"` // formatted as "enc:slot"
+	Label   string  `json:"label,omitempty"`
 }
 
+// OutputSchemaVersion is the schema_version of 'status --json' output.
+// Bump it when fields are renamed, removed or change meaning.
+const OutputSchemaVersion = 1
+
 // CoreOutput is the default output structure (realtime/essential data only)
 type CoreOutput struct {
-	Drives  []CoreDriveInfo `json:"drives"`
-	Summary Summary         `json:"summary"`
+	SchemaVersion int             `json:"schema_version"`
+	Drives        []CoreDriveInfo `json:"drives"`
+	Summary       Summary         `json:"summary"`
 }
 
 // DetailOutput includes full drive data plus controllers/enclosures
 type DetailOutput struct {
-	Drives      []DriveInfo          `json:"drives"`
-	Summary     Summary              `json:"summary"`
-	Controllers []hba.ControllerInfo `json:"controllers,omitempty"`
-	Enclosures  []hba.EnclosureInfo  `json:"enclosures,omitempty"`
+	SchemaVersion int                  `json:"schema_version"`
+	Drives        []DriveInfo          `json:"drives"`
+	Summary       Summary              `json:"summary"`
+	Controllers   []hba.ControllerInfo `json:"controllers,omitempty"`
+	Enclosures    []hba.EnclosureInfo  `json:"enclosures,omitempty"`
 }
 
 // Output is an alias for DetailOutput for backwards compatibility
 type Output = DetailOutput
 
+// GetAll collects information for all configured drives with the default
+// concurrency
 func GetAll(cfg *config.Config) []DriveInfo {
+	return GetAllParallel(cfg, collector.DefaultConcurrency(), false)
+}
+
+// GetAllParallel collects information for all configured drives, probing at
+// most maxConcurrency drives at once. forceRefresh bypasses cached data,
+// e.g. after drives were swapped.
+func GetAllParallel(cfg *config.Config, maxConcurrency int, forceRefresh bool) []DriveInfo {
 	drives := cfg.GetAllDrives()
 
 	// Collect device paths
@@ -125,17 +160,123 @@ func GetAll(cfg *config.Config) []DriveInfo {
 	}
 
 	// Use new collector for bulk data collection
-	driveData := collector.GetAllDriveData(devices, false)
+	driveData := collector.GetAllDriveDataParallel(devices, forceRefresh, maxConcurrency)
 
 	// Convert to DriveInfo
 	results := make([]DriveInfo, len(driveData))
 	for i, data := range driveData {
 		results[i] = driveDataToInfo(data, nameMap[data.Device])
+		applyLabels(cfg, &results[i])
+		// Drive-managed SMR drives don't report zones; know them by model
+		if results[i].Model != nil && cfg.IsSMRModel(*results[i].Model) {
+			smr := true
+			results[i].IsSMR = &smr
+		}
+	}
+
+	results = mergeMultipath(results)
+
+	// smartctl doesn't wake standby drives for a temperature, but the
+	// enclosure may report the bay's
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].State == "standby" && results[i].Temp == nil {
+			wg.Add(1)
+			go func(d *DriveInfo) {
+				defer wg.Done()
+				if temp := getSlotTemp(*d); temp != nil {
+					d.Temp = temp
+					source := "ses"
+					d.TempSource = &source
+				}
+			}(&results[i])
+		}
 	}
+	wg.Wait()
 
 	return results
 }
 
+// applyLabels sets the friendly drive and enclosure names from config
+func applyLabels(cfg *config.Config, info *DriveInfo) {
+	for _, serial := range []*string{info.Serial, info.SerialVPD} {
+		if serial == nil {
+			continue
+		}
+		if l := cfg.DriveLabel(*serial); l != nil {
+			if l.Name != "" {
+				info.Label = &l.Name
+			}
+			if l.Role != "" {
+				info.Role = &l.Role
+			}
+			break
+		}
+	}
+	if info.Enclosure != nil {
+		if name := cfg.EnclosureLabel(*info.Enclosure); name != "" {
+			info.EnclosureLabel = &name
+		}
+	}
+}
+
+// slotText formats a drive's location as enc:slot, using the enclosure's
+// configured name when it has one
+func (d DriveInfo) slotText() string {
+	if d.Enclosure == nil || d.Slot == nil {
+		return "-"
+	}
+	if d.EnclosureLabel != nil {
+		return fmt.Sprintf("%s:%d", *d.EnclosureLabel, *d.Slot)
+	}
+	return fmt.Sprintf("%d:%d", *d.Enclosure, *d.Slot)
+}
+
+// labelText formats a drive's configured name and role, or "-"
+func (d DriveInfo) labelText() string {
+	switch {
+	case d.Label != nil && d.Role != nil:
+		return fmt.Sprintf("%s (%s)", *d.Label, *d.Role)
+	case d.Label != nil:
+		return *d.Label
+	case d.Role != nil:
+		return "(" + *d.Role + ")"
+	}
+	return "-"
+}
+
+// hasLabels reports whether any drive has a configured label, so the
+// tables only grow a LABEL column when it carries information
+func hasLabels(drives []DriveInfo) bool {
+	for _, d := range drives {
+		if d.Label != nil || d.Role != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// tableWidth returns the separator width for a table whose base width
+// assumes an 8-character SLOT column and no LABEL column
+func tableWidth(base, slotWidth int, labels bool) int {
+	width := base + slotWidth - 8
+	if labels {
+		width += 20
+	}
+	return width
+}
+
+// slotWidth returns the SLOT column width needed for enclosure labels
+func slotWidth(drives []DriveInfo) int {
+	width := 8
+	for _, d := range drives {
+		if n := len(d.slotText()); n > width {
+			width = n
+		}
+	}
+	return width
+}
+
 // driveDataToInfo converts collector.DriveData to DriveInfo
 func driveDataToInfo(data *collector.DriveData, name string) DriveInfo {
 	info := DriveInfo{
@@ -154,8 +295,11 @@ func driveDataToInfo(data *collector.DriveData, name string) DriveInfo {
 		Protocol:       data.Protocol,
 		DriveType:      data.DriveType,
 		FormFactor:     data.FormFactor,
+		RotationRate:   data.RotationRate,
+		IsSMR:          data.IsSMR,
 		SectorSize:     data.SectorSize,
 		LinkSpeed:      data.LinkSpeed,
+		LinkSpeedMax:   data.LinkSpeedMax,
 		ControllerID:   data.ControllerID,
 		Enclosure:      data.Enclosure,
 		Slot:           data.Slot,
@@ -174,141 +318,49 @@ func driveDataToInfo(data *collector.DriveData, name string) DriveInfo {
 		FSUUID:         data.FSUUID,
 		PartUUID:       data.PartUUID,
 		PartLabel:      data.PartLabel,
+		LastProbed:     data.LastProbed,
 		PowerOnHours:   data.PowerOnHours,
 		Reallocated:    data.Reallocated,
 		PendingSectors: data.PendingSectors,
 		MediaErrors:    data.MediaErrors,
+
+		ReportedUncorrect: data.ReportedUncorrect,
+		CommandTimeouts:   data.CommandTimeouts,
+		CRCErrors:         data.CRCErrors,
+		GrownDefects:      data.GrownDefects,
 	}
 	return info
 }
 
-func getInfo(d config.Drive) DriveInfo {
-	info := DriveInfo{
-		Device: d.Device,
-		Name:   d.Name,
-	}
-
-	// Check state
-	out, err := exec.Command("smartctl", "-i", "-n", "standby", d.Device).CombinedOutput()
-	output := string(out)
-
-	// Check for standby FIRST - smartctl returns non-zero exit code for standby drives
-	// but this is not an error condition
-	if strings.Contains(output, "STANDBY") || strings.Contains(output, "NOT READY") {
-		info.State = "standby"
-		return info
-	}
-
-	// Check for device access failures
-	if err != nil {
-		// Device doesn't exist or can't be opened
-		if strings.Contains(output, "No such device") ||
-			strings.Contains(output, "No such file") {
-			info.State = "missing"
-			return info
-		}
-		// Device exists but failed to respond (I/O error, etc.)
-		if strings.Contains(output, "I/O error") {
-			info.State = "failed"
-			return info
-		}
-		// Other errors - mark as failed
-		info.State = "failed"
-		return info
-	}
-
-	info.State = "active"
-
-	// Get SMART attributes
-	smartOut, _ := exec.Command("smartctl", "-A", d.Device).CombinedOutput()
-	smartStr := string(smartOut)
-
-	// Temperature
-	re := regexp.MustCompile(`Current Drive Temperature:\s+(\d+)`)
-	if matches := re.FindStringSubmatch(smartStr); len(matches) > 1 {
-		if temp, err := strconv.Atoi(matches[1]); err == nil {
-			info.Temp = &temp
-		}
-	}
-
-	// Get info
-	infoOut, _ := exec.Command("smartctl", "-i", d.Device).CombinedOutput()
-	infoStr := string(infoOut)
-
-	// Serial
-	re = regexp.MustCompile(`Serial number:\s+(\S+)`)
-	if matches := re.FindStringSubmatch(infoStr); len(matches) > 1 {
-		info.Serial = &matches[1]
-	}
-
-	// LUID
-	re = regexp.MustCompile(`Logical Unit id:\s+(\S+)`)
-	if matches := re.FindStringSubmatch(infoStr); len(matches) > 1 {
-		info.LUID = &matches[1]
-	}
-
-	// SCSI address
-	lsscsiOut, _ := exec.Command("lsscsi").CombinedOutput()
-	deviceName := strings.TrimPrefix(d.Device, "/dev/")
-	re = regexp.MustCompile(`\[([^\]]+)\].*` + deviceName + `\s*$`)
-	for _, line := range strings.Split(string(lsscsiOut), "\n") {
-		if matches := re.FindStringSubmatch(line); len(matches) > 1 {
-			info.SCSIAddr = &matches[1]
-			break
+// FilterDrives returns the drives in the given pool (if pool is set) whose
+// state is one of states (if any are given)
+func FilterDrives(drives []DriveInfo, pool string, states []string) []DriveInfo {
+	filtered := make([]DriveInfo, 0, len(drives))
+	for _, d := range drives {
+		if pool != "" && (d.Zpool == nil || *d.Zpool != pool) {
+			continue
 		}
-	}
-
-	// Model
-	lsblkOut, _ := exec.Command("lsblk", "-d", "-o", "MODEL", d.Device).CombinedOutput()
-	lines := strings.Split(strings.TrimSpace(string(lsblkOut)), "\n")
-	if len(lines) > 1 {
-		model := strings.TrimSpace(lines[1])
-		if model != "" {
-			info.Model = &model
+		if len(states) > 0 && !containsFold(states, d.State) {
+			continue
 		}
+		filtered = append(filtered, d)
 	}
-
-	// Zpool info
-	pool, vdev := getZpoolInfo(deviceName)
-	if pool != "" {
-		info.Zpool = &pool
-	}
-	if vdev != "" {
-		info.Vdev = &vdev
-	}
-
-	return info
+	return filtered
 }
 
-func getZpoolInfo(device string) (pool, vdev string) {
-	out, err := exec.Command("zpool", "status", "-L").CombinedOutput()
-	if err != nil {
-		return "", ""
-	}
-
-	lines := strings.Split(string(out), "\n")
-	var currentPool, currentVdev string
-
-	for _, line := range lines {
-		if strings.HasPrefix(line, "  pool:") {
-			currentPool = strings.TrimSpace(strings.TrimPrefix(line, "  pool:"))
-			currentVdev = ""
-		} else if strings.Contains(line, "raidz") || strings.Contains(line, "mirror") {
-			fields := strings.Fields(line)
-			if len(fields) > 0 {
-				currentVdev = fields[0]
-			}
-		} else if strings.Contains(line, device) {
-			return currentPool, currentVdev
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
 		}
 	}
-
-	return "", ""
+	return false
 }
 
 // BuildSummary calculates summary statistics from drive data
 func BuildSummary(drives []DriveInfo) Summary {
-	var active, standby, missing, failed int
+	var active, standby, missing, failed, unresponsive int
 	var temps []int
 
 	for _, d := range drives {
@@ -324,16 +376,19 @@ func BuildSummary(drives []DriveInfo) Summary {
 			missing++
 		case "failed":
 			failed++
+		case "unresponsive":
+			unresponsive++
 		default:
 			failed++
 		}
 	}
 
 	summary := Summary{
-		Active:  active,
-		Standby: standby,
-		Missing: missing,
-		Failed:  failed,
+		Active:       active,
+		Standby:      standby,
+		Missing:      missing,
+		Failed:       failed,
+		Unresponsive: unresponsive,
 	}
 
 	if len(temps) > 0 {
@@ -368,6 +423,9 @@ func DriveInfoToCore(d DriveInfo) CoreDriveInfo {
 	if d.Enclosure != nil && d.Slot != nil {
 		core.Slot = fmt.Sprintf("%d:%d", *d.Enclosure, *d.Slot)
 	}
+	if d.Label != nil {
+		core.Label = *d.Label
+	}
 	return core
 }
 
@@ -387,14 +445,18 @@ func PrintStatus(drives []DriveInfo, detail bool) {
 }
 
 func printCoreTable(drives []DriveInfo) {
-	fmt.Printf("%-10s %-8s %-10s %-6s %-12s\n", "DEVICE", "SLOT", "STATE", "TEMP", "ZPOOL")
-	fmt.Println(strings.Repeat("-", 52))
+	labels := hasLabels(drives)
+	sw := slotWidth(drives)
+
+	header := fmt.Sprintf("%-10s %-*s %-10s %-6s %-12s", "DEVICE", sw, "SLOT", "STATE", "TEMP", "ZPOOL")
+	if labels {
+		header += " LABEL"
+	}
+	fmt.Println(header)
+	fmt.Println(strings.Repeat("-", tableWidth(52, sw, labels)))
 
 	for _, d := range drives {
-		slot := "-"
-		if d.Enclosure != nil && d.Slot != nil {
-			slot = fmt.Sprintf("%d:%d", *d.Enclosure, *d.Slot)
-		}
+		slot := d.slotText()
 		temp := "-"
 		if d.Temp != nil {
 			temp = fmt.Sprintf("%d°C", *d.Temp)
@@ -403,21 +465,29 @@ func printCoreTable(drives []DriveInfo) {
 		if d.Zpool != nil {
 			zpool = *d.Zpool
 		}
-		fmt.Printf("%-10s %-8s %-10s %-6s %-12s\n",
-			d.Device, slot, strings.ToUpper(d.State), temp, zpool)
+		line := fmt.Sprintf("%-10s %-*s %-10s %-6s %-12s",
+			d.Device, sw, slot, strings.ToUpper(d.State), temp, zpool)
+		if labels {
+			line += " " + d.labelText()
+		}
+		fmt.Println(line)
 	}
 }
 
 func printDetailTable(drives []DriveInfo) {
-	fmt.Printf("%-10s %-8s %-10s %-6s %-12s %-20s %-15s\n",
-		"DEVICE", "SLOT", "STATE", "TEMP", "ZPOOL", "MODEL", "SERIAL")
-	fmt.Println(strings.Repeat("-", 90))
+	labels := hasLabels(drives)
+	sw := slotWidth(drives)
+
+	header := fmt.Sprintf("%-10s %-*s %-10s %-6s %-12s %-20s %-15s",
+		"DEVICE", sw, "SLOT", "STATE", "TEMP", "ZPOOL", "MODEL", "SERIAL")
+	if labels {
+		header += " LABEL"
+	}
+	fmt.Println(header)
+	fmt.Println(strings.Repeat("-", tableWidth(90, sw, labels)))
 
 	for _, d := range drives {
-		slot := "-"
-		if d.Enclosure != nil && d.Slot != nil {
-			slot = fmt.Sprintf("%d:%d", *d.Enclosure, *d.Slot)
-		}
+		slot := d.slotText()
 		temp := "-"
 		if d.Temp != nil {
 			temp = fmt.Sprintf("%d°C", *d.Temp)
@@ -434,8 +504,12 @@ func printDetailTable(drives []DriveInfo) {
 		if d.Serial != nil {
 			serial = truncate(*d.Serial, 13)
 		}
-		fmt.Printf("%-10s %-8s %-10s %-6s %-12s %-20s %-15s\n",
-			d.Device, slot, strings.ToUpper(d.State), temp, zpool, model, serial)
+		line := fmt.Sprintf("%-10s %-*s %-10s %-6s %-12s %-20s %-15s",
+			d.Device, sw, slot, strings.ToUpper(d.State), temp, zpool, model, serial)
+		if labels {
+			line += " " + d.labelText()
+		}
+		fmt.Println(line)
 	}
 }
 
@@ -450,6 +524,9 @@ func printSummary(summary Summary) {
 	if summary.Failed > 0 {
 		parts = append(parts, fmt.Sprintf("Failed: %d", summary.Failed))
 	}
+	if summary.Unresponsive > 0 {
+		parts = append(parts, fmt.Sprintf("Unresponsive: %d", summary.Unresponsive))
+	}
 	fmt.Println(strings.Join(parts, " | "))
 
 	if summary.TempMin != nil && summary.TempMax != nil && summary.TempAvg != nil {
@@ -476,10 +553,11 @@ func PrintJSON(drives []DriveInfo, controllers []hba.ControllerInfo, enclosures
 
 	if detail {
 		output := DetailOutput{
-			Drives:      drives,
-			Summary:     summary,
-			Controllers: controllers,
-			Enclosures:  enclosures,
+			SchemaVersion: OutputSchemaVersion,
+			Drives:        drives,
+			Summary:       summary,
+			Controllers:   controllers,
+			Enclosures:    enclosures,
 		}
 		enc.Encode(output)
 	} else {
@@ -488,13 +566,47 @@ func PrintJSON(drives []DriveInfo, controllers []hba.ControllerInfo, enclosures
 			coreDrives[i] = DriveInfoToCore(d)
 		}
 		output := CoreOutput{
-			Drives:  coreDrives,
-			Summary: summary,
+			SchemaVersion: OutputSchemaVersion,
+			Drives:        coreDrives,
+			Summary:       summary,
 		}
 		enc.Encode(output)
 	}
 }
 
+// CSVColumns is the header of CSV drive listings ('status --csv' and
+// 'inventory list --csv')
+var CSVColumns = []string{"serial", "enclosure", "slot", "state", "device", "pool", "model", "size_bytes", "firmware"}
+
+// PrintCSV outputs drive data as CSV with a CSVColumns header row
+func PrintCSV(drives []DriveInfo) error {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	num := func(n *int) string {
+		if n == nil {
+			return ""
+		}
+		return strconv.Itoa(*n)
+	}
+
+	w := csv.NewWriter(os.Stdout)
+	w.Write(CSVColumns)
+	for _, d := range drives {
+		size := ""
+		if d.SizeBytes != nil {
+			size = strconv.FormatInt(*d.SizeBytes, 10)
+		}
+		w.Write([]string{str(d.Serial), num(d.Enclosure), num(d.Slot), d.State, d.Device,
+			str(d.Zpool), str(d.Model), size, str(d.Firmware)})
+	}
+	w.Flush()
+	return w.Error()
+}
+
 // filterDrivesByController returns only drives attached to the specified controller.
 // If controller is empty, returns all drives.
 // Uses serial number matching between smartctl output and HBA device data.
@@ -539,38 +651,18 @@ func filterDrivesByController(drives []config.Drive, controller string) []config
 	return filtered
 }
 
-func Spindown(cfg *config.Config, controller string, devices []string) {
-	var drives []config.Drive
-
-	if len(devices) > 0 {
-		// Specific devices provided - use those directly
-		for _, dev := range devices {
-			drives = append(drives, config.Drive{Device: dev, Name: dev})
-		}
-		fmt.Printf("Spinning down %d specified drive(s)...\n", len(drives))
-	} else {
-		// Filter by controller
-		allDrives := cfg.GetAllDrives()
-		drives = filterDrivesByController(allDrives, controller)
-
-		if len(drives) == 0 {
-			if controller != "" {
-				fmt.Printf("No drives found for controller %s\n", controller)
-			} else {
-				fmt.Println("No drives found")
-			}
-			return
-		}
-
-		if controller != "" {
-			fmt.Printf("Spinning down %d drives on controller %s...\n", len(drives), controller)
-		} else {
-			fmt.Printf("Spinning down %d drives...\n", len(drives))
+// skipExcludedDrives drops excluded drives (the boot/OS disk, the config's
+// exclude list) from an explicitly chosen set, saying which were skipped
+func skipExcludedDrives(cfg *config.Config, drives []config.Drive) []config.Drive {
+	var remaining []config.Drive
+	for _, d := range drives {
+		if cfg.IsExcluded(d.Device) {
+			fmt.Printf("Skipping %s: excluded (boot/OS drive or exclude list; use --include-excluded to override)\n", d.Device)
+			continue
 		}
+		remaining = append(remaining, d)
 	}
-
-	// Use the common spindown logic
-	spindownDrives(drives)
+	return remaining
 }
 
 func Spinup(cfg *config.Config, controller string, devices []string) {
@@ -604,18 +696,77 @@ func Spinup(cfg *config.Config, controller string, devices []string) {
 	}
 
 	// Use the common spinup logic
-	spinupDrives(drives)
+	spinupDrives(drives, cfg.Spinup, false)
 }
 
 // SpindownOptions controls spindown behavior
 type SpindownOptions struct {
-	Force    bool // Skip all ZFS handling
-	ForceAll bool // Export all pools without prompts
+	Force    bool     // Skip all ZFS handling
+	ForceAll bool     // Export all pools without prompts
+	Pools    []string // Only drives in these ZFS pools
+	DryRun   bool     // Print what would be exported and stopped, change nothing
 }
 
 // SpinupOptions controls spinup behavior
 type SpinupOptions struct {
-	NoImport bool // Skip automatic pool import
+	NoImport bool     // Skip automatic pool import
+	Pools    []string // Only drives in these ZFS pools
+	DryRun   bool     // Print what would be started and imported, change nothing
+}
+
+// resolvePoolDrives returns the drives in the named pools. Imported pools
+// are read from zpool status; exported pools (e.g. after a spindown) from
+// the export recorded in the database, matching serials to devices.
+func resolvePoolDrives(pools []string, database *db.DB) ([]config.Drive, error) {
+	var drives []config.Drive
+	seen := make(map[string]bool)
+	add := func(device string) {
+		if !seen[device] {
+			seen[device] = true
+			drives = append(drives, config.Drive{Device: device, Name: device})
+		}
+	}
+
+	for _, pool := range pools {
+		if zfs.IsPoolImported(pool) {
+			devices, err := zfs.GetPoolDevices(pool)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get devices of pool '%s': %w", pool, err)
+			}
+			for _, dev := range devices {
+				add(dev)
+			}
+			continue
+		}
+
+		if database == nil {
+			return nil, fmt.Errorf("pool '%s' is not imported (and no database to look up its drives)", pool)
+		}
+		pending, err := database.GetPendingImports()
+		if err != nil {
+			return nil, fmt.Errorf("failed to query exported pools: %w", err)
+		}
+		var export *db.ExportedPool
+		for _, p := range pending {
+			if p.PoolName == pool {
+				export = p
+			}
+		}
+		if export == nil {
+			return nil, fmt.Errorf("pool '%s' is not imported and has no recorded export", pool)
+		}
+
+		bySerial := collector.CollectSystemData(false).DevicesBySerial()
+		for _, serial := range export.GetDriveSerials() {
+			if dev, ok := bySerial[strings.ToUpper(serial)]; ok {
+				add(dev)
+			} else {
+				fmt.Fprintf(os.Stderr, "Warning: drive %s of pool '%s' not found\n", serial, pool)
+			}
+		}
+	}
+
+	return drives, nil
 }
 
 // SpindownWithZFS performs ZFS-aware spindown
@@ -623,7 +774,15 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 	// 1. Resolve target drives (same logic as existing Spindown)
 	var drives []config.Drive
 
-	if len(devices) > 0 {
+	if len(opts.Pools) > 0 {
+		var err error
+		drives, err = resolvePoolDrives(opts.Pools, nil)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		drives = filterDrivesByController(drives, controller)
+	} else if len(devices) > 0 {
 		for _, dev := range devices {
 			drives = append(drives, config.Drive{Device: dev, Name: dev})
 		}
@@ -631,9 +790,13 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 		allDrives := cfg.GetAllDrives()
 		drives = filterDrivesByController(allDrives, controller)
 	}
+	// Named devices and pool members bypass GetAllDrives' filtering
+	drives = skipExcludedDrives(cfg, drives)
 
 	if len(drives) == 0 {
-		if controller != "" {
+		if len(opts.Pools) > 0 {
+			fmt.Printf("No drives found in pool(s) %s\n", strings.Join(opts.Pools, ", "))
+		} else if controller != "" {
 			fmt.Printf("No drives found for controller %s\n", controller)
 		} else {
 			fmt.Println("No drives found")
@@ -644,7 +807,7 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 	// 2. If --force, skip ZFS handling entirely
 	if opts.Force {
 		fmt.Println("--force specified: skipping ZFS pool checks")
-		spindownDrives(drives)
+		spindownDrives(drives, opts.DryRun)
 		return
 	}
 
@@ -659,7 +822,7 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Warning: could not analyze ZFS membership: %v\n", err)
 		// Continue without ZFS handling
-		spindownDrives(drives)
+		spindownDrives(drives, opts.DryRun)
 		return
 	}
 
@@ -669,17 +832,55 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 
 	if len(zfsPools) > 0 {
 		// Open database for tracking (optional)
-		database, dbErr := db.New("")
-		if dbErr != nil {
-			fmt.Fprintf(os.Stderr, "Warning: database unavailable, cannot track pool exports: %v\n", dbErr)
+		var database *db.DB
+		if !opts.DryRun {
+			var dbErr error
+			database, dbErr = db.New(cfg.DBPath)
+			if dbErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: database unavailable, cannot track pool exports: %v\n", dbErr)
+			}
 		}
 		if database != nil {
 			defer database.Close()
 		}
 
 		reader := bufio.NewReader(os.Stdin)
+		interactive := isTerminal(os.Stdin)
 
 		for _, pool := range zfsPools {
+			// Never interrupt a scrub/resilver, even with --force-all
+			if health, err := zfs.GetPoolHealth(pool.PoolName); err == nil && health.ScanInProgress() {
+				eta := health.ScanETA
+				if eta == "" {
+					eta = "unknown"
+				}
+				fmt.Fprintf(os.Stderr, "Refusing to export pool '%s': %s in progress (%.2f%% done, ETA %s)\n",
+					pool.PoolName, health.ScanState, health.ScanPercent, eta)
+				fmt.Fprintln(os.Stderr, "Wait for it to complete, or use --force to skip ZFS checks entirely.")
+				skippedDevices = append(skippedDevices, pool.Devices...)
+				continue
+			}
+
+			if opts.DryRun {
+				how := "would be asked to export"
+				if opts.ForceAll {
+					how = "would export"
+				}
+				fmt.Printf("Pool '%s' (%s): %s (zpool sync, zpool export) and record the export\n",
+					pool.PoolName, strings.Join(pool.Devices, ", "), how)
+				exportedPools = append(exportedPools, pool.PoolName)
+				continue
+			}
+
+			// Unattended runs (cron, pipes) can't answer the prompt: leave
+			// the pool's drives running rather than have ZFS wake or fault them
+			if !opts.ForceAll && !interactive {
+				fmt.Printf("Skipping %s: in imported pool '%s' (export the pool, or use --force-all or --force)\n",
+					strings.Join(pool.Devices, ", "), pool.PoolName)
+				skippedDevices = append(skippedDevices, pool.Devices...)
+				continue
+			}
+
 			shouldExport := opts.ForceAll
 
 			if !shouldExport {
@@ -704,7 +905,8 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 				exportedPools = append(exportedPools, pool.PoolName)
 				fmt.Printf("Pool '%s' exported successfully\n", pool.PoolName)
 			} else {
-				fmt.Printf("Skipping drives in pool '%s' (pool not exported)\n", pool.PoolName)
+				fmt.Printf("Skipping %s: in imported pool '%s' (pool not exported)\n",
+					strings.Join(pool.Devices, ", "), pool.PoolName)
 				skippedDevices = append(skippedDevices, pool.Devices...)
 			}
 		}
@@ -725,13 +927,17 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 
 	// 7. Spindown remaining drives
 	if len(drivesToSpindown) > 0 {
-		spindownDrives(drivesToSpindown)
+		spindownDrives(drivesToSpindown, opts.DryRun)
 	} else {
 		fmt.Println("No drives to spin down after ZFS handling")
 	}
 
 	// 8. Summary
-	if len(exportedPools) > 0 {
+	if opts.DryRun {
+		if len(exportedPools) > 0 {
+			fmt.Printf("\nPools that would be exported: %s\n", strings.Join(exportedPools, ", "))
+		}
+	} else if len(exportedPools) > 0 {
 		fmt.Printf("\nExported pools: %s\n", strings.Join(exportedPools, ", "))
 		fmt.Println("Use 'jbodgod spinup' to re-import these pools automatically")
 	}
@@ -739,8 +945,54 @@ func SpindownWithZFS(cfg *config.Config, controller string, devices []string, op
 	_ = nonZfsDrives // non-ZFS drives are included in drivesToSpindown
 }
 
-// spindownDrives is the core spindown logic
-func spindownDrives(drives []config.Drive) {
+// isNVMe returns true if the device (or the node a by-id/by-path symlink
+// resolves to) is an NVMe namespace
+func isNVMe(device string) bool {
+	return strings.HasPrefix(filepath.Base(resolveDevicePath(device)), "nvme")
+}
+
+// splitNVMe separates NVMe devices, which don't support SCSI START/STOP UNIT
+// and never report standby, from drives that can be spun down
+func splitNVMe(drives []config.Drive) (spinnable, nvme []config.Drive) {
+	for _, d := range drives {
+		if isNVMe(d.Device) {
+			nvme = append(nvme, d)
+		} else {
+			spinnable = append(spinnable, d)
+		}
+	}
+	return spinnable, nvme
+}
+
+// reportSkippedNVMe prints the NVMe devices excluded from a spin operation
+func reportSkippedNVMe(nvme []config.Drive, action string) {
+	if len(nvme) == 0 {
+		return
+	}
+	names := make([]string, len(nvme))
+	for i, d := range nvme {
+		names[i] = d.Device
+	}
+	fmt.Printf("Skipping %d NVMe device(s) (%s not supported): %s\n", len(nvme), action, strings.Join(names, ", "))
+}
+
+// spindownDrives is the core spindown logic. With dryRun the sdparm
+// commands are printed instead of run.
+func spindownDrives(drives []config.Drive, dryRun bool) {
+	drives, nvme := splitNVMe(drives)
+	reportSkippedNVMe(nvme, "spindown")
+	if len(drives) == 0 {
+		return
+	}
+
+	if dryRun {
+		fmt.Printf("Would spin down %d drives:\n", len(drives))
+		for _, d := range drives {
+			fmt.Printf("  sdparm --command=stop %s\n", d.Device)
+		}
+		return
+	}
+
 	fmt.Printf("Spinning down %d drives...\n", len(drives))
 
 	// Track sdparm command results
@@ -752,8 +1004,7 @@ func spindownDrives(drives []config.Drive) {
 		wg.Add(1)
 		go func(idx int, device string) {
 			defer wg.Done()
-			cmd := exec.Command("sdparm", "--command=stop", device)
-			if err := cmd.Run(); err != nil {
+			if _, err := privexec.Run("sdparm", "--command=stop", device); err != nil {
 				errorMu.Lock()
 				spindownErrors[idx] = fmt.Sprintf("%s: %v", device, err)
 				errorMu.Unlock()
@@ -782,7 +1033,7 @@ func spindownDrives(drives []config.Drive) {
 		time.Sleep(time.Second)
 		stopped := 0
 		for _, d := range drives {
-			out, _ := exec.Command("smartctl", "-i", "-n", "standby", d.Device).CombinedOutput()
+			out, _ := privexec.Run("smartctl", "-i", "-n", "standby", d.Device)
 			if strings.Contains(string(out), "NOT READY") {
 				stopped++
 			}
@@ -803,12 +1054,35 @@ func spindownDrives(drives []config.Drive) {
 	}
 }
 
+// openInventory opens the inventory database, read-only for a dry run so it
+// isn't created or migrated
+func openInventory(cfg *config.Config, dryRun bool) (*db.DB, error) {
+	if dryRun {
+		return db.OpenReadOnly(cfg.DBPath)
+	}
+	return db.New(cfg.DBPath)
+}
+
 // SpinupWithZFS performs ZFS-aware spinup
 func SpinupWithZFS(cfg *config.Config, controller string, devices []string, opts SpinupOptions) {
 	// 1. Resolve target drives (same logic as existing Spinup)
 	var drives []config.Drive
 
-	if len(devices) > 0 {
+	if len(opts.Pools) > 0 {
+		// Pools spun down by jbodgod are exported, so their drives come
+		// from the recorded export
+		database, _ := openInventory(cfg, opts.DryRun)
+		var err error
+		drives, err = resolvePoolDrives(opts.Pools, database)
+		if database != nil {
+			database.Close()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		drives = filterDrivesByController(drives, controller)
+	} else if len(devices) > 0 {
 		for _, dev := range devices {
 			drives = append(drives, config.Drive{Device: dev, Name: dev})
 		}
@@ -816,9 +1090,13 @@ func SpinupWithZFS(cfg *config.Config, controller string, devices []string, opts
 		allDrives := cfg.GetAllDrives()
 		drives = filterDrivesByController(allDrives, controller)
 	}
+	// Named devices and pool members bypass GetAllDrives' filtering
+	drives = skipExcludedDrives(cfg, drives)
 
 	if len(drives) == 0 {
-		if controller != "" {
+		if len(opts.Pools) > 0 {
+			fmt.Printf("No drives found in pool(s) %s\n", strings.Join(opts.Pools, ", "))
+		} else if controller != "" {
 			fmt.Printf("No drives found for controller %s\n", controller)
 		} else {
 			fmt.Println("No drives found")
@@ -827,7 +1105,7 @@ func SpinupWithZFS(cfg *config.Config, controller string, devices []string, opts
 	}
 
 	// 2. Spinup the drives first
-	spinupDrives(drives)
+	spinupDrives(drives, cfg.Spinup, opts.DryRun)
 
 	// 3. Skip import if requested
 	if opts.NoImport {
@@ -836,11 +1114,13 @@ func SpinupWithZFS(cfg *config.Config, controller string, devices []string, opts
 	}
 
 	// 4. Wait for drives to stabilize
-	fmt.Println("Waiting for drives to stabilize...")
-	time.Sleep(3 * time.Second)
+	if !opts.DryRun {
+		fmt.Println("Waiting for drives to stabilize...")
+		time.Sleep(3 * time.Second)
+	}
 
 	// 5. Check database for pools to import
-	database, dbErr := db.New("")
+	database, dbErr := openInventory(cfg, opts.DryRun)
 	if dbErr != nil {
 		fmt.Fprintf(os.Stderr, "Warning: database unavailable, cannot auto-import pools: %v\n", dbErr)
 		return
@@ -867,6 +1147,13 @@ func SpinupWithZFS(cfg *config.Config, controller string, devices []string, opts
 		return
 	}
 
+	if opts.DryRun {
+		for _, pool := range pendingPools {
+			fmt.Printf("Would import pool '%s' (zpool import %s)\n", pool.PoolName, pool.PoolName)
+		}
+		return
+	}
+
 	// 8. Import each pending pool
 	fmt.Printf("\nRe-importing %d pool(s)...\n", len(pendingPools))
 
@@ -883,70 +1170,179 @@ func SpinupWithZFS(cfg *config.Config, controller string, devices []string, opts
 	}
 }
 
-// spinupDrives is the core spinup logic
-func spinupDrives(drives []config.Drive) {
+// spinupDrives is the core spinup logic. With dryRun the sdparm commands
+// of each spinup group are printed instead of run.
+func spinupDrives(drives []config.Drive, sched config.SpinupSchedule, dryRun bool) {
+	drives, nvme := splitNVMe(drives)
+	reportSkippedNVMe(nvme, "spinup")
+	if len(drives) == 0 {
+		return
+	}
+
+	groups := spinupGroups(drives, sched)
+	if dryRun {
+		fmt.Printf("Would spin up %d drives:\n", len(drives))
+		for i, group := range groups {
+			if len(groups) > 1 {
+				after := ""
+				if i > 0 {
+					after = fmt.Sprintf(", %v after the previous group", sched.GroupDelay)
+				}
+				fmt.Printf("  Group %d/%d%s:\n", i+1, len(groups), after)
+			}
+			for _, d := range group {
+				fmt.Printf("  sdparm --command=start %s\n", d.Device)
+			}
+		}
+		return
+	}
+
 	fmt.Printf("Spinning up %d drives...\n", len(drives))
+	// Drives of earlier groups still spinning up are watched along with
+	// the next group
+	var pending []config.Drive
+	for i, group := range groups {
+		label := ""
+		if len(groups) > 1 {
+			label = fmt.Sprintf("Group %d/%d: ", i+1, len(groups))
+			fmt.Printf("%sstarting %d drives...\n", label, len(group))
+		}
 
-	var wg sync.WaitGroup
-	for _, d := range drives {
-		wg.Add(1)
-		go func(device string) {
-			defer wg.Done()
-			exec.Command("sdparm", "--command=start", device).Run()
-		}(d.Device)
+		started := time.Now()
+		var wg sync.WaitGroup
+		for _, d := range group {
+			wg.Add(1)
+			go func(device string) {
+				defer wg.Done()
+				privexec.Run("sdparm", "--command=start", device)
+			}(d.Device)
+		}
+		wg.Wait()
+
+		pending = append(pending, group...)
+		if i == len(groups)-1 {
+			pending = monitorSpinup(pending, label, time.Minute)
+			break
+		}
+		// The delay spaces out the inrush current, so it's waited out in
+		// full even if the group is active sooner
+		pending = monitorSpinup(pending, label, sched.GroupDelay)
+		time.Sleep(time.Until(started.Add(sched.GroupDelay)))
 	}
-	wg.Wait()
 
-	// Monitor progress
-	for i := 0; i < 60; i++ {
-		time.Sleep(time.Second)
-		active := 0
+	if len(pending) > 0 {
+		devices := make([]string, len(pending))
+		for i, d := range pending {
+			devices[i] = d.Device
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %d of %d drives not active after spinup: %s\n",
+			len(pending), len(drives), strings.Join(devices, ", "))
+		return
+	}
+	fmt.Println("All drives active.")
+}
+
+// monitorSpinup shows how many of the drives are active, once a second,
+// until all are or the timeout passes. Returns the drives not active yet.
+func monitorSpinup(drives []config.Drive, label string, timeout time.Duration) []config.Drive {
+	pending := drives
+	deadline := time.Now().Add(timeout)
+	checked := false
+	for len(pending) > 0 && time.Now().Before(deadline) {
+		time.Sleep(min(time.Second, time.Until(deadline)))
+		var notReady []config.Drive
 		for _, d := range drives {
-			out, _ := exec.Command("smartctl", "-i", "-n", "standby", d.Device).CombinedOutput()
-			if !strings.Contains(string(out), "NOT READY") {
-				active++
+			out, _ := privexec.Run("smartctl", "-i", "-n", "standby", d.Device)
+			if strings.Contains(string(out), "NOT READY") {
+				notReady = append(notReady, d)
 			}
 		}
-		fmt.Printf("\r  Progress: %d/%d drives active...", active, len(drives))
-		if active == len(drives) {
-			break
+		pending = notReady
+		checked = true
+		fmt.Printf("\r  %sProgress: %d/%d drives active...", label, len(drives)-len(pending), len(drives))
+	}
+	if checked {
+		fmt.Println()
+	}
+	return pending
+}
+
+// spinupGroups splits drives into the configured spinup groups, in order,
+// followed by the drives in no group. Group entries match a drive's device
+// (symlinks resolved) or its serial; empty groups are dropped.
+func spinupGroups(drives []config.Drive, sched config.SpinupSchedule) [][]config.Drive {
+	if len(sched.Groups) == 0 {
+		return [][]config.Drive{drives}
+	}
+
+	groupOf := make(map[string]int)
+	for i, group := range sched.Groups {
+		for _, entry := range group {
+			key := strings.ToUpper(entry)
+			if strings.HasPrefix(entry, "/") {
+				key = resolveDevicePath(entry)
+			}
+			if _, ok := groupOf[key]; !ok {
+				groupOf[key] = i
+			}
+		}
+	}
+
+	serials := make(map[string][]string) // device -> serials
+	for serial, dev := range collector.CollectSystemData(false).DevicesBySerial() {
+		serials[dev] = append(serials[dev], serial)
+	}
+
+	grouped := make([][]config.Drive, len(sched.Groups)+1)
+	for _, d := range drives {
+		dev := resolveDevicePath(d.Device)
+		i, ok := groupOf[dev]
+		for _, serial := range serials[dev] {
+			if ok {
+				break
+			}
+			i, ok = groupOf[serial]
+		}
+		if !ok {
+			i = len(sched.Groups)
+		}
+		grouped[i] = append(grouped[i], d)
+	}
+
+	var groups [][]config.Drive
+	for _, g := range grouped {
+		if len(g) > 0 {
+			groups = append(groups, g)
 		}
 	}
-	fmt.Println("\nAll drives active.")
+	return groups
+}
+
+// resolveDevicePath resolves by-id/by-path symlinks to the device node
+func resolveDevicePath(device string) string {
+	if resolved, err := filepath.EvalSymlinks(device); err == nil {
+		return resolved
+	}
+	return device
 }
 
-// MonitorState holds cached state for efficient updates
+// MonitorState holds the monitor's state between refreshes
 type MonitorState struct {
 	drives         []DriveInfo
-	controllers    []hba.ControllerInfo
-	enclosures     []hba.EnclosureInfo
 	controllerTemp *int
 	lastTempUpdate time.Time
 	lastCtrlUpdate time.Time
-	lastHBAUpdate  time.Time
-	hbaLoaded      bool
+	thermal        *thermalTracker
+	tempRates      []float64
+	runaway        []bool
 }
 
 // FetchHBAData retrieves controller and enclosure information from HBA tools
 // Returns controllers, enclosures, and any error encountered
 func FetchHBAData(forceRefresh bool) ([]hba.ControllerInfo, []hba.EnclosureInfo, error) {
-	var controllers []hba.ControllerInfo
-	var enclosures []hba.EnclosureInfo
-
-	// Get list of available controllers
-	controllerNums := hba.ListControllers()
-
-	for _, ctrlNum := range controllerNums {
-		ctrl, encs, _, err := hba.GetFullControllerInfo("c"+strconv.Itoa(ctrlNum), forceRefresh)
-		if err != nil {
-			continue
-		}
-		if ctrl != nil {
-			controllers = append(controllers, *ctrl)
-		}
-		enclosures = append(enclosures, encs...)
-	}
-
+	// Per-controller failures are skipped; a shelf cabled to two HBAs is
+	// reported once
+	controllers, enclosures, _, _ := hba.GetAllControllerData(forceRefresh)
 	return controllers, enclosures, nil
 }
 
@@ -961,7 +1357,7 @@ func getSerialForDevice(device string) string {
 	}
 
 	// Fetch serial
-	out, _ := exec.Command("smartctl", "-i", device).CombinedOutput()
+	out, _ := privexec.Run("smartctl", "-i", device)
 	re := regexp.MustCompile(`Serial number:\s+(\S+)`)
 	if matches := re.FindStringSubmatch(string(out)); len(matches) > 1 {
 		c.SetStatic(cacheKey, matches[1])
@@ -970,69 +1366,22 @@ func getSerialForDevice(device string) string {
 	return ""
 }
 
-// checkDriveState does a lightweight check of drive state only (no temp/serial)
-// Uses cache with fast TTL to avoid hammering the drives
-func checkDriveState(device string) string {
-	c := cache.Global()
-	cacheKey := "drive:state:" + device
-
-	// Check cache first
-	if cached := c.Get(cacheKey); cached != nil {
-		return cached.(string)
-	}
-
-	// Fetch fresh state
-	out, err := exec.Command("smartctl", "-i", "-n", "standby", device).CombinedOutput()
-	output := string(out)
-
-	var state string
-
-	// Check for standby FIRST - smartctl returns non-zero exit code for standby
-	// but this is not an error condition
-	if strings.Contains(output, "STANDBY") || strings.Contains(output, "NOT READY") {
-		state = "standby"
-	} else if err != nil {
-		// Check for device access failures
-		if strings.Contains(output, "No such device") ||
-			strings.Contains(output, "No such file") {
-			state = "missing"
-		} else if strings.Contains(output, "I/O error") {
-			state = "failed"
-		} else {
-			// Other errors - mark as failed
-			state = "failed"
-		}
-	} else {
-		state = "active"
+// getSlotTemp reads a drive's temperature from its enclosure's SES sensors,
+// without touching the drive. Returns nil if the drive's bay is unknown or
+// the enclosure has no per-slot sensor.
+func getSlotTemp(d DriveInfo) *int {
+	if d.Enclosure == nil || d.Slot == nil {
+		return nil
 	}
-
-	// Cache with fast TTL
-	c.SetFast(cacheKey, state)
-	return state
-}
-
-// getDriveTemp gets temperature for a single drive (only if active)
-// Uses cache with dynamic TTL
-func getDriveTemp(device string) *int {
-	c := cache.Global()
-	cacheKey := "drive:temp:" + device
-
-	// Check cache first
-	if cached := c.Get(cacheKey); cached != nil {
-		temp := cached.(int)
-		return &temp
+	info, err := ses.GetLocateInfoBySlot("", *d.Enclosure, *d.Slot)
+	if err != nil || info.SGDevice == "" {
+		return nil
 	}
-
-	// Fetch fresh temp
-	out, _ := exec.Command("smartctl", "-A", device).CombinedOutput()
-	re := regexp.MustCompile(`Current Drive Temperature:\s+(\d+)`)
-	if matches := re.FindStringSubmatch(string(out)); len(matches) > 1 {
-		if temp, err := strconv.Atoi(matches[1]); err == nil {
-			c.SetDynamic(cacheKey, temp)
-			return &temp
-		}
+	temp, err := ses.GetSlotTemperature(info.SGDevice, info.DevSlotNum())
+	if err != nil {
+		return nil
 	}
-	return nil
+	return &temp
 }
 
 // getControllerTemp fetches controller temperature via HBA package
@@ -1041,34 +1390,6 @@ func getControllerTemp(controller string) *int {
 	return temp
 }
 
-// getDeviceHBAInfo fetches enclosure/slot info from HBA
-// Uses cache with static TTL (hardware doesn't change)
-func getDeviceHBAInfo(serial string) (enclosure, slot *int) {
-	if serial == "" {
-		return nil, nil
-	}
-
-	c := cache.Global()
-	cacheKey := "drive:hba:" + serial
-
-	// Check cache first
-	if cached := c.Get(cacheKey); cached != nil {
-		info := cached.([2]*int)
-		return info[0], info[1]
-	}
-
-	// Look up device
-	dev := hba.GetDeviceBySerial(serial)
-	if dev != nil {
-		enc := dev.EnclosureID
-		sl := dev.Slot
-		c.SetStatic(cacheKey, [2]*int{&enc, &sl})
-		return &enc, &sl
-	}
-
-	return nil, nil
-}
-
 // ANSI escape sequences for cursor control
 const (
 	cursorHome    = "\033[H"
@@ -1089,11 +1410,22 @@ func clearLine() {
 	fmt.Print("\033[K")
 }
 
-// Monitor provides live monitoring with efficient in-place updates
-func Monitor(cfg *config.Config, interval int, tempInterval int, controller string) {
+// isTerminal reports whether f is a terminal rather than a pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Monitor provides live monitoring with efficient in-place updates. With
+// plain, or when stdout isn't a terminal, a timestamped block is appended
+// per refresh instead.
+func Monitor(cfg *config.Config, interval int, tempInterval int, controller string, plain bool) {
 	drives := cfg.GetAllDrives()
 	state := &MonitorState{
-		drives: make([]DriveInfo, len(drives)),
+		drives:    make([]DriveInfo, len(drives)),
+		thermal:   newThermalTracker(cfg.Thresholds.ThermalRate, cfg.Thresholds.ThermalSamples),
+		tempRates: make([]float64, len(drives)),
+		runaway:   make([]bool, len(drives)),
 	}
 
 	// Initialize drive info with names
@@ -1105,16 +1437,6 @@ func Monitor(cfg *config.Config, interval int, tempInterval int, controller stri
 		}
 	}
 
-	// Pre-load HBA data (background, cached)
-	go func() {
-		// Trigger HBA data fetch to populate cache and store in state
-		controllers, enclosures, _ := FetchHBAData(false)
-		state.controllers = controllers
-		state.enclosures = enclosures
-		state.lastHBAUpdate = time.Now()
-		state.hbaLoaded = true
-	}()
-
 	// Header row positions
 	const headerRow = 1
 	const infoRow = 2
@@ -1127,92 +1449,89 @@ func Monitor(cfg *config.Config, interval int, tempInterval int, controller stri
 	tempStatsRow := footerRow + 2
 	ctrlTempRow := footerRow + 3
 
-	// Initial screen setup
-	fmt.Print("\033[H\033[2J") // Clear screen once
-	fmt.Print(hideCursor)
+	// Without a terminal (piped, systemd) escape sequences are just noise
+	plain = plain || !isTerminal(os.Stdout)
+
+	if plain {
+		fmt.Printf("JBOD drive monitor: refreshing every %ds (temps every %ds)\n\n", interval, tempInterval)
+	} else {
+		// Initial screen setup
+		fmt.Print("\033[H\033[2J") // Clear screen once
+		fmt.Print(hideCursor)
 
-	// Ensure cursor is shown on exit
-	defer fmt.Print(showCursor)
+		// Ensure cursor is shown on exit
+		defer fmt.Print(showCursor)
 
-	// Draw static header
-	moveCursor(headerRow, 1)
-	fmt.Print("=== JBOD Drive Monitor === (Ctrl+C to exit)")
+		// Draw static header
+		moveCursor(headerRow, 1)
+		fmt.Print("=== JBOD Drive Monitor === (Ctrl+C to exit)")
 
-	// Draw table header (with SLOT column)
-	moveCursor(tableHeaderRow, 1)
-	fmt.Printf("%-10s %-8s %-10s %-8s %s", "DRIVE", "SLOT", "STATE", "TEMP", "STATUS")
-	moveCursor(tableHeaderRow+1, 1)
-	fmt.Print("-----------------------------------------------------")
+		// Draw table header (with SLOT column)
+		moveCursor(tableHeaderRow, 1)
+		fmt.Printf("%-10s %-8s %-10s %-8s %-10s %s", "DRIVE", "SLOT", "STATE", "TEMP", "TREND", "STATUS")
+		moveCursor(tableHeaderRow+1, 1)
+		fmt.Print(strings.Repeat("-", 64))
+	}
+
+	devices := make([]string, len(drives))
+	for i, d := range drives {
+		devices[i] = d.Device
+	}
 
 	tickCount := 0
-	tempTicks := tempInterval / interval // How many ticks between temp updates
-	ctrlTicks := 30 / interval           // Controller temp every 30 seconds
-	hbaTicks := 300 / interval           // HBA data every 5 minutes
+	tempTicks := tempInterval / interval // How many ticks between temp updates (drives and controller)
 	if tempTicks < 1 {
 		tempTicks = 1
 	}
-	if ctrlTicks < 1 {
-		ctrlTicks = 1
-	}
-	if hbaTicks < 1 {
-		hbaTicks = 1
-	}
 
 	for {
 		tickCount++
 		shouldUpdateTemps := tickCount == 1 || tickCount%tempTicks == 0
-		shouldUpdateCtrl := controller != "" && (tickCount == 1 || tickCount%ctrlTicks == 0)
-		shouldUpdateHBA := state.hbaLoaded && tickCount%hbaTicks == 0
-
-		// Update timestamp
-		moveCursor(infoRow, 1)
-		clearLine()
-		fmt.Printf("Refreshing every %ds (temps every %ds) | %s",
-			interval, tempInterval, time.Now().Format("2006-01-02 15:04:05"))
-
-		// Update drive states (lightweight, every tick)
-		var wg sync.WaitGroup
-		stateResults := make([]string, len(drives))
-
-		for i, d := range drives {
-			wg.Add(1)
-			go func(idx int, device string) {
-				defer wg.Done()
-				stateResults[idx] = checkDriveState(device)
-			}(i, d.Device)
-		}
-		wg.Wait()
-
-		// Apply state results
-		for i, newState := range stateResults {
-			state.drives[i].State = newState
+		shouldUpdateCtrl := controller != "" && shouldUpdateTemps
+
+		// Drives are probed through the collector, so identity, bay and pool
+		// come from its cached bulk data and only state (smartctl -n standby,
+		// cached for seconds) and SMART attributes are re-read
+		driveData := collector.GetAllDriveDataParallel(devices, false, collector.DefaultConcurrency())
+		for i, data := range driveData {
+			state.drives[i].State = data.State
+			if data.Enclosure != nil && data.Slot != nil {
+				state.drives[i].Enclosure = data.Enclosure
+				state.drives[i].Slot = data.Slot
+			}
 		}
 
-		// Update temperatures for active drives (less frequent)
+		// Update temperatures (less frequent)
 		if shouldUpdateTemps {
-			var tempWg sync.WaitGroup
 			tempResults := make([]*int, len(drives))
-
+			var tempWg sync.WaitGroup
 			for i, d := range state.drives {
-				if d.State == "active" {
+				switch d.State {
+				case "active":
+					tempResults[i] = driveData[i].Temp
+				case "standby":
+					// Read from the enclosure so the drive isn't woken
 					tempWg.Add(1)
-					go func(idx int, device string) {
+					go func(idx int, d DriveInfo) {
 						defer tempWg.Done()
-						tempResults[idx] = getDriveTemp(device)
-					}(i, drives[i].Device)
+						tempResults[idx] = getSlotTemp(d)
+					}(i, d)
 				}
 			}
 			tempWg.Wait()
 
-			// Apply temp results
+			// Apply temp results and track rate of change
+			now := time.Now()
 			for i, temp := range tempResults {
-				if state.drives[i].State == "active" {
-					state.drives[i].Temp = temp
+				state.drives[i].Temp = temp
+				if temp != nil {
+					state.tempRates[i], state.runaway[i] = state.thermal.Record(drives[i].Device, *temp, now)
 				} else {
-					state.drives[i].Temp = nil
+					state.thermal.Reset(drives[i].Device)
+					state.tempRates[i], state.runaway[i] = 0, false
 				}
 			}
-			state.lastTempUpdate = time.Now()
+			state.lastTempUpdate = now
 		}
 
 		// Update controller temperature
@@ -1221,36 +1540,12 @@ func Monitor(cfg *config.Config, interval int, tempInterval int, controller stri
 			state.lastCtrlUpdate = time.Now()
 		}
 
-		// Refresh HBA data periodically (every 5 minutes)
-		if shouldUpdateHBA {
-			go func() {
-				controllers, enclosures, _ := FetchHBAData(true) // Force refresh
-				state.controllers = controllers
-				state.enclosures = enclosures
-				state.lastHBAUpdate = time.Now()
-			}()
-		}
-
-		// Render drive rows (in-place updates)
-		var active, standby, missing, failed int
+		// Build drive rows
+		var active, standby, missing, failed, unresponsive, runaway int
 		var temps []int
+		rows := make([]string, len(state.drives))
 
 		for i, d := range state.drives {
-			row := tableDataStart + i
-			moveCursor(row, 1)
-			clearLine()
-
-			// Get slot info if HBA data is loaded and we don't have it yet
-			if state.hbaLoaded && d.Enclosure == nil && d.State == "active" {
-				serial := getSerialForDevice(drives[i].Device)
-				if serial != "" {
-					enc, slot := getDeviceHBAInfo(serial)
-					state.drives[i].Enclosure = enc
-					state.drives[i].Slot = slot
-					d = state.drives[i] // Refresh local copy
-				}
-			}
-
 			// Format slot info
 			slotStr := "-"
 			if d.Enclosure != nil && d.Slot != nil {
@@ -1274,6 +1569,11 @@ func Monitor(cfg *config.Config, interval int, tempInterval int, controller stri
 					} else {
 						status = "🟢 OK"
 					}
+
+					if state.runaway[i] {
+						runaway++
+						status += fmt.Sprintf(" 🔥 THERMAL RUNAWAY +%.1f°C/min", state.tempRates[i])
+					}
 				} else {
 					status = "⏳" // Active but temp not yet fetched
 				}
@@ -1286,21 +1586,25 @@ func Monitor(cfg *config.Config, interval int, tempInterval int, controller stri
 			case "failed":
 				failed++
 				status = "⛔ FAILED"
+			case "unresponsive":
+				unresponsive++
+				status = "⌛ UNRESPONSIVE"
 			default:
 				failed++
 				status = "⚠️  UNKNOWN"
 			}
 
-			fmt.Printf("%-10s %-8s %-10s %-8s %s", d.Device, slotStr, strings.ToUpper(d.State), temp, status)
-		}
+			// Last readings, oldest first; the tracker drops a drive's history
+			// when it stops reporting, so this is blank until it reads again
+			trend := sparkline(state.thermal.Samples(drives[i].Device))
+			if trend == "" {
+				trend = "-"
+			}
 
-		// Update summary section
-		moveCursor(footerRow, 1)
-		clearLine()
-		fmt.Print("-----------------------------------------------------")
+			rows[i] = fmt.Sprintf("%-10s %-8s %-10s %-8s %-10s %s", d.Device, slotStr, strings.ToUpper(d.State), temp, trend, status)
+		}
 
-		moveCursor(summaryRow, 1)
-		clearLine()
+		// Summary section
 		summaryParts := []string{fmt.Sprintf("Active: %d", active), fmt.Sprintf("Standby: %d", standby)}
 		if missing > 0 {
 			summaryParts = append(summaryParts, fmt.Sprintf("Missing: %d", missing))
@@ -1308,10 +1612,15 @@ func Monitor(cfg *config.Config, interval int, tempInterval int, controller stri
 		if failed > 0 {
 			summaryParts = append(summaryParts, fmt.Sprintf("Failed: %d", failed))
 		}
-		fmt.Print(strings.Join(summaryParts, " | "))
+		if unresponsive > 0 {
+			summaryParts = append(summaryParts, fmt.Sprintf("Unresponsive: %d", unresponsive))
+		}
+		if runaway > 0 {
+			summaryParts = append(summaryParts, fmt.Sprintf("Thermal runaway: %d", runaway))
+		}
+		summaryLine := strings.Join(summaryParts, " | ")
 
-		moveCursor(tempStatsRow, 1)
-		clearLine()
+		var tempStatsLine string
 		if len(temps) > 0 {
 			min, max, sum := temps[0], temps[0], 0
 			for _, t := range temps {
@@ -1324,28 +1633,78 @@ func Monitor(cfg *config.Config, interval int, tempInterval int, controller stri
 				sum += t
 			}
 			avg := sum / len(temps)
-			fmt.Printf("Temps: Min %d°C | Max %d°C | Avg %d°C", min, max, avg)
+			tempStatsLine = fmt.Sprintf("Temps: Min %d°C | Max %d°C | Avg %d°C", min, max, avg)
 		}
 
 		// Controller temperature
+		var ctrlLine string
 		if controller != "" {
-			moveCursor(ctrlTempRow, 1)
-			clearLine()
 			if state.controllerTemp != nil {
-				ctrlStatus := "🟢"
+				// Same thresholds as 'detail <controller>'
+				ctrlStatus := "🟢 OK"
 				if *state.controllerTemp >= 80 {
-					ctrlStatus = "🔴"
+					ctrlStatus = "🔴 HOT"
 				} else if *state.controllerTemp >= 70 {
-					ctrlStatus = "🟡"
+					ctrlStatus = "🟡 WARM"
 				}
-				fmt.Printf("Controller %s: %d°C %s", controller, *state.controllerTemp, ctrlStatus)
+				ctrlLine = fmt.Sprintf("Controller %s: %d°C %s", controller, *state.controllerTemp, ctrlStatus)
+			} else if !state.lastCtrlUpdate.IsZero() {
+				ctrlLine = fmt.Sprintf("Controller %s: temperature not reported", controller)
 			} else {
-				fmt.Printf("Controller %s: -", controller)
+				ctrlLine = fmt.Sprintf("Controller %s: -", controller)
 			}
 		}
 
-		// Move cursor to a safe spot (below all content)
-		moveCursor(ctrlTempRow+2, 1)
+		if plain {
+			// Append a self-contained block per refresh
+			fmt.Printf("=== %s ===\n", time.Now().Format("2006-01-02 15:04:05"))
+			fmt.Printf("%-10s %-8s %-10s %-8s %-10s %s\n", "DRIVE", "SLOT", "STATE", "TEMP", "TREND", "STATUS")
+			for _, row := range rows {
+				fmt.Println(row)
+			}
+			fmt.Println(summaryLine)
+			for _, line := range []string{tempStatsLine, ctrlLine} {
+				if line != "" {
+					fmt.Println(line)
+				}
+			}
+			fmt.Println()
+		} else {
+			// Update timestamp
+			moveCursor(infoRow, 1)
+			clearLine()
+			fmt.Printf("Refreshing every %ds (temps every %ds) | %s",
+				interval, tempInterval, time.Now().Format("2006-01-02 15:04:05"))
+
+			// Render drive rows (in-place updates)
+			for i, row := range rows {
+				moveCursor(tableDataStart+i, 1)
+				clearLine()
+				fmt.Print(row)
+			}
+
+			// Update summary section
+			moveCursor(footerRow, 1)
+			clearLine()
+			fmt.Print(strings.Repeat("-", 64))
+
+			moveCursor(summaryRow, 1)
+			clearLine()
+			fmt.Print(summaryLine)
+
+			moveCursor(tempStatsRow, 1)
+			clearLine()
+			fmt.Print(tempStatsLine)
+
+			if controller != "" {
+				moveCursor(ctrlTempRow, 1)
+				clearLine()
+				fmt.Print(ctrlLine)
+			}
+
+			// Move cursor to a safe spot (below all content)
+			moveCursor(ctrlTempRow+2, 1)
+		}
 
 		time.Sleep(time.Duration(interval) * time.Second)
 	}
@@ -0,0 +1,209 @@
+package drive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/hba"
+)
+
+// defaultHeatmapCols is used when no layout is configured for an enclosure
+// (most 3.5" chassis are four bays wide)
+const defaultHeatmapCols = 4
+
+// ANSI 256-color backgrounds for the temperature bands
+const (
+	heatCold     = 39  // blue
+	heatCool     = 34  // green
+	heatWarm     = 178 // yellow
+	heatHot      = 208 // orange
+	heatCritical = 196 // red
+	heatStandby  = 240 // grey
+	heatFailed   = 88  // dark red
+)
+
+// heatCell is a single bay in the rendered grid
+type heatCell struct {
+	slot  int
+	used  bool // a bay exists at this grid position
+	label string
+	color int // 0 = no background
+}
+
+// DefaultLayout returns a layout for an enclosure with no configured one:
+// defaultHeatmapCols wide, filled row by row
+func DefaultLayout(numSlots int) config.BayLayout {
+	if numSlots < 1 {
+		numSlots = defaultHeatmapCols
+	}
+	return config.BayLayout{
+		Rows: (numSlots + defaultHeatmapCols - 1) / defaultHeatmapCols,
+		Cols: defaultHeatmapCols,
+		Fill: "row",
+	}
+}
+
+// bayPosition returns the grid row/column of the index-th bay, or false if
+// it doesn't fit the layout
+func bayPosition(layout config.BayLayout, index int) (row, col int, ok bool) {
+	if index < 0 || index >= layout.Rows*layout.Cols {
+		return 0, 0, false
+	}
+	if layout.Fill == "column" {
+		row, col = index%layout.Rows, index/layout.Rows
+	} else {
+		row, col = index/layout.Cols, index%layout.Cols
+	}
+	if layout.BottomUp {
+		row = layout.Rows - 1 - row
+	}
+	return row, col, true
+}
+
+// heatColor returns the background color for a temperature
+func heatColor(temp int, th config.Thresholds) int {
+	switch {
+	case temp >= th.CriticalTemp:
+		return heatCritical
+	case temp >= th.WarningTemp:
+		return heatHot
+	case temp >= th.WarningTemp-10:
+		return heatWarm
+	case temp >= th.WarningTemp-20:
+		return heatCool
+	default:
+		return heatCold
+	}
+}
+
+// heatCellFor builds the cell for a slot from the drive in it (may be nil)
+func heatCellFor(slot int, d *DriveInfo, th config.Thresholds) heatCell {
+	cell := heatCell{slot: slot, used: true, label: "--"}
+	if d == nil {
+		return cell
+	}
+	switch d.State {
+	case "active":
+		if d.Temp != nil {
+			cell.label = fmt.Sprintf("%d°C", *d.Temp)
+			cell.color = heatColor(*d.Temp, th)
+		} else {
+			cell.label = "?"
+			cell.color = heatStandby
+		}
+	case "standby":
+		cell.label = "stby"
+		cell.color = heatStandby
+	default:
+		cell.label = "FAIL"
+		cell.color = heatFailed
+	}
+	return cell
+}
+
+// PrintHeatmap renders the enclosure's bays as a grid colored by drive
+// temperature, followed by a legend
+func PrintHeatmap(enc hba.EnclosureInfo, layout config.BayLayout, drives []DriveInfo, th config.Thresholds, color bool) {
+	if layout.Rows < 1 || layout.Cols < 1 {
+		layout = DefaultLayout(enc.NumSlots)
+	}
+
+	// Index the enclosure's drives by slot (enclosure and slot always paired)
+	bySlot := make(map[int]*DriveInfo)
+	for i := range drives {
+		d := &drives[i]
+		if d.Enclosure != nil && d.Slot != nil && *d.Enclosure == enc.ID {
+			bySlot[*d.Slot] = d
+		}
+	}
+
+	numSlots := enc.NumSlots
+	if numSlots == 0 {
+		for slot := range bySlot {
+			if slot-enc.StartSlot+1 > numSlots {
+				numSlots = slot - enc.StartSlot + 1
+			}
+		}
+	}
+
+	grid := make([][]heatCell, layout.Rows)
+	for r := range grid {
+		grid[r] = make([]heatCell, layout.Cols)
+	}
+
+	var overflow []int
+	for i := 0; i < numSlots; i++ {
+		slot := enc.StartSlot + i
+		row, col, ok := bayPosition(layout, i)
+		if !ok {
+			overflow = append(overflow, slot)
+			continue
+		}
+		grid[row][col] = heatCellFor(slot, bySlot[slot], th)
+	}
+
+	title := fmt.Sprintf("Enclosure %d", enc.ID)
+	if enc.Model != "" {
+		title += " (" + strings.TrimSpace(enc.Manufacturer+" "+enc.Model) + ")"
+	}
+	fmt.Printf("%s - %d bays, %dx%d\n\n", title, numSlots, layout.Rows, layout.Cols)
+
+	for _, row := range grid {
+		var line strings.Builder
+		for _, cell := range row {
+			line.WriteString(renderHeatCell(cell, color))
+			line.WriteString(" ")
+		}
+		fmt.Println(strings.TrimRight(line.String(), " "))
+	}
+
+	if len(overflow) > 0 {
+		fmt.Printf("\nWarning: %d slot(s) don't fit the %dx%d layout: %v\n", len(overflow), layout.Rows, layout.Cols, overflow)
+	}
+
+	if color {
+		fmt.Println()
+		printHeatLegend(th)
+	}
+}
+
+// renderHeatCell formats a cell as a fixed-width block
+func renderHeatCell(cell heatCell, color bool) string {
+	if !cell.used {
+		return strings.Repeat(" ", 10)
+	}
+	text := fmt.Sprintf(" %2d %-5s ", cell.slot, cell.label)
+	if !color {
+		return "[" + text[1:len(text)-1] + "]"
+	}
+	if cell.color == 0 {
+		return text
+	}
+	return fmt.Sprintf("\033[48;5;%dm\033[38;5;16m%s\033[0m", cell.color, text)
+}
+
+// printHeatLegend prints the temperature bands used by the heatmap
+func printHeatLegend(th config.Thresholds) {
+	bands := []struct {
+		color int
+		label string
+	}{
+		{heatCold, fmt.Sprintf("<%d°C", th.WarningTemp-20)},
+		{heatCool, fmt.Sprintf("%d-%d°C", th.WarningTemp-20, th.WarningTemp-11)},
+		{heatWarm, fmt.Sprintf("%d-%d°C", th.WarningTemp-10, th.WarningTemp-1)},
+		{heatHot, fmt.Sprintf("%d-%d°C (warning)", th.WarningTemp, th.CriticalTemp-1)},
+		{heatCritical, fmt.Sprintf(">=%d°C (critical)", th.CriticalTemp)},
+		{heatStandby, "standby"},
+		{heatFailed, "failed/missing"},
+	}
+
+	fmt.Print("Legend: ")
+	for i, b := range bands {
+		if i > 0 {
+			fmt.Print("  ")
+		}
+		fmt.Printf("\033[48;5;%dm  \033[0m %s", b.color, b.label)
+	}
+	fmt.Println("  -- empty")
+}
@@ -0,0 +1,105 @@
+package drive
+
+import (
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/collector"
+	"github.com/sigreer/jbodgod/internal/wwn"
+)
+
+// mergeMultipath folds drives seen through more than one path (dual-ported
+// SAS with dm-multipath: /dev/sdc and /dev/sdk are the same disk) into one
+// entry. Paths are matched by their dm-multipath map, then WWN, then
+// serial. The first working path in config order supplies the data; Device
+// becomes the multipath map when there is one and Paths lists the SCSI
+// paths.
+func mergeMultipath(drives []DriveInfo) []DriveInfo {
+	maps := collector.CollectMultipathDevices()
+
+	merged := make([]DriveInfo, 0, len(drives))
+	index := make(map[string]int) // identity key -> position in merged
+	for _, d := range drives {
+		mp := maps[filepath.Base(resolveDevicePath(d.Device))]
+		keys := identityKeys(d, mp)
+
+		pos := -1
+		for _, k := range keys {
+			if i, ok := index[k]; ok {
+				pos = i
+				break
+			}
+		}
+		if pos < 0 {
+			pos = len(merged)
+			merged = append(merged, d)
+		}
+		for _, k := range keys {
+			index[k] = pos
+		}
+
+		m := &merged[pos]
+		// A failed path mustn't hide a working one
+		if pathUsable(d.State) && !pathUsable(m.State) {
+			paths := m.Paths
+			*m = d
+			m.Paths = paths
+		}
+		m.Paths = appendPath(m.Paths, d.Device)
+		if mp != nil {
+			m.Device = mp.Device
+		}
+	}
+
+	// Single-path drives keep no path list
+	for i := range merged {
+		if len(merged[i].Paths) == 1 && merged[i].Device == merged[i].Paths[0] {
+			merged[i].Paths = nil
+		}
+		sort.Strings(merged[i].Paths)
+	}
+	return merged
+}
+
+// pathUsable reports whether a path's state means the drive answers on it
+func pathUsable(state string) bool {
+	return state == "active" || state == "standby"
+}
+
+// identityKeys returns the keys a drive's paths share
+func identityKeys(d DriveInfo, mp *collector.MultipathDevice) []string {
+	var keys []string
+	if mp != nil {
+		keys = append(keys, "mpath:"+mp.Name)
+	}
+	if d.WWN != nil && *d.WWN != "" {
+		keys = append(keys, "wwn:"+wwn.Normalize(*d.WWN))
+	}
+	for _, serial := range []*string{d.Serial, d.SerialVPD} {
+		if serial != nil && *serial != "" {
+			keys = append(keys, "serial:"+strings.ToUpper(*serial))
+		}
+	}
+	return keys
+}
+
+// appendPath adds a path if it isn't already listed
+func appendPath(paths []string, path string) []string {
+	for _, p := range paths {
+		if p == path {
+			return paths
+		}
+	}
+	return append(paths, path)
+}
+
+// ProbeDevice returns a path smartctl can open for the drive: the first
+// SCSI path of a multipath drive, whose map device doesn't pass SMART
+// commands through
+func (d DriveInfo) ProbeDevice() string {
+	if strings.HasPrefix(d.Device, "/dev/mapper/") && len(d.Paths) > 0 {
+		return d.Paths[0]
+	}
+	return d.Device
+}
@@ -0,0 +1,77 @@
+package drive
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/collector"
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// SelfTestResult is the newest entry in a drive's SMART self-test log
+type SelfTestResult struct {
+	Description string // e.g. "Extended offline" (ATA), "Background long" (SCSI)
+	Status      string // e.g. "Completed without error"
+	InProgress  bool
+	Passed      bool
+	Aborted     bool   // interrupted or aborted; neither passed nor failed
+	Raw         string // the log line
+}
+
+// StartLongSelfTest starts a SMART extended (long) self-test. The test runs
+// in the drive's background; smartctl returns immediately.
+func StartLongSelfTest(device string) error {
+	out, err := privexec.Run("smartctl", "-t", "long", device)
+	if err != nil {
+		return fmt.Errorf("failed to start self-test on %s: %s: %w", device, strings.TrimSpace(lastLine(string(out))), err)
+	}
+	return nil
+}
+
+// GetSelfTestResult reads the newest self-test log entry for a device.
+// Returns nil if no self-tests have been logged. Drives in standby are not
+// woken; reading one returns an error.
+func GetSelfTestResult(device string) (*SelfTestResult, error) {
+	out, err := privexec.Run("smartctl", "-n", "standby", "-l", "selftest", device)
+	if strings.Contains(string(out), "STANDBY") || strings.Contains(string(out), "NOT READY") {
+		return nil, fmt.Errorf("%s is in standby", device)
+	}
+	// smartctl sets bit 6 of its exit status when the log contains errors,
+	// so only give up if there's no log to parse
+	result := parseSelfTestLog(string(out))
+	if result == nil && err != nil {
+		return nil, fmt.Errorf("failed to read self-test log for %s: %w", device, err)
+	}
+	return result, nil
+}
+
+// parseSelfTestLog parses the newest entry of 'smartctl -l selftest' output
+func parseSelfTestLog(output string) *SelfTestResult {
+	entries := collector.ParseSelfTestLog(output)
+	if len(entries) == 0 || entries[0].Num != 1 {
+		return nil
+	}
+
+	r := &SelfTestResult{
+		Description: entries[0].Type,
+		Status:      entries[0].Status,
+		Raw:         entries[0].Raw,
+	}
+
+	status := strings.ToLower(r.Status)
+	switch {
+	case strings.Contains(status, "in progress"):
+		r.InProgress = true
+	case status == "completed without error" || status == "completed":
+		r.Passed = true
+	case strings.Contains(status, "abort") || strings.Contains(status, "interrupt"):
+		r.Aborted = true
+	}
+	return r
+}
+
+// lastLine returns the last non-empty line of command output
+func lastLine(out string) string {
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	return lines[len(lines)-1]
+}
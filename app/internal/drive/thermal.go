@@ -0,0 +1,166 @@
+package drive
+
+import (
+	"time"
+)
+
+// tempHistorySize is the number of samples kept per drive for trend analysis.
+// History never grows past this, so a monitor left running for days uses the
+// same memory as one started a minute ago.
+const tempHistorySize = 10
+
+// tempSample is a single timestamped temperature reading
+type tempSample struct {
+	Temp int
+	At   time.Time
+}
+
+// tempHistory is a fixed-size ring buffer of temperature samples
+type tempHistory struct {
+	samples [tempHistorySize]tempSample
+	next    int
+	count   int
+}
+
+// Add records a sample, overwriting the oldest once the buffer is full
+func (h *tempHistory) Add(temp int, at time.Time) {
+	h.samples[h.next] = tempSample{Temp: temp, At: at}
+	h.next = (h.next + 1) % tempHistorySize
+	if h.count < tempHistorySize {
+		h.count++
+	}
+}
+
+// Samples returns the buffered samples from oldest to newest
+func (h *tempHistory) Samples() []tempSample {
+	out := make([]tempSample, 0, h.count)
+	start := (h.next - h.count + tempHistorySize) % tempHistorySize
+	for i := 0; i < h.count; i++ {
+		out = append(out, h.samples[(start+i)%tempHistorySize])
+	}
+	return out
+}
+
+// Slope returns the least-squares temperature trend in °C per minute.
+// Returns false if there are fewer than two samples or no time has elapsed.
+func (h *tempHistory) Slope() (float64, bool) {
+	samples := h.Samples()
+	if len(samples) < 2 {
+		return 0, false
+	}
+
+	origin := samples[0].At
+	var sumX, sumY, sumXY, sumXX float64
+	for _, s := range samples {
+		x := s.At.Sub(origin).Minutes()
+		y := float64(s.Temp)
+		sumX += x
+		sumY += y
+		sumXY += x * y
+		sumXX += x * x
+	}
+
+	n := float64(len(samples))
+	denom := n*sumXX - sumX*sumX
+	if denom == 0 {
+		return 0, false
+	}
+	return (n*sumXY - sumX*sumY) / denom, true
+}
+
+// thermalTracker watches per-drive temperature trends for cooling failures.
+// It holds one fixed-size history per device; callers Reset devices that stop
+// reporting so the maps only ever contain currently monitored drives.
+type thermalTracker struct {
+	rate      float64 // °C/minute considered a runaway
+	sustained int     // consecutive readings above rate before warning
+	history   map[string]*tempHistory
+	exceeded  map[string]int
+}
+
+// newThermalTracker creates a tracker with the given rate and sample thresholds
+func newThermalTracker(rate float64, sustained int) *thermalTracker {
+	if sustained < 1 {
+		sustained = 1
+	}
+	return &thermalTracker{
+		rate:      rate,
+		sustained: sustained,
+		history:   make(map[string]*tempHistory),
+		exceeded:  make(map[string]int),
+	}
+}
+
+// Record adds a reading for a device and returns the current trend and
+// whether the drive is in thermal runaway
+func (t *thermalTracker) Record(device string, temp int, at time.Time) (float64, bool) {
+	h, ok := t.history[device]
+	if !ok {
+		h = &tempHistory{}
+		t.history[device] = h
+	}
+	h.Add(temp, at)
+
+	slope, ok := h.Slope()
+	if !ok || t.rate <= 0 || slope < t.rate {
+		t.exceeded[device] = 0
+		return slope, false
+	}
+
+	// Saturate at the threshold so a drive that stays hot for a long run
+	// can't overflow the counter
+	if t.exceeded[device] < t.sustained {
+		t.exceeded[device]++
+	}
+	return slope, t.exceeded[device] >= t.sustained
+}
+
+// Reset clears the history for a device (e.g. after it spins down)
+func (t *thermalTracker) Reset(device string) {
+	delete(t.history, device)
+	delete(t.exceeded, device)
+}
+
+// Samples returns a device's buffered readings from oldest to newest
+func (t *thermalTracker) Samples(device string) []tempSample {
+	h, ok := t.history[device]
+	if !ok {
+		return nil
+	}
+	return h.Samples()
+}
+
+// sparkBlocks are the sparkline levels, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// sparklineMinSpan is the smallest temperature range (°C) a sparkline is
+// scaled to, so a drive wobbling by a degree draws as flat, not as a cliff
+const sparklineMinSpan = 4
+
+// sparkline renders samples as one block per reading, scaled to their range
+func sparkline(samples []tempSample) string {
+	if len(samples) == 0 {
+		return ""
+	}
+	lo, hi := samples[0].Temp, samples[0].Temp
+	for _, s := range samples {
+		if s.Temp < lo {
+			lo = s.Temp
+		}
+		if s.Temp > hi {
+			hi = s.Temp
+		}
+	}
+	span := hi - lo
+	if span < sparklineMinSpan {
+		// Centre the readings in the minimum span
+		lo -= (sparklineMinSpan - span) / 2
+		span = sparklineMinSpan
+	}
+
+	out := make([]rune, len(samples))
+	for i, s := range samples {
+		out[i] = sparkBlocks[(s.Temp-lo)*(len(sparkBlocks)-1)/span]
+	}
+	return string(out)
+}
@@ -0,0 +1,84 @@
+package drive
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestThermalTrackerBounded simulates a monitor left running for a week at a
+// 5s refresh and checks the per-drive history never grows past its cap
+func TestThermalTrackerBounded(t *testing.T) {
+	const (
+		drives = 24
+		cycles = 7 * 24 * 60 * 12
+	)
+	tracker := newThermalTracker(0.5, 3)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	for cycle := 0; cycle < cycles; cycle++ {
+		at := start.Add(time.Duration(cycle) * 5 * time.Second)
+		for d := 0; d < drives; d++ {
+			device := fmt.Sprintf("/dev/sd%c", 'a'+d)
+			// Every fourth drive spins down for a while each hour
+			if d%4 == 0 && cycle%720 >= 600 {
+				tracker.Reset(device)
+				continue
+			}
+			// Hot drives keep climbing, the rest wobble around 35°C
+			temp := 35 + cycle%3
+			if d%5 == 0 {
+				temp = 30 + cycle/10
+			}
+			tracker.Record(device, temp, at)
+		}
+
+		if len(tracker.history) > drives || len(tracker.exceeded) > drives {
+			t.Fatalf("cycle %d: tracking %d histories and %d counters for %d drives",
+				cycle, len(tracker.history), len(tracker.exceeded), drives)
+		}
+	}
+
+	last := start.Add(time.Duration(cycles-1) * 5 * time.Second)
+	for device, h := range tracker.history {
+		samples := h.Samples()
+		if len(samples) > tempHistorySize {
+			t.Errorf("%s: %d samples, want at most %d", device, len(samples), tempHistorySize)
+		}
+		if len(samples) == 0 || !samples[len(samples)-1].At.Equal(last) {
+			t.Errorf("%s: newest sample is not the last reading", device)
+		}
+		for i := 1; i < len(samples); i++ {
+			if !samples[i].At.After(samples[i-1].At) {
+				t.Errorf("%s: samples out of order at %d", device, i)
+			}
+		}
+		if n := len([]rune(sparkline(samples))); n != len(samples) {
+			t.Errorf("%s: sparkline has %d blocks for %d samples", device, n, len(samples))
+		}
+		if tracker.exceeded[device] > tracker.sustained {
+			t.Errorf("%s: runaway counter %d past %d", device, tracker.exceeded[device], tracker.sustained)
+		}
+	}
+}
+
+func TestTempHistoryWraps(t *testing.T) {
+	var h tempHistory
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3*tempHistorySize+3; i++ {
+		h.Add(i, start.Add(time.Duration(i)*time.Minute))
+
+		samples := h.Samples()
+		want := i + 1
+		if want > tempHistorySize {
+			want = tempHistorySize
+		}
+		if len(samples) != want {
+			t.Fatalf("after %d adds: %d samples, want %d", i+1, len(samples), want)
+		}
+		if samples[0].Temp != i+1-want || samples[len(samples)-1].Temp != i {
+			t.Fatalf("after %d adds: samples %d..%d, want %d..%d",
+				i+1, samples[0].Temp, samples[len(samples)-1].Temp, i+1-want, i)
+		}
+	}
+}
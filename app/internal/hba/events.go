@@ -0,0 +1,193 @@
+package hba
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// GetControllerEvents returns up to count of a controller's most recent
+// events, oldest first. MegaRAID controllers keep an event log that storcli
+// reads; IT-mode HBAs don't, so for those the mpt2sas/mpt3sas kernel
+// messages of the controller's IOC are used instead. Events aren't cached.
+func GetControllerEvents(controllerID string, count int) ([]ControllerEvent, error) {
+	if count <= 0 {
+		count = 50
+	}
+	if events, err := fetchStorcliEvents(controllerID, count); err == nil && len(events) > 0 {
+		return events, nil
+	}
+
+	ctrl, _, _, err := GetFullControllerInfo(controllerID, false)
+	if err != nil {
+		return nil, err
+	}
+	ioc, ok := findIOCNumber(ctrl.SASAddress)
+	if !ok {
+		return nil, fmt.Errorf("no event log for %s (needs storcli or an mpt2sas/mpt3sas host)", controllerID)
+	}
+	return fetchKernelEvents(ioc, count)
+}
+
+// fetchStorcliEvents runs 'storcli /cX show events type=latest=N'. The
+// event log has no JSON form, so the text blocks are parsed.
+func fetchStorcliEvents(controllerID string, count int) ([]ControllerEvent, error) {
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", "/"+controllerID, "show", "events", "type=latest="+strconv.Itoa(count))
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storcli failed: %w", err)
+	}
+	events := parseStorcliEvents(out)
+	if len(events) == 0 {
+		return nil, errors.New("storcli returned no events")
+	}
+	return events, nil
+}
+
+// storcliEventClasses maps storcli's event class numbers to severities
+var storcliEventClasses = map[string]string{
+	"-2": "debug",
+	"-1": "progress",
+	"0":  "info",
+	"1":  "warning",
+	"2":  "critical",
+	"3":  "fatal",
+	"4":  "dead",
+}
+
+// parseStorcliEvents parses the event blocks of 'storcli /cX show events',
+// each starting at a "seqNum:" line:
+//
+//	seqNum: 0x00004a1b
+//	Time: Mon Jan  8 10:12:33 2024
+//	Code: 0x0000005b
+//	Class: 0
+//	Locale: 0x02
+//	Event Description: Inserted: PD 0a(e0x09/s10)
+func parseStorcliEvents(data []byte) []ControllerEvent {
+	var events []ControllerEvent
+	var cur *ControllerEvent
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		key, val, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		val = strings.TrimSpace(val)
+
+		if strings.EqualFold(key, "seqNum") {
+			events = append(events, ControllerEvent{Source: "storcli"})
+			cur = &events[len(events)-1]
+			if n, err := strconv.ParseInt(val, 0, 64); err == nil {
+				cur.Seq = n
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		switch strings.ToLower(key) {
+		case "time":
+			cur.Time = val
+		case "code":
+			cur.Code = val
+		case "class":
+			if sev, ok := storcliEventClasses[val]; ok {
+				cur.Severity = sev
+			} else {
+				cur.Severity = val
+			}
+		case "event description":
+			cur.Description = val
+		}
+	}
+
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	return events
+}
+
+// findIOCNumber finds the mpt2sas/mpt3sas IOC number of the controller with
+// the given SAS address: its host PHYs (phy-H:N) name the SCSI host, whose
+// unique_id the driver sets to the IOC number used in "mpt3sas_cmN:"
+func findIOCNumber(sasAddress string) (int, bool) {
+	want := normalizeSASAddress(sasAddress)
+	if want == "" {
+		return 0, false
+	}
+
+	dirs, _ := filepath.Glob("/sys/class/sas_phy/phy-*")
+	for _, dir := range dirs {
+		name := strings.TrimPrefix(filepath.Base(dir), "phy-")
+		if strings.Count(name, ":") != 1 {
+			continue
+		}
+		if normalizeSASAddress(readSysfs(dir, "sas_address")) != want {
+			continue
+		}
+		host, _, _ := strings.Cut(name, ":")
+		if n, err := strconv.Atoi(readSysfs("/sys/class/scsi_host/host"+host, "unique_id")); err == nil {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// kernelEventRe matches a journalctl short-iso line from an mpt2sas/mpt3sas
+// IOC, capturing the timestamp, IOC number and message
+var kernelEventRe = regexp.MustCompile(`^(\S+)\s+\S+\s+kernel:\s+(?:\[[^\]]*\]\s+)?mpt[23]sas_cm(\d+):\s+(.*)$`)
+
+// fetchKernelEvents returns the last count kernel messages of an IOC from
+// the current boot's journal
+func fetchKernelEvents(ioc, count int) ([]ControllerEvent, error) {
+	out, err := privexec.RunSudo("journalctl", "-k", "-b", "--no-pager", "-o", "short-iso")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read kernel log: %w", err)
+	}
+	events := parseKernelEvents(out, ioc)
+	if len(events) > count {
+		events = events[len(events)-count:]
+	}
+	return events, nil
+}
+
+// parseKernelEvents picks an IOC's messages out of journalctl output. Lines
+// that mention a fault, reset or removal are flagged as warnings.
+func parseKernelEvents(data []byte, ioc int) []ControllerEvent {
+	want := strconv.Itoa(ioc)
+	var events []ControllerEvent
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		m := kernelEventRe.FindStringSubmatch(scanner.Text())
+		if m == nil || m[2] != want {
+			continue
+		}
+		severity := "info"
+		lower := strings.ToLower(m[3])
+		for _, word := range []string{"fault", "reset", "removing", "removed", "timeout", "log_info", "error"} {
+			if strings.Contains(lower, word) {
+				severity = "warning"
+				break
+			}
+		}
+		events = append(events, ControllerEvent{
+			Seq:         int64(len(events) + 1),
+			Time:        m[1],
+			Severity:    severity,
+			Description: m[3],
+			Source:      "kernel",
+		})
+	}
+	return events
+}
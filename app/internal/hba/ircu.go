@@ -0,0 +1,98 @@
+package hba
+
+import (
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// ircuTools are the LSI/Broadcom IR utilities in order of preference.
+// sas2ircu (SAS2008/2308 era HBAs) produces nearly identical output to
+// sas3ircu, so both share the same parser.
+var ircuTools = []string{"sas3ircu", "sas2ircu"}
+
+// ircuController is a controller as its IR utility numbers it
+type ircuController struct {
+	tool  string
+	index int // the tool's own controller number
+}
+
+var (
+	ircuOnce        sync.Once
+	ircuControllers map[int]ircuController // controller number -> tool and its index
+	ircuOrder       []int
+)
+
+// detectIrcu probes each installed IR utility once and records which tool
+// manages each controller. Both tools number their controllers from 0, so a
+// controller keeps its tool's index unless another tool already reported it;
+// then it takes the next free number (a SAS2 controller 0 next to a SAS3
+// controller 0 becomes controller 1).
+func detectIrcu() {
+	ircuOnce.Do(func() {
+		ircuControllers = make(map[int]ircuController)
+
+		re := regexp.MustCompile(`^\s*(\d+)\s+`)
+		for _, tool := range ircuTools {
+			if _, err := exec.LookPath(tool); err != nil {
+				continue
+			}
+			out, err := privexec.Retry(tool, func() ([]byte, error) {
+				return privexec.RunSudo(tool, "list")
+			})
+			if err != nil {
+				continue
+			}
+			seen := make(map[int]bool)
+			for _, line := range strings.Split(string(out), "\n") {
+				matches := re.FindStringSubmatch(line)
+				if len(matches) < 2 {
+					continue
+				}
+				index, err := strconv.Atoi(matches[1])
+				if err != nil || seen[index] {
+					continue
+				}
+				seen[index] = true
+				num := index
+				for {
+					if _, taken := ircuControllers[num]; !taken {
+						break
+					}
+					num++
+				}
+				ircuControllers[num] = ircuController{tool: tool, index: index}
+				ircuOrder = append(ircuOrder, num)
+			}
+		}
+	})
+}
+
+// ircuFor returns the tool managing a controller and the tool's own number
+// for it. Falls back to sas3ircu with the same number if none was detected.
+func ircuFor(controllerNum int) ircuController {
+	detectIrcu()
+	if c, ok := ircuControllers[controllerNum]; ok {
+		return c
+	}
+	return ircuController{tool: ircuTools[0], index: controllerNum}
+}
+
+// IrcuTool returns the IR utility (sas3ircu or sas2ircu) that manages the
+// given controller. Falls back to sas3ircu if none was detected.
+func IrcuTool(controllerNum int) string {
+	return ircuFor(controllerNum).tool
+}
+
+// IrcuDisplay runs '<tool> <n> display' for the controller, with the tool
+// detected for it and that tool's own controller number
+func IrcuDisplay(controllerNum int) ([]byte, error) {
+	c := ircuFor(controllerNum)
+	return privexec.Retry(c.tool, func() ([]byte, error) {
+		return privexec.RunSudo(c.tool, strconv.Itoa(c.index), "display")
+	})
+}
@@ -0,0 +1,177 @@
+package hba
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// GetAllControllerData fetches enclosures and devices from every controller
+// and merges the duplicates a dual-ported shelf produces when it is cabled
+// to more than one HBA. Each merged entry lists the controllers it is
+// reachable through in Paths. Data from controllers that answered is
+// returned even if others failed; the error describes the failures.
+func GetAllControllerData(forceRefresh bool) ([]ControllerInfo, []EnclosureInfo, []PhysicalDevice, error) {
+	var controllers []ControllerInfo
+	var enclosures []EnclosureInfo
+	var devices []PhysicalDevice
+	var errs []error
+
+	for _, ctrlNum := range ListControllers() {
+		ctrlID := fmt.Sprintf("c%d", ctrlNum)
+		ctrl, encs, devs, err := GetFullControllerInfo(ctrlID, forceRefresh)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("controller %s: %w", ctrlID, err))
+			continue
+		}
+		if ctrl != nil {
+			controllers = append(controllers, *ctrl)
+		}
+
+		// Copy before tagging so cached slices aren't modified
+		for _, e := range encs {
+			e.Paths = []string{ctrlID}
+			enclosures = append(enclosures, e)
+		}
+		for _, d := range devs {
+			d.Paths = []string{ctrlID}
+			devices = append(devices, d)
+		}
+	}
+
+	return controllers, MergeEnclosures(enclosures), MergeDevices(devices), errors.Join(errs...)
+}
+
+// MergeEnclosures collapses enclosures seen through several controllers into
+// one entry, matched by logical ID (falling back to serial). The first
+// controller's view is kept and the other paths are appended.
+func MergeEnclosures(enclosures []EnclosureInfo) []EnclosureInfo {
+	var merged []EnclosureInfo
+	index := make(map[string]int)
+
+	for _, e := range enclosures {
+		key := enclosureKey(e)
+		if i, ok := index[key]; ok && key != "" {
+			merged[i].Paths = appendPaths(merged[i].Paths, e.Paths)
+			continue
+		}
+		if key != "" {
+			index[key] = len(merged)
+		}
+		merged = append(merged, e)
+	}
+	return merged
+}
+
+// MergeDevices collapses drives seen through several controllers into one
+// entry per physical drive, matched by serial. The ports of a dual-ported
+// drive have different SAS addresses, so the address is only used when a
+// drive reports no serial. Two drives sharing a serial on the same controller
+// are different drives (duplicate serials) and are kept apart.
+func MergeDevices(devices []PhysicalDevice) []PhysicalDevice {
+	var merged []PhysicalDevice
+	index := make(map[string]int)
+
+	for _, d := range devices {
+		key := deviceKey(d)
+		if i, ok := index[key]; ok && key != "" {
+			if sharesPath(merged[i].Paths, d.Paths) {
+				merged = append(merged, d)
+				continue
+			}
+			merged[i].Paths = appendPaths(merged[i].Paths, d.Paths)
+			continue
+		}
+		if key != "" {
+			index[key] = len(merged)
+		}
+		merged = append(merged, d)
+	}
+	return merged
+}
+
+// IsMultipath reports whether a device is reachable through more than one
+// controller
+func (d *PhysicalDevice) IsMultipath() bool {
+	return len(d.Paths) > 1
+}
+
+// enclosureKey identifies a physical enclosure across controllers
+func enclosureKey(e EnclosureInfo) string {
+	if id := strings.ToLower(strings.TrimSpace(e.LogicalID)); id != "" {
+		return "lid:" + id
+	}
+	if e.Serial != "" {
+		return "serial:" + strings.ToUpper(e.Serial)
+	}
+	return ""
+}
+
+// deviceKey identifies a physical drive across controllers
+func deviceKey(d PhysicalDevice) string {
+	if d.SerialVPD != "" {
+		return "serial:" + strings.ToUpper(d.SerialVPD)
+	}
+	if d.Serial != "" {
+		return "serial:" + strings.ToUpper(d.Serial)
+	}
+	if d.SASAddress != "" {
+		return "sas:" + strings.ToLower(strings.ReplaceAll(d.SASAddress, "-", ""))
+	}
+	return ""
+}
+
+// DuplicateSerials returns the devices that share a serial with another
+// device in a different slot, keyed by upper-case serial
+func DuplicateSerials(devices []PhysicalDevice) map[string][]PhysicalDevice {
+	bySerial := make(map[string][]PhysicalDevice)
+	for _, d := range devices {
+		serial := d.Serial
+		if d.SerialVPD != "" {
+			serial = d.SerialVPD
+		}
+		if serial = strings.ToUpper(strings.TrimSpace(serial)); serial != "" {
+			bySerial[serial] = append(bySerial[serial], d)
+		}
+	}
+
+	dups := make(map[string][]PhysicalDevice)
+	for serial, devs := range bySerial {
+		for _, d := range devs[1:] {
+			if d.EnclosureID != devs[0].EnclosureID || d.Slot != devs[0].Slot || sharesPath(d.Paths, devs[0].Paths) {
+				dups[serial] = devs
+				break
+			}
+		}
+	}
+	return dups
+}
+
+// sharesPath reports whether two path lists have a controller in common
+func sharesPath(a, b []string) bool {
+	for _, p := range a {
+		for _, q := range b {
+			if p == q {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// appendPaths adds paths not already present
+func appendPaths(paths, more []string) []string {
+	for _, p := range more {
+		found := false
+		for _, existing := range paths {
+			if existing == p {
+				found = true
+				break
+			}
+		}
+		if !found {
+			paths = append(paths, p)
+		}
+	}
+	return paths
+}
@@ -0,0 +1,131 @@
+package hba
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeEnclosures(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    []EnclosureInfo
+		paths [][]string // Paths of each merged enclosure, in order
+	}{
+		{
+			name: "same logical ID on two controllers",
+			in: []EnclosureInfo{
+				{ID: 2, LogicalID: "500605B0:0A1B2C3D", Paths: []string{"c0"}},
+				{ID: 3, LogicalID: "500605b0:0a1b2c3d", Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0", "c1"}},
+		},
+		{
+			name: "serial when there is no logical ID",
+			in: []EnclosureInfo{
+				{ID: 2, Serial: "SHELF01", Paths: []string{"c0"}},
+				{ID: 2, Serial: "shelf01", Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0", "c1"}},
+		},
+		{
+			name: "different enclosures",
+			in: []EnclosureInfo{
+				{ID: 2, LogicalID: "500605b0:0a1b2c3d", Paths: []string{"c0"}},
+				{ID: 2, LogicalID: "500605b0:99999999", Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0"}, {"c1"}},
+		},
+		{
+			name: "no identity is never merged",
+			in: []EnclosureInfo{
+				{ID: 2, Paths: []string{"c0"}},
+				{ID: 2, Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0"}, {"c1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeEnclosures(tt.in)
+			var got [][]string
+			for _, e := range merged {
+				got = append(got, e.Paths)
+			}
+			if !reflect.DeepEqual(got, tt.paths) {
+				t.Errorf("paths = %v, want %v", got, tt.paths)
+			}
+			// The first controller's view is kept
+			if len(merged) > 0 && merged[0].ID != tt.in[0].ID {
+				t.Errorf("kept enclosure %d, want %d", merged[0].ID, tt.in[0].ID)
+			}
+		})
+	}
+}
+
+func TestMergeDevices(t *testing.T) {
+	tests := []struct {
+		name  string
+		in    []PhysicalDevice
+		paths [][]string // Paths of each merged device, in order
+	}{
+		{
+			name: "dual-ported drive on two controllers",
+			in: []PhysicalDevice{
+				{EnclosureID: 2, Slot: 5, Serial: "ZA1DKJT7", SASAddress: "5000c500-a1b2c3d5", Paths: []string{"c0"}},
+				{EnclosureID: 3, Slot: 5, Serial: "za1dkjt7", SASAddress: "5000c500-a1b2c3d6", Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0", "c1"}},
+		},
+		{
+			name: "VPD serial preferred over the HBA serial",
+			in: []PhysicalDevice{
+				{EnclosureID: 2, Slot: 1, Serial: "ZA1DKJT7", SerialVPD: "ZA1DKJT70000C9", Paths: []string{"c0"}},
+				{EnclosureID: 3, Slot: 1, Serial: "ZA1DKJT7-X", SerialVPD: "ZA1DKJT70000C9", Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0", "c1"}},
+		},
+		{
+			name: "SAS address when there is no serial",
+			in: []PhysicalDevice{
+				{EnclosureID: 2, Slot: 3, SASAddress: "5000C500-A1B2C3D5", Paths: []string{"c0"}},
+				{EnclosureID: 3, Slot: 3, SASAddress: "5000c500a1b2c3d5", Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0", "c1"}},
+		},
+		{
+			name: "duplicate serial on one controller stays apart",
+			in: []PhysicalDevice{
+				{EnclosureID: 2, Slot: 4, Serial: "CLONE01", Paths: []string{"c0"}},
+				{EnclosureID: 2, Slot: 9, Serial: "CLONE01", Paths: []string{"c0"}},
+			},
+			paths: [][]string{{"c0"}, {"c0"}},
+		},
+		{
+			name: "three drives over two controllers",
+			in: []PhysicalDevice{
+				{EnclosureID: 2, Slot: 0, Serial: "A", Paths: []string{"c0"}},
+				{EnclosureID: 2, Slot: 1, Serial: "B", Paths: []string{"c0"}},
+				{EnclosureID: 3, Slot: 0, Serial: "A", Paths: []string{"c1"}},
+				{EnclosureID: 3, Slot: 2, Serial: "C", Paths: []string{"c1"}},
+			},
+			paths: [][]string{{"c0", "c1"}, {"c0"}, {"c1"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged := MergeDevices(tt.in)
+			var got [][]string
+			for _, d := range merged {
+				got = append(got, d.Paths)
+			}
+			if !reflect.DeepEqual(got, tt.paths) {
+				t.Errorf("paths = %v, want %v", got, tt.paths)
+			}
+			if len(merged) > 0 && merged[0].EnclosureID != tt.in[0].EnclosureID {
+				t.Errorf("kept enclosure %d, want %d", merged[0].EnclosureID, tt.in[0].EnclosureID)
+			}
+		})
+	}
+}
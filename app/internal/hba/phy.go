@@ -0,0 +1,215 @@
+package hba
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// GetControllerPhys returns a controller's info with the link rate and error
+// counters of its PHYs. storcli is used when it manages the controller;
+// otherwise the counters come from the kernel's sas_phy entries (mpt3sas),
+// matched to the controller by SAS address. Counters aren't cached: they're
+// set on a copy, so they never reach the cached (and persisted) controller
+// info.
+func GetControllerPhys(controllerID string) (*ControllerInfo, error) {
+	ctrl, _, _, err := GetFullControllerInfo(controllerID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	phys, err := fetchStorcliPhys(controllerID)
+	if err != nil || len(phys) == 0 {
+		phys = readSysfsPhys(ctrl.SASAddress)
+	}
+	if len(phys) == 0 {
+		return nil, fmt.Errorf("no PHY information for %s (needs storcli or the sas_phy sysfs class)", controllerID)
+	}
+
+	info := *ctrl
+	info.Phys = phys
+	return &info, nil
+}
+
+// storcliPhyJSON is the envelope of storcli's JSON output
+type storcliPhyJSON struct {
+	Controllers []struct {
+		CommandStatus struct {
+			Status      string `json:"Status"`
+			Description string `json:"Description"`
+		} `json:"Command Status"`
+		ResponseData map[string]json.RawMessage `json:"Response Data"`
+	} `json:"Controllers"`
+}
+
+// fetchStorcliPhys combines 'storcli /cX show phyerrorcounters J' with the
+// link speeds from 'storcli /cX/pall show J'
+func fetchStorcliPhys(controllerID string) ([]PhyInfo, error) {
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", "/"+controllerID, "show", "phyerrorcounters", "J")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("storcli failed: %w", err)
+	}
+	phys, err := parseStorcliPhyRows(out)
+	if err != nil {
+		return nil, err
+	}
+
+	// Link speeds are optional; older storcli versions lack 'pall'
+	out, err = privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", "/"+controllerID+"/pall", "show", "J")
+	})
+	if err == nil {
+		if status, err := parseStorcliPhyRows(out); err == nil {
+			rates := make(map[int]string, len(status))
+			for _, p := range status {
+				rates[p.ID] = p.LinkRate
+			}
+			for i := range phys {
+				if phys[i].LinkRate == "" {
+					phys[i].LinkRate = rates[phys[i].ID]
+				}
+			}
+		}
+	}
+
+	return phys, nil
+}
+
+// parseStorcliPhyRows parses the per-PHY rows of storcli JSON output. Column
+// names vary between storcli versions, so they're matched loosely.
+func parseStorcliPhyRows(data []byte) ([]PhyInfo, error) {
+	var resp storcliPhyJSON
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse storcli JSON: %w", err)
+	}
+	if len(resp.Controllers) == 0 {
+		return nil, errors.New("storcli JSON has no controllers")
+	}
+	ctrl := resp.Controllers[0]
+	if !strings.EqualFold(ctrl.CommandStatus.Status, "Success") {
+		return nil, fmt.Errorf("storcli: %s", ctrl.CommandStatus.Description)
+	}
+
+	var phys []PhyInfo
+	for _, raw := range ctrl.ResponseData {
+		// UseNumber keeps large counters from printing as floats
+		var rows []map[string]interface{}
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if dec.Decode(&rows) != nil {
+			continue
+		}
+		for _, row := range rows {
+			if p, ok := phyFromRow(row); ok {
+				phys = append(phys, p)
+			}
+		}
+	}
+	sort.Slice(phys, func(i, j int) bool { return phys[i].ID < phys[j].ID })
+	return phys, nil
+}
+
+// phyFromRow builds a PhyInfo from one storcli table row; rows without a
+// PHY number are skipped
+func phyFromRow(row map[string]interface{}) (PhyInfo, bool) {
+	var p PhyInfo
+	hasID := false
+	for key, val := range row {
+		k := strings.Map(func(r rune) rune {
+			if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+				return r
+			}
+			return -1
+		}, strings.ToLower(key))
+		s := strings.TrimSpace(fmt.Sprint(val))
+
+		switch {
+		case k == "id" || k == "phy" || k == "phyno" || k == "phyid":
+			if n, err := strconv.Atoi(s); err == nil {
+				p.ID = n
+				hasID = true
+			}
+		case strings.Contains(k, "invaliddword"):
+			p.InvalidDwords = parseCounter(s)
+		case strings.Contains(k, "disparity"):
+			p.DisparityErrors = parseCounter(s)
+		case strings.Contains(k, "sync"):
+			p.LossOfSync = parseCounter(s)
+		case strings.Contains(k, "reset"):
+			p.ResetProblems = parseCounter(s)
+		case strings.Contains(k, "linkspeed") || strings.Contains(k, "linkrate"):
+			p.LinkRate = s
+		}
+	}
+	return p, hasID
+}
+
+// parseCounter parses an error counter, returning nil if it isn't a number
+func parseCounter(s string) *int64 {
+	n, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return nil
+	}
+	return &n
+}
+
+// readSysfsPhys reads the host PHYs (phy-H:N, not expander phy-H:E:N) whose
+// SAS address is the controller's
+func readSysfsPhys(sasAddress string) []PhyInfo {
+	want := normalizeSASAddress(sasAddress)
+	if want == "" {
+		return nil
+	}
+
+	var phys []PhyInfo
+	dirs, _ := filepath.Glob("/sys/class/sas_phy/phy-*")
+	for _, dir := range dirs {
+		if strings.Count(filepath.Base(dir), ":") != 1 {
+			continue
+		}
+		if normalizeSASAddress(readSysfs(dir, "sas_address")) != want {
+			continue
+		}
+		id, err := strconv.Atoi(readSysfs(dir, "phy_identifier"))
+		if err != nil {
+			continue
+		}
+		phys = append(phys, PhyInfo{
+			ID:              id,
+			LinkRate:        readSysfs(dir, "negotiated_linkrate"),
+			InvalidDwords:   parseCounter(readSysfs(dir, "invalid_dword_count")),
+			DisparityErrors: parseCounter(readSysfs(dir, "running_disparity_error_count")),
+			LossOfSync:      parseCounter(readSysfs(dir, "loss_of_dword_sync_count")),
+			ResetProblems:   parseCounter(readSysfs(dir, "phy_reset_problem_count")),
+		})
+	}
+	sort.Slice(phys, func(i, j int) bool { return phys[i].ID < phys[j].ID })
+	return phys
+}
+
+// readSysfs reads a sysfs attribute, returning "" if it can't be read
+func readSysfs(dir, attr string) string {
+	data, err := os.ReadFile(filepath.Join(dir, attr))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// normalizeSASAddress normalizes a SAS address for comparison
+func normalizeSASAddress(addr string) string {
+	addr = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(addr)), "0x")
+	addr = strings.ReplaceAll(addr, "-", "")
+	addr = strings.ReplaceAll(addr, ":", "")
+	return addr
+}
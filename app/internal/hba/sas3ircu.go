@@ -1,12 +1,13 @@
 package hba
 
 import (
-	"os/exec"
-	"regexp"
+	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 
 	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/humanize"
 )
 
 // parseSas3ircuDisplay parses output from 'sas3ircu <n> display'
@@ -193,12 +194,12 @@ func FetchSas3ircuData(controllerNum int, forceRefresh bool) (*ControllerInfo, [
 	if !forceRefresh {
 		if cached := c.Get(cacheKey); cached != nil {
 			data := cached.(*sas3ircuCached)
-			return data.ctrl, data.enclosures, data.devices, nil
+			return data.Ctrl, data.Enclosures, data.Devices, nil
 		}
 	}
 
 	// Fetch fresh data
-	out, err := exec.Command("sudo", "sas3ircu", strconv.Itoa(controllerNum), "display").CombinedOutput()
+	out, err := IrcuDisplay(controllerNum)
 	if err != nil {
 		return nil, nil, nil, err
 	}
@@ -207,18 +208,25 @@ func FetchSas3ircuData(controllerNum int, forceRefresh bool) (*ControllerInfo, [
 
 	// Cache with slow TTL (static hardware info)
 	c.SetSlow(cacheKey, &sas3ircuCached{
-		ctrl:       ctrl,
-		enclosures: enclosures,
-		devices:    devices,
+		Ctrl:       ctrl,
+		Enclosures: enclosures,
+		Devices:    devices,
 	})
 
 	return ctrl, enclosures, devices, nil
 }
 
 type sas3ircuCached struct {
-	ctrl       *ControllerInfo
-	enclosures []EnclosureInfo
-	devices    []PhysicalDevice
+	Ctrl       *ControllerInfo
+	Enclosures []EnclosureInfo
+	Devices    []PhysicalDevice
+}
+
+// Only storcli's controller info is persisted across runs: the sas3ircu
+// entry also holds the drive roster, which goes stale as drives come and go
+func init() {
+	cache.Register(&ControllerInfo{})
+	cache.PersistPrefix("storcli:c")
 }
 
 // GetDeviceBySASAddress looks up a device by SAS address
@@ -241,66 +249,205 @@ func GetDeviceBySASAddress(sasAddr string) *PhysicalDevice {
 	return nil
 }
 
+// ErrAmbiguousSerial is returned when a serial number matches devices in
+// more than one slot. Some cheap drives report blank or duplicate serials.
+var ErrAmbiguousSerial = errors.New("ambiguous serial")
+
 // GetDeviceBySerial looks up a device by serial number
 // Matches against both Serial (short form) and SerialVPD (full form)
+// Returns nil if not found or ambiguous; use FindDeviceBySerial to tell them
+// apart.
 func GetDeviceBySerial(serial string) *PhysicalDevice {
-	serial = strings.ToUpper(strings.TrimSpace(serial))
+	dev, _ := FindDeviceBySerial(serial)
+	return dev
+}
 
-	_, _, devices, err := FetchSas3ircuData(0, false)
+// FindDeviceBySerial looks up a device by serial number. Returns
+// ErrAmbiguousSerial if devices in different slots match, rather than
+// picking one of them.
+func FindDeviceBySerial(serial string) (*PhysicalDevice, error) {
+	devices, err := allControllerDevices()
 	if err != nil {
-		return nil
+		return nil, err
 	}
+	return findDeviceBySerial(devices, serial)
+}
 
-	for _, d := range devices {
-		// Check exact match on Serial (short form)
-		if strings.ToUpper(d.Serial) == serial {
-			return &d
+// allControllerDevices returns the devices of every controller, with the
+// views of a drive on a multipath shelf merged into one entry. An error is
+// only returned if no controller could be read.
+func allControllerDevices() ([]PhysicalDevice, error) {
+	_, devices, err := fetchAllControllers()
+	return devices, err
+}
+
+// AllEnclosures returns the enclosures of every controller, with a shelf
+// cabled to several controllers merged into one entry. An error is only
+// returned if no controller could be read.
+func AllEnclosures() ([]EnclosureInfo, error) {
+	enclosures, _, err := fetchAllControllers()
+	return enclosures, err
+}
+
+// fetchAllControllers reads the sas3ircu data of every controller, tagging
+// each entry with the controller it was seen through before merging
+func fetchAllControllers() ([]EnclosureInfo, []PhysicalDevice, error) {
+	var enclosures []EnclosureInfo
+	var devices []PhysicalDevice
+	var errs []error
+	read := 0
+	for _, n := range ListControllers() {
+		_, encs, devs, err := FetchSas3ircuData(n, false)
+		if err != nil {
+			errs = append(errs, err)
+			continue
 		}
-		// Check exact match on SerialVPD (full form from smartctl)
-		if strings.ToUpper(d.SerialVPD) == serial {
-			return &d
+		read++
+		// Copy before tagging so cached slices aren't modified
+		path := fmt.Sprintf("c%d", n)
+		for _, e := range encs {
+			e.Paths = []string{path}
+			enclosures = append(enclosures, e)
 		}
-		// Check if input starts with short serial (prefix match)
-		if d.Serial != "" && strings.HasPrefix(serial, strings.ToUpper(d.Serial)) {
-			return &d
+		for _, d := range devs {
+			d.Paths = []string{path}
+			devices = append(devices, d)
 		}
 	}
-	return nil
+	if read == 0 && len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return MergeEnclosures(enclosures), MergeDevices(devices), nil
+}
+
+// findDeviceBySerial matches a serial against a device list. Exact matches
+// on Serial (short form) or SerialVPD (full form from smartctl) win over an
+// input that merely starts with a short serial.
+func findDeviceBySerial(devices []PhysicalDevice, serial string) (*PhysicalDevice, error) {
+	serial = strings.ToUpper(strings.TrimSpace(serial))
+
+	exact := func(d *PhysicalDevice) bool {
+		return strings.ToUpper(d.Serial) == serial || strings.ToUpper(d.SerialVPD) == serial
+	}
+	prefix := func(d *PhysicalDevice) bool {
+		return d.Serial != "" && strings.HasPrefix(serial, strings.ToUpper(d.Serial))
+	}
+
+	for _, matches := range []func(*PhysicalDevice) bool{exact, prefix} {
+		var match *PhysicalDevice
+		for i := range devices {
+			d := &devices[i]
+			if !matches(d) {
+				continue
+			}
+			if match != nil && (match.EnclosureID != d.EnclosureID || match.Slot != d.Slot) {
+				return nil, fmt.Errorf("%w %s: found in slots %d:%d and %d:%d", ErrAmbiguousSerial, serial,
+					match.EnclosureID, match.Slot, d.EnclosureID, d.Slot)
+			}
+			if match == nil {
+				match = d
+			}
+		}
+		if match != nil {
+			return match, nil
+		}
+	}
+	return nil, nil
 }
 
-// GetDeviceBySlot looks up a device by enclosure and slot
+// ErrAmbiguousSlot is returned when an enclosure:slot pair (or enclosure id)
+// matches more than one entry, e.g. duplicate enclosure ids
+var ErrAmbiguousSlot = errors.New("ambiguous enclosure:slot")
+
+// GetDeviceBySlot looks up a device by enclosure and slot. Returns nil if the
+// slot is empty or ambiguous; use FindDeviceBySlot to tell them apart.
 func GetDeviceBySlot(enclosure, slot int) *PhysicalDevice {
-	_, _, devices, err := FetchSas3ircuData(0, false)
+	dev, _ := FindDeviceBySlot("", enclosure, slot)
+	return dev
+}
+
+// FindDeviceBySlot looks up a device by enclosure and slot. The slot is only
+// ever matched together with its enclosure, since slot numbers repeat across
+// enclosures. Enclosures are numbered per controller, so controller (e.g.
+// "c1") limits the match to one; "" searches all of them. Returns
+// ErrAmbiguousSlot if more than one device matches.
+func FindDeviceBySlot(controller string, enclosure, slot int) (*PhysicalDevice, error) {
+	devices, err := allControllerDevices()
 	if err != nil {
-		return nil
+		return nil, err
 	}
+	return findDeviceBySlot(devices, controller, enclosure, slot)
+}
 
-	for _, d := range devices {
-		if d.EnclosureID == enclosure && d.Slot == slot {
-			return &d
+// findDeviceBySlot matches an enclosure:slot pair against a device list
+func findDeviceBySlot(devices []PhysicalDevice, controller string, enclosure, slot int) (*PhysicalDevice, error) {
+	var match *PhysicalDevice
+	for i := range devices {
+		d := &devices[i]
+		if d.EnclosureID != enclosure || d.Slot != slot || !onController(d.Paths, controller) {
+			continue
 		}
+		if match != nil && match.Serial != d.Serial {
+			return nil, fmt.Errorf("%w %d:%d: serials %s and %s (prefix the controller, e.g. %s:%d:%d)",
+				ErrAmbiguousSlot, enclosure, slot, match.Serial, d.Serial, exampleController(d.Paths), enclosure, slot)
+		}
+		match = d
 	}
-	return nil
+	return match, nil
 }
 
-// BuildSlotToDeviceMap creates a mapping from "enclosure:slot" to device path
-func BuildSlotToDeviceMap() map[string]string {
-	result := make(map[string]string)
+// FindEnclosure returns the enclosure with the given id on controller ("" for
+// any), or ErrAmbiguousSlot if the id is reported more than once
+func FindEnclosure(enclosures []EnclosureInfo, controller string, id int) (*EnclosureInfo, error) {
+	var match *EnclosureInfo
+	for i := range enclosures {
+		if enclosures[i].ID != id || !onController(enclosures[i].Paths, controller) {
+			continue
+		}
+		if match != nil {
+			return nil, fmt.Errorf("%w: enclosure id %d reported more than once (prefix the controller, e.g. %s:%d)",
+				ErrAmbiguousSlot, id, exampleController(enclosures[i].Paths), id)
+		}
+		match = &enclosures[i]
+	}
+	return match, nil
+}
 
-	_, _, devices, err := FetchSas3ircuData(0, false)
-	if err != nil {
-		return result
+// exampleController names a controller an entry was seen through, for
+// error hints
+func exampleController(paths []string) string {
+	if len(paths) > 0 {
+		return paths[0]
 	}
+	return "c0"
+}
 
-	// Get device paths by matching serial numbers
-	for _, dev := range devices {
-		key := strconv.Itoa(dev.EnclosureID) + ":" + strconv.Itoa(dev.Slot)
-		// The actual device path mapping would need to come from
-		// matching serial numbers with lsblk/smartctl output
-		result[key] = dev.Serial
+// onController reports whether an entry seen through paths is reachable via
+// controller; "" matches any
+func onController(paths []string, controller string) bool {
+	if controller == "" {
+		return true
+	}
+	for _, p := range paths {
+		if p == controller {
+			return true
+		}
 	}
+	return false
+}
 
-	return result
+// DevicePathForSerial finds a drive's device path by either of its serials
+// in a map of upper-cased serial to device path
+func DevicePathForSerial(dev PhysicalDevice, devicesBySerial map[string]string) string {
+	for _, serial := range []string{dev.Serial, dev.SerialVPD} {
+		if serial == "" {
+			continue
+		}
+		if path, ok := devicesBySerial[strings.ToUpper(serial)]; ok {
+			return path
+		}
+	}
+	return ""
 }
 
 // EnrichWithSas3ircu adds sas3ircu data to a device path lookup
@@ -321,40 +468,19 @@ func EnrichWithSas3ircu(serial string) map[string]string {
 	result["manufacturer"] = dev.Manufacturer
 	result["firmware"] = dev.Firmware
 
-	// Format size nicely
 	if dev.SizeMB > 0 {
-		sizeGB := dev.SizeMB / 1024
-		if sizeGB >= 1000 {
-			sizeTB := float64(sizeGB) / 1024
-			result["size"] = strconv.FormatFloat(sizeTB, 'f', 2, 64) + " TB"
-		} else {
-			result["size"] = strconv.Itoa(int(sizeGB)) + " GB"
-		}
+		result["size"] = humanize.Bytes(dev.SizeMB * humanize.MiB)
 	}
 
 	return result
 }
 
-// ListControllers returns available controller numbers
+// ListControllers returns available controller numbers across sas3ircu and
+// sas2ircu
 func ListControllers() []int {
-	// Try sas3ircu list to enumerate controllers
-	out, err := exec.Command("sudo", "sas3ircu", "list").CombinedOutput()
-	if err != nil {
+	detectIrcu()
+	if len(ircuOrder) == 0 {
 		return []int{0} // Default to controller 0
 	}
-
-	var controllers []int
-	re := regexp.MustCompile(`^\s*(\d+)\s+`)
-	for _, line := range strings.Split(string(out), "\n") {
-		if matches := re.FindStringSubmatch(line); len(matches) > 1 {
-			if num, err := strconv.Atoi(matches[1]); err == nil {
-				controllers = append(controllers, num)
-			}
-		}
-	}
-
-	if len(controllers) == 0 {
-		return []int{0}
-	}
-	return controllers
+	return append([]int(nil), ircuOrder...)
 }
@@ -0,0 +1,111 @@
+package hba
+
+import (
+	"errors"
+	"testing"
+)
+
+// twoShelves is one controller with two enclosures that both number their
+// bays from 0, so every slot number appears twice
+var twoShelves = []PhysicalDevice{
+	{EnclosureID: 2, Slot: 0, Serial: "ENC2SLOT0"},
+	{EnclosureID: 2, Slot: 5, Serial: "ENC2SLOT5"},
+	{EnclosureID: 3, Slot: 0, Serial: "ENC3SLOT0"},
+	{EnclosureID: 3, Slot: 5, Serial: "ENC3SLOT5"},
+	{EnclosureID: 3, Slot: 7, Serial: "ENC3SLOT7"},
+}
+
+// twoControllers is two HBAs whose shelves both report enclosure 2
+var twoControllers = []PhysicalDevice{
+	{EnclosureID: 2, Slot: 5, Serial: "C0ENC2SLOT5", Paths: []string{"c0"}},
+	{EnclosureID: 2, Slot: 5, Serial: "C1ENC2SLOT5", Paths: []string{"c1"}},
+}
+
+func TestFindDeviceBySlot(t *testing.T) {
+	tests := []struct {
+		name            string
+		devices         []PhysicalDevice
+		controller      string
+		enclosure, slot int
+		want            string // serial, "" for an empty bay
+		err             error
+	}{
+		{"first enclosure", twoShelves, "", 2, 5, "ENC2SLOT5", nil},
+		{"second enclosure, same slot", twoShelves, "", 3, 5, "ENC3SLOT5", nil},
+		{"slot 0 in each", twoShelves, "", 3, 0, "ENC3SLOT0", nil},
+		{"slot only in the other enclosure", twoShelves, "", 2, 7, "", nil},
+		{"unknown enclosure", twoShelves, "", 4, 5, "", nil},
+		{"same enclosure on two controllers", twoControllers, "", 2, 5, "", ErrAmbiguousSlot},
+		{"qualified by the first controller", twoControllers, "c0", 2, 5, "C0ENC2SLOT5", nil},
+		{"qualified by the second controller", twoControllers, "c1", 2, 5, "C1ENC2SLOT5", nil},
+		{"qualified by an unknown controller", twoControllers, "c2", 2, 5, "", nil},
+		{
+			name: "same drive seen twice",
+			devices: append([]PhysicalDevice{
+				{EnclosureID: 2, Slot: 5, Serial: "ENC2SLOT5"},
+			}, twoShelves...),
+			enclosure: 2, slot: 5, want: "ENC2SLOT5",
+		},
+		{
+			name: "two drives in one bay",
+			devices: append([]PhysicalDevice{
+				{EnclosureID: 2, Slot: 5, Serial: "OTHER"},
+			}, twoShelves...),
+			enclosure: 2, slot: 5, err: ErrAmbiguousSlot,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dev, err := findDeviceBySlot(tt.devices, tt.controller, tt.enclosure, tt.slot)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			got := ""
+			if dev != nil {
+				got = dev.Serial
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindEnclosure(t *testing.T) {
+	enclosures := []EnclosureInfo{
+		{ID: 2, LogicalID: "500605b0:0a1b2c3d"},
+		{ID: 3, LogicalID: "500605b0:99999999"},
+	}
+
+	for _, id := range []int{2, 3} {
+		enc, err := FindEnclosure(enclosures, "", id)
+		if err != nil || enc == nil || enc.ID != id {
+			t.Errorf("FindEnclosure(%d) = %v, %v", id, enc, err)
+		}
+	}
+	if enc, err := FindEnclosure(enclosures, "", 4); enc != nil || err != nil {
+		t.Errorf("FindEnclosure(4) = %v, %v; want nil, nil", enc, err)
+	}
+
+	dup := append(enclosures, EnclosureInfo{ID: 3, LogicalID: "500605b0:77777777"})
+	if _, err := FindEnclosure(dup, "", 3); !errors.Is(err, ErrAmbiguousSlot) {
+		t.Errorf("duplicate id: err = %v, want %v", err, ErrAmbiguousSlot)
+	}
+
+	// Each controller numbers its own enclosures
+	perController := []EnclosureInfo{
+		{ID: 2, LogicalID: "500605b0:0a1b2c3d", Paths: []string{"c0"}},
+		{ID: 2, LogicalID: "500605b0:99999999", Paths: []string{"c1"}},
+	}
+	enc, err := FindEnclosure(perController, "c1", 2)
+	if err != nil || enc == nil || enc.LogicalID != "500605b0:99999999" {
+		t.Errorf("FindEnclosure(c1, 2) = %v, %v", enc, err)
+	}
+}
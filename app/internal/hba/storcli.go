@@ -1,12 +1,12 @@
 package hba
 
 import (
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
 
 	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/privexec"
 )
 
 // parseStorcliOutput parses output from 'storcli /cX show all'
@@ -150,7 +150,9 @@ func FetchStorcliData(controllerID string, forceRefresh bool) (*ControllerInfo,
 
 	// Fetch fresh data
 	storcliPath := "/" + controllerID
-	out, err := exec.Command("sudo", "storcli", storcliPath, "show", "all").CombinedOutput()
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", storcliPath, "show", "all")
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -176,7 +178,9 @@ func FetchControllerTemperature(controllerID string) (*int, error) {
 
 	// Fetch temperature
 	storcliPath := "/" + controllerID
-	out, err := exec.Command("sudo", "storcli", storcliPath, "show", "temperature").CombinedOutput()
+	out, err := privexec.Retry("storcli", func() ([]byte, error) {
+		return privexec.RunSudo("storcli", storcliPath, "show", "temperature")
+	})
 	if err != nil {
 		return nil, err
 	}
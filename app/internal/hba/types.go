@@ -34,6 +34,21 @@ type ControllerInfo struct {
 	Temperature     *int   `json:"temperature,omitempty"` // ROC temperature
 	ChannelDesc     string `json:"channel_desc,omitempty"`
 	PhyCount        int    `json:"phy_count,omitempty"`
+
+	// Per-PHY link state and error counters; only set by GetControllerPhys,
+	// never on the cached controller info
+	Phys []PhyInfo `json:"phys,omitempty"`
+}
+
+// PhyInfo is the link rate and error counters of one controller PHY. A
+// rising invalid dword count usually means a failing cable.
+type PhyInfo struct {
+	ID              int    `json:"id"`
+	LinkRate        string `json:"link_rate,omitempty"` // e.g. "12.0 Gbit"
+	InvalidDwords   *int64 `json:"invalid_dword_count,omitempty"`
+	DisparityErrors *int64 `json:"running_disparity_error_count,omitempty"`
+	LossOfSync      *int64 `json:"loss_of_dword_sync_count,omitempty"`
+	ResetProblems   *int64 `json:"phy_reset_problem_count,omitempty"`
 }
 
 // EnclosureInfo contains JBOD enclosure information
@@ -47,6 +62,10 @@ type EnclosureInfo struct {
 	Firmware     string `json:"firmware"`      // Enclosure firmware
 	Serial       string `json:"serial"`        // Enclosure serial
 	SASAddress   string `json:"sas_address"`   // Enclosure SAS address
+
+	// Controllers the enclosure is reachable through (multipath shelves
+	// list more than one); only set by GetAllControllerData
+	Paths []string `json:"paths,omitempty"`
 }
 
 // PhysicalDevice contains per-drive information from HBA
@@ -72,6 +91,10 @@ type PhysicalDevice struct {
 
 	// State
 	State string `json:"state"` // Ready, Standby, etc.
+
+	// Controllers the drive is reachable through (dual-ported drives in a
+	// multipath shelf list more than one); only set by GetAllControllerData
+	Paths []string `json:"paths,omitempty"`
 }
 
 // HBAData contains all data retrieved from HBA tools
@@ -80,3 +103,13 @@ type HBAData struct {
 	Enclosures  []EnclosureInfo   `json:"enclosures"`
 	Devices     []PhysicalDevice  `json:"devices"`
 }
+
+// ControllerEvent is one entry of a controller's event log
+type ControllerEvent struct {
+	Seq         int64  `json:"seq"`
+	Time        string `json:"time"`           // as reported, e.g. "Mon Jan  8 10:12:33 2024"
+	Code        string `json:"code,omitempty"` // storcli event code
+	Severity    string `json:"severity"`       // info, warning, critical, fatal
+	Description string `json:"description"`
+	Source      string `json:"source"` // "storcli" or "kernel"
+}
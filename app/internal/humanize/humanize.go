@@ -0,0 +1,33 @@
+package humanize
+
+import "fmt"
+
+// Binary (base-1024) size units
+const (
+	KiB int64 = 1 << (10 * (iota + 1))
+	MiB
+	GiB
+	TiB
+	PiB
+)
+
+// Bytes formats a device or pool size in binary units: whole numbers up to
+// GiB ("931 GiB") and one decimal from TiB up ("3.6 TiB"). A size switches
+// to the next unit only once it reaches a full unit, so 1000 GiB prints as
+// "1000 GiB" and exactly 1 TiB as "1.0 TiB".
+func Bytes(n int64) string {
+	switch {
+	case n >= PiB:
+		return fmt.Sprintf("%.1f PiB", float64(n)/float64(PiB))
+	case n >= TiB:
+		return fmt.Sprintf("%.1f TiB", float64(n)/float64(TiB))
+	case n >= GiB:
+		return fmt.Sprintf("%d GiB", n/GiB)
+	case n >= MiB:
+		return fmt.Sprintf("%d MiB", n/MiB)
+	case n >= KiB:
+		return fmt.Sprintf("%d KiB", n/KiB)
+	default:
+		return fmt.Sprintf("%d B", n)
+	}
+}
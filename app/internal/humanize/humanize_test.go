@@ -0,0 +1,31 @@
+package humanize
+
+import "testing"
+
+func TestBytes(t *testing.T) {
+	tests := []struct {
+		n    int64
+		want string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{KiB, "1 KiB"},
+		{MiB - 1, "1023 KiB"},
+		{512 * MiB, "512 MiB"},
+		{931 * GiB, "931 GiB"},
+		{1000 * GiB, "1000 GiB"},
+		{1000*GiB + 512*MiB, "1000 GiB"},
+		{TiB - 1, "1023 GiB"},
+		{TiB, "1.0 TiB"},
+		{TiB + 1, "1.0 TiB"},
+		{4000787030016, "3.6 TiB"}, // "4 TB" drive
+		{8001563222016, "7.3 TiB"}, // "8 TB" drive
+		{PiB, "1.0 PiB"},
+		{3 * PiB / 2, "1.5 PiB"},
+	}
+	for _, tt := range tests {
+		if got := Bytes(tt.n); got != tt.want {
+			t.Errorf("Bytes(%d) = %q, want %q", tt.n, got, tt.want)
+		}
+	}
+}
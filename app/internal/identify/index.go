@@ -1,10 +1,16 @@
 package identify
 
 import (
+	"errors"
+	"fmt"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"unicode"
 
 	"github.com/sigreer/jbodgod/internal/identify/sources"
+	"github.com/sigreer/jbodgod/internal/wwn"
 )
 
 // DataSource is the interface for device data sources
@@ -50,6 +56,7 @@ type DeviceIndex struct {
 
 	// MD RAID indexes
 	ByMDArrUUID map[string]string
+	ByMDDevUUID map[string]string
 	ByMDName    map[string]string
 
 	// Device-mapper indexes
@@ -58,6 +65,9 @@ type DeviceIndex struct {
 
 	// Symlink path -> device path
 	SymlinkMap map[string]string
+
+	// Identifiers that should be unique but map to multiple devices
+	Conflicts []Conflict
 }
 
 // NewDeviceIndex creates an empty device index
@@ -90,6 +100,7 @@ func NewDeviceIndex() *DeviceIndex {
 		ByLVMLVName:   make(map[string]string),
 		ByLVMLVPath:   make(map[string]string),
 		ByMDArrUUID:   make(map[string]string),
+		ByMDDevUUID:   make(map[string]string),
 		ByMDName:      make(map[string]string),
 		ByDMName:      make(map[string]string),
 		ByDMUUID:      make(map[string]string),
@@ -377,6 +388,8 @@ func (idx *DeviceIndex) mergeIntoEntity(dst *DeviceEntity, src *sources.SourceEn
 
 // buildIndexes creates reverse lookup indexes from entities
 func (idx *DeviceIndex) buildIndexes() {
+	wwnDevices := make(map[string][]string)
+
 	for key, entity := range idx.Entities {
 		devicePath := entity.DevicePath
 		if devicePath == "" {
@@ -387,10 +400,15 @@ func (idx *DeviceIndex) buildIndexes() {
 			idx.ByKernelName[entity.KernelName] = devicePath
 		}
 		if entity.Serial != nil {
-			idx.BySerial[*entity.Serial] = devicePath
+			idx.BySerial[normalizeSerial(*entity.Serial)] = devicePath
 		}
 		if entity.WWN != nil {
-			idx.ByWWN[*entity.WWN] = devicePath
+			wwn := wwn.Normalize(*entity.WWN)
+			idx.ByWWN[wwn] = devicePath
+			// Partitions inherit the parent WWN, so only whole disks count
+			if entity.Type == TypeDisk {
+				wwnDevices[wwn] = append(wwnDevices[wwn], devicePath)
+			}
 		}
 		if entity.LUID != nil {
 			idx.ByLUID[*entity.LUID] = devicePath
@@ -423,6 +441,14 @@ func (idx *DeviceIndex) buildIndexes() {
 		// Index by-id names
 		for _, byID := range entity.ByID {
 			idx.ByIDPath[byID] = devicePath
+
+			// Also index the serial as it appears in the by-id name, which
+			// may be formatted differently from the SMART serial
+			if serial := serialFromByID(byID); serial != "" {
+				if _, exists := idx.BySerial[serial]; !exists {
+					idx.BySerial[serial] = devicePath
+				}
+			}
 		}
 
 		// Index by-path names
@@ -467,12 +493,18 @@ func (idx *DeviceIndex) buildIndexes() {
 			idx.ByLVMLVPath[*entity.LVMLVPath] = devicePath
 		}
 
-		// MD RAID indexes
-		if entity.MDArrUUID != nil {
-			idx.ByMDArrUUID[*entity.MDArrUUID] = devicePath
+		// MD RAID indexes (members carry their array's UUID and name, which
+		// should resolve to the array itself)
+		if entity.Type == TypeMDArray {
+			if entity.MDArrUUID != nil {
+				idx.ByMDArrUUID[*entity.MDArrUUID] = devicePath
+			}
+			if entity.MDName != nil {
+				idx.ByMDName[*entity.MDName] = devicePath
+			}
 		}
-		if entity.MDName != nil {
-			idx.ByMDName[*entity.MDName] = devicePath
+		if entity.MDDevUUID != nil {
+			idx.ByMDDevUUID[*entity.MDDevUUID] = devicePath
 		}
 
 		// Device-mapper indexes
@@ -483,9 +515,115 @@ func (idx *DeviceIndex) buildIndexes() {
 			idx.ByDMUUID[*entity.DMUUID] = devicePath
 		}
 	}
+
+	idx.detectConflicts(wwnDevices)
+}
+
+// detectConflicts records WWNs shared by more than one disk. Cloned SSDs
+// and misconfigured SAS drives can share a WWN, which would otherwise make
+// ByWWN silently last-writer-wins.
+func (idx *DeviceIndex) detectConflicts(wwnDevices map[string][]string) {
+	idx.Conflicts = nil
+	for wwn, devices := range wwnDevices {
+		if len(devices) < 2 {
+			continue
+		}
+		sort.Strings(devices)
+		idx.Conflicts = append(idx.Conflicts, Conflict{
+			Type:    IDWWN,
+			Value:   wwn,
+			Devices: devices,
+		})
+	}
+	sort.Slice(idx.Conflicts, func(i, j int) bool {
+		return idx.Conflicts[i].Value < idx.Conflicts[j].Value
+	})
+}
+
+// conflictFor returns the conflict for an identifier value, if any
+func (idx *DeviceIndex) conflictFor(idType IdentifierType, value string) *Conflict {
+	if idType == IDWWN {
+		value = wwn.Normalize(value)
+	}
+	for i := range idx.Conflicts {
+		if idx.Conflicts[i].Type == idType && idx.Conflicts[i].Value == value {
+			return &idx.Conflicts[i]
+		}
+	}
+	return nil
 }
 
-// Lookup finds a device by any unique identifier
+// serialFromByID extracts the serial embedded in an ata-, scsi-S or nvme-
+// by-id link name (e.g. ata-ST8000VN004-2M2101_WKD3CHXY -> WKD3CHXY).
+// Returns an empty string for partitions and WWN/EUI-based names.
+func serialFromByID(name string) string {
+	if strings.Contains(name, "-part") {
+		return ""
+	}
+
+	var rest string
+	switch {
+	case strings.HasPrefix(name, "ata-"):
+		rest = strings.TrimPrefix(name, "ata-")
+	case strings.HasPrefix(name, "scsi-S"):
+		// scsi-S<VENDOR>_<MODEL>_<SERIAL>; other scsi-N forms are WWN/NAA ids
+		rest = strings.TrimPrefix(name, "scsi-S")
+	case strings.HasPrefix(name, "nvme-"):
+		rest = strings.TrimPrefix(name, "nvme-")
+		if strings.HasPrefix(rest, "eui.") || strings.HasPrefix(rest, "nvme.") {
+			return ""
+		}
+		// Newer udev appends the namespace id (..._<SERIAL>_1)
+		if i := strings.LastIndex(rest, "_"); i > 0 && len(rest)-i <= 3 && isDigits(rest[i+1:]) {
+			rest = rest[:i]
+		}
+	default:
+		return ""
+	}
+
+	i := strings.LastIndex(rest, "_")
+	if i < 0 || i == len(rest)-1 {
+		return ""
+	}
+	return normalizeSerial(rest[i+1:])
+}
+
+// normalizeSerial strips the padding around a serial: ATA drives pad theirs
+// with spaces, which udev turns into underscores in by-id names. Serials are
+// indexed and looked up in this form.
+func normalizeSerial(serial string) string {
+	return strings.TrimFunc(serial, func(r rune) bool {
+		return r == '_' || unicode.IsSpace(r)
+	})
+}
+
+// isDigits returns true if s is non-empty and contains only ASCII digits
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// LookupWithConfidence is Lookup with a fallback for identifiers that
+// don't match exactly: serials are compared case-insensitively and then
+// serials and WWNs by prefix, for serials that kernel logs truncate or print
+// in another case. A fallback match must single out one device, and the
+// confidence reports how the query matched.
+func (idx *DeviceIndex) LookupWithConfidence(query string) (*DeviceEntity, IdentifierType, Confidence, error) {
+	entity, idType, err := idx.Lookup(query)
+	if !errors.Is(err, ErrNotFound) {
+		return entity, idType, ConfidenceExact, err
+	}
+	return idx.lookupFuzzy(query)
+}
+
+// Lookup finds a device by an exact identifier
 func (idx *DeviceIndex) Lookup(query string) (*DeviceEntity, IdentifierType, error) {
 	// 1. Try direct device path or entity key
 	if entity, ok := idx.Entities[query]; ok {
@@ -507,10 +645,100 @@ func (idx *DeviceIndex) Lookup(query string) (*DeviceEntity, IdentifierType, err
 	}
 
 	// 4. Try each reverse index in order of specificity
-	lookups := []struct {
+	for _, lookup := range idx.reverseIndexes() {
+		key := query
+		switch lookup.idType {
+		case IDSerial:
+			key = normalizeSerial(query)
+		case IDWWN:
+			key = wwn.Normalize(query)
+		}
+		if devPath, ok := lookup.index[key]; ok {
+			// Refuse to pick an arbitrary device for a shared identifier
+			if conflict := idx.conflictFor(lookup.idType, query); conflict != nil {
+				return nil, lookup.idType, fmt.Errorf("%w: %s %s matches %s - use a more specific identifier",
+					ErrAmbiguous, lookup.idType, query, strings.Join(conflict.Devices, ", "))
+			}
+			if entity, ok := idx.Entities[devPath]; ok {
+				return entity, lookup.idType, nil
+			}
+		}
+	}
+
+	return nil, IDUnknown, ErrNotFound
+}
+
+// lookupFuzzy matches a query against the serial and WWN indexes
+// case-insensitively, then by prefix
+func (idx *DeviceIndex) lookupFuzzy(query string) (*DeviceEntity, IdentifierType, Confidence, error) {
+	query = strings.TrimSpace(query)
+	lower := strings.ToLower(query)
+	wwn := wwn.Normalize(query)
+
+	tiers := []struct {
+		confidence Confidence
+		match      func(idType IdentifierType, key string) bool
+	}{
+		{ConfidenceCaseInsensitive, func(idType IdentifierType, key string) bool {
+			return idType == IDSerial && strings.EqualFold(key, query)
+		}},
+		{ConfidencePrefix, func(idType IdentifierType, key string) bool {
+			if idType == IDWWN {
+				return len(wwn) >= minFuzzyQuery && strings.HasPrefix(key, wwn)
+			}
+			return len(lower) >= minFuzzyQuery && strings.HasPrefix(strings.ToLower(key), lower)
+		}},
+	}
+	indexes := []struct {
 		index  map[string]string
 		idType IdentifierType
 	}{
+		{idx.BySerial, IDSerial},
+		{idx.ByWWN, IDWWN},
+	}
+
+	for _, tier := range tiers {
+		var matched []string
+		var matchedAs IdentifierType
+		seen := make(map[string]bool)
+		for _, ix := range indexes {
+			for key, devPath := range ix.index {
+				if !tier.match(ix.idType, key) || seen[devPath] {
+					continue
+				}
+				seen[devPath] = true
+				matched = append(matched, devPath)
+				matchedAs = ix.idType
+			}
+		}
+
+		switch len(matched) {
+		case 0:
+			continue
+		case 1:
+			if entity, ok := idx.Entities[matched[0]]; ok {
+				return entity, matchedAs, tier.confidence, nil
+			}
+		default:
+			sort.Strings(matched)
+			return nil, IDUnknown, tier.confidence, fmt.Errorf("%w: %s matches %s - use a longer identifier",
+				ErrAmbiguous, query, strings.Join(matched, ", "))
+		}
+	}
+
+	return nil, IDUnknown, "", ErrNotFound
+}
+
+// reverseIndex is one identifier -> device path index
+type reverseIndex struct {
+	index  map[string]string
+	idType IdentifierType
+}
+
+// reverseIndexes lists the reverse indexes in the order lookups try them,
+// most specific first
+func (idx *DeviceIndex) reverseIndexes() []reverseIndex {
+	return []reverseIndex{
 		{idx.ByKernelName, IDKernelName},
 		{idx.BySerial, IDSerial},
 		{idx.ByWWN, IDWWN},
@@ -537,18 +765,56 @@ func (idx *DeviceIndex) Lookup(query string) (*DeviceEntity, IdentifierType, err
 		{idx.ByLVMLVName, IDLVMLVName},
 		{idx.ByLVMLVPath, IDLVMLVPath},
 		{idx.ByMDArrUUID, IDMDArrUUID},
+		{idx.ByMDDevUUID, IDMDDevUUID},
 		{idx.ByMDName, IDMDName},
 		{idx.ByDMName, IDDMName},
 		{idx.ByDMUUID, IDDMUUID},
 	}
+}
 
-	for _, lookup := range lookups {
-		if devPath, ok := lookup.index[query]; ok {
-			if entity, ok := idx.Entities[devPath]; ok {
-				return entity, lookup.idType, nil
-			}
+// Dump returns every entity in the index, sorted by key, with the identifiers
+// that resolve to it, for seeing why a lookup does or doesn't match
+func (idx *DeviceIndex) Dump() []IndexedEntity {
+	indexes := idx.reverseIndexes()
+	order := make(map[IdentifierType]int, len(indexes)+1)
+	refs := make(map[string][]IndexRef)
+	for i, ri := range indexes {
+		order[ri.idType] = i
+		for value, devPath := range ri.index {
+			refs[devPath] = append(refs[devPath], IndexRef{Type: ri.idType, Value: value})
 		}
 	}
+	order[IDSymlink] = len(indexes)
+	for link, devPath := range idx.SymlinkMap {
+		refs[devPath] = append(refs[devPath], IndexRef{Type: IDSymlink, Value: link})
+	}
 
-	return nil, IDUnknown, ErrNotFound
+	keys := make([]string, 0, len(idx.Entities))
+	for key := range idx.Entities {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	dump := make([]IndexedEntity, 0, len(keys))
+	for _, key := range keys {
+		entity := idx.Entities[key]
+		devPath := entity.DevicePath
+		if devPath == "" {
+			devPath = key
+		}
+
+		reachable := refs[devPath]
+		sort.Slice(reachable, func(i, j int) bool {
+			if reachable[i].Type != reachable[j].Type {
+				return order[reachable[i].Type] < order[reachable[j].Type]
+			}
+			return reachable[i].Value < reachable[j].Value
+		})
+		if reachable == nil {
+			reachable = []IndexRef{}
+		}
+
+		dump = append(dump, IndexedEntity{Key: key, ReachableBy: reachable, Device: entity})
+	}
+	return dump
 }
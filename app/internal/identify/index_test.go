@@ -0,0 +1,139 @@
+package identify
+
+import (
+	"errors"
+	"testing"
+)
+
+// fuzzyIndex holds two drives whose serials share the prefix "ZA1D"
+func fuzzyIndex() *DeviceIndex {
+	idx := NewDeviceIndex()
+	for _, d := range []struct{ dev, serial, wwn string }{
+		{"/dev/sda", "ZA1DKJT7", "5000c500a1b2c3d4"},
+		{"/dev/sdb", "ZA1DQ9X2", "5000c500e5f60718"},
+	} {
+		serial, wwn := d.serial, d.wwn
+		idx.Entities[d.dev] = &DeviceEntity{Type: TypeDisk, DevicePath: d.dev, Serial: &serial, WWN: &wwn}
+		idx.BySerial[serial] = d.dev
+		idx.ByWWN[wwn] = d.dev
+	}
+	return idx
+}
+
+func TestLookupWithConfidence(t *testing.T) {
+	tests := []struct {
+		query      string
+		device     string
+		matchedAs  IdentifierType
+		confidence Confidence
+		err        error
+	}{
+		{"ZA1DKJT7", "/dev/sda", IDSerial, ConfidenceExact, nil},
+		{"za1dkjt7", "/dev/sda", IDSerial, ConfidenceCaseInsensitive, nil},
+		{"ZA1DKJT", "/dev/sda", IDSerial, ConfidencePrefix, nil},
+		{"za1dq", "/dev/sdb", IDSerial, ConfidencePrefix, nil},
+		{"0x5000c500e5f6", "/dev/sdb", IDWWN, ConfidencePrefix, nil},
+		{"ZA1D", "", IDUnknown, ConfidencePrefix, ErrAmbiguous},
+		{"ZA1", "", IDUnknown, "", ErrNotFound},
+		{"WKD3CHXY", "", IDUnknown, "", ErrNotFound},
+	}
+
+	idx := fuzzyIndex()
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			entity, matchedAs, confidence, err := idx.LookupWithConfidence(tt.query)
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("err = %v, want %v", err, tt.err)
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			} else if entity.DevicePath != tt.device {
+				t.Errorf("device = %s, want %s", entity.DevicePath, tt.device)
+			}
+			if matchedAs != tt.matchedAs {
+				t.Errorf("matchedAs = %s, want %s", matchedAs, tt.matchedAs)
+			}
+			if confidence != tt.confidence {
+				t.Errorf("confidence = %q, want %q", confidence, tt.confidence)
+			}
+		})
+	}
+}
+
+func TestLookupIsExact(t *testing.T) {
+	idx := fuzzyIndex()
+	for _, query := range []string{"za1dkjt7", "ZA1DKJT", "0x5000c500e5f6"} {
+		if entity, _, err := idx.Lookup(query); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Lookup(%q) = %v, %v; want ErrNotFound", query, entity, err)
+		}
+	}
+}
+
+func TestSerialFromByID(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"ata-ST8000VN004-2M2101_WKD3CHXY", "WKD3CHXY"},
+		{"ata-WDC_WD80EFAX-68KNBN0_VAGWJ8XL", "VAGWJ8XL"},
+		{"ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567", "WD-WCC7K1234567"},
+		{"ata-Samsung_SSD_870_EVO_1TB_S6PTNM0T123456A", "S6PTNM0T123456A"},
+		{"ata-ST8000VN004-2M2101_WKD3CHXY-part1", ""},
+		{"ata-NOSERIAL", ""},
+		{"ata-MODEL_", ""},
+		{"scsi-SSEAGATE_ST8000NM0075_ZA1DKJT70000C9", "ZA1DKJT70000C9"},
+		{"scsi-35000c500a1b2c3d4", ""},
+		{"nvme-Samsung_SSD_980_PRO_1TB_S5GXNF0R123456_1", "S5GXNF0R123456"},
+		{"nvme-eui.0025385b71b12345", ""},
+		{"wwn-0x5000c500a1b2c3d4", ""},
+	}
+	for _, tt := range tests {
+		if got := serialFromByID(tt.name); got != tt.want {
+			t.Errorf("serialFromByID(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestLookupByIDSerial checks a drive can be found by the serial in its
+// ata- by-id name, and by its SMART serial with the drive's padding
+func TestLookupByIDSerial(t *testing.T) {
+	idx := NewDeviceIndex()
+	serial := "     WD-WCC7K1234567"
+	idx.Entities["/dev/sdc"] = &DeviceEntity{
+		Type:       TypeDisk,
+		DevicePath: "/dev/sdc",
+		Serial:     &serial,
+		ByID:       []string{"ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567"},
+	}
+	sataSerial := "2233E6A1B2C3"
+	idx.Entities["/dev/sdd"] = &DeviceEntity{
+		Type:       TypeDisk,
+		DevicePath: "/dev/sdd",
+		Serial:     &sataSerial,
+		ByID:       []string{"ata-CT2000MX500SSD1_2233E6A1B2C3X"},
+	}
+	idx.buildIndexes()
+
+	tests := []struct {
+		query  string
+		device string
+	}{
+		{"WD-WCC7K1234567", "/dev/sdc"},
+		{"     WD-WCC7K1234567", "/dev/sdc"},
+		{"WD-WCC7K1234567_", "/dev/sdc"},
+		{"2233E6A1B2C3", "/dev/sdd"},
+		{"2233E6A1B2C3X", "/dev/sdd"},
+		{"ata-WDC_WD40EFRX-68N32N0_WD-WCC7K1234567", "/dev/sdc"},
+	}
+	for _, tt := range tests {
+		entity, _, err := idx.Lookup(tt.query)
+		if err != nil {
+			t.Errorf("Lookup(%q): %v", tt.query, err)
+			continue
+		}
+		if entity.DevicePath != tt.device {
+			t.Errorf("Lookup(%q) = %s, want %s", tt.query, entity.DevicePath, tt.device)
+		}
+	}
+}
@@ -18,6 +18,9 @@ func PrintJSON(w io.Writer, result *LookupResult) error {
 func PrintTable(w io.Writer, result *LookupResult) {
 	fmt.Fprintf(w, "Query:      %s\n", result.Query)
 	fmt.Fprintf(w, "Matched As: %s\n", result.MatchedAs)
+	if result.Confidence != "" && result.Confidence != ConfidenceExact {
+		fmt.Fprintf(w, "Confidence: %s\n", result.Confidence)
+	}
 	if result.Device.DevicePath != "" {
 		fmt.Fprintf(w, "Device:     %s\n", result.Device.DevicePath)
 	}
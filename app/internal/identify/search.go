@@ -0,0 +1,129 @@
+package identify
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/wwn"
+)
+
+// Match quality, best first
+const (
+	scoreExact     = 3
+	scorePrefix    = 2
+	scoreSubstring = 1
+	scoreFuzzy     = 0
+)
+
+// minFuzzyQuery is the shortest query matched as a subsequence; shorter
+// queries would match almost every identifier
+const minFuzzyQuery = 4
+
+// SearchMatch is a device matched by a partial identifier
+type SearchMatch struct {
+	Device    *DeviceEntity  `json:"device"`
+	MatchedAs IdentifierType `json:"matched_as"`
+	Value     string         `json:"value"` // the identifier that matched
+	Score     int            `json:"score"` // 3 exact, 2 prefix, 1 substring, 0 fuzzy
+}
+
+// Search finds whole disks with any identifier (serial, WWN, LUID, model,
+// by-id name, kernel name or pool) matching a partial, case-insensitive
+// query. Unlike Lookup it returns every match, best first: exact, then
+// prefix, then substring, then fuzzy (characters in order) matches.
+func (idx *DeviceIndex) Search(query string) []SearchMatch {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	// WWNs are indexed normalized, so "0x5000C5..." must match "5000c5..."
+	wwnQuery := wwn.Normalize(query)
+
+	var matches []SearchMatch
+	for _, entity := range idx.Entities {
+		if entity.Type != TypeDisk {
+			continue
+		}
+
+		best := SearchMatch{Score: -1}
+		for _, c := range searchCandidates(entity) {
+			q := query
+			if c.idType == IDWWN {
+				q = wwnQuery
+			}
+			score, ok := matchScore(q, c.value)
+			if ok && score > best.Score {
+				best = SearchMatch{Device: entity, MatchedAs: c.idType, Value: c.value, Score: score}
+			}
+		}
+		if best.Device != nil {
+			matches = append(matches, best)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].Device.DevicePath < matches[j].Device.DevicePath
+	})
+	return matches
+}
+
+// searchCandidate is an identifier value searched for a device
+type searchCandidate struct {
+	idType IdentifierType
+	value  string
+}
+
+// searchCandidates lists the identifiers of an entity that Search considers
+func searchCandidates(e *DeviceEntity) []searchCandidate {
+	var out []searchCandidate
+	add := func(t IdentifierType, v *string) {
+		if v != nil && *v != "" {
+			out = append(out, searchCandidate{t, *v})
+		}
+	}
+
+	if e.KernelName != "" {
+		out = append(out, searchCandidate{IDKernelName, e.KernelName})
+	}
+	add(IDSerial, e.Serial)
+	add(IDWWN, e.WWN)
+	add(IDLUID, e.LUID)
+	add(IDModel, e.Model)
+	add(IDZFSPoolName, e.ZFSPoolName)
+	for _, byID := range e.ByID {
+		out = append(out, searchCandidate{IDByID, byID})
+	}
+	return out
+}
+
+// matchScore rates how well query matches value (both compared lowercased)
+func matchScore(query, value string) (int, bool) {
+	value = strings.ToLower(value)
+	switch {
+	case value == query:
+		return scoreExact, true
+	case strings.HasPrefix(value, query):
+		return scorePrefix, true
+	case strings.Contains(value, query):
+		return scoreSubstring, true
+	case len(query) >= minFuzzyQuery && isSubsequence(query, value):
+		return scoreFuzzy, true
+	}
+	return 0, false
+}
+
+// isSubsequence reports whether all characters of query appear in value in
+// order (e.g. "wd40rx" in "WDC_WD40EFRX")
+func isSubsequence(query, value string) bool {
+	i := 0
+	for j := 0; j < len(value) && i < len(query); j++ {
+		if value[j] == query[i] {
+			i++
+		}
+	}
+	return i == len(query)
+}
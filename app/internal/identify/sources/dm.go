@@ -1,6 +1,7 @@
 package sources
 
 import (
+	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
@@ -14,43 +15,63 @@ type dmInfo struct {
 	Name   string
 	UUID   string
 	MajMin string
+	Device string // /dev/dm-N, if known
 }
 
 // Collect gathers device-mapper information
 func (s *DMSource) Collect() (map[string]*SourceEntity, error) {
 	entities := make(map[string]*SourceEntity)
 
-	// Check if dmsetup is available
-	if _, err := exec.LookPath("dmsetup"); err != nil {
-		return entities, nil
+	// sysfs needs neither dmsetup nor root; dmsetup adds any mappings it
+	// doesn't show
+	devices := s.getSysfsDevices()
+	if _, err := exec.LookPath("dmsetup"); err == nil {
+		devices = append(devices, s.getDevices()...)
 	}
 
-	// Get DM device information
-	devices := s.getDevices()
-
 	for _, dm := range devices {
-		// Construct device path from name
-		devPath := "/dev/mapper/" + dm.Name
-		resolved := s.resolveDevice(devPath)
+		devPath := dm.Device
+		if devPath == "" {
+			// Construct device path from name
+			devPath = s.resolveDevice("/dev/mapper/" + dm.Name)
+		}
+		if _, ok := entities[devPath]; ok {
+			continue
+		}
 
 		entity := &SourceEntity{
-			Type:       "dm_device",
-			DevicePath: resolved,
+			Type:       dmType(dm.UUID),
+			DevicePath: devPath,
 			DMName:     ptr(dm.Name),
+			DMUUID:     ptr(dm.UUID),
+			MajMin:     ptr(dm.MajMin),
 		}
 
-		if dm.UUID != "" {
-			entity.DMUUID = ptr(dm.UUID)
-		}
+		entities[devPath] = entity
+	}
 
-		if dm.MajMin != "" {
-			entity.MajMin = ptr(dm.MajMin)
-		}
+	return entities, nil
+}
 
-		entities[resolved] = entity
+// getSysfsDevices reads device-mapper devices from /sys/block/dm-*/dm
+func (s *DMSource) getSysfsDevices() []dmInfo {
+	var devices []dmInfo
+
+	dirs, _ := filepath.Glob("/sys/block/dm-*")
+	for _, dir := range dirs {
+		name := readSysfsString(filepath.Join(dir, "dm", "name"))
+		if name == "" {
+			continue
+		}
+		devices = append(devices, dmInfo{
+			Name:   name,
+			UUID:   readSysfsString(filepath.Join(dir, "dm", "uuid")),
+			MajMin: readSysfsString(filepath.Join(dir, "dev")),
+			Device: "/dev/" + filepath.Base(dir),
+		})
 	}
 
-	return entities, nil
+	return devices
 }
 
 // getDevices returns device-mapper device information
@@ -91,6 +112,29 @@ func (s *DMSource) getDevices() []dmInfo {
 	return devices
 }
 
+// dmType classifies a mapping by its UUID prefix (set by the tool that
+// created it: cryptsetup, multipathd, LVM)
+func dmType(uuid string) string {
+	switch {
+	case strings.HasPrefix(uuid, "CRYPT-"):
+		return "crypt"
+	case strings.HasPrefix(uuid, "mpath-"):
+		return "mpath"
+	case strings.HasPrefix(uuid, "LVM-"):
+		return "lvm"
+	}
+	return "dm_device"
+}
+
+// readSysfsString reads a sysfs attribute, returning "" if it can't be read
+func readSysfsString(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
 // resolveDevice resolves a device path to its canonical form
 func (s *DMSource) resolveDevice(device string) string {
 	if device == "" {
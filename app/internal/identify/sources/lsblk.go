@@ -5,6 +5,8 @@ import (
 	"os/exec"
 	"strconv"
 	"strings"
+
+	"github.com/sigreer/jbodgod/internal/wwn"
 )
 
 // LsblkSource collects device information from lsblk
@@ -76,7 +78,7 @@ func (s *LsblkSource) processDevice(dev lsblkDevice, entities map[string]*Source
 		entity.Serial = ptr(dev.Serial)
 	}
 	if dev.WWN != "" {
-		entity.WWN = ptr(dev.WWN)
+		entity.WWN = ptr(wwn.Normalize(dev.WWN))
 	}
 	if dev.Model != "" {
 		entity.Model = ptr(strings.TrimSpace(dev.Model))
@@ -1,10 +1,13 @@
 package sources
 
 import (
+	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strings"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
 )
 
 // MDRaidSource collects MD RAID array information
@@ -12,23 +15,36 @@ type MDRaidSource struct{}
 
 // arrayInfo holds parsed MD array data
 type arrayInfo struct {
-	Device string
-	Name   string
-	UUID   string
+	Device  string
+	Name    string
+	UUID    string
+	Members []string // member device paths, e.g. /dev/sdb1
 }
 
+var (
+	// "md127 : active raid1 sdb1[1] sda1[0]"
+	mdstatArrayRe  = regexp.MustCompile(`^(md\S+)\s*:\s*\S+\s+(.*)$`)
+	mdstatMemberRe = regexp.MustCompile(`^([^\s\[]+)\[\d+\]`)
+
+	// mdadm --examine fields ("UUID" alone is 0.90 metadata's array UUID)
+	mdExamineArrayUUIDRe = regexp.MustCompile(`(?m)^\s*Array UUID\s*:\s*(\S+)`)
+	mdExamineDevUUIDRe   = regexp.MustCompile(`(?m)^\s*Device UUID\s*:\s*(\S+)`)
+	mdExamineUUIDRe      = regexp.MustCompile(`(?m)^\s*UUID\s*:\s*(\S+)`)
+)
+
 // Collect gathers MD RAID information
 func (s *MDRaidSource) Collect() (map[string]*SourceEntity, error) {
 	entities := make(map[string]*SourceEntity)
 
-	// Check if mdadm is available
-	if _, err := exec.LookPath("mdadm"); err != nil {
-		return entities, nil
+	// Arrays and their members come from /proc/mdstat, UUIDs and names
+	// from mdadm when it's available
+	arrays := s.getMdstatArrays()
+	hasMdadm := false
+	if _, err := exec.LookPath("mdadm"); err == nil {
+		hasMdadm = true
+		arrays = s.mergeArrays(arrays, s.getArrays())
 	}
 
-	// Get array information from mdadm --detail --scan
-	arrays := s.getArrays()
-
 	for _, arr := range arrays {
 		devPath := s.resolveDevice(arr.Device)
 
@@ -36,13 +52,27 @@ func (s *MDRaidSource) Collect() (map[string]*SourceEntity, error) {
 			Type:       "md_array",
 			DevicePath: devPath,
 			MDArrUUID:  ptr(arr.UUID),
+			MDName:     ptr(arr.Name),
 		}
+		entities[devPath] = entity
 
-		if arr.Name != "" {
-			entity.MDName = ptr(arr.Name)
+		// Members keep their own type (disk/partition) from lsblk
+		for _, member := range arr.Members {
+			m := &SourceEntity{
+				DevicePath: member,
+				MDArrUUID:  ptr(arr.UUID),
+				MDName:     ptr(arr.Name),
+			}
+			// Reading the superblock would wake a member in standby
+			if hasMdadm && !inStandby(member) {
+				arrUUID, devUUID := s.examineMember(member)
+				if m.MDArrUUID == nil {
+					m.MDArrUUID = ptr(arrUUID)
+				}
+				m.MDDevUUID = ptr(devUUID)
+			}
+			entities[member] = m
 		}
-
-		entities[devPath] = entity
 	}
 
 	return entities, nil
@@ -94,6 +124,84 @@ func (s *MDRaidSource) getArrays() []arrayInfo {
 	return arrays
 }
 
+// getMdstatArrays returns running arrays and their members from /proc/mdstat
+func (s *MDRaidSource) getMdstatArrays() []arrayInfo {
+	data, err := os.ReadFile("/proc/mdstat")
+	if err != nil {
+		return nil
+	}
+	return parseMdstat(string(data))
+}
+
+// parseMdstat parses /proc/mdstat array lines:
+//
+//	md127 : active raid1 sdb1[1] sda1[0]
+//	md0 : inactive sdc[0](S)
+func parseMdstat(output string) []arrayInfo {
+	var arrays []arrayInfo
+	for _, line := range strings.Split(output, "\n") {
+		m := mdstatArrayRe.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			continue
+		}
+
+		arr := arrayInfo{Device: "/dev/" + m[1]}
+		for _, field := range strings.Fields(m[2]) {
+			if mm := mdstatMemberRe.FindStringSubmatch(field); mm != nil {
+				arr.Members = append(arr.Members, "/dev/"+mm[1])
+			}
+		}
+		arrays = append(arrays, arr)
+	}
+	return arrays
+}
+
+// mergeArrays adds the UUIDs and names from mdadm to the mdstat arrays,
+// matching them by resolved device path. Arrays only mdadm reports are kept.
+func (s *MDRaidSource) mergeArrays(mdstat, scanned []arrayInfo) []arrayInfo {
+	byPath := make(map[string]int, len(mdstat))
+	for i, arr := range mdstat {
+		byPath[s.resolveDevice(arr.Device)] = i
+	}
+
+	for _, arr := range scanned {
+		i, ok := byPath[s.resolveDevice(arr.Device)]
+		if !ok {
+			mdstat = append(mdstat, arr)
+			continue
+		}
+		mdstat[i].UUID = arr.UUID
+		mdstat[i].Name = arr.Name
+	}
+	return mdstat
+}
+
+// examineMember reads the array and device UUIDs from a member's superblock.
+// Device UUIDs only exist in 1.x metadata.
+func (s *MDRaidSource) examineMember(device string) (arrUUID, devUUID string) {
+	out, err := privexec.Run("mdadm", "--examine", device)
+	if err != nil {
+		return "", ""
+	}
+
+	if m := mdExamineArrayUUIDRe.FindSubmatch(out); m != nil {
+		arrUUID = string(m[1])
+	} else if m := mdExamineUUIDRe.FindSubmatch(out); m != nil {
+		arrUUID = string(m[1])
+	}
+	if m := mdExamineDevUUIDRe.FindSubmatch(out); m != nil {
+		devUUID = string(m[1])
+	}
+	return arrUUID, devUUID
+}
+
+// inStandby reports whether a device's drive is spun down, asking smartctl
+// without waking it. Drives smartctl can't query count as active.
+func inStandby(device string) bool {
+	out, _ := privexec.Run("smartctl", "-i", "-n", "standby", device)
+	return strings.Contains(string(out), "STANDBY") || strings.Contains(string(out), "NOT READY")
+}
+
 // resolveDevice resolves a device path to its canonical form
 func (s *MDRaidSource) resolveDevice(device string) string {
 	if device == "" {
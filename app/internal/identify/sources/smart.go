@@ -5,6 +5,10 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+
+	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/privexec"
+	"github.com/sigreer/jbodgod/internal/wwn"
 )
 
 // SmartSource collects device information from smartctl
@@ -63,14 +67,24 @@ func (s *SmartSource) getPhysicalDevices() []string {
 	return devices
 }
 
-// queryDevice queries a single device with smartctl
+// queryDevice queries a single device with smartctl. Results for active
+// drives are cached per device so building the index again in the same
+// process doesn't re-run smartctl; standby drives aren't cached so they're
+// picked up once they spin up.
 func (s *SmartSource) queryDevice(device string) *SourceEntity {
+	c := cache.Global()
+	cacheKey := "smart:identify:" + device
+
+	if cached := c.Get(cacheKey); cached != nil {
+		return cached.(*SourceEntity)
+	}
+
 	entity := &SourceEntity{
 		DevicePath: device,
 	}
 
 	// Get device info (skip if in standby)
-	out, err := exec.Command("smartctl", "-i", "-n", "standby", device).CombinedOutput()
+	out, err := privexec.Run("smartctl", "-i", "-n", "standby", device)
 	if err != nil {
 		// Device might be in standby or not SMART capable
 		return nil
@@ -98,9 +112,7 @@ func (s *SmartSource) queryDevice(device string) *SourceEntity {
 	// Extract WWN if not found by lsblk
 	reWWN := regexp.MustCompile(`LU WWN Device Id:\s+(\S+(?:\s+\S+)*)`)
 	if matches := reWWN.FindStringSubmatch(output); len(matches) > 1 {
-		// Normalize WWN format (remove spaces)
-		wwn := strings.ReplaceAll(matches[1], " ", "")
-		entity.WWN = ptr("0x" + wwn)
+		entity.WWN = ptr(wwn.Normalize(matches[1]))
 	}
 
 	// Extract Model
@@ -128,13 +140,14 @@ func (s *SmartSource) queryDevice(device string) *SourceEntity {
 		s.extractNVMeIdentifiers(device, entity)
 	}
 
+	c.SetMedium(cacheKey, entity)
 	return entity
 }
 
 // extractNVMeIdentifiers extracts NVMe-specific identifiers
 func (s *SmartSource) extractNVMeIdentifiers(device string, entity *SourceEntity) {
 	// Try nvme id-ns command if available
-	out, err := exec.Command("nvme", "id-ns", device, "-o", "normal").CombinedOutput()
+	out, err := privexec.Run("nvme", "id-ns", device, "-o", "normal")
 	if err != nil {
 		return
 	}
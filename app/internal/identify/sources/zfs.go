@@ -3,8 +3,9 @@ package sources
 import (
 	"os/exec"
 	"path/filepath"
-	"regexp"
 	"strings"
+
+	"github.com/sigreer/jbodgod/internal/collector"
 )
 
 // ZFSSource collects ZFS pool, vdev, and dataset information
@@ -125,63 +126,28 @@ func (s *ZFSSource) getPools() []poolInfo {
 	return pools
 }
 
-// getVdevs parses zpool status -gL to get vdev GUIDs and device mappings
+// getVdevs returns leaf vdevs with the disk their GUID maps to. The mapping
+// comes from the vdev labels rather than the names zpool status prints,
+// which are GUIDs under -g and by-id links in many pools.
 func (s *ZFSSource) getVdevs() []vdevInfo {
 	var vdevs []vdevInfo
 
-	out, err := exec.Command("zpool", "status", "-gL").Output()
-	if err != nil {
-		return vdevs
-	}
-
-	lines := strings.Split(string(out), "\n")
-	var currentPool string
-	var currentPoolGUID string
-
-	// Get pool GUIDs first
 	poolGUIDs := make(map[string]string)
-	pools := s.getPools()
-	for _, p := range pools {
+	for _, p := range s.getPools() {
 		poolGUIDs[p.Name] = p.GUID
 	}
 
-	// Regex to match device lines with GUID
-	// Format: /dev/sdX  ONLINE  0  0  0  <guid>
-	// or:     sdX       ONLINE  0  0  0  <guid>
-	reDevice := regexp.MustCompile(`^\s+(/dev/\S+|\S+)\s+\S+\s+\d+\s+\d+\s+\d+\s*(\d*)`)
-
-	for _, line := range lines {
-		// Check for pool name
-		if strings.HasPrefix(line, "  pool:") {
-			currentPool = strings.TrimSpace(strings.TrimPrefix(line, "  pool:"))
-			currentPoolGUID = poolGUIDs[currentPool]
+	for guid, vdev := range collector.CollectZpoolVdevs() {
+		// A vdev whose device is gone has nothing to index
+		if vdev.Disk == nil {
 			continue
 		}
-
-		// Check for device line
-		if matches := reDevice.FindStringSubmatch(line); len(matches) >= 2 {
-			device := matches[1]
-			vdevGUID := ""
-			if len(matches) > 2 {
-				vdevGUID = matches[2]
-			}
-
-			// Skip non-device entries
-			if strings.HasPrefix(device, "raidz") ||
-				strings.HasPrefix(device, "mirror") ||
-				strings.HasPrefix(device, "spare") ||
-				strings.HasPrefix(device, "log") ||
-				strings.HasPrefix(device, "cache") {
-				continue
-			}
-
-			vdevs = append(vdevs, vdevInfo{
-				PoolName: currentPool,
-				PoolGUID: currentPoolGUID,
-				VdevGUID: vdevGUID,
-				Device:   device,
-			})
-		}
+		vdevs = append(vdevs, vdevInfo{
+			PoolName: vdev.PoolName,
+			PoolGUID: poolGUIDs[vdev.PoolName],
+			VdevGUID: guid,
+			Device:   "/dev/" + *vdev.Disk,
+		})
 	}
 
 	return vdevs
@@ -5,6 +5,19 @@ import "errors"
 // ErrNotFound is returned when a query doesn't match any device
 var ErrNotFound = errors.New("device not found")
 
+// ErrAmbiguous is returned when a query matches an identifier shared by
+// multiple devices (e.g. a cloned WWN)
+var ErrAmbiguous = errors.New("identifier is shared by multiple devices")
+
+// Confidence says how closely a lookup query matched the identifier
+type Confidence string
+
+const (
+	ConfidenceExact           Confidence = "exact"
+	ConfidenceCaseInsensitive Confidence = "case_insensitive"
+	ConfidencePrefix          Confidence = "prefix" // e.g. a serial truncated in a kernel log
+)
+
 // DeviceType categorizes the entity type
 type DeviceType string
 
@@ -60,6 +73,7 @@ const (
 	IDDMName      IdentifierType = "dm_name"
 	IDDMUUID      IdentifierType = "dm_uuid"
 	IDSymlink     IdentifierType = "symlink"
+	IDModel       IdentifierType = "model" // search only; models aren't unique
 	IDUnknown     IdentifierType = "unknown"
 )
 
@@ -134,9 +148,10 @@ type DeviceEntity struct {
 
 // LookupResult contains the matched entity and metadata about the match
 type LookupResult struct {
-	Query     string         `json:"query"`
-	MatchedAs IdentifierType `json:"matched_as"`
-	Device    *DeviceEntity  `json:"device"`
+	Query      string         `json:"query"`
+	MatchedAs  IdentifierType `json:"matched_as"`
+	Confidence Confidence     `json:"confidence"`
+	Device     *DeviceEntity  `json:"device"`
 }
 
 // ptr is a helper to create a pointer to a string
@@ -151,3 +166,24 @@ func ptr(s string) *string {
 func ptrInt(i int) *int {
 	return &i
 }
+
+// Conflict records an identifier value that maps to more than one device
+type Conflict struct {
+	Type    IdentifierType `json:"type"`
+	Value   string         `json:"value"`
+	Devices []string       `json:"devices"`
+}
+
+// IndexRef is an identifier under which the index finds an entity
+type IndexRef struct {
+	Type  IdentifierType `json:"type"`
+	Value string         `json:"value"`
+}
+
+// IndexedEntity is an index entity with every identifier that resolves to
+// it (identify --all)
+type IndexedEntity struct {
+	Key         string        `json:"key"`
+	ReachableBy []IndexRef    `json:"reachable_by"`
+	Device      *DeviceEntity `json:"device"`
+}
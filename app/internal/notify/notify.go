@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/db"
+)
+
+// Notifier delivers an alert to an external destination
+type Notifier interface {
+	Send(alert *db.Alert) error
+}
+
+// FromConfig builds the notifiers enabled in the alerts config
+func FromConfig(cfg config.Alerts) []Notifier {
+	var notifiers []Notifier
+	if cfg.Webhook != "" {
+		notifiers = append(notifiers, NewWebhookNotifier(cfg.Webhook))
+	}
+	if cfg.Email != "" && cfg.SMTP.Server != "" {
+		notifiers = append(notifiers, NewSMTPNotifier(cfg.SMTP, cfg.Email))
+	}
+	return notifiers
+}
+
+// Dispatcher sends alerts to every notifier, skipping alerts for the same
+// problem that were already sent within RenotifyAfter
+type Dispatcher struct {
+	Notifiers     []Notifier
+	DB            *db.DB
+	RenotifyAfter time.Duration
+}
+
+// NewDispatcher creates a dispatcher for the configured notifiers
+func NewDispatcher(cfg config.Alerts, database *db.DB) *Dispatcher {
+	return &Dispatcher{
+		Notifiers:     FromConfig(cfg),
+		DB:            database,
+		RenotifyAfter: cfg.RenotifyAfter,
+	}
+}
+
+// Notify sends an alert unless it was sent recently. Returns true if it was
+// delivered by at least one notifier.
+func (d *Dispatcher) Notify(alert *db.Alert) (bool, error) {
+	if len(d.Notifiers) == 0 {
+		return false, nil
+	}
+
+	key := alertKey(alert)
+	if d.DB != nil {
+		last, err := d.DB.LastNotified(key)
+		if err != nil {
+			return false, err
+		}
+		if last != nil && time.Since(*last) < d.RenotifyAfter {
+			return false, nil
+		}
+	}
+
+	var errs []string
+	sent := false
+	for _, n := range d.Notifiers {
+		if err := n.Send(alert); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		sent = true
+	}
+
+	if sent && d.DB != nil {
+		if err := d.DB.RecordNotification(key, alert.ID); err != nil {
+			return sent, err
+		}
+	}
+	if len(errs) > 0 {
+		return sent, fmt.Errorf("failed to send notification: %s", strings.Join(errs, "; "))
+	}
+	return sent, nil
+}
+
+// alertKey identifies the problem an alert is about, so repeated alerts for
+// it (e.g. a drive missing on every run, or a temperature that changes by a
+// degree) share one key. Different kinds of message in one category about
+// the same subject (e.g. a pool going from DEGRADED to FAULTED) get their
+// own keys.
+func alertKey(alert *db.Alert) string {
+	subject := alert.DriveSerial
+	if subject == "" {
+		subject = alert.PoolName
+	}
+
+	if subject == "" && alert.Details != "" {
+		var details map[string]any
+		if json.Unmarshal([]byte(alert.Details), &details) == nil {
+			for _, k := range []string{"serial", "device", "pool"} {
+				if v, ok := details[k].(string); ok && v != "" {
+					subject = v
+					break
+				}
+			}
+			if subject == "" {
+				if enc, ok := details["enclosure"]; ok {
+					subject = fmt.Sprintf("%v:%v", enc, details["slot"])
+				}
+			}
+		}
+	}
+
+	if subject == "" {
+		subject = alert.Message
+	}
+	return alert.Category + ":" + subject + ":" + messageKind(alert.Message)
+}
+
+// digitsRe matches the numbers in an alert message
+var digitsRe = regexp.MustCompile(`[0-9]+`)
+
+// messageKind is an alert message with its numbers masked, so readings that
+// change from run to run don't make it a new kind of alert
+func messageKind(message string) string {
+	return digitsRe.ReplaceAllString(message, "#")
+}
@@ -0,0 +1,131 @@
+package notify
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/config"
+	"github.com/sigreer/jbodgod/internal/db"
+)
+
+// smtpTimeout bounds a whole SMTP exchange so a dead or stalled mail server
+// can't stall a run
+const smtpTimeout = 30 * time.Second
+
+// SMTPNotifier emails alerts through an SMTP server
+type SMTPNotifier struct {
+	Server   string // host:port
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// NewSMTPNotifier creates a notifier that mails to a comma-separated list of
+// recipients
+func NewSMTPNotifier(cfg config.SMTP, to string) *SMTPNotifier {
+	n := &SMTPNotifier{
+		Server:   cfg.Server,
+		Username: cfg.Username,
+		Password: cfg.Password,
+		From:     cfg.From,
+	}
+	for _, addr := range strings.Split(to, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			n.To = append(n.To, addr)
+		}
+	}
+	if n.From == "" {
+		host, _ := os.Hostname()
+		n.From = "jbodgod@" + host
+	}
+	return n
+}
+
+// Send emails the alert to all recipients
+func (s *SMTPNotifier) Send(alert *db.Alert) error {
+	host, _ := os.Hostname()
+
+	var msg strings.Builder
+	fmt.Fprintf(&msg, "From: %s\r\n", s.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(s.To, ", "))
+	fmt.Fprintf(&msg, "Subject: [jbodgod %s] %s: %s\r\n", host, strings.ToUpper(alert.Severity), alert.Message)
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+
+	fmt.Fprintf(&msg, "Host:      %s\r\n", host)
+	fmt.Fprintf(&msg, "Severity:  %s\r\n", alert.Severity)
+	fmt.Fprintf(&msg, "Category:  %s\r\n", alert.Category)
+	fmt.Fprintf(&msg, "Time:      %s\r\n", alert.Timestamp.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&msg, "\r\n%s\r\n", alert.Message)
+	if alert.Details != "" {
+		fmt.Fprintf(&msg, "\r\nDetails: %s\r\n", alert.Details)
+	}
+
+	if err := s.sendMail([]byte(msg.String())); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return nil
+}
+
+// sendMail is smtp.SendMail with a connect timeout and a deadline on the
+// whole exchange
+func (s *SMTPNotifier) sendMail(msg []byte) error {
+	serverHost, _, err := net.SplitHostPort(s.Server)
+	if err != nil {
+		serverHost = s.Server
+	}
+
+	conn, err := net.DialTimeout("tcp", s.Server, smtpTimeout)
+	if err != nil {
+		return err
+	}
+	if err := conn.SetDeadline(time.Now().Add(smtpTimeout)); err != nil {
+		conn.Close()
+		return err
+	}
+	c, err := smtp.NewClient(conn, serverHost)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: serverHost}); err != nil {
+			return err
+		}
+	}
+	// Only authenticate when credentials are configured (local relays
+	// usually accept unauthenticated mail)
+	if s.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.Username, s.Password, serverHost)); err != nil {
+			return err
+		}
+	}
+
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, addr := range s.To {
+		if err := c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,80 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/db"
+)
+
+// webhookTimeout bounds a webhook POST so a dead endpoint can't stall a run
+const webhookTimeout = 10 * time.Second
+
+// WebhookNotifier POSTs alerts as JSON to a URL
+type WebhookNotifier struct {
+	URL    string
+	Client *http.Client
+}
+
+// webhookPayload is the JSON body sent for an alert
+type webhookPayload struct {
+	ID          int64           `json:"id"`
+	Host        string          `json:"host"`
+	Severity    string          `json:"severity"`
+	Category    string          `json:"category"`
+	Message     string          `json:"message"`
+	DriveSerial string          `json:"drive_serial,omitempty"`
+	PoolName    string          `json:"pool,omitempty"`
+	EnclosureID *int            `json:"enclosure,omitempty"`
+	Slot        *int            `json:"slot,omitempty"`
+	Details     json.RawMessage `json:"details,omitempty"`
+	Timestamp   time.Time       `json:"timestamp"`
+}
+
+// NewWebhookNotifier creates a notifier for a webhook URL
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		URL:    url,
+		Client: &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+// Send posts the alert to the webhook URL
+func (w *WebhookNotifier) Send(alert *db.Alert) error {
+	host, _ := os.Hostname()
+	payload := webhookPayload{
+		ID:          alert.ID,
+		Host:        host,
+		Severity:    alert.Severity,
+		Category:    alert.Category,
+		Message:     alert.Message,
+		DriveSerial: alert.DriveSerial,
+		PoolName:    alert.PoolName,
+		EnclosureID: alert.EnclosureID,
+		Slot:        alert.Slot,
+		Timestamp:   alert.Timestamp,
+	}
+	if json.Valid([]byte(alert.Details)) {
+		payload.Details = json.RawMessage(alert.Details)
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	resp, err := w.Client.Post(w.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Package privexec runs privileged external tools (smartctl, sdparm,
+// storcli, sas3ircu, sg_ses, ...) with a timeout and throttles them through
+// a shared rate limiter so parallel collection can't flood the system.
+package privexec
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout is how long Run and RunSudo let a command run before
+// killing it
+const DefaultTimeout = 15 * time.Second
+
+// ErrTimeout is returned by Run and RunSudo when a command was killed for
+// exceeding the timeout (e.g. smartctl stuck on a failing drive)
+var ErrTimeout = errors.New("command timed out")
+
+// Limiter is a token bucket shared by all goroutines
+type Limiter struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewLimiter creates a limiter allowing rate commands per second with the
+// given burst size
+func NewLimiter(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available
+func (l *Limiter) Wait() {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	// Reserve a token; a negative balance queues callers behind each other
+	l.tokens--
+	if l.tokens >= 0 {
+		l.mu.Unlock()
+		return
+	}
+	wait := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.mu.Unlock()
+
+	time.Sleep(wait)
+}
+
+var (
+	globalMu sync.RWMutex
+	global   *Limiter
+	timeout  = DefaultTimeout
+)
+
+// SetRate configures the global limit in commands per second (0 disables it)
+func SetRate(perSecond float64) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	if perSecond <= 0 {
+		global = nil
+		return
+	}
+	global = NewLimiter(perSecond, int(perSecond))
+}
+
+// SetTimeout configures how long Run and RunSudo wait for a command
+// (0 restores DefaultTimeout)
+func SetTimeout(d time.Duration) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+
+	if d <= 0 {
+		d = DefaultTimeout
+	}
+	timeout = d
+}
+
+// wait blocks on the global limiter, if one is configured
+func wait() {
+	globalMu.RLock()
+	l := global
+	globalMu.RUnlock()
+
+	if l != nil {
+		l.Wait()
+	}
+}
+
+var (
+	sudoOnce   sync.Once
+	sudoNeeded bool
+)
+
+// useSudo reports whether privileged commands should be prefixed with sudo.
+// sudo is skipped when already running as root or when it isn't installed
+// (e.g. in containers), so the tool runs directly.
+func useSudo() bool {
+	sudoOnce.Do(func() {
+		if os.Geteuid() == 0 {
+			return
+		}
+		_, err := exec.LookPath("sudo")
+		sudoNeeded = err == nil
+	})
+	return sudoNeeded
+}
+
+// Run runs a privileged tool without sudo and returns its combined output.
+// The command is killed after the configured timeout, returning ErrTimeout
+// along with any output produced so far.
+func Run(name string, args ...string) ([]byte, error) {
+	wait()
+	return run(name, name, args)
+}
+
+// RunSudo is Run for tools that need sudo (see useSudo)
+func RunSudo(name string, args ...string) ([]byte, error) {
+	wait()
+	if !useSudo() {
+		return run(name, name, args)
+	}
+	return run(name, "sudo", append([]string{name}, args...))
+}
+
+// RunTimeout is Run with its own timeout, for operations that can
+// legitimately outlast the configured one (e.g. exporting a busy pool)
+func RunTimeout(d time.Duration, name string, args ...string) ([]byte, error) {
+	wait()
+	return runFor(d, name, name, args)
+}
+
+// run executes a command with the configured timeout; tool names the
+// command in the timeout error
+func run(tool, name string, args []string) ([]byte, error) {
+	globalMu.RLock()
+	d := timeout
+	globalMu.RUnlock()
+
+	return runFor(d, tool, name, args)
+}
+
+// runFor executes a command, killing it after d
+func runFor(d time.Duration, tool, name string, args []string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), d)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, name, args...).CombinedOutput()
+	if ctx.Err() == context.DeadlineExceeded {
+		return out, fmt.Errorf("%s after %s: %w", tool, d, ErrTimeout)
+	}
+	return out, err
+}
+
+// RetryAttempts is how many times Retry runs a command before giving up
+const RetryAttempts = 3
+
+// retryBackoff is the delay before the first retry; it doubles each attempt
+var retryBackoff = 250 * time.Millisecond
+
+var verbose bool
+
+// SetVerbose enables logging of retried commands to stderr
+func SetVerbose(v bool) {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	verbose = v
+}
+
+// Retry runs a command up to RetryAttempts times with exponential backoff,
+// for HBA tools (storcli, sas3ircu) that fail spuriously under heavy I/O.
+// Only transient failures are retried; a missing tool fails immediately.
+func Retry(tool string, run func() ([]byte, error)) ([]byte, error) {
+	delay := retryBackoff
+	for attempt := 1; ; attempt++ {
+		out, err := run()
+		if err == nil || attempt == RetryAttempts || !isTransient(out, err) {
+			return out, err
+		}
+
+		globalMu.RLock()
+		v := verbose
+		globalMu.RUnlock()
+		if v {
+			fmt.Fprintf(os.Stderr, "%s failed (attempt %d/%d), retrying in %s: %v\n", tool, attempt, RetryAttempts, delay, err)
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// isTransient reports whether a failed command is worth retrying: it exited
+// with no output or reported a busy device. A tool that isn't installed,
+// or a timeout, is not.
+func isTransient(out []byte, err error) bool {
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		return false
+	}
+	output := strings.ToLower(string(out) + string(exitErr.Stderr))
+	if strings.Contains(output, "command not found") {
+		return false // sudo couldn't find the tool
+	}
+	return strings.TrimSpace(output) == "" ||
+		strings.Contains(output, "busy") ||
+		strings.Contains(output, "temporarily unavailable")
+}
@@ -5,9 +5,11 @@ import (
 	"fmt"
 	"os/exec"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/privexec"
 )
 
 // DiscoverSESDevices finds all SES-capable enclosure devices
@@ -48,11 +50,13 @@ func DiscoverSESDevices() ([]*EnclosureSES, error) {
 			continue
 		}
 
-		// Get SAS address via sg_ses for matching with HBA data
-		sasAddr := getSESDeviceSASAddress(enc.SGDevice)
+		// Get SAS address via sg_ses for matching with HBA data, and the
+		// slot labels from the same element descriptor page
+		sasAddr, labels := getSESDeviceDescriptors(enc.SGDevice)
 		if sasAddr != "" {
 			enc.SASAddress = sasAddr
 		}
+		enc.SlotLabels = labels
 
 		enclosures = append(enclosures, enc)
 	}
@@ -65,6 +69,9 @@ func DiscoverSESDevices() ([]*EnclosureSES, error) {
 	return enclosures, nil
 }
 
+// hctlRe matches the leading [H:C:T:L] of an lsscsi line
+var hctlRe = regexp.MustCompile(`^\s*\[(\d+:\d+:\d+:\d+)\]`)
+
 // parseLsscsiEnclosureLine parses a single lsscsi output line for an enclosure
 func parseLsscsiEnclosureLine(line string) (*EnclosureSES, error) {
 	// Example: [6:0:24:0]   enclosu SMC      SC826-P          0001  -         /dev/sg23
@@ -80,6 +87,9 @@ func parseLsscsiEnclosureLine(line string) (*EnclosureSES, error) {
 	enc := &EnclosureSES{
 		SGDevice: sgMatches[1],
 	}
+	if hctl := hctlRe.FindStringSubmatch(line); len(hctl) == 2 {
+		enc.HCTL = hctl[1]
+	}
 
 	// Extract vendor and product (after "enclosu" field)
 	fields := strings.Fields(line)
@@ -94,19 +104,28 @@ func parseLsscsiEnclosureLine(line string) (*EnclosureSES, error) {
 	return enc, nil
 }
 
-// getSESDeviceSASAddress retrieves the SAS address for an SES device
+// getSESDeviceDescriptors retrieves the SAS address and slot labels for an
+// SES device
 // Uses: sg_ses --page=ed /dev/sg<N>
-func getSESDeviceSASAddress(sgDevice string) string {
-	// Try to get SAS address from enclosure descriptor page
-	out, err := exec.Command("sudo", "sg_ses", "--page=ed", sgDevice).CombinedOutput()
+func getSESDeviceDescriptors(sgDevice string) (string, map[int]string) {
+	// Try to get SAS address from element descriptor page
+	out, err := privexec.RunSudo("sg_ses", "--page=ed", sgDevice)
 	if err != nil {
 		// Fallback: try to get it from the additional element status page
-		out, err = exec.Command("sudo", "sg_ses", "--page=aes", sgDevice).CombinedOutput()
+		// (no slot labels there)
+		out, err = privexec.RunSudo("sg_ses", "--page=aes", sgDevice)
 		if err != nil {
-			return ""
+			return "", nil
 		}
+		return parseSASAddress(string(out)), nil
 	}
 
+	return parseSASAddress(string(out)), parseSlotDescriptors(string(out))
+}
+
+// parseSASAddress extracts the enclosure SAS address from sg_ses output
+func parseSASAddress(out string) string {
+
 	// Parse for SAS address
 	// Look for patterns like: "SAS address: 0x500304800f1xxxxx" or "attached SAS address: 5..."
 	patterns := []string{
@@ -117,7 +136,7 @@ func getSESDeviceSASAddress(sgDevice string) string {
 
 	for _, pattern := range patterns {
 		re := regexp.MustCompile(pattern)
-		if matches := re.FindStringSubmatch(string(out)); len(matches) > 1 {
+		if matches := re.FindStringSubmatch(out); len(matches) > 1 {
 			return strings.ToLower(matches[1])
 		}
 	}
@@ -125,6 +144,50 @@ func getSESDeviceSASAddress(sgDevice string) string {
 	return ""
 }
 
+// elementDescriptorPattern matches an element line on the element descriptor
+// page, e.g. "Element 3 descriptor: SLOT 03"
+var elementDescriptorPattern = regexp.MustCompile(`(?i)^element\s+(\d+)\s+descriptor:\s*(.*)$`)
+
+// parseSlotDescriptors extracts device slot labels from 'sg_ses --page=ed'
+// output. Only elements under an "Array device slot" or "Device slot" type
+// header are used; the element index is taken as the slot number, matching
+// how --dev-slot-num addresses bays.
+//
+//	Element type: Array device slot, subenclosure id: 0 [ti=0]
+//	  Overall descriptor: <empty>
+//	  Element 0 descriptor: SLOT 00
+func parseSlotDescriptors(out string) map[int]string {
+	labels := make(map[int]string)
+	inSlots := false
+
+	for _, line := range strings.Split(out, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(strings.ToLower(line), "element type:") {
+			inSlots = strings.Contains(strings.ToLower(line), "device slot")
+			continue
+		}
+		if !inSlots {
+			continue
+		}
+
+		matches := elementDescriptorPattern.FindStringSubmatch(line)
+		if len(matches) != 3 {
+			continue
+		}
+		label := strings.TrimSpace(matches[2])
+		if label == "" || label == "<empty>" {
+			continue
+		}
+		slot, _ := strconv.Atoi(matches[1])
+		labels[slot] = label
+	}
+
+	if len(labels) == 0 {
+		return nil
+	}
+	return labels
+}
+
 // MapEnclosureToSGDevice maps an HBA enclosure to its SES sg device
 // Cross-references using SAS address
 func MapEnclosureToSGDevice(enclosureID int, enclosureLogicalID string, enclosureSASAddr string) (*EnclosureSES, error) {
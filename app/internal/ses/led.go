@@ -4,8 +4,19 @@ import (
 	"context"
 	"fmt"
 	"os/exec"
+	"sort"
 	"strings"
 	"time"
+
+	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/collector"
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// Locate LED mechanisms reported by SetLocateLED
+const (
+	LEDMechanismSysfs = "sysfs"  // /sys/class/enclosure/<hctl>/SlotNN/locate
+	LEDMechanismSgSes = "sg_ses" // sg_ses --set=ident via sudo
 )
 
 // CheckSgSesInstalled verifies sg_ses is available
@@ -25,18 +36,7 @@ func SetSlotIdentLED(sgDevice string, slot int, on bool) error {
 		return err
 	}
 
-	action := "--clear=ident"
-	if on {
-		action = "--set=ident"
-	}
-
-	cmd := exec.Command("sudo", "sg_ses",
-		fmt.Sprintf("--dev-slot-num=%d", slot),
-		action,
-		sgDevice,
-	)
-
-	out, err := cmd.CombinedOutput()
+	out, err := privexec.RunSudo("sg_ses", identArgs(sgDevice, slot, on)...)
 	if err != nil {
 		outStr := string(out)
 		// Check for permission errors
@@ -47,9 +47,53 @@ func SetSlotIdentLED(sgDevice string, slot int, on bool) error {
 		return fmt.Errorf("sg_ses failed: %s: %w", strings.TrimSpace(outStr), err)
 	}
 
+	cache.Global().Delete(collector.SysfsEnclosuresCacheKey)
 	return nil
 }
 
+// identArgs are the sg_ses arguments that set or clear a slot's ident LED
+func identArgs(sgDevice string, slot int, on bool) []string {
+	action := "--clear=ident"
+	if on {
+		action = "--set=ident"
+	}
+	return []string{fmt.Sprintf("--dev-slot-num=%d", slot), action, sgDevice}
+}
+
+// SysfsLocateAvailable reports whether the kernel enclosure driver exposes a
+// writable locate LED for the slot, so sg_ses isn't needed
+func SysfsLocateAvailable(info *LocateInfo) bool {
+	return info != nil && info.EnclosureHCTL != "" &&
+		collector.SlotLocateWritable(info.EnclosureHCTL, info.DevSlotNum())
+}
+
+// SetLocateLED turns the locate LED for a slot on or off, preferring the
+// kernel's sysfs enclosure interface and falling back to sg_ses. Returns the
+// mechanism used.
+// Both paths address the slot by its SES element index (DevSlotNum), which
+// is the slot's position in the kernel's element-ordered SlotNN entries.
+func SetLocateLED(info *LocateInfo, on bool) (string, error) {
+	if info.EnclosureHCTL != "" {
+		if err := collector.SetSlotLocateLED(info.EnclosureHCTL, info.DevSlotNum(), on); err == nil {
+			return LEDMechanismSysfs, nil
+		}
+	}
+	return LEDMechanismSgSes, SetSlotIdentLED(info.SGDevice, info.DevSlotNum(), on)
+}
+
+// LocateLEDCommand describes what SetLocateLED would do for a slot, for dry
+// runs: the mechanism and the sysfs write or sg_ses command line
+func LocateLEDCommand(info *LocateInfo, on bool) (string, string) {
+	if SysfsLocateAvailable(info) {
+		value := "0"
+		if on {
+			value = "1"
+		}
+		return LEDMechanismSysfs, fmt.Sprintf("echo %s > %s", value, collector.SlotLocatePath(info.EnclosureHCTL, info.DevSlotNum()))
+	}
+	return LEDMechanismSgSes, "sg_ses " + strings.Join(identArgs(info.SGDevice, info.DevSlotNum(), on), " ")
+}
+
 // SetSlotFaultLED turns the fault LED on or off
 func SetSlotFaultLED(sgDevice string, slot int, on bool) error {
 	if err := CheckSgSesInstalled(); err != nil {
@@ -61,47 +105,125 @@ func SetSlotFaultLED(sgDevice string, slot int, on bool) error {
 		action = "--set=fault"
 	}
 
-	cmd := exec.Command("sudo", "sg_ses",
+	out, err := privexec.RunSudo("sg_ses",
 		fmt.Sprintf("--dev-slot-num=%d", slot),
 		action,
 		sgDevice,
 	)
-
-	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return fmt.Errorf("sg_ses failed: %s: %w", strings.TrimSpace(string(out)), err)
 	}
 
+	cache.Global().Delete(collector.SysfsEnclosuresCacheKey)
 	return nil
 }
 
-// GetSlotLEDState retrieves the current LED state for a slot
+// GetSlotLEDState retrieves the current LED state for a slot (SES element
+// index)
 func GetSlotLEDState(sgDevice string, slot int) (*SlotLEDState, error) {
+	slots, err := readJoinSlots(sgDevice)
+	if err != nil {
+		return nil, err
+	}
+	s, ok := slots[slot]
+	if !ok {
+		return nil, ErrSlotNotFound
+	}
+	return &SlotLEDState{Slot: slot, Ident: s.Locate, Fault: s.Fault}, nil
+}
+
+// readJoinSlots reads the device slots of an enclosure from
+// 'sg_ses --page=es --join', keyed by element index
+func readJoinSlots(sgDevice string) (map[int]*SlotStatus, error) {
 	if err := CheckSgSesInstalled(); err != nil {
 		return nil, err
 	}
 
-	cmd := exec.Command("sudo", "sg_ses",
+	out, err := privexec.RunSudo("sg_ses",
 		"--page=es", // Element status page
 		"--join",    // Join with element descriptor page
 		sgDevice,
 	)
-
-	out, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("sg_ses failed: %w", err)
 	}
+	return parseJoinSlots(string(out)), nil
+}
 
-	state := &SlotLEDState{Slot: slot}
-	outStr := strings.ToLower(string(out))
+// BlinkAllSlots turns on the ident LED of every populated slot of an
+// enclosure for duration, so the enclosure can be picked out in a rack.
+// enc names the enclosure (Controller, EnclosureID, StartSlot, SGDevice);
+// its Slot is ignored. Returns the element indexes of the populated slots.
+func BlinkAllSlots(enc *LocateInfo, duration time.Duration) ([]int, error) {
+	return BlinkAllSlotsWithContext(context.Background(), enc, duration, false)
+}
 
-	// Parse LED states from output
-	// Looking for patterns like "ident=1", "fault=1", etc.
-	state.Ident = strings.Contains(outStr, "ident=1") || strings.Contains(outStr, "identify=1")
-	state.Fault = strings.Contains(outStr, "fault=1")
-	state.Active = strings.Contains(outStr, "active=1")
+// BlinkAllSlotsWithContext is BlinkAllSlots, stopping early when ctx is
+// cancelled. Each slot's ident LED is put back the way it was: slots whose
+// LED was already on (e.g. a bay being located) are left on. Every LED
+// turned on here gets an LED marker until it's off again, so 'locate
+// --reset-all' can clear them if the process is killed. With dryRun the
+// populated slots are returned without touching any LED.
+func BlinkAllSlotsWithContext(ctx context.Context, enc *LocateInfo, duration time.Duration, dryRun bool) ([]int, error) {
+	sgDevice := enc.SGDevice
+	slots, err := readJoinSlots(sgDevice)
+	if err != nil {
+		return nil, err
+	}
+
+	var populated, lit []int
+	for idx, s := range slots {
+		if s.Populated {
+			populated = append(populated, idx)
+		}
+	}
+	sort.Ints(populated)
+	if len(populated) == 0 {
+		return nil, fmt.Errorf("no populated slots in %s", sgDevice)
+	}
+	if dryRun {
+		return populated, nil
+	}
+
+	// slots is the captured prior state (what GetSlotLEDState reports)
+	var setErr error
+	for _, idx := range populated {
+		if slots[idx].Locate {
+			continue
+		}
+		if err := SetSlotIdentLED(sgDevice, idx, true); err != nil {
+			setErr = fmt.Errorf("failed to turn on LED of slot %d: %w", idx, err)
+			break
+		}
+		lit = append(lit, idx)
+		// Best effort, like the single-bay locate: a missing marker only
+		// matters if this process dies before the restore below
+		WriteLEDMarker(slotInfo(enc, idx), duration)
+	}
+
+	if setErr == nil {
+		select {
+		case <-time.After(duration):
+		case <-ctx.Done():
+		}
+	}
+
+	// Restore: only the LEDs turned on here go off again
+	for _, idx := range lit {
+		if err := SetSlotIdentLED(sgDevice, idx, false); err != nil && setErr == nil {
+			setErr = fmt.Errorf("failed to turn off LED of slot %d: %w", idx, err)
+			continue
+		}
+		RemoveLEDMarker(slotInfo(enc, idx))
+	}
+	return populated, setErr
+}
 
-	return state, nil
+// slotInfo returns enc addressed at SES element idx
+func slotInfo(enc *LocateInfo, idx int) *LocateInfo {
+	info := *enc
+	info.Slot = enc.StartSlot + idx
+	return &info
 }
 
 // LocateWithTimeout turns on the locate LED for a specified duration
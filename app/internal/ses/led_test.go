@@ -0,0 +1,35 @@
+package ses
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLocateLEDCommandStartSlot(t *testing.T) {
+	tests := []struct {
+		name      string
+		slot      int
+		startSlot int
+		want      string
+	}{
+		{"zero-based enclosure", 5, 0, "--dev-slot-num=5"},
+		{"one-based enclosure", 5, 1, "--dev-slot-num=4"},
+		{"first bay of one-based enclosure", 1, 1, "--dev-slot-num=0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info := &LocateInfo{Slot: tt.slot, StartSlot: tt.startSlot, SGDevice: "/dev/sg3"}
+			mechanism, cmd := LocateLEDCommand(info, true)
+			if mechanism != LEDMechanismSgSes {
+				t.Fatalf("mechanism = %q, want %q", mechanism, LEDMechanismSgSes)
+			}
+			if !strings.Contains(cmd, tt.want+" ") {
+				t.Errorf("command = %q, want %s", cmd, tt.want)
+			}
+			if got := info.DevSlotNum(); got != tt.slot-tt.startSlot {
+				t.Errorf("DevSlotNum() = %d, want %d", got, tt.slot-tt.startSlot)
+			}
+		})
+	}
+}
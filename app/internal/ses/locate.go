@@ -2,6 +2,7 @@ package ses
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"regexp"
 	"strconv"
@@ -15,19 +16,21 @@ import (
 // DefaultLocateTimeout is the default duration for locate LED
 const DefaultLocateTimeout = 30 * time.Second
 
-// encSlotPattern matches "enclosure:slot" format like "0:5" or "1:12"
-var encSlotPattern = regexp.MustCompile(`^(\d+):(\d+)$`)
+// encSlotPattern matches "enclosure:slot" format like "0:5" or "1:12",
+// optionally prefixed with the controller ("c1:0:5")
+var encSlotPattern = regexp.MustCompile(`^(?:(c\d+):)?(\d+):(\d+)$`)
 
-// ParseEnclosureSlot parses an "enclosure:slot" string
-// Returns enclosure, slot, and true if parsing succeeded
-func ParseEnclosureSlot(query string) (enclosure, slot int, ok bool) {
+// ParseEnclosureSlot parses an "enclosure:slot" or "controller:enclosure:slot"
+// string. Returns the controller ("" if not given), enclosure, slot, and true
+// if parsing succeeded.
+func ParseEnclosureSlot(query string) (controller string, enclosure, slot int, ok bool) {
 	matches := encSlotPattern.FindStringSubmatch(query)
-	if len(matches) != 3 {
-		return 0, 0, false
+	if len(matches) != 4 {
+		return "", 0, 0, false
 	}
-	enclosure, _ = strconv.Atoi(matches[1])
-	slot, _ = strconv.Atoi(matches[2])
-	return enclosure, slot, true
+	enclosure, _ = strconv.Atoi(matches[2])
+	slot, _ = strconv.Atoi(matches[3])
+	return matches[1], enclosure, slot, true
 }
 
 // GetLocateInfo returns detailed information about a device for the locate command
@@ -41,6 +44,9 @@ func GetLocateInfo(query string) (*LocateInfo, error) {
 
 	entity, matchedAs, err := idx.Lookup(query)
 	if err != nil {
+		if errors.Is(err, identify.ErrAmbiguous) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("device not found: %s", query)
 	}
 
@@ -64,28 +70,32 @@ func GetLocateInfo(query string) (*LocateInfo, error) {
 		return info, fmt.Errorf("device %s has no serial number (needed for HBA lookup)", query)
 	}
 
-	hbaDev := hba.GetDeviceBySerial(info.Serial)
+	hbaDev, err := hba.FindDeviceBySerial(info.Serial)
+	if err != nil && errors.Is(err, hba.ErrAmbiguousSerial) {
+		return info, err
+	}
 	if hbaDev == nil {
 		return info, fmt.Errorf("device %s not found in HBA (serial: %s) - not in a JBOD enclosure?", query, info.Serial)
 	}
 
 	info.EnclosureID = hbaDev.EnclosureID
 	info.Slot = hbaDev.Slot
+	// Enclosure numbers are per controller, so resolve the enclosure
+	// through one the drive was seen on
+	if len(hbaDev.Paths) > 0 {
+		info.Controller = hbaDev.Paths[0]
+	}
 
 	// Get enclosure info to find SAS address for SES mapping
-	_, enclosures, _, err := hba.FetchSas3ircuData(0, false)
+	enclosures, err := hba.AllEnclosures()
 	if err != nil {
 		return info, fmt.Errorf("failed to fetch HBA enclosure data: %w", err)
 	}
 
-	var enclosure *hba.EnclosureInfo
-	for i := range enclosures {
-		if enclosures[i].ID == hbaDev.EnclosureID {
-			enclosure = &enclosures[i]
-			break
-		}
+	enclosure, err := hba.FindEnclosure(enclosures, info.Controller, hbaDev.EnclosureID)
+	if err != nil {
+		return info, err
 	}
-
 	if enclosure == nil {
 		return info, fmt.Errorf("enclosure %d not found in HBA data", hbaDev.EnclosureID)
 	}
@@ -97,53 +107,93 @@ func GetLocateInfo(query string) (*LocateInfo, error) {
 	}
 
 	info.SGDevice = sesEnc.SGDevice
+	info.EnclosureHCTL = sesEnc.HCTL
+	info.StartSlot = enclosure.StartSlot
+	info.SlotLabel = sesEnc.SlotLabel(info.DevSlotNum())
 
 	return info, nil
 }
 
-// GetLocateInfoBySlot returns locate info for a specific enclosure:slot
+// GetLocateInfoBySlot returns locate info for a specific enclosure:slot on
+// controller ("" for any controller; see hba.FindDeviceBySlot)
 // This works even when no drive is present (for locating empty bays)
-func GetLocateInfoBySlot(enclosure, slot int) (*LocateInfo, error) {
+func GetLocateInfoBySlot(controller string, enclosure, slot int) (*LocateInfo, error) {
 	info := &LocateInfo{
 		Query:       fmt.Sprintf("%d:%d", enclosure, slot),
 		MatchedAs:   "enclosure_slot",
+		Controller:  controller,
 		EnclosureID: enclosure,
 		Slot:        slot,
 	}
+	if controller != "" {
+		info.Query = controller + ":" + info.Query
+	}
 
 	// Check if there's a device at this slot
-	hbaDev := hba.GetDeviceBySlot(enclosure, slot)
+	hbaDev, err := hba.FindDeviceBySlot(controller, enclosure, slot)
+	if err != nil && errors.Is(err, hba.ErrAmbiguousSlot) {
+		return info, err
+	}
 	if hbaDev != nil {
 		info.Serial = hbaDev.Serial
 		info.Model = hbaDev.Model
 	}
 
-	// Get enclosure info for SES mapping
-	_, enclosures, _, err := hba.FetchSas3ircuData(0, false)
+	sesEnc, startSlot, err := GetEnclosureSES(controller, enclosure)
 	if err != nil {
-		return info, fmt.Errorf("failed to fetch HBA enclosure data: %w", err)
+		return info, err
 	}
 
-	var enc *hba.EnclosureInfo
-	for i := range enclosures {
-		if enclosures[i].ID == enclosure {
-			enc = &enclosures[i]
-			break
-		}
+	info.SGDevice = sesEnc.SGDevice
+	info.EnclosureHCTL = sesEnc.HCTL
+	info.StartSlot = startSlot
+	info.SlotLabel = sesEnc.SlotLabel(info.DevSlotNum())
+	return info, nil
+}
+
+// GetEnclosureSES maps an HBA enclosure ID on controller ("" for any) to its
+// SES device, also returning the enclosure's first slot number
+// (hba.EnclosureInfo.StartSlot)
+func GetEnclosureSES(controller string, enclosure int) (*EnclosureSES, int, error) {
+	enclosures, err := hba.AllEnclosures()
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch HBA enclosure data: %w", err)
 	}
 
+	enc, err := hba.FindEnclosure(enclosures, controller, enclosure)
+	if err != nil {
+		return nil, 0, err
+	}
 	if enc == nil {
-		return info, fmt.Errorf("enclosure %d not found", enclosure)
+		return nil, 0, fmt.Errorf("enclosure %d not found", enclosure)
 	}
 
-	// Map to SES device
 	sesEnc, err := MapEnclosureToSGDevice(enc.ID, enc.LogicalID, enc.SASAddress)
 	if err != nil {
-		return info, fmt.Errorf("could not find SES device for enclosure %d: %w", enclosure, err)
+		return nil, 0, fmt.Errorf("could not find SES device for enclosure %d: %w", enclosure, err)
 	}
+	return sesEnc, enc.StartSlot, nil
+}
 
-	info.SGDevice = sesEnc.SGDevice
-	return info, nil
+// LookupSlotLabel returns the enclosure's own label for a bay of an
+// enclosure on controller ("" for any; see EnclosureSES.SlotLabels), or ""
+// if the enclosure can't be mapped to an SES device or provides no labels
+func LookupSlotLabel(controller string, enclosureID, slot int) string {
+	enclosures, err := hba.AllEnclosures()
+	if err != nil {
+		return ""
+	}
+
+	enc, err := hba.FindEnclosure(enclosures, controller, enclosureID)
+	if err != nil || enc == nil {
+		return ""
+	}
+
+	sesEnc, err := MapEnclosureToSGDevice(enc.ID, enc.LogicalID, enc.SASAddress)
+	if err != nil {
+		return ""
+	}
+	return sesEnc.SlotLabel(slot - enc.StartSlot)
 }
 
 // GetLocateInfoFromDB looks up a drive's last-known location from database
@@ -176,19 +226,15 @@ func GetLocateInfoFromDB(query string, database *db.DB) (*LocateInfo, error) {
 	}
 
 	// Get enclosure info for SES mapping
-	_, enclosures, _, err := hba.FetchSas3ircuData(0, false)
+	enclosures, err := hba.AllEnclosures()
 	if err != nil {
 		return info, fmt.Errorf("failed to fetch HBA enclosure data: %w", err)
 	}
 
-	var enc *hba.EnclosureInfo
-	for i := range enclosures {
-		if enclosures[i].ID == *drive.EnclosureID {
-			enc = &enclosures[i]
-			break
-		}
+	enc, err := hba.FindEnclosure(enclosures, "", *drive.EnclosureID)
+	if err != nil {
+		return info, err
 	}
-
 	if enc == nil {
 		return info, fmt.Errorf("enclosure %d not found", *drive.EnclosureID)
 	}
@@ -200,6 +246,9 @@ func GetLocateInfoFromDB(query string, database *db.DB) (*LocateInfo, error) {
 	}
 
 	info.SGDevice = sesEnc.SGDevice
+	info.EnclosureHCTL = sesEnc.HCTL
+	info.StartSlot = enc.StartSlot
+	info.SlotLabel = sesEnc.SlotLabel(info.DevSlotNum())
 	return info, nil
 }
 
@@ -207,8 +256,8 @@ func GetLocateInfoFromDB(query string, database *db.DB) (*LocateInfo, error) {
 // It also supports enclosure:slot format directly
 func GetLocateInfoWithFallback(query string, database *db.DB) (*LocateInfo, error) {
 	// First, check if query is enclosure:slot format
-	if enc, slot, ok := ParseEnclosureSlot(query); ok {
-		return GetLocateInfoBySlot(enc, slot)
+	if controller, enc, slot, ok := ParseEnclosureSlot(query); ok {
+		return GetLocateInfoBySlot(controller, enc, slot)
 	}
 
 	// Try normal live lookup
@@ -217,6 +266,12 @@ func GetLocateInfoWithFallback(query string, database *db.DB) (*LocateInfo, erro
 		return info, nil
 	}
 
+	// Never fall back to the inventory for an ambiguous identifier or a
+	// serial shared by several drives
+	if errors.Is(err, identify.ErrAmbiguous) || errors.Is(err, hba.ErrAmbiguousSerial) {
+		return nil, err
+	}
+
 	// If live lookup failed and we have a database, try DB lookup
 	if database != nil {
 		dbInfo, dbErr := GetLocateInfoFromDB(query, database)
@@ -246,7 +301,7 @@ func LocateByIdentifier(query string, timeout time.Duration) (*LocateInfo, error
 
 	// Turn on LED with timeout
 	ctx := context.Background()
-	err = LocateWithTimeout(ctx, info.SGDevice, info.Slot, timeout)
+	err = LocateWithTimeout(ctx, info.SGDevice, info.DevSlotNum(), timeout)
 
 	return info, err
 }
@@ -262,7 +317,7 @@ func LocateOn(query string) (*LocateInfo, error) {
 		return info, fmt.Errorf("could not determine SES device for enclosure")
 	}
 
-	if err := SetSlotIdentLED(info.SGDevice, info.Slot, true); err != nil {
+	if _, err := SetLocateLED(info, true); err != nil {
 		return info, fmt.Errorf("failed to turn on LED: %w", err)
 	}
 
@@ -280,7 +335,7 @@ func LocateOff(query string) (*LocateInfo, error) {
 		return info, fmt.Errorf("could not determine SES device for enclosure")
 	}
 
-	if err := SetSlotIdentLED(info.SGDevice, info.Slot, false); err != nil {
+	if _, err := SetLocateLED(info, false); err != nil {
 		return info, fmt.Errorf("failed to turn off LED: %w", err)
 	}
 
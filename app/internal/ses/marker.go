@@ -0,0 +1,175 @@
+package ses
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+)
+
+// LEDMarkerDir holds a marker file for every locate LED jbodgod turned on
+// and hasn't turned off yet. It outlives reboots, like the enclosure's LEDs.
+const LEDMarkerDir = "/var/lib/jbodgod/leds"
+
+// LEDMarker records a locate LED turned on by a jbodgod process, so the LED
+// can be found and cleared if that process dies without turning it off
+// (SIGKILL, power loss)
+type LEDMarker struct {
+	Controller    string     `json:"controller,omitempty"`
+	EnclosureID   int        `json:"enclosure"`
+	Slot          int        `json:"slot"`
+	StartSlot     int        `json:"start_slot,omitempty"`
+	SGDevice      string     `json:"sg_device"`
+	EnclosureHCTL string     `json:"enclosure_hctl,omitempty"`
+	DevicePath    string     `json:"device,omitempty"`
+	Serial        string     `json:"serial,omitempty"`
+	PID           int        `json:"pid"`
+	Since         time.Time  `json:"since"`
+	Until         *time.Time `json:"until,omitempty"` // nil when left on (--on)
+}
+
+// markerPath returns the marker file of an enclosure slot
+func markerPath(enclosure, slot int) string {
+	return filepath.Join(LEDMarkerDir, fmt.Sprintf("%d-%d.json", enclosure, slot))
+}
+
+// WriteLEDMarker records that the locate LED of info's slot was turned on by
+// this process. timeout is how long it should stay on, 0 if indefinitely.
+func WriteLEDMarker(info *LocateInfo, timeout time.Duration) error {
+	m := LEDMarker{
+		Controller:    info.Controller,
+		EnclosureID:   info.EnclosureID,
+		Slot:          info.Slot,
+		StartSlot:     info.StartSlot,
+		SGDevice:      info.SGDevice,
+		EnclosureHCTL: info.EnclosureHCTL,
+		DevicePath:    info.DevicePath,
+		Serial:        info.Serial,
+		PID:           os.Getpid(),
+		Since:         time.Now(),
+	}
+	if timeout > 0 {
+		until := m.Since.Add(timeout)
+		m.Until = &until
+	}
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode LED marker: %w", err)
+	}
+	if err := os.MkdirAll(LEDMarkerDir, 0755); err != nil {
+		return fmt.Errorf("failed to create LED marker directory: %w", err)
+	}
+	if err := os.WriteFile(markerPath(info.EnclosureID, info.Slot), data, 0644); err != nil {
+		return fmt.Errorf("failed to write LED marker: %w", err)
+	}
+	return nil
+}
+
+// RemoveLEDMarker deletes the marker of info's slot, once its LED is off
+func RemoveLEDMarker(info *LocateInfo) error {
+	if err := os.Remove(markerPath(info.EnclosureID, info.Slot)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove LED marker: %w", err)
+	}
+	return nil
+}
+
+// ListLEDMarkers returns the recorded LED markers, oldest first
+func ListLEDMarkers() ([]*LEDMarker, error) {
+	files, err := filepath.Glob(filepath.Join(LEDMarkerDir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	var markers []*LEDMarker
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read LED marker: %w", err)
+		}
+		var m LEDMarker
+		if err := json.Unmarshal(data, &m); err != nil {
+			// A torn write from a killed process; nothing to go on
+			os.Remove(f)
+			continue
+		}
+		markers = append(markers, &m)
+	}
+	sort.Slice(markers, func(i, j int) bool { return markers[i].Since.Before(markers[j].Since) })
+	return markers, nil
+}
+
+// Orphaned reports whether the process that turned the LED on is gone, so
+// nothing will turn it off. A timed LED well past its deadline counts as
+// orphaned too, in case its PID has since been reused. LEDs left on with
+// --on (no Until) are never orphaned: they're meant to stay on until
+// 'locate --off'.
+func (m *LEDMarker) Orphaned() bool {
+	if m.Until == nil {
+		return false
+	}
+	if m.PID <= 0 {
+		return true
+	}
+	if m.PID == os.Getpid() {
+		return false
+	}
+	if time.Since(*m.Until) > time.Minute {
+		return true
+	}
+	p, err := os.FindProcess(m.PID)
+	if err != nil {
+		return true
+	}
+	// Signal 0 checks for existence; EPERM means it exists as another user
+	err = p.Signal(syscall.Signal(0))
+	return err != nil && !errors.Is(err, syscall.EPERM)
+}
+
+// ResetOrphanedLEDs turns off the locate LED of every orphaned marker and
+// removes the marker. The slot is looked up again first, since sg device
+// numbers can change across reboots; the recorded one is the fallback.
+// Markers whose LED can't be turned off are kept for another attempt. With
+// dryRun the orphaned markers are returned without changing anything.
+func ResetOrphanedLEDs(dryRun bool) (cleared []*LEDMarker, err error) {
+	markers, err := ListLEDMarkers()
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for _, m := range markers {
+		if !m.Orphaned() {
+			continue
+		}
+		if dryRun {
+			cleared = append(cleared, m)
+			continue
+		}
+
+		info, lookupErr := GetLocateInfoBySlot(m.Controller, m.EnclosureID, m.Slot)
+		if lookupErr != nil || info.SGDevice == "" {
+			info = &LocateInfo{
+				Controller:    m.Controller,
+				EnclosureID:   m.EnclosureID,
+				Slot:          m.Slot,
+				StartSlot:     m.StartSlot,
+				SGDevice:      m.SGDevice,
+				EnclosureHCTL: m.EnclosureHCTL,
+			}
+		}
+		if _, err := SetLocateLED(info, false); err != nil {
+			errs = append(errs, fmt.Errorf("enclosure %d slot %d: %w", m.EnclosureID, m.Slot, err))
+			continue
+		}
+		if err := RemoveLEDMarker(info); err != nil {
+			errs = append(errs, err)
+		}
+		cleared = append(cleared, m)
+	}
+	return cleared, errors.Join(errs...)
+}
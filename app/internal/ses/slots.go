@@ -0,0 +1,127 @@
+package ses
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/collector"
+)
+
+// SlotStatus is the state of one enclosure bay, merged from sg_ses and the
+// kernel's sysfs enclosure class
+type SlotStatus struct {
+	Index      int    `json:"index"` // SES element index, as used by --dev-slot-num
+	Label      string `json:"label,omitempty"`
+	Status     string `json:"status,omitempty"` // OK, Not installed, ...
+	Populated  bool   `json:"populated"`
+	DeviceHCTL string `json:"device_hctl,omitempty"`
+	Locate     bool   `json:"locate"`
+	Fault      bool   `json:"fault"`
+}
+
+// GetSlotStatuses returns every device slot of the enclosure behind an SES
+// device. Either source may be missing (no sg_ses, or no ses kernel module);
+// an error is returned only if neither yields any slots.
+func GetSlotStatuses(sgDevice string) ([]SlotStatus, error) {
+	if !strings.HasPrefix(sgDevice, "/dev/") {
+		sgDevice = "/dev/" + sgDevice
+	}
+
+	var enc *EnclosureSES
+	if encs, err := DiscoverSESDevices(); err == nil {
+		for _, e := range encs {
+			if e.SGDevice == sgDevice {
+				enc = e
+				break
+			}
+		}
+	}
+
+	slots, sesErr := readJoinSlots(sgDevice)
+	if sesErr != nil {
+		slots = make(map[int]*SlotStatus)
+	}
+
+	if enc != nil && enc.HCTL != "" {
+		if sysEnc := collector.CollectSysfsEnclosures()[enc.HCTL]; sysEnc != nil {
+			for i, s := range sysEnc.ElementSlots() {
+				slot := slots[i]
+				if slot == nil {
+					slot = &SlotStatus{Index: i, Status: s.Status}
+					slots[i] = slot
+				}
+				if s.DeviceHCTL != nil {
+					slot.DeviceHCTL = *s.DeviceHCTL
+				}
+				slot.Populated = slot.Populated || s.Occupied()
+				slot.Locate = slot.Locate || s.Locate
+				slot.Fault = slot.Fault || s.Fault
+			}
+		}
+	}
+
+	if len(slots) == 0 {
+		if sesErr != nil {
+			return nil, sesErr
+		}
+		return nil, fmt.Errorf("no device slots found for %s", sgDevice)
+	}
+
+	result := make([]SlotStatus, 0, len(slots))
+	for _, s := range slots {
+		s.Label = enc.SlotLabel(s.Index)
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Index < result[j].Index })
+	return result, nil
+}
+
+// joinElementPattern matches an element header of 'sg_ses --join' output,
+// optionally preceded by the element's descriptor, e.g.
+// "SLOT 03 [0,3]  Element type: Array device slot"
+var joinElementPattern = regexp.MustCompile(`\[\d+,(-?\d+)\]\s+Element type:\s*(.*)$`)
+
+// joinStatusPattern matches the element status code, e.g. "status: Not installed"
+var joinStatusPattern = regexp.MustCompile(`(?i)\bstatus:\s*([^,]+)`)
+
+// parseJoinSlots extracts the device slots from 'sg_ses --page=es --join'
+// output, keyed by element index. Overall elements (index -1) are skipped.
+func parseJoinSlots(out string) map[int]*SlotStatus {
+	slots := make(map[int]*SlotStatus)
+	var cur *SlotStatus
+
+	for _, line := range strings.Split(out, "\n") {
+		if m := joinElementPattern.FindStringSubmatch(line); m != nil {
+			cur = nil
+			idx, err := strconv.Atoi(m[1])
+			if err != nil || idx < 0 || !strings.Contains(strings.ToLower(m[2]), "device slot") {
+				continue
+			}
+			cur = &SlotStatus{Index: idx}
+			slots[idx] = cur
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		if m := joinStatusPattern.FindStringSubmatch(line); m != nil && cur.Status == "" {
+			if status := strings.TrimSpace(m[1]); status != "" {
+				cur.Status = status
+				lower := strings.ToLower(status)
+				cur.Populated = lower != "not installed" && lower != "unsupported"
+			}
+		}
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "ident=1") {
+			cur.Locate = true
+		}
+		if strings.Contains(lower, "fault sensed=1") || strings.Contains(lower, "fault reqstd=1") {
+			cur.Fault = true
+		}
+	}
+	return slots
+}
@@ -0,0 +1,145 @@
+package ses
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/cache"
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// GetSlotTemperature returns the temperature (°C) the enclosure reports for a
+// device slot, read from its SES temperature sensor elements. The drive
+// itself isn't touched, so this works for drives in standby. slot is the SES
+// element index (LocateInfo.DevSlotNum).
+func GetSlotTemperature(sgDevice string, slot int) (int, error) {
+	temps, err := slotTemperatures(sgDevice)
+	if err != nil {
+		return 0, err
+	}
+	temp, ok := temps[slot]
+	if !ok {
+		return 0, fmt.Errorf("%s has no temperature sensor for slot %d", sgDevice, slot)
+	}
+	return temp, nil
+}
+
+// slotTemperatures returns the per-slot sensor readings of an enclosure,
+// cached with the dynamic (temperature) TTL so a full shelf costs one sg_ses
+func slotTemperatures(sgDevice string) (map[int]int, error) {
+	c := cache.Global()
+	cacheKey := "ses:temps:" + sgDevice
+
+	if cached := c.Get(cacheKey); cached != nil {
+		return cached.(map[int]int), nil
+	}
+
+	if err := CheckSgSesInstalled(); err != nil {
+		return nil, err
+	}
+	out, err := privexec.RunSudo("sg_ses", "--page=es", "--join", sgDevice)
+	if err != nil {
+		return nil, fmt.Errorf("sg_ses failed: %w", err)
+	}
+
+	temps := parseSlotTemperatures(string(out))
+	c.SetDynamic(cacheKey, temps)
+	return temps, nil
+}
+
+// joinHeaderPattern matches an element header of 'sg_ses --join' output and
+// captures the element's descriptor, index and type, e.g.
+// "Temp Sensor Slot 03 [0,3]  Element type: Temperature sensor"
+var joinHeaderPattern = regexp.MustCompile(`^\s*(.*?)\s*\[\d+,(-?\d+)\]\s+Element type:\s*(.*)$`)
+
+// joinTempPattern matches a sensor reading, e.g. "Temperature=31 C"
+var joinTempPattern = regexp.MustCompile(`(?i)temperature\s*=\s*(-?\d+)\s*C`)
+
+// slotNumberPattern finds a slot number in a sensor descriptor, e.g.
+// "Drive Temp 03", "Slot 3 Temperature", "HDD12"
+var slotNumberPattern = regexp.MustCompile(`(?i)(?:slot|bay|disk|drive|hdd)\D*?(\d+)`)
+
+// parseSlotTemperatures maps temperature sensor readings from 'sg_ses
+// --page=es --join' output to device slots (element indexes). A sensor
+// belongs to a slot when its descriptor names one, resolved through the slot
+// descriptors when they're numbered too (so "Slot 01 Temp" finds the slot
+// described as "SLOT 01" whether or not the enclosure counts from 1); failing
+// that, if the enclosure has exactly one sensor per slot they're paired in
+// element order. Enclosure-wide sensors (ambient, PSU, ...) are ignored.
+func parseSlotTemperatures(out string) map[int]int {
+	type sensor struct {
+		descriptor string
+		temp       *int
+	}
+	var sensors []*sensor
+	deviceSlots := 0
+	slotByNumber := make(map[int]int) // number in slot descriptor -> element index
+	var cur *sensor
+
+	for _, line := range strings.Split(out, "\n") {
+		if m := joinHeaderPattern.FindStringSubmatch(line); m != nil {
+			cur = nil
+			idx, err := strconv.Atoi(m[2])
+			if err != nil || idx < 0 {
+				continue
+			}
+			elemType := strings.ToLower(m[3])
+			switch {
+			case strings.Contains(elemType, "device slot"):
+				deviceSlots++
+				if n := descriptorNumber(m[1]); n >= 0 {
+					slotByNumber[n] = idx
+				}
+			case strings.Contains(elemType, "temperature"):
+				cur = &sensor{descriptor: m[1]}
+				sensors = append(sensors, cur)
+			}
+			continue
+		}
+		if cur == nil || cur.temp != nil {
+			continue
+		}
+		if m := joinTempPattern.FindStringSubmatch(line); m != nil {
+			if t, err := strconv.Atoi(m[1]); err == nil {
+				cur.temp = &t
+			}
+		}
+	}
+
+	temps := make(map[int]int)
+	for _, s := range sensors {
+		if s.temp == nil {
+			continue
+		}
+		if m := slotNumberPattern.FindStringSubmatch(s.descriptor); m != nil {
+			slot, _ := strconv.Atoi(m[1])
+			if idx, ok := slotByNumber[slot]; ok {
+				slot = idx
+			}
+			temps[slot] = *s.temp
+		}
+	}
+	if len(temps) == 0 && deviceSlots > 1 && len(sensors) == deviceSlots {
+		for i, s := range sensors {
+			if s.temp != nil {
+				temps[i] = *s.temp
+			}
+		}
+	}
+	return temps
+}
+
+// descriptorNumber returns the first number in an element descriptor, or -1
+func descriptorNumber(descriptor string) int {
+	if m := digitsPattern.FindString(descriptor); m != "" {
+		if n, err := strconv.Atoi(m); err == nil {
+			return n
+		}
+	}
+	return -1
+}
+
+// digitsPattern matches the first number in a descriptor
+var digitsPattern = regexp.MustCompile(`\d+`)
@@ -18,17 +18,30 @@ type EnclosureSES struct {
 	LogicalID   string // Enclosure logical ID for cross-reference
 	SASAddress  string // SAS address for matching
 	SGDevice    string // /dev/sg<N> control device
+	HCTL        string // H:C:T:L of the SES device (its /sys/class/enclosure name)
 	NumSlots    int    // Total slots in enclosure
 	Vendor      string // Enclosure vendor
 	Product     string // Enclosure product name
+
+	// SlotLabels holds the element descriptor text for each device slot
+	// (e.g. "SLOT 00", "Bay A1"), usually matching the chassis silkscreen
+	SlotLabels map[int]string
+}
+
+// SlotLabel returns the enclosure's own label for a slot, or "" if it
+// doesn't provide one
+func (e *EnclosureSES) SlotLabel(slot int) string {
+	if e == nil {
+		return ""
+	}
+	return e.SlotLabels[slot]
 }
 
 // SlotLEDState represents the LED state of a slot
 type SlotLEDState struct {
-	Slot   int
-	Ident  bool // Locate/Identify LED
-	Fault  bool // Fault LED
-	Active bool // Active/Activity LED
+	Slot  int
+	Ident bool // Locate/Identify LED
+	Fault bool // Fault LED
 }
 
 // LocateInfo contains information about a located device for display
@@ -38,7 +51,22 @@ type LocateInfo struct {
 	DevicePath  string `json:"device_path"`
 	Serial      string `json:"serial"`
 	Model       string `json:"model,omitempty"`
+	Controller  string `json:"controller,omitempty"` // HBA (c0, c1, ...) whose enclosure numbering EnclosureID uses
 	EnclosureID int    `json:"enclosure_id"`
 	Slot        int    `json:"slot"`
+	SlotLabel   string `json:"slot_label,omitempty"`
 	SGDevice    string `json:"sg_device"`
+	// EnclosureHCTL names the enclosure under /sys/class/enclosure, used to
+	// drive the locate LED without sg_ses
+	EnclosureHCTL string `json:"enclosure_hctl,omitempty"`
+	// StartSlot is the enclosure's first slot number as reported by the HBA
+	// (hba.EnclosureInfo.StartSlot); see DevSlotNum
+	StartSlot int `json:"start_slot,omitempty"`
+}
+
+// DevSlotNum returns the SES element index of the slot, as used by sg_ses
+// --dev-slot-num and the sysfs enclosure slot entries. The HBA numbers slots
+// from the enclosure's StartSlot while SES elements always start at 0.
+func (i *LocateInfo) DevSlotNum() int {
+	return i.Slot - i.StartSlot
 }
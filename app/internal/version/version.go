@@ -4,4 +4,4 @@ package version
 // This MUST be incremented for each build that includes changes.
 // Use semantic versioning: MAJOR.MINOR.PATCH
 // For very minor changes, append alpha characters (e.g., 1.2.3a, 1.2.3b)
-const Version = "1.7.1"
+const Version = "1.85.48"
@@ -0,0 +1,19 @@
+// Package wwn normalizes World Wide Names so the same drive compares equal
+// whichever tool reported it.
+package wwn
+
+import "strings"
+
+// Normalize converts a WWN from any source (sysfs "naa.5000c5...",
+// udev/smartctl "0x5000c5...", "5 000c50 0a6e7b82b") to lowercase hex with
+// no prefix or separators
+func Normalize(wwn string) string {
+	wwn = strings.ToLower(strings.TrimSpace(wwn))
+	for _, prefix := range []string{"0x", "naa.", "t10.", "eui."} {
+		if strings.HasPrefix(wwn, prefix) {
+			wwn = wwn[len(prefix):]
+			break
+		}
+	}
+	return strings.NewReplacer(" ", "", ":", "", "-", "", ".", "").Replace(wwn)
+}
@@ -0,0 +1,157 @@
+package zfs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// PoolEvent is one entry of 'zpool events -v'
+type PoolEvent struct {
+	Time    time.Time         `json:"time"`
+	Class   string            `json:"class"` // e.g. sysevent.fs.zfs.scrub_finish
+	Pool    string            `json:"pool,omitempty"`
+	Vdev    string            `json:"vdev,omitempty"` // vdev_path, for device events
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// eventTimeLayout is the TIME column of 'zpool events', e.g.
+// "Oct 15 2026 10:12:33.123456789" (spaces collapsed before parsing)
+const eventTimeLayout = "Jan _2 2006 15:04:05.999999999"
+
+// GetPoolEvents returns the events ZFS has logged for a pool, oldest first.
+// The kernel keeps a bounded number of events in memory, so this only goes
+// back to the last boot or module load. limit > 0 keeps the newest limit.
+func GetPoolEvents(poolName string, limit int) ([]PoolEvent, error) {
+	out, err := privexec.Run("zpool", "events", "-v")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool events: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+
+	var events []PoolEvent
+	for _, e := range parseZpoolEvents(string(out)) {
+		if e.Pool == poolName {
+			events = append(events, e)
+		}
+	}
+	if limit > 0 && len(events) > limit {
+		events = events[len(events)-limit:]
+	}
+	return events, nil
+}
+
+// parseZpoolEvents parses 'zpool events -v' output: a "TIME CLASS" line per
+// event followed by its indented name = value pairs. Nested nvlists
+// (detector, ...) are skipped.
+func parseZpoolEvents(output string) []PoolEvent {
+	var events []PoolEvent
+	var cur *PoolEvent
+	depth := 0
+
+	for _, line := range strings.Split(output, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] != ' ' && line[0] != '\t' {
+			fields := strings.Fields(line)
+			if len(fields) < 5 || fields[0] == "TIME" {
+				continue
+			}
+			t, err := time.ParseInLocation(eventTimeLayout, strings.Join(fields[:4], " "), time.Local)
+			if err != nil {
+				continue
+			}
+			events = append(events, PoolEvent{Time: t, Class: fields[4]})
+			cur = &events[len(events)-1]
+			depth = 0
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "(end ") {
+			if depth > 0 {
+				depth--
+			}
+			continue
+		}
+		key, value, ok := strings.Cut(trimmed, " = ")
+		if !ok {
+			continue
+		}
+		if value == "(embedded nvlist)" || strings.HasPrefix(value, "(array of embedded nvlists)") {
+			depth++
+			continue
+		}
+		if depth > 0 {
+			continue
+		}
+
+		value = eventValue(value)
+		switch key {
+		case "class", "version", "eid", "time":
+		case "pool":
+			cur.Pool = value
+		case "vdev_path":
+			cur.Vdev = value
+		default:
+			if cur.Details == nil {
+				cur.Details = make(map[string]string)
+			}
+			cur.Details[key] = value
+		}
+	}
+	return events
+}
+
+// eventValue unquotes a string value; a trailing code is dropped, so
+// "FAULTED" (0x5) becomes FAULTED
+func eventValue(v string) string {
+	if strings.HasPrefix(v, `"`) {
+		if end := strings.Index(v[1:], `"`); end >= 0 {
+			return v[1 : end+1]
+		}
+	}
+	return v
+}
+
+// vdevStateNames are the vdev_state_t values
+var vdevStateNames = []string{"UNKNOWN", "CLOSED", "OFFLINE", "REMOVED", "CANT_OPEN", "FAULTED", "DEGRADED", "ONLINE"}
+
+// VdevStateName returns the name of a vdev_state/vdev_laststate event value
+func VdevStateName(v string) string {
+	if n, err := strconv.ParseInt(v, 0, 64); err == nil && n >= 0 && int(n) < len(vdevStateNames) {
+		return vdevStateNames[n]
+	}
+	return v
+}
+
+// ShortClass returns the class without its sysevent/resource/ereport
+// prefix, e.g. "scrub_finish", "statechange", "checksum"
+func (e PoolEvent) ShortClass() string {
+	if _, after, ok := strings.Cut(e.Class, ".fs.zfs."); ok {
+		return after
+	}
+	return e.Class
+}
+
+// IsDeviceOrScan reports whether the event is a device fault or state
+// change, a device error report, or a scrub/resilver start or finish - the
+// events that line up with drive events in the inventory
+func (e PoolEvent) IsDeviceOrScan() bool {
+	if strings.HasPrefix(e.Class, "ereport.fs.zfs.") {
+		return true
+	}
+	switch e.ShortClass() {
+	case "statechange", "removed", "vdev_attach", "vdev_remove", "vdev_clear", "vdev_online",
+		"scrub_start", "scrub_finish", "scrub_abort", "resilver_start", "resilver_finish":
+		return true
+	}
+	return false
+}
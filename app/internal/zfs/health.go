@@ -3,10 +3,11 @@ package zfs
 import (
 	"bufio"
 	"fmt"
-	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
 )
 
 // PoolHealth represents the health status of a ZFS pool
@@ -18,15 +19,18 @@ type PoolHealth struct {
 	ScanState   string       `json:"scan_state,omitempty"` // scrub, resilver, none
 	ScanPercent float64      `json:"scan_percent,omitempty"` // Progress percentage
 	ScanMessage string       `json:"scan_message,omitempty"` // Full scan line
+	ScanETA     string       `json:"scan_eta,omitempty"` // Time remaining for in-progress scan
+	ScanRate    string       `json:"scan_rate,omitempty"` // Issue (or scan) rate of in-progress scan, e.g. "400M/s"
 	Errors      string       `json:"errors,omitempty"` // Error summary
 	Vdevs       []VdevHealth `json:"vdevs"`
 	TotalErrors int64        `json:"total_errors"` // Sum of all error counts
+	TotalSlowIOs int64       `json:"total_slow_ios,omitempty"` // Sum of slow I/O counts (a warning, not an error)
 }
 
 // VdevHealth represents per-vdev/device health
 type VdevHealth struct {
 	Name       string       `json:"name"`
-	Type       string       `json:"type"`        // pool, raidz, mirror, disk, spare, log, cache
+	Type       string       `json:"type"`        // pool, raidz, mirror, disk, spare, log, cache, special, dedup
 	State      string       `json:"state"`       // ONLINE, DEGRADED, FAULTED, OFFLINE, REMOVED, UNAVAIL
 	DevicePath string       `json:"device_path,omitempty"` // /dev/sdX for leaf devices
 	ReadErrs   int64        `json:"read_errors"`
@@ -49,18 +53,21 @@ const (
 
 // Vdev types
 const (
-	TypePool   = "pool"
-	TypeRaidz  = "raidz"
-	TypeMirror = "mirror"
-	TypeDisk   = "disk"
-	TypeSpare  = "spare"
-	TypeLog    = "log"
-	TypeCache  = "cache"
+	TypePool    = "pool"
+	TypeRaidz   = "raidz"
+	TypeMirror  = "mirror"
+	TypeDisk    = "disk"
+	TypeSpare   = "spare"
+	TypeLog     = "log"
+	TypeCache   = "cache"
+	// Allocation classes for metadata and dedup tables
+	TypeSpecial = "special"
+	TypeDedup   = "dedup"
 )
 
 // GetPoolHealth parses zpool status for a specific pool
 func GetPoolHealth(poolName string) (*PoolHealth, error) {
-	out, err := exec.Command("zpool", "status", "-vL", poolName).CombinedOutput()
+	out, err := zpoolStatus(poolName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pool status: %w", err)
 	}
@@ -75,7 +82,7 @@ func GetPoolHealth(poolName string) (*PoolHealth, error) {
 
 // GetAllPoolHealth returns health for all pools
 func GetAllPoolHealth() ([]*PoolHealth, error) {
-	out, err := exec.Command("zpool", "status", "-vL").CombinedOutput()
+	out, err := zpoolStatus()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get pool status: %w", err)
 	}
@@ -83,6 +90,21 @@ func GetAllPoolHealth() ([]*PoolHealth, error) {
 	return parseZpoolStatus(string(out)), nil
 }
 
+// zpoolStatus runs zpool status with the SLOW column (-s), retrying without
+// it on ZFS versions that don't support the flag
+func zpoolStatus(pools ...string) ([]byte, error) {
+	out, err := privexec.Run("zpool", append([]string{"status", "-svL"}, pools...)...)
+	if err != nil {
+		out, err = privexec.Run("zpool", append([]string{"status", "-vL"}, pools...)...)
+	}
+	return out, err
+}
+
+// ScanInProgress returns true if a scrub or resilver is running
+func (p *PoolHealth) ScanInProgress() bool {
+	return p.ScanState == "scrub" || p.ScanState == "resilver"
+}
+
 // IsDegraded returns true if pool is not fully healthy
 func (p *PoolHealth) IsDegraded() bool {
 	return p.State != StateOnline
@@ -93,6 +115,11 @@ func (p *PoolHealth) HasErrors() bool {
 	return p.TotalErrors > 0
 }
 
+// HasSlowIOs returns true if any device reported slow I/Os
+func (p *PoolHealth) HasSlowIOs() bool {
+	return p.TotalSlowIOs > 0
+}
+
 // GetFaultedDevices returns devices that are not ONLINE
 func (p *PoolHealth) GetFaultedDevices() []VdevHealth {
 	var faulted []VdevHealth
@@ -113,7 +140,8 @@ func (p *PoolHealth) GetAllDevices() []VdevHealth {
 
 func getFaultedRecursive(v VdevHealth) []VdevHealth {
 	var faulted []VdevHealth
-	if v.State != StateOnline && v.Type == TypeDisk {
+	// Idle hot spares report AVAIL, spares in use INUSE
+	if v.State != StateOnline && v.State != "AVAIL" && v.State != "INUSE" && v.Type == TypeDisk {
 		faulted = append(faulted, v)
 	}
 	for _, child := range v.Children {
@@ -138,6 +166,8 @@ func parseZpoolStatus(output string) []*PoolHealth {
 	var pools []*PoolHealth
 	var current *PoolHealth
 	var inConfig bool
+	var inScan bool
+	var slowColumn bool
 	var configLines []string
 
 	scanner := bufio.NewScanner(strings.NewReader(output))
@@ -148,7 +178,7 @@ func parseZpoolStatus(output string) []*PoolHealth {
 		if strings.HasPrefix(line, "  pool:") {
 			// Save previous pool
 			if current != nil {
-				parseConfigSection(current, configLines)
+				parseConfigSection(current, configLines, slowColumn)
 				pools = append(pools, current)
 			}
 
@@ -156,6 +186,7 @@ func parseZpoolStatus(output string) []*PoolHealth {
 				Name: strings.TrimSpace(strings.TrimPrefix(line, "  pool:")),
 			}
 			inConfig = false
+			slowColumn = false
 			configLines = nil
 			continue
 		}
@@ -164,6 +195,14 @@ func parseZpoolStatus(output string) []*PoolHealth {
 			continue
 		}
 
+		// Scan progress continues on tab-indented lines after "  scan:"
+		if inScan && strings.HasPrefix(line, "\t") {
+			current.ScanMessage += " " + strings.TrimSpace(line)
+			parseScanState(current)
+			continue
+		}
+		inScan = false
+
 		// Parse pool properties
 		if strings.HasPrefix(line, " state:") {
 			current.State = strings.TrimSpace(strings.TrimPrefix(line, " state:"))
@@ -174,13 +213,17 @@ func parseZpoolStatus(output string) []*PoolHealth {
 		} else if strings.HasPrefix(line, "  scan:") {
 			current.ScanMessage = strings.TrimSpace(strings.TrimPrefix(line, "  scan:"))
 			parseScanState(current)
+			inScan = true
 		} else if strings.HasPrefix(line, "errors:") {
 			current.Errors = strings.TrimSpace(strings.TrimPrefix(line, "errors:"))
 		} else if strings.HasPrefix(line, "config:") {
 			inConfig = true
 		} else if inConfig {
-			// Skip header line (NAME STATE READ WRITE CKSUM)
+			// Skip header line (NAME STATE READ WRITE CKSUM [SLOW]), noting
+			// whether this ZFS version prints the SLOW column
 			if strings.Contains(line, "NAME") && strings.Contains(line, "STATE") {
+				fields := strings.Fields(line)
+				slowColumn = len(fields) >= 6 && fields[5] == "SLOW"
 				continue
 			}
 			// Skip empty lines in config
@@ -198,13 +241,20 @@ func parseZpoolStatus(output string) []*PoolHealth {
 
 	// Save last pool
 	if current != nil {
-		parseConfigSection(current, configLines)
+		parseConfigSection(current, configLines, slowColumn)
 		pools = append(pools, current)
 	}
 
 	return pools
 }
 
+// scanRatePatterns extract the scan rate, most useful first
+var scanRatePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`issued at (\S+/s)`),
+	regexp.MustCompile(`scanned at (\S+/s)`),
+	regexp.MustCompile(`\bat (\S+/s)`),
+}
+
 func parseScanState(p *PoolHealth) {
 	msg := p.ScanMessage
 	if strings.Contains(msg, "scrub in progress") {
@@ -225,10 +275,31 @@ func parseScanState(p *PoolHealth) {
 	} else if strings.Contains(msg, "resilvered") {
 		p.ScanState = "none"
 	}
+
+	if p.ScanInProgress() {
+		// e.g. "12.34% done, 01:23:45 to go"
+		re := regexp.MustCompile(`(\S+) to go`)
+		if matches := re.FindStringSubmatch(msg); len(matches) > 1 {
+			p.ScanETA = matches[1]
+		} else if strings.Contains(msg, "no estimated completion time") {
+			p.ScanETA = "unknown"
+		}
+
+		// e.g. "1.23T scanned at 456M/s, 1.00T issued at 400M/s, 5.00T total".
+		// The issue rate is what the drives are actually doing; older ZFS
+		// only prints "scanned out of ... at"
+		for _, re := range scanRatePatterns {
+			if matches := re.FindStringSubmatch(msg); len(matches) > 1 {
+				p.ScanRate = matches[1]
+				break
+			}
+		}
+	}
 }
 
-// parseConfigSection parses the config section lines into vdevs
-func parseConfigSection(p *PoolHealth, lines []string) {
+// parseConfigSection parses the config section lines into vdevs. slowColumn
+// is set when the header included SLOW after CKSUM
+func parseConfigSection(p *PoolHealth, lines []string, slowColumn bool) {
 	if len(lines) == 0 {
 		return
 	}
@@ -238,7 +309,7 @@ func parseConfigSection(p *PoolHealth, lines []string) {
 	var vdevStack []*VdevHealth
 
 	for _, line := range lines {
-		// Count leading tabs to determine depth
+		// Depth is the leading tab plus one per two spaces of nesting
 		depth := 0
 		for _, c := range line {
 			if c == '\t' {
@@ -247,18 +318,34 @@ func parseConfigSection(p *PoolHealth, lines []string) {
 				break
 			}
 		}
+		rest := strings.TrimLeft(line, "\t")
+		depth += (len(rest) - len(strings.TrimLeft(rest, " "))) / 2
 
-		// Parse the line: NAME STATE READ WRITE CKSUM
+		// Parse the line: NAME STATE READ WRITE CKSUM [SLOW]
 		fields := strings.Fields(line)
+
+		// Class headers (special, dedup, logs, cache, spares) have no
+		// state; their vdevs are nested under them like under the pool
+		if len(fields) == 1 && depth == 1 {
+			if t := determineVdevType(fields[0]); t != TypePool && t != TypeDisk {
+				p.Vdevs = append(p.Vdevs, VdevHealth{Name: fields[0], Type: t, Depth: depth})
+				vdevStack = []*VdevHealth{&p.Vdevs[len(p.Vdevs)-1]}
+			}
+			continue
+		}
 		if len(fields) < 5 {
 			continue
 		}
 
 		name := fields[0]
 		state := fields[1]
-		readErrs, _ := strconv.ParseInt(fields[2], 10, 64)
-		writeErrs, _ := strconv.ParseInt(fields[3], 10, 64)
-		cksumErrs, _ := strconv.ParseInt(fields[4], 10, 64)
+		readErrs := parseCount(fields[2])
+		writeErrs := parseCount(fields[3])
+		cksumErrs := parseCount(fields[4])
+		var slowIOs int64
+		if slowColumn && len(fields) >= 6 {
+			slowIOs = parseCount(fields[5])
+		}
 
 		vdev := VdevHealth{
 			Name:      name,
@@ -266,9 +353,14 @@ func parseConfigSection(p *PoolHealth, lines []string) {
 			ReadErrs:  readErrs,
 			WriteErrs: writeErrs,
 			CksumErrs: cksumErrs,
+			SlowIOs:   slowIOs,
 			Depth:     depth,
 			Type:      determineVdevType(name),
 		}
+		// Below the pool root, anything that isn't a group vdev is a disk
+		if vdev.Type == TypePool && depth > 1 {
+			vdev.Type = TypeDisk
+		}
 
 		// Set device path for leaf devices
 		if vdev.Type == TypeDisk {
@@ -281,6 +373,7 @@ func parseConfigSection(p *PoolHealth, lines []string) {
 
 		// Add errors to pool total
 		p.TotalErrors += readErrs + writeErrs + cksumErrs
+		p.TotalSlowIOs += slowIOs
 
 		// Build hierarchy based on depth
 		if depth == 1 {
@@ -308,6 +401,28 @@ func parseConfigSection(p *PoolHealth, lines []string) {
 	}
 }
 
+// parseCount parses a zpool status counter, which is abbreviated once large
+// (e.g. "1.2K"). Non-numeric values such as "-" count as zero
+func parseCount(s string) int64 {
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "K"):
+		mult = 1e3
+	case strings.HasSuffix(s, "M"):
+		mult = 1e6
+	case strings.HasSuffix(s, "G"):
+		mult = 1e9
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return int64(n * mult)
+}
+
 func determineVdevType(name string) string {
 	if strings.HasPrefix(name, "raidz") {
 		return TypeRaidz
@@ -324,6 +439,12 @@ func determineVdevType(name string) string {
 	if strings.HasPrefix(name, "cache") {
 		return TypeCache
 	}
+	if strings.HasPrefix(name, "special") {
+		return TypeSpecial
+	}
+	if strings.HasPrefix(name, "dedup") {
+		return TypeDedup
+	}
 	// If it starts with sd, nvme, or similar, it's a disk
 	if strings.HasPrefix(name, "sd") || strings.HasPrefix(name, "nvme") ||
 		strings.HasPrefix(name, "hd") || strings.HasPrefix(name, "vd") ||
@@ -336,7 +457,7 @@ func determineVdevType(name string) string {
 
 // ListPools returns the names of all pools
 func ListPools() ([]string, error) {
-	out, err := exec.Command("zpool", "list", "-H", "-o", "name").CombinedOutput()
+	out, err := privexec.Run("zpool", "list", "-H", "-o", "name")
 	if err != nil {
 		return nil, fmt.Errorf("failed to list pools: %w", err)
 	}
@@ -350,9 +471,56 @@ func ListPools() ([]string, error) {
 	return pools, nil
 }
 
+// PoolCapacity holds pool space usage from 'zpool list'
+type PoolCapacity struct {
+	Name        string `json:"name"`
+	Size        uint64 `json:"size_bytes"`
+	Alloc       uint64 `json:"alloc_bytes"`
+	Free        uint64 `json:"free_bytes"`
+	CapacityPct int    `json:"capacity_percent"`
+	FragPct     *int   `json:"fragmentation_percent,omitempty"` // nil when zpool reports "-"
+}
+
+// GetPoolCapacity returns the size, allocation and fragmentation of a pool
+func GetPoolCapacity(poolName string) (*PoolCapacity, error) {
+	out, err := privexec.Run("zpool", "list", "-Hp", "-o", "size,alloc,free,cap,frag", poolName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool capacity: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return parsePoolCapacity(poolName, string(out))
+}
+
+// parsePoolCapacity parses one line of 'zpool list -Hp -o size,alloc,free,cap,frag'
+func parsePoolCapacity(poolName, output string) (*PoolCapacity, error) {
+	fields := strings.Fields(strings.TrimSpace(output))
+	if len(fields) < 5 {
+		return nil, fmt.Errorf("unexpected zpool list output for %s: %q", poolName, strings.TrimSpace(output))
+	}
+
+	c := &PoolCapacity{Name: poolName}
+	var err error
+	if c.Size, err = strconv.ParseUint(fields[0], 10, 64); err != nil {
+		return nil, fmt.Errorf("failed to parse pool size: %w", err)
+	}
+	if c.Alloc, err = strconv.ParseUint(fields[1], 10, 64); err != nil {
+		return nil, fmt.Errorf("failed to parse pool allocation: %w", err)
+	}
+	if c.Free, err = strconv.ParseUint(fields[2], 10, 64); err != nil {
+		return nil, fmt.Errorf("failed to parse pool free space: %w", err)
+	}
+	if c.CapacityPct, err = strconv.Atoi(strings.TrimSuffix(fields[3], "%")); err != nil {
+		return nil, fmt.Errorf("failed to parse pool capacity: %w", err)
+	}
+	// Fragmentation is "-" on pools without the spacemap_histogram feature
+	if frag, err := strconv.Atoi(strings.TrimSuffix(fields[4], "%")); err == nil {
+		c.FragPct = &frag
+	}
+	return c, nil
+}
+
 // GetPoolProperty gets a single property from a pool
 func GetPoolProperty(poolName, property string) (string, error) {
-	out, err := exec.Command("zpool", "get", "-H", "-o", "value", property, poolName).CombinedOutput()
+	out, err := privexec.Run("zpool", "get", "-H", "-o", "value", property, poolName)
 	if err != nil {
 		return "", fmt.Errorf("failed to get pool property: %w", err)
 	}
@@ -0,0 +1,157 @@
+package zfs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iostatTimeout bounds 'zpool iostat' so an unresponsive pool can't hang the caller
+const iostatTimeout = 15 * time.Second
+
+// PoolIOStats holds per-second I/O rates for a pool and its vdevs
+type PoolIOStats struct {
+	Name       string        `json:"name"`
+	ReadOps    uint64        `json:"read_ops"`
+	WriteOps   uint64        `json:"write_ops"`
+	ReadBytes  uint64        `json:"read_bytes"`  // bytes/second
+	WriteBytes uint64        `json:"write_bytes"` // bytes/second
+	Vdevs      []VdevIOStats `json:"vdevs"`
+}
+
+// VdevIOStats holds I/O rates for a top-level vdev or leaf device
+type VdevIOStats struct {
+	Name       string        `json:"name"`
+	Type       string        `json:"type"`            // raidz, mirror, draid, disk
+	Class      string        `json:"class,omitempty"` // logs, cache, special, dedup, spares (empty = data)
+	ReadOps    uint64        `json:"read_ops"`
+	WriteOps   uint64        `json:"write_ops"`
+	ReadBytes  uint64        `json:"read_bytes"`
+	WriteBytes uint64        `json:"write_bytes"`
+	Children   []VdevIOStats `json:"children,omitempty"`
+}
+
+// iostatClasses are the allocation class headers zpool iostat prints
+var iostatClasses = map[string]bool{
+	"logs": true, "cache": true, "special": true, "dedup": true, "spares": true,
+}
+
+// GetPoolIOStats samples 'zpool iostat' over one second and returns per-vdev
+// operation and bandwidth rates. The first sample (averages since import) is
+// discarded; the second reflects current activity.
+func GetPoolIOStats(poolName string) (*PoolIOStats, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), iostatTimeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "zpool", "iostat", "-HpLv", poolName, "1", "2").CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return nil, fmt.Errorf("zpool iostat timed out after %s for pool %s", iostatTimeout, poolName)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool iostat: %w", err)
+	}
+
+	return parseZpoolIostat(string(out), poolName)
+}
+
+// parseZpoolIostat parses scripted (-H) 'zpool iostat -v' output containing
+// two samples. Without indentation, hierarchy is recovered from the alloc
+// column: top-level vdevs report capacity, their children show "-".
+func parseZpoolIostat(output, poolName string) (*PoolIOStats, error) {
+	var rows [][]string
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Split(strings.TrimRight(line, "\r"), "\t")
+		if len(fields) < 7 {
+			continue
+		}
+		rows = append(rows, fields)
+	}
+
+	// Find the start of the last sample (each begins with the pool row)
+	start := -1
+	for i, f := range rows {
+		if f[0] == poolName {
+			start = i
+		}
+	}
+	if start < 0 {
+		return nil, fmt.Errorf("pool not found in iostat output: %s", poolName)
+	}
+	rows = rows[start:]
+
+	stats := &PoolIOStats{Name: poolName}
+	stats.ReadOps, stats.WriteOps, stats.ReadBytes, stats.WriteBytes = parseIostatRates(rows[0])
+
+	class := ""
+	var top *VdevIOStats
+	for _, f := range rows[1:] {
+		name := f[0]
+		if iostatClasses[name] {
+			class = name
+			top = nil
+			continue
+		}
+
+		v := VdevIOStats{Name: name, Class: class}
+		v.ReadOps, v.WriteOps, v.ReadBytes, v.WriteBytes = parseIostatRates(f)
+
+		isTopLevel := f[1] != "-" || top == nil || class == "spares"
+		if isTopLevel {
+			v.Type = iostatVdevType(name)
+			stats.Vdevs = append(stats.Vdevs, v)
+			top = &stats.Vdevs[len(stats.Vdevs)-1]
+			// Single-disk vdevs have no children to attach
+			if v.Type == TypeDisk {
+				top = nil
+			}
+			continue
+		}
+
+		v.Type = TypeDisk
+		top.Children = append(top.Children, v)
+	}
+
+	return stats, nil
+}
+
+// parseIostatRates extracts read/write ops and bandwidth from an iostat row
+// (name, alloc, free, read ops, write ops, read bw, write bw)
+func parseIostatRates(f []string) (readOps, writeOps, readBytes, writeBytes uint64) {
+	parse := func(s string) uint64 {
+		n, _ := strconv.ParseUint(s, 10, 64)
+		return n
+	}
+	return parse(f[3]), parse(f[4]), parse(f[5]), parse(f[6])
+}
+
+// iostatVdevType classifies a top-level vdev name
+func iostatVdevType(name string) string {
+	switch {
+	case strings.HasPrefix(name, "raidz"):
+		return TypeRaidz
+	case strings.HasPrefix(name, "draid"):
+		return "draid"
+	case strings.HasPrefix(name, "mirror"):
+		return TypeMirror
+	default:
+		return TypeDisk
+	}
+}
+
+// Busiest returns the top-level vdev with the most operations per second,
+// usually the bottleneck during a resilver or heavy load. Returns nil if the
+// pool has no vdevs.
+func (p *PoolIOStats) Busiest() *VdevIOStats {
+	var busiest *VdevIOStats
+	for i := range p.Vdevs {
+		v := &p.Vdevs[i]
+		if busiest == nil || v.ReadOps+v.WriteOps > busiest.ReadOps+busiest.WriteOps {
+			busiest = v
+		}
+	}
+	return busiest
+}
@@ -2,24 +2,30 @@ package zfs
 
 import (
 	"fmt"
-	"os/exec"
 	"strings"
+	"time"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
 )
 
+// poolOpTimeout bounds commands that flush or open a whole pool, which can
+// take far longer than a query on a busy or large pool
+const poolOpTimeout = 10 * time.Minute
+
 // ExportPool safely exports a ZFS pool with sync
 func ExportPool(poolName string) error {
 	// 1. Sync filesystem buffers
-	if err := exec.Command("sync").Run(); err != nil {
+	if _, err := privexec.RunTimeout(poolOpTimeout, "sync"); err != nil {
 		return fmt.Errorf("sync failed: %w", err)
 	}
 
 	// 2. Sync the specific pool
-	if out, err := exec.Command("zpool", "sync", poolName).CombinedOutput(); err != nil {
+	if out, err := privexec.RunTimeout(poolOpTimeout, "zpool", "sync", poolName); err != nil {
 		return fmt.Errorf("zpool sync failed: %s: %w", strings.TrimSpace(string(out)), err)
 	}
 
 	// 3. Export the pool
-	if out, err := exec.Command("zpool", "export", poolName).CombinedOutput(); err != nil {
+	if out, err := privexec.RunTimeout(poolOpTimeout, "zpool", "export", poolName); err != nil {
 		return fmt.Errorf("zpool export failed: %s: %w", strings.TrimSpace(string(out)), err)
 	}
 
@@ -28,16 +34,64 @@ func ExportPool(poolName string) error {
 
 // ImportPool imports a previously exported ZFS pool
 func ImportPool(poolName string) error {
-	out, err := exec.Command("zpool", "import", poolName).CombinedOutput()
+	out, err := privexec.RunTimeout(poolOpTimeout, "zpool", "import", poolName)
 	if err != nil {
 		return fmt.Errorf("zpool import failed: %s: %w", strings.TrimSpace(string(out)), err)
 	}
 	return nil
 }
 
+// ReplaceDevice runs 'zpool replace', resilvering oldVdev (a device path or
+// vdev GUID) onto newDevice
+func ReplaceDevice(poolName, oldVdev, newDevice string) error {
+	out, err := privexec.RunTimeout(poolOpTimeout, "zpool", "replace", poolName, oldVdev, newDevice)
+	if err != nil {
+		return fmt.Errorf("zpool replace failed: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// TriggerScrub starts a scrub of a pool. Returns an error without starting
+// one if a scrub or resilver is already running. With dryRun only that check
+// is made.
+func TriggerScrub(poolName string, dryRun bool) error {
+	health, err := GetPoolHealth(poolName)
+	if err != nil {
+		return err
+	}
+	if health.ScanInProgress() {
+		return fmt.Errorf("pool '%s' already has a %s in progress (%.2f%% done, ETA %s)",
+			poolName, health.ScanState, health.ScanPercent, scanETAOrUnknown(health))
+	}
+	if dryRun {
+		return nil
+	}
+
+	if out, err := privexec.Run("zpool", "scrub", poolName); err != nil {
+		return fmt.Errorf("zpool scrub failed: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// CancelScrub stops a running scrub of a pool
+func CancelScrub(poolName string) error {
+	if out, err := privexec.Run("zpool", "scrub", "-s", poolName); err != nil {
+		return fmt.Errorf("zpool scrub -s failed: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+	return nil
+}
+
+// scanETAOrUnknown returns the scan ETA, or "unknown" if zpool gave none
+func scanETAOrUnknown(p *PoolHealth) string {
+	if p.ScanETA == "" {
+		return "unknown"
+	}
+	return p.ScanETA
+}
+
 // IsPoolImported checks if a pool is currently imported
 func IsPoolImported(poolName string) bool {
-	out, err := exec.Command("zpool", "list", "-H", "-o", "name").CombinedOutput()
+	out, err := privexec.Run("zpool", "list", "-H", "-o", "name")
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,156 @@
+package zfs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sigreer/jbodgod/internal/privexec"
+)
+
+// auditedProperties are the pool properties GetPoolProperties reads
+var auditedProperties = []string{"ashift", "autotrim", "autoreplace", "failmode"}
+
+// PoolProperties holds pool settings that are easy to get wrong and hard to
+// fix once the pool is in use
+type PoolProperties struct {
+	Name string `json:"name"`
+	// Raw property values as zpool get reports them
+	Properties map[string]string `json:"properties"`
+	// Ashift each top-level vdev was created with (e.g. mirror-0 -> 12).
+	// The pool's ashift property is only the default for new vdevs.
+	VdevAshift map[string]int `json:"vdev_ashift,omitempty"`
+}
+
+// GetPoolProperties returns the ashift, autotrim, autoreplace and failmode
+// properties of a pool and the ashift of each top-level vdev. Vdev ashifts
+// come from zdb and are left empty when it can't read the pool config (e.g.
+// a pool imported without a cachefile).
+func GetPoolProperties(poolName string) (*PoolProperties, error) {
+	out, err := privexec.Run("zpool", "get", "-Hp", "-o", "property,value",
+		strings.Join(auditedProperties, ","), poolName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pool properties: %s: %w", strings.TrimSpace(string(out)), err)
+	}
+
+	props := &PoolProperties{Name: poolName, Properties: make(map[string]string)}
+	for _, line := range strings.Split(string(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 {
+			props.Properties[fields[0]] = fields[1]
+		}
+	}
+
+	if out, err := privexec.Run("zdb", "-C", poolName); err == nil {
+		props.VdevAshift = parseZdbVdevAshift(string(out))
+	}
+	return props, nil
+}
+
+// Get returns a property value, or "" if it wasn't reported
+func (p *PoolProperties) Get(name string) string {
+	return p.Properties[name]
+}
+
+// AshiftMismatch reports whether the pool's top-level vdevs were created with
+// different ashifts. Log vdevs are included; a resilver onto a vdev with a
+// smaller ashift than its drives need is what makes this dangerous.
+func (p *PoolProperties) AshiftMismatch() bool {
+	seen := -1
+	for _, ashift := range p.VdevAshift {
+		if seen >= 0 && ashift != seen {
+			return true
+		}
+		seen = ashift
+	}
+	return false
+}
+
+// VdevAshiftSummary formats the vdev ashifts as "mirror-0=12, mirror-1=9"
+func (p *PoolProperties) VdevAshiftSummary() string {
+	names := make([]string, 0, len(p.VdevAshift))
+	for name := range p.VdevAshift {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s=%d", name, p.VdevAshift[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+// parseZdbVdevAshift extracts the ashift of each top-level vdev from
+// 'zdb -C <pool>' output. Top-level vdevs are the children of vdev_tree and
+// are named like zpool status names them (mirror-0, raidz2-1), or by device
+// path for single-disk vdevs.
+func parseZdbVdevAshift(output string) map[string]int {
+	ashifts := make(map[string]int)
+
+	type vdev struct {
+		kind, path string
+		id, ashift int
+	}
+	var current *vdev
+	topIndent, keyIndent := -1, -1
+
+	flush := func() {
+		if current == nil || current.ashift == 0 {
+			return
+		}
+		name := fmt.Sprintf("%s-%d", current.kind, current.id)
+		if current.kind == "disk" || current.kind == "file" {
+			name = current.path
+		}
+		ashifts[name] = current.ashift
+	}
+
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		indent := len(line) - len(strings.TrimLeft(line, " \t"))
+
+		if strings.HasPrefix(trimmed, "children[") {
+			// The first children[] under vdev_tree sets the top-level depth
+			if topIndent < 0 {
+				topIndent = indent
+			}
+			if indent == topIndent {
+				flush()
+				current = &vdev{}
+				keyIndent = -1
+			}
+			continue
+		}
+		if current == nil || indent <= topIndent {
+			continue
+		}
+
+		key, value, ok := strings.Cut(trimmed, ":")
+		if !ok {
+			continue
+		}
+		// Only the vdev's own keys, not those of its leaf children
+		if keyIndent < 0 {
+			keyIndent = indent
+		}
+		if indent != keyIndent {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), "'")
+		switch key {
+		case "type":
+			current.kind = value
+		case "id":
+			current.id, _ = strconv.Atoi(value)
+		case "path":
+			current.path = value
+		case "ashift":
+			current.ashift, _ = strconv.Atoi(value)
+		}
+	}
+	flush()
+
+	return ashifts
+}